@@ -0,0 +1,119 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// the keys of the node fields written after the embedded comment, taken from the json tags of [CommentNode]
+var (
+	commentETagKey    = jsonFieldKey(reflect.TypeFor[CommentNode](), "ETag")
+	commentRepliesKey = jsonFieldKey(reflect.TypeFor[CommentNode](), "Replies")
+)
+
+// jsonFieldKey returns the key of the struct field written by [json.Marshal], with the preceding comma, e.g. `,"etag":`.
+func jsonFieldKey(typ reflect.Type, name string) string {
+	field, ok := typ.FieldByName(name)
+	if !ok {
+		panic("api: no field " + name + " in " + typ.String())
+	}
+
+	key, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if key == "" {
+		key = field.Name
+	}
+
+	quoted, _ := json.Marshal(key)
+	return "," + string(quoted) + ":"
+}
+
+// commentTreeEncoder writes comment trees as JSON while walking them, so only a single comment
+// is held serialized in memory at a time rather than the whole response.
+// The output is byte for byte the same as of [json.Encoder].
+type commentTreeEncoder struct {
+	w   *bufio.Writer
+	buf bytes.Buffer
+	enc *json.Encoder
+	err error
+}
+
+func newCommentTreeEncoder(w io.Writer) *commentTreeEncoder {
+	e := &commentTreeEncoder{w: bufio.NewWriter(w)}
+	e.enc = json.NewEncoder(&e.buf)
+	return e
+}
+
+// EncodeResponse writes resp followed by a newline, like [json.Encoder.Encode].
+func (e *commentTreeEncoder) EncodeResponse(resp GetCommentsResponse) error {
+	e.writeString(`{"comments":`)
+	e.encodeNodes(resp.Comments)
+	e.writeString("}\n")
+	if e.err != nil {
+		return e.err
+	}
+	return e.w.Flush()
+}
+
+func (e *commentTreeEncoder) encodeNodes(nodes []*CommentNode) {
+	if nodes == nil {
+		e.writeString("null")
+		return
+	}
+
+	e.writeString("[")
+	for i, node := range nodes {
+		if i > 0 {
+			e.writeString(",")
+		}
+		e.encodeNode(node)
+	}
+	e.writeString("]")
+}
+
+func (e *commentTreeEncoder) encodeNode(node *CommentNode) {
+	if e.err != nil {
+		return
+	}
+
+	if node == nil {
+		e.writeString("null")
+		return
+	}
+
+	// the embedded comment is flattened into the node, so its object is left open for the replies
+	e.buf.Reset()
+	if e.err = e.enc.Encode(&node.CommentsWithAuthor); e.err != nil {
+		return
+	}
+	fields := bytes.TrimSuffix(e.buf.Bytes(), []byte("}\n"))
+	e.write(fields)
+
+	e.writeString(commentETagKey)
+	e.buf.Reset()
+	if e.err = e.enc.Encode(node.ETag); e.err != nil {
+		return
+	}
+	e.write(bytes.TrimSuffix(e.buf.Bytes(), []byte("\n")))
+
+	if len(node.Replies) > 0 {
+		e.writeString(commentRepliesKey)
+		e.encodeNodes(node.Replies)
+	}
+	e.writeString("}")
+}
+
+func (e *commentTreeEncoder) write(b []byte) {
+	if e.err == nil {
+		_, e.err = e.w.Write(b)
+	}
+}
+
+func (e *commentTreeEncoder) writeString(s string) {
+	if e.err == nil {
+		_, e.err = e.w.WriteString(s)
+	}
+}
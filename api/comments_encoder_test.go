@@ -0,0 +1,119 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	db "github.com/Drolfothesgnir/shitposter/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+// commentThread builds n comments of nRoots threads in depth-first order,
+// each thread being a mix of chains and siblings up to maxDepth deep.
+func commentThread(n, nRoots int, maxDepth int32) []db.CommentsWithAuthor {
+	comments := make([]db.CommentsWithAuthor, 0, n)
+	perRoot := n / nRoots
+	created := time.Date(2026, 4, 4, 12, 0, 0, 0, time.UTC)
+
+	var id int64
+	for range nRoots {
+		id++
+		c := root(id, 1)
+		c.Body = `root with <html> & "quotes"`
+		c.CreatedAt = created
+		comments = append(comments, c)
+
+		parents := []int64{id}
+		for i := 1; i < perRoot; i++ {
+			id++
+			// go one level deeper every third comment, otherwise reply at the same depth
+			depth := int32(len(parents))
+			if i%3 != 0 || depth > maxDepth {
+				depth = max(1, int32(i%len(parents)))
+				parents = parents[:depth]
+			}
+
+			c := child(id, parents[depth-1], depth, 1)
+			c.Body = "reply ÿ ✓ " + c.UserDisplayName
+			c.Upvotes = id % 7
+			c.CreatedAt = created.Add(time.Duration(id) * time.Second)
+			comments = append(comments, c)
+			parents = append(parents, id)
+		}
+	}
+
+	return comments
+}
+
+func TestCommentTreeEncoder_MatchesJSONEncoder(t *testing.T) {
+	tree, err := PrepareCommentTree(commentThread(500, 10, 8), 10)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name string
+		resp GetCommentsResponse
+	}{
+		{"Nil", GetCommentsResponse{}},
+		{"Empty", GetCommentsResponse{Comments: []*CommentNode{}}},
+		{"Thread", GetCommentsResponse{Comments: tree}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var want bytes.Buffer
+			require.NoError(t, json.NewEncoder(&want).Encode(tc.resp))
+
+			var got bytes.Buffer
+			require.NoError(t, newCommentTreeEncoder(&got).EncodeResponse(tc.resp))
+
+			require.Equal(t, want.String(), got.String())
+		})
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, io.ErrClosedPipe }
+
+func TestCommentTreeEncoder_WriteError(t *testing.T) {
+	tree, err := PrepareCommentTree(commentThread(10, 2, 3), 2)
+	require.NoError(t, err)
+
+	err = newCommentTreeEncoder(failingWriter{}).EncodeResponse(GetCommentsResponse{Comments: tree})
+	require.ErrorIs(t, err, io.ErrClosedPipe)
+}
+
+// BenchmarkEncodeComments compares the memory used to write a 10k-comment thread
+// by marshaling the whole response into one buffer and by streaming the tree.
+func BenchmarkEncodeComments(b *testing.B) {
+	tree, err := PrepareCommentTree(commentThread(10_000, 100, 10), 100)
+	if err != nil {
+		b.Fatal(err)
+	}
+	resp := GetCommentsResponse{Comments: tree}
+
+	// json.Encoder materializes the whole response too, but reuses the buffer from a pool,
+	// which hides the allocation from the stats while keeping the memory resident
+	b.Run("Buffered", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			body, err := json.Marshal(resp)
+			if err != nil {
+				b.Fatal(err)
+			}
+			io.Discard.Write(body)
+		}
+	})
+
+	b.Run("Streaming", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			if err := newCommentTreeEncoder(io.Discard).EncodeResponse(resp); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
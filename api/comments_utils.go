@@ -10,6 +10,9 @@ import (
 
 type CommentNode struct {
 	db.CommentsWithAuthor
+	// ETag is the version ETag of the comment, to be sent in If-Match when the comment is modified.
+	// The response itself is tagged by its content, which changes with the votes.
+	ETag    string         `json:"etag"`
 	Replies []*CommentNode `json:"replies,omitempty"`
 }
 
@@ -31,6 +34,7 @@ func PrepareCommentTree(orderedPlainComments []db.CommentsWithAuthor, n_roots in
 		// taking &CommentNode instead of &comment is crucial to avoit address-of-loop-variable bug
 		comment := &CommentNode{
 			CommentsWithAuthor: orderedPlainComments[i],
+			ETag:               versionETag(etagComment, orderedPlainComments[i].ID, orderedPlainComments[i].LastModifiedAt),
 		}
 
 		d := int(comment.Depth)
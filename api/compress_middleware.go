@@ -0,0 +1,261 @@
+package api
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// compressMinSize is the smallest response body worth compressing,
+// below it the encoding overhead outweighs the savings.
+const compressMinSize = 1024
+
+// compressibleTypes are the media types which are compressed, besides text/*.
+// Everything else, e.g. images, is most likely already compressed.
+var compressibleTypes = []string{
+	contentJSON,
+	"application/problem+json",
+	"application/javascript",
+	"application/xml",
+	"image/svg+xml",
+}
+
+const (
+	encodingGzip    = "gzip"
+	encodingDeflate = "deflate"
+)
+
+// compressor is implemented by both [gzip.Writer] and [zlib.Writer].
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// the writers allocate large internal tables, so they are reused between responses
+var compressorPools = map[string]*sync.Pool{
+	encodingGzip: {New: func() any { return gzip.NewWriter(io.Discard) }},
+	// "deflate" in HTTP is the zlib format, not the raw DEFLATE stream
+	encodingDeflate: {New: func() any { return zlib.NewWriter(io.Discard) }},
+}
+
+// negotiateEncoding picks the preferred content-coding from the Accept-Encoding header,
+// preferring gzip over deflate on equal weights. Returns "" if neither is acceptable.
+func negotiateEncoding(header string) string {
+	weights := make(map[string]float64, 3)
+	for entry := range strings.SplitSeq(header, ",") {
+		coding, params, _ := strings.Cut(entry, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+
+		q := 1.0
+		for param := range strings.SplitSeq(params, ";") {
+			name, value, _ := strings.Cut(param, "=")
+			if strings.TrimSpace(name) != "q" {
+				continue
+			}
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				parsed = 0
+			}
+			q = parsed
+		}
+
+		weights[coding] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, coding := range []string{encodingGzip, encodingDeflate} {
+		q, ok := weights[coding]
+		if !ok {
+			q, ok = weights["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = coding, q
+		}
+	}
+
+	return best
+}
+
+// compressible reports whether the media type of the Content-Type header is worth compressing.
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return strings.HasPrefix(mediaType, "text/") || slices.Contains(compressibleTypes, mediaType)
+}
+
+// compressWriter holds back the beginning of the response until either [compressMinSize] bytes
+// are written or the handler returns, then decides whether to compress the rest.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	status   int
+	pending  []byte
+	decided  bool
+	// zw is nil when the response is passed through as is
+	zw compressor
+	// revalidatesCoded is true if the client revalidates a copy it got compressed with the encoding
+	revalidatesCoded bool
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.decided {
+		// superfluous call, let the server report it
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if cw.status != 0 {
+		return
+	}
+
+	cw.status = code
+	// responses without bodies are never compressed
+	if code < http.StatusOK || code == http.StatusNoContent || code == http.StatusNotModified {
+		// 304 carries the ETag of the copy being revalidated
+		if code == http.StatusNotModified && cw.revalidatesCoded {
+			cw.tagCoding()
+		}
+		cw.decide(false)
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.decided {
+		if !cw.eligible() {
+			cw.decide(false)
+		} else {
+			cw.pending = append(cw.pending, b...)
+			if len(cw.pending) >= compressMinSize {
+				if err := cw.decide(true); err != nil {
+					return 0, err
+				}
+			}
+			return len(b), nil
+		}
+	}
+
+	if cw.zw != nil {
+		return cw.zw.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// Flush sends what was written so far, so streaming handlers are not stalled by the threshold.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		cw.decide(cw.eligible() && len(cw.pending) > 0)
+	}
+
+	if cw.zw != nil {
+		cw.zw.Flush()
+	}
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Unwrap allows [http.ResponseController] to reach the underlying writer.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// eligible reports whether the response can be compressed based on its headers.
+func (cw *compressWriter) eligible() bool {
+	header := cw.Header()
+	return header.Get("Content-Encoding") == "" && compressible(header.Get("Content-Type"))
+}
+
+// decide sends the header and the pending bytes, compressed or not.
+func (cw *compressWriter) decide(compress bool) error {
+	cw.decided = true
+
+	if compress {
+		header := cw.Header()
+		header.Set("Content-Encoding", cw.encoding)
+		header.Del("Content-Length")
+		cw.tagCoding()
+
+		cw.zw = compressorPools[cw.encoding].Get().(compressor)
+		cw.zw.Reset(cw.ResponseWriter)
+	}
+
+	if cw.status != 0 {
+		cw.ResponseWriter.WriteHeader(cw.status)
+	}
+
+	if len(cw.pending) == 0 {
+		return nil
+	}
+
+	pending := cw.pending
+	cw.pending = nil
+	if cw.zw != nil {
+		_, err := cw.zw.Write(pending)
+		return err
+	}
+	_, err := cw.ResponseWriter.Write(pending)
+	return err
+}
+
+// tagCoding replaces the strong ETag of the response with the one of its compressed representation,
+// since the byte-for-byte equality of the strong tags doesn't hold across the codings.
+func (cw *compressWriter) tagCoding() {
+	header := cw.Header()
+	if etag := header.Get("ETag"); etag != "" {
+		header.Set("ETag", codingETag(etag, cw.encoding))
+	}
+}
+
+// close flushes the held back bytes of short responses and finishes the compressed stream.
+func (cw *compressWriter) close() error {
+	if !cw.decided {
+		cw.decide(false)
+	}
+
+	if cw.zw == nil {
+		return nil
+	}
+
+	err := cw.zw.Close()
+	cw.zw.Reset(io.Discard)
+	compressorPools[cw.encoding].Put(cw.zw)
+	cw.zw = nil
+
+	return err
+}
+
+// compressMiddleware compresses the responses with gzip or deflate, as negotiated with
+// the Accept-Encoding header, if their type is in the allowlist and they are at least [compressMinSize] long.
+//
+// The strong ETags of the compressed responses get the coding appended, see [codingETag],
+// and Vary: Accept-Encoding keeps the shared caches from mixing up the codings.
+func compressMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{
+			ResponseWriter:   w,
+			encoding:         encoding,
+			revalidatesCoded: strings.Contains(r.Header.Get("If-None-Match"), "-"+encoding+`"`),
+		}
+		defer cw.close()
+
+		next.ServeHTTP(cw, r)
+	})
+}
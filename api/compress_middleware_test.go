@@ -0,0 +1,265 @@
+package api
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	testCases := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"br", ""},
+		{"gzip", encodingGzip},
+		{"deflate", encodingDeflate},
+		{"deflate, gzip", encodingGzip},
+		{"GZIP;q=0.5, deflate", encodingDeflate},
+		{"gzip;q=0, deflate;q=0.1", encodingDeflate},
+		{"gzip;q=0", ""},
+		{"*", encodingGzip},
+		{"*;q=0.2, gzip;q=0", encodingDeflate},
+		{"gzip;q=bogus", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.header, func(t *testing.T) {
+			require.Equal(t, tc.want, negotiateEncoding(tc.header))
+		})
+	}
+}
+
+func TestCompressMiddleware(t *testing.T) {
+	large := strings.Repeat(`{"body":"comment"},`, compressMinSize)
+	small := `{"body":"comment"}`
+
+	respond := func(contentType, body string, status int) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if contentType != "" {
+				w.Header().Set("Content-Type", contentType)
+			}
+			w.WriteHeader(status)
+			// write in chunks to cross the threshold in the middle of a write
+			for chunk := range chunkString(body, 100) {
+				w.Write([]byte(chunk))
+			}
+		})
+	}
+
+	decoders := map[string]func(io.Reader) (io.Reader, error){
+		encodingGzip: func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		encodingDeflate: func(r io.Reader) (io.Reader, error) {
+			return zlib.NewReader(r)
+		},
+	}
+
+	testCases := []struct {
+		name           string
+		acceptEncoding string
+		method         string
+		handler        http.Handler
+		wantStatus     int
+		wantEncoding   string
+		wantBody       string
+	}{
+		{
+			name:           "Gzip",
+			acceptEncoding: "gzip, deflate",
+			handler:        respond(contentJSON, large, http.StatusOK),
+			wantStatus:     http.StatusOK,
+			wantEncoding:   encodingGzip,
+			wantBody:       large,
+		},
+		{
+			name:           "Deflate",
+			acceptEncoding: "deflate",
+			handler:        respond(contentJSON, large, http.StatusCreated),
+			wantStatus:     http.StatusCreated,
+			wantEncoding:   encodingDeflate,
+			wantBody:       large,
+		},
+		{
+			name:           "NotAccepted",
+			acceptEncoding: "br",
+			handler:        respond(contentJSON, large, http.StatusOK),
+			wantStatus:     http.StatusOK,
+			wantBody:       large,
+		},
+		{
+			name:           "BelowThreshold",
+			acceptEncoding: "gzip",
+			handler:        respond(contentJSON, small, http.StatusOK),
+			wantStatus:     http.StatusOK,
+			wantBody:       small,
+		},
+		{
+			name:           "TypeNotAllowed",
+			acceptEncoding: "gzip",
+			handler:        respond("image/png", large, http.StatusOK),
+			wantStatus:     http.StatusOK,
+			wantBody:       large,
+		},
+		{
+			name:           "TextType",
+			acceptEncoding: "gzip",
+			handler:        respond("text/plain; charset=utf-8", large, http.StatusOK),
+			wantStatus:     http.StatusOK,
+			wantEncoding:   encodingGzip,
+			wantBody:       large,
+		},
+		{
+			name:           "NotModified",
+			acceptEncoding: "gzip",
+			handler:        respond(contentJSON, "", http.StatusNotModified),
+			wantStatus:     http.StatusNotModified,
+		},
+		{
+			name:           "Head",
+			acceptEncoding: "gzip",
+			method:         http.MethodHead,
+			handler:        respond(contentJSON, "", http.StatusOK),
+			wantStatus:     http.StatusOK,
+		},
+		{
+			name:           "AlreadyEncoded",
+			acceptEncoding: "gzip",
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", contentJSON)
+				w.Header().Set("Content-Encoding", "br")
+				w.Write([]byte(large))
+			}),
+			wantStatus:   http.StatusOK,
+			wantEncoding: "br",
+			wantBody:     large,
+		},
+		{
+			name:           "ImplicitStatus",
+			acceptEncoding: "gzip",
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", contentJSON)
+				w.Write([]byte(large))
+			}),
+			wantStatus:   http.StatusOK,
+			wantEncoding: encodingGzip,
+			wantBody:     large,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			method := tc.method
+			if method == "" {
+				method = http.MethodGet
+			}
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(method, "/", nil)
+			request.Header.Set("Accept-Encoding", tc.acceptEncoding)
+
+			compressMiddleware(tc.handler).ServeHTTP(recorder, request)
+
+			require.Equal(t, tc.wantStatus, recorder.Code)
+			require.Equal(t, tc.wantEncoding, recorder.Header().Get("Content-Encoding"))
+			require.Contains(t, recorder.Header().Values("Vary"), "Accept-Encoding")
+
+			var body io.Reader = recorder.Body
+			if decode, ok := decoders[tc.wantEncoding]; ok {
+				var err error
+				body, err = decode(body)
+				require.NoError(t, err)
+			}
+
+			got, err := io.ReadAll(body)
+			require.NoError(t, err)
+			require.Equal(t, tc.wantBody, string(got))
+		})
+	}
+}
+
+func TestCompressMiddleware_ETag(t *testing.T) {
+	large := strings.Repeat(`{"body":"comment"},`, compressMinSize)
+
+	respond := func(body string, status int) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("ETag", `"abc"`)
+			w.Header().Set("Content-Type", contentJSON)
+			w.WriteHeader(status)
+			w.Write([]byte(body))
+		})
+	}
+
+	testCases := []struct {
+		name        string
+		handler     http.Handler
+		ifNoneMatch string
+		wantETag    string
+	}{
+		{name: "Compressed", handler: respond(large, http.StatusOK), wantETag: `"abc-gzip"`},
+		{name: "BelowThreshold", handler: respond("{}", http.StatusOK), wantETag: `"abc"`},
+		{name: "NotModifiedCompressedCopy", handler: respond("", http.StatusNotModified), ifNoneMatch: `"abc-gzip"`, wantETag: `"abc-gzip"`},
+		{name: "NotModifiedIdentityCopy", handler: respond("", http.StatusNotModified), ifNoneMatch: `"abc"`, wantETag: `"abc"`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			request.Header.Set("Accept-Encoding", "gzip")
+			if tc.ifNoneMatch != "" {
+				request.Header.Set("If-None-Match", tc.ifNoneMatch)
+			}
+			recorder := httptest.NewRecorder()
+
+			compressMiddleware(tc.handler).ServeHTTP(recorder, request)
+
+			require.Equal(t, tc.wantETag, recorder.Header().Get("ETag"))
+		})
+	}
+}
+
+func TestCompressMiddleware_Flush(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentJSON)
+		w.Write([]byte(`{"comments":[`))
+		require.NoError(t, http.NewResponseController(w).Flush())
+		w.Write([]byte(`]}`))
+	})
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set("Accept-Encoding", "gzip")
+
+	compressMiddleware(handler).ServeHTTP(recorder, request)
+
+	require.True(t, recorder.Flushed)
+	require.Equal(t, encodingGzip, recorder.Header().Get("Content-Encoding"))
+
+	zr, err := gzip.NewReader(recorder.Body)
+	require.NoError(t, err)
+	got, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	require.Equal(t, `{"comments":[]}`, string(got))
+}
+
+// chunkString splits s into chunks of at most n bytes.
+func chunkString(s string, n int) func(yield func(string) bool) {
+	return func(yield func(string) bool) {
+		for len(s) > n {
+			if !yield(s[:n]) {
+				return
+			}
+			s = s[n:]
+		}
+		if s != "" {
+			yield(s)
+		}
+	}
+}
@@ -6,7 +6,7 @@ import (
 	"strings"
 )
 
-func (s Service) corsMiddleware(next http.Handler) http.Handler {
+func (s *Service) corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// 1. Always tell caches that this response varies based on the Origin
 		w.Header().Add("Vary", "Origin")
@@ -27,8 +27,12 @@ func (s Service) corsMiddleware(next http.Handler) http.Handler {
 				"Content-Type",
 				"Authorization",
 				WebauthnTransportHeader, // Assuming this is defined elsewhere in your package
+				"If-Match",
+				"If-None-Match",
 			}
 			w.Header().Set("Access-Control-Allow-Headers", strings.Join(allowedHeaders, ","))
+			// let the browser scripts read the versions for the If-Match of the following writes
+			w.Header().Set("Access-Control-Expose-Headers", "ETag")
 		}
 
 		// 3. Intercept preflight OPTIONS requests
@@ -34,7 +34,7 @@ func TestCORSMiddleware(t *testing.T) {
 			wantAllowOrigin:      allowedOrigin,
 			wantAllowCredentials: "true",
 			wantAllowMethods:     "GET, POST, PUT, PATCH, DELETE, OPTIONS",
-			wantAllowHeaders:     "Content-Type,Authorization," + WebauthnTransportHeader,
+			wantAllowHeaders:     "Content-Type,Authorization," + WebauthnTransportHeader + ",If-Match,If-None-Match",
 		},
 		{
 			name:           "DisallowedOriginPassesThroughWithoutCORSHeaders",
@@ -54,7 +54,7 @@ func TestCORSMiddleware(t *testing.T) {
 			wantAllowOrigin:      allowedOrigin,
 			wantAllowCredentials: "true",
 			wantAllowMethods:     "GET, POST, PUT, PATCH, DELETE, OPTIONS",
-			wantAllowHeaders:     "Content-Type,Authorization," + WebauthnTransportHeader,
+			wantAllowHeaders:     "Content-Type,Authorization," + WebauthnTransportHeader + ",If-Match,If-None-Match",
 		},
 		{
 			name:           "PreflightWithoutAllowedOriginStopsChainWithoutCORSHeaders",
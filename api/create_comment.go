@@ -9,12 +9,12 @@ import (
 )
 
 type CreateCommentRequest struct {
-	Body string `json:"body"`
+	Body string `json:"body" openapi:"required,max=500"`
 }
 
 func (r CreateCommentRequest) Validate() *Vomit {
 	issues := make([]Issue, 0)
-	validate(&issues, r.Body, "body", strRequired, strMax(500))
+	validate(&issues, r.Body, "body", strRequired, strMax(commentBodyMaxLen))
 	return barf(issues)
 }
 
@@ -38,6 +38,12 @@ func (s *Service) createComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	poop, vErr := munchMarkup(ctx, s.commentEater, req.Body, "body")
+	if vErr != nil {
+		abortWithError(w, vErr)
+		return
+	}
+
 	// extracting comment id to check if comment is a reply
 	// i.e. comment_id from /posts/:post_id/comments/:comment_id is available
 	desc := getCommentIDDescriptor(r)
@@ -54,12 +60,19 @@ func (s *Service) createComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// the mentioned users are recorded with the comment, so they can be notified
+	var mentioned []int64
+	for _, m := range poop.Mentions {
+		mentioned = append(mentioned, m.UserID)
+	}
+
 	// otherwise assume the comment is a reply
 	arg := db.InsertCommentTxParams{
-		UserID:   authPayload.UserID,
-		PostID:   postID,
-		Body:     req.Body,
-		ParentID: pgtype.Int8{Int64: desc.parsedValue, Valid: desc.valid},
+		UserID:           authPayload.UserID,
+		PostID:           postID,
+		Body:             req.Body,
+		ParentID:         pgtype.Int8{Int64: desc.parsedValue, Valid: desc.valid},
+		MentionedUserIDs: mentioned,
 	}
 
 	comment, err := s.store.InsertCommentTx(ctx, arg)
@@ -69,5 +82,6 @@ func (s *Service) createComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	w.Header().Set("ETag", versionETag(etagComment, comment.ID, comment.LastModifiedAt))
 	respondWithJSON(w, http.StatusOK, comment)
 }
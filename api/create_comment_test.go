@@ -77,6 +77,29 @@ func TestCreateComment(t *testing.T) {
 				require.Equal(t, "max", resp.Issues[0].Tag)
 			},
 		},
+		{
+			name: "MarkupBudgetExceeded",
+			url:  "/posts/1/comments",
+			body: reqBody{
+				"body": strings.Repeat("[a]!href{/a}", 11),
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().InsertCommentTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				setAuthorizationHeader(t, tokenMaker, authorizationTypeBearer, user.ID, time.Minute, request)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				var resp Vomit
+				err := json.NewDecoder(recorder.Body).Decode(&resp)
+				require.NoError(t, err)
+				require.Equal(t, ReqInvalidArguments, resp.Reason)
+				require.Len(t, resp.Issues, 1)
+				require.Equal(t, "body", resp.Issues[0].FieldName)
+				require.Equal(t, validatorMaxLinks, resp.Issues[0].Tag)
+			},
+		},
 		{
 			name: "InvalidParentId",
 			url:  "/posts/1/comments/inv_par_id",
@@ -310,6 +333,55 @@ func TestCreateComment(t *testing.T) {
 				require.Equal(t, http.StatusOK, recorder.Code)
 			},
 		},
+		{
+			name: "OKMentions",
+			url:  "/posts/1/comments",
+			body: reqBody{
+				"body": "hi @alice and @ghost, @alice",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ResolveUsernames(gomock.Any(), []string{"alice", "ghost"}).
+					Times(1).
+					Return(map[string]int64{"alice": 7}, nil)
+
+				arg := db.InsertCommentTxParams{
+					UserID:           user.ID,
+					PostID:           1,
+					Body:             "hi @alice and @ghost, @alice",
+					MentionedUserIDs: []int64{7},
+				}
+
+				store.EXPECT().InsertCommentTx(gomock.Any(), arg).Times(1).Return(db.Comment{ID: 2, UserID: user.ID, PostID: 1}, nil)
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				setAuthorizationHeader(t, tokenMaker, authorizationTypeBearer, user.ID, time.Minute, request)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "MentionsNotVerified",
+			url:  "/posts/1/comments",
+			body: reqBody{
+				"body": "hi @alice",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ResolveUsernames(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil, &db.OpError{Kind: db.KindInternal})
+
+				store.EXPECT().InsertCommentTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				setAuthorizationHeader(t, tokenMaker, authorizationTypeBearer, user.ID, time.Minute, request)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
 		{
 			name: "OKReply",
 			url:  "/posts/1/comments/1",
@@ -20,10 +20,17 @@ func (s *Service) deleteComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	version, vErr := ifMatchVersion(r, etagComment, commentID)
+	if vErr != nil {
+		abortWithError(w, vErr)
+		return
+	}
+
 	_, err := s.store.DeleteCommentTx(ctx, db.DeleteCommentTxParams{
-		CommentID: commentID,
-		UserID:    authPayload.UserID,
-		PostID:    postID,
+		CommentID:        commentID,
+		UserID:           authPayload.UserID,
+		PostID:           postID,
+		IfLastModifiedAt: version,
 	})
 
 	if err != nil {
@@ -2,13 +2,24 @@ package api
 
 import (
 	"net/http"
+
+	db "github.com/Drolfothesgnir/shitposter/db/sqlc"
 )
 
 func (service *Service) deleteUser(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	authPayload := getAuthPayload(ctx)
 
-	_, err := service.store.SoftDeleteUserTx(ctx, authPayload.UserID)
+	version, vErr := ifMatchVersion(r, etagUser, authPayload.UserID)
+	if vErr != nil {
+		abortWithError(w, vErr)
+		return
+	}
+
+	_, err := service.store.SoftDeleteUserTx(ctx, db.SoftDeleteUserTxParams{
+		UserID:           authPayload.UserID,
+		IfLastModifiedAt: version,
+	})
 	if err != nil {
 		opErr := newResourceError(err)
 		abortWithError(w, opErr)
@@ -29,7 +29,7 @@ func TestSoftDeleteUser(t *testing.T) {
 		{
 			name: "UserNotFound",
 			buildStubs: func(store *mockdb.MockStore) {
-				store.EXPECT().SoftDeleteUserTx(gomock.Any(), user.ID).Times(1).Return(
+				store.EXPECT().SoftDeleteUserTx(gomock.Any(), db.SoftDeleteUserTxParams{UserID: user.ID}).Times(1).Return(
 					db.SoftDeleteUserTxResult{},
 					&db.OpError{
 						Op:       "soft-delete-user",
@@ -63,7 +63,7 @@ func TestSoftDeleteUser(t *testing.T) {
 		{
 			name: "SoftDeleteUserTxErr",
 			buildStubs: func(store *mockdb.MockStore) {
-				store.EXPECT().SoftDeleteUserTx(gomock.Any(), user.ID).Times(1).Return(
+				store.EXPECT().SoftDeleteUserTx(gomock.Any(), db.SoftDeleteUserTxParams{UserID: user.ID}).Times(1).Return(
 					db.SoftDeleteUserTxResult{},
 					&db.OpError{
 						Op:     "soft-delete-user",
@@ -96,7 +96,7 @@ func TestSoftDeleteUser(t *testing.T) {
 		{
 			name: "OK",
 			buildStubs: func(store *mockdb.MockStore) {
-				store.EXPECT().SoftDeleteUserTx(gomock.Any(), user.ID).Times(1).Return(
+				store.EXPECT().SoftDeleteUserTx(gomock.Any(), db.SoftDeleteUserTxParams{UserID: user.ID}).Times(1).Return(
 					db.SoftDeleteUserTxResult{
 						ID:        user.ID,
 						Username:  "deleted",
@@ -0,0 +1,194 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cache-Control policies of the read endpoints.
+const (
+	// cacheUser lets shared caches keep public profiles for a short time,
+	// after which they revalidate with the ETag.
+	cacheUser = "public, max-age=60"
+	// cacheComments forces revalidation on every use, since votes change the trees constantly.
+	cacheComments = "public, no-cache"
+)
+
+// ETag prefixes of the versioned resources.
+const (
+	etagUser    = "u"
+	etagComment = "c"
+)
+
+// versionETag returns the strong ETag of a versioned resource, which changes
+// whenever the resource's last_modified_at does, e.g. "u42.1712232000000000".
+func versionETag(prefix string, id int64, lastModifiedAt time.Time) string {
+	return fmt.Sprintf(`"%s%d.%d"`, prefix, id, lastModifiedAt.UnixMicro())
+}
+
+// parseVersionETag is the inverse of [versionETag].
+// It returns false if the tag is weak or doesn't belong to the resource.
+func parseVersionETag(tag, prefix string, id int64) (time.Time, bool) {
+	opaque, ok := strings.CutPrefix(tag, `"`+prefix+strconv.FormatInt(id, 10)+".")
+	if !ok {
+		return time.Time{}, false
+	}
+
+	opaque, ok = strings.CutSuffix(opaque, `"`)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	micro, err := strconv.ParseInt(opaque, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return time.UnixMicro(micro).UTC(), true
+}
+
+// hashETag returns the strong ETag of the response body from its SHA-256 sum.
+func hashETag(sum []byte) string {
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+}
+
+// codingETag returns the ETag of the representation compressed with the content-coding,
+// e.g. "abc-gzip" for "abc", so it differs from the tags of the other codings of the same content.
+// Weak tags are returned as is, they don't have to change with the coding.
+func codingETag(etag, coding string) string {
+	opaque, ok := strings.CutSuffix(etag, `"`)
+	if !ok || !strings.HasPrefix(etag, `"`) {
+		return etag
+	}
+	return opaque + "-" + coding + `"`
+}
+
+// trimCodingETag is the inverse of [codingETag], so the preconditions match the tags of all codings
+// of the current content. Other tags are returned as is.
+func trimCodingETag(tag string) string {
+	for _, coding := range []string{encodingGzip, encodingDeflate} {
+		if opaque, ok := strings.CutSuffix(tag, "-"+coding+`"`); ok {
+			return opaque + `"`
+		}
+	}
+	return tag
+}
+
+// splitETags splits the list of entity tags of the If-Match and If-None-Match headers.
+func splitETags(header string) []string {
+	var tags []string
+	for tag := range strings.SplitSeq(header, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// notModified evaluates the If-None-Match and If-Modified-Since preconditions of the GET request
+// and reports whether the client's copy is fresh. If-Modified-Since is ignored when If-None-Match is present,
+// and so is a zero lastModified.
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		for _, tag := range splitETags(header) {
+			// If-None-Match uses the weak comparison
+			if tag == "*" || trimCodingETag(strings.TrimPrefix(tag, "W/")) == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if lastModified.IsZero() {
+		return false
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+
+	// Last-Modified has a precision of one second
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// respondWithVersionedJSON responds with the body of a versioned resource
+// or with 304 if the client's copy is still fresh. Last-Modified is omitted if lastModified is zero.
+func respondWithVersionedJSON(w http.ResponseWriter, r *http.Request, etag string, lastModified time.Time, cacheControl string, body any) {
+	header := w.Header()
+	header.Set("ETag", etag)
+	if !lastModified.IsZero() {
+		header.Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	header.Set("Cache-Control", cacheControl)
+
+	if notModified(r, etag, lastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, body)
+}
+
+// respondWithHashedJSON responds with the body written by encode, tagged with the hash of its serialization,
+// or with 304 if the client's copy is still fresh.
+// Since the ETag has to be sent before the body, encode is called twice, first into the hash
+// and then into the response, so the body is never held in memory as a whole. It must write the same bytes both times.
+func respondWithHashedJSON(w http.ResponseWriter, r *http.Request, cacheControl string, encode func(io.Writer) error) {
+	hash := sha256.New()
+	if err := encode(hash); err != nil {
+		respondWithJSON(w, http.StatusInternalServerError, internalResourceError())
+		return
+	}
+
+	etag := hashETag(hash.Sum(nil))
+	header := w.Header()
+	header.Set("ETag", etag)
+	header.Set("Cache-Control", cacheControl)
+
+	if notModified(r, etag, time.Time{}) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	header.Set("Content-Type", contentJSON)
+	w.WriteHeader(http.StatusOK)
+	// the headers are sent, so a failed write can only be a disconnected client
+	encode(w)
+}
+
+// ifMatchVersion extracts the version of the resource the client expects to modify from the If-Match header.
+// Returns nil if the header is absent or "*", in which case the write is unconditional.
+// Since the version ETags are strong, weak tags and tags of other resources never match and result in 412.
+// The tags of the compressed representations, see [codingETag], match as the version they were derived from.
+func ifMatchVersion(r *http.Request, prefix string, id int64) (*time.Time, *Vomit) {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return nil, nil
+	}
+
+	for _, tag := range splitETags(header) {
+		if tag == "*" {
+			return nil, nil
+		}
+
+		if version, ok := parseVersionETag(trimCodingETag(tag), prefix, id); ok {
+			return &version, nil
+		}
+	}
+
+	vErr := puke(
+		ReqPreconditionFailed,
+		http.StatusPreconditionFailed,
+		fmt.Sprintf("If-Match %q does not match the current version of the resource", header),
+		nil,
+	)
+
+	return nil, vErr
+}
@@ -0,0 +1,213 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/Drolfothesgnir/shitposter/db/mock"
+	db "github.com/Drolfothesgnir/shitposter/db/sqlc"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestVersionETag_RoundTrip(t *testing.T) {
+	version := time.Date(2026, 4, 4, 12, 0, 0, 123456000, time.UTC)
+	tag := versionETag(etagUser, 42, version)
+	require.Equal(t, `"u42.1775304000123456"`, tag)
+
+	parsed, ok := parseVersionETag(tag, etagUser, 42)
+	require.True(t, ok)
+	require.Equal(t, version, parsed)
+
+	_, ok = parseVersionETag(tag, etagUser, 4)
+	require.False(t, ok, "tag of another user")
+	_, ok = parseVersionETag(tag, etagComment, 42)
+	require.False(t, ok, "tag of another resource")
+	_, ok = parseVersionETag("W/"+tag, etagUser, 42)
+	require.False(t, ok, "weak tag")
+	_, ok = parseVersionETag(`"u42.abc"`, etagUser, 42)
+	require.False(t, ok, "malformed tag")
+}
+
+func TestNotModified(t *testing.T) {
+	lastModified := time.Date(2026, 4, 4, 12, 0, 0, 500000000, time.UTC)
+	etag := `"abc"`
+
+	testCases := []struct {
+		name   string
+		header map[string]string
+		want   bool
+	}{
+		{"NoHeaders", nil, false},
+		{"ETagMatch", map[string]string{"If-None-Match": `"abc"`}, true},
+		{"WeakETagMatch", map[string]string{"If-None-Match": `W/"abc"`}, true},
+		{"ETagInList", map[string]string{"If-None-Match": `"x", "abc"`}, true},
+		{"CodingETagMatch", map[string]string{"If-None-Match": `"abc-gzip"`}, true},
+		{"Wildcard", map[string]string{"If-None-Match": "*"}, true},
+		{"ETagMismatch", map[string]string{"If-None-Match": `"x"`}, false},
+		{"ModifiedSinceSameSecond", map[string]string{"If-Modified-Since": "Sat, 04 Apr 2026 12:00:00 GMT"}, true},
+		{"ModifiedSinceLater", map[string]string{"If-Modified-Since": "Sat, 04 Apr 2026 12:00:01 GMT"}, true},
+		{"ModifiedSinceEarlier", map[string]string{"If-Modified-Since": "Sat, 04 Apr 2026 11:59:59 GMT"}, false},
+		{"ModifiedSinceMalformed", map[string]string{"If-Modified-Since": "yesterday"}, false},
+		{
+			name: "ETagTakesPrecedence",
+			header: map[string]string{
+				"If-None-Match":     `"x"`,
+				"If-Modified-Since": "Sat, 04 Apr 2026 12:00:01 GMT",
+			},
+			want: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, v := range tc.header {
+				request.Header.Set(k, v)
+			}
+			require.Equal(t, tc.want, notModified(request, etag, lastModified))
+		})
+	}
+}
+
+func TestIfMatchVersion(t *testing.T) {
+	version := time.Date(2026, 4, 4, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name        string
+		header      string
+		wantVersion *time.Time
+		wantErr     bool
+	}{
+		{name: "Absent"},
+		{name: "Wildcard", header: "*"},
+		{name: "Version", header: versionETag(etagComment, 7, version), wantVersion: &version},
+		{name: "VersionInList", header: `"x", ` + versionETag(etagComment, 7, version), wantVersion: &version},
+		{name: "Compressed", header: codingETag(versionETag(etagComment, 7, version), encodingGzip), wantVersion: &version},
+		{name: "Weak", header: "W/" + versionETag(etagComment, 7, version), wantErr: true},
+		{name: "OtherComment", header: versionETag(etagComment, 8, version), wantErr: true},
+		{name: "Malformed", header: "garbage", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPatch, "/", nil)
+			if tc.header != "" {
+				request.Header.Set("If-Match", tc.header)
+			}
+
+			got, vErr := ifMatchVersion(request, etagComment, 7)
+			if tc.wantErr {
+				require.NotNil(t, vErr)
+				require.Equal(t, http.StatusPreconditionFailed, vErr.Status)
+				require.Equal(t, ReqPreconditionFailed, vErr.Reason)
+				return
+			}
+
+			require.Nil(t, vErr)
+			require.Equal(t, tc.wantVersion, got)
+		})
+	}
+}
+
+func TestGetUser_Conditional(t *testing.T) {
+	user := db.User{
+		ID:             1,
+		DisplayName:    "Alice",
+		LastModifiedAt: time.Date(2026, 4, 4, 12, 0, 0, 123456000, time.UTC),
+	}
+	etag := versionETag(etagUser, user.ID, user.LastModifiedAt)
+
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetUser(gomock.Any(), user.ID).Times(2).Return(user, nil)
+
+	service := newTestService(t, store, nil, nil, nil)
+
+	// first request gets the full body and the validators
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	service.router.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, etag, recorder.Header().Get("ETag"))
+	require.Equal(t, "Sat, 04 Apr 2026 12:00:00 GMT", recorder.Header().Get("Last-Modified"))
+	require.Equal(t, cacheUser, recorder.Header().Get("Cache-Control"))
+
+	// revalidation gets 304 without the body
+	recorder = httptest.NewRecorder()
+	request = httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	request.Header.Set("If-None-Match", etag)
+	service.router.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusNotModified, recorder.Code)
+	require.Equal(t, etag, recorder.Header().Get("ETag"))
+	require.Empty(t, recorder.Body.Bytes())
+}
+
+func TestRespondWithVersionedJSON_ZeroLastModified(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	respondWithVersionedJSON(recorder, request, `"u1.0"`, time.Time{}, cacheUser, struct{}{})
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Empty(t, recorder.Header().Values("Last-Modified"))
+}
+
+func TestGetComments_Conditional(t *testing.T) {
+	comments := []db.CommentsWithAuthor{root(1, 1), child(2, 1, 1, 1)}
+
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().QueryComments(gomock.Any(), gomock.Any()).Times(3).Return(comments, nil)
+
+	service := newTestService(t, store, nil, nil, nil)
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/posts/1/comments", nil)
+	service.router.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	etag := recorder.Header().Get("ETag")
+	sum := sha256.Sum256(recorder.Body.Bytes())
+	require.Equal(t, hashETag(sum[:]), etag)
+	require.Equal(t, cacheComments, recorder.Header().Get("Cache-Control"))
+	require.Empty(t, recorder.Header().Get("Last-Modified"))
+
+	// each comment carries the version ETag its PATCH and DELETE expect in If-Match
+	var resp struct {
+		Comments []struct {
+			ETag    string `json:"etag"`
+			Replies []struct {
+				ETag string `json:"etag"`
+			} `json:"replies"`
+		} `json:"comments"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	require.Equal(t, versionETag(etagComment, 1, comments[0].LastModifiedAt), resp.Comments[0].ETag)
+	_, ok := parseVersionETag(resp.Comments[0].Replies[0].ETag, etagComment, 2)
+	require.True(t, ok)
+
+	recorder = httptest.NewRecorder()
+	request = httptest.NewRequest(http.MethodGet, "/posts/1/comments", nil)
+	request.Header.Set("If-None-Match", etag)
+	service.router.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusNotModified, recorder.Code)
+	require.Empty(t, recorder.Body.Bytes())
+
+	// a changed tree, e.g. after a vote, gets a new body
+	comments[1].Upvotes = 1
+	recorder = httptest.NewRecorder()
+	request = httptest.NewRequest(http.MethodGet, "/posts/1/comments", nil)
+	request.Header.Set("If-None-Match", etag)
+	service.router.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.NotEqual(t, etag, recorder.Header().Get("ETag"))
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"errors"
+	"io"
 	"net/http"
 	"net/url"
 
@@ -9,9 +10,9 @@ import (
 )
 
 type GetCommentsRequest struct {
-	RootOffset int32           `json:"root_offset"`
-	NRoots     int32           `json:"n_roots"`
-	Order      db.CommentOrder `json:"order"`
+	RootOffset int32           `json:"root_offset" openapi:"min=0,default=0"`
+	NRoots     int32           `json:"n_roots" openapi:"min=1,max=100,default=10"`
+	Order      db.CommentOrder `json:"order" openapi:"enum=pop|new|old,default=pop"`
 }
 
 func (r *GetCommentsRequest) ExtractQueryParams(m url.Values) *Vomit {
@@ -105,5 +106,9 @@ func (s *Service) getComments(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, GetCommentsResponse{tree})
+	// votes don't bump last_modified_at of the comments, so the trees are tagged by content
+	// the tree is encoded straight into the hash and the response, without marshaling it first
+	respondWithHashedJSON(w, r, cacheComments, func(w io.Writer) error {
+		return newCommentTreeEncoder(w).EncodeResponse(GetCommentsResponse{tree})
+	})
 }
@@ -194,3 +194,67 @@ func makeComment(id int64, depth int32, parentID *int64) db.CommentsWithAuthor {
 	}
 	return c
 }
+
+// discardResponseWriter is the [http.ResponseWriter] counting the body instead of holding it,
+// so the benchmarks measure the memory of the handler alone.
+type discardResponseWriter struct {
+	header http.Header
+	code   int
+	n      int
+}
+
+func (w *discardResponseWriter) Header() http.Header { return w.header }
+
+func (w *discardResponseWriter) WriteHeader(code int) { w.code = code }
+
+func (w *discardResponseWriter) Write(b []byte) (int, error) {
+	w.n += len(b)
+	return len(b), nil
+}
+
+// BenchmarkGetComments measures the memory used to respond with a 10k-comment thread,
+// and to revalidate it with a fresh ETag.
+func BenchmarkGetComments(b *testing.B) {
+	comments := commentThread(10_000, 100, 10)
+
+	store := mockdb.NewMockStore(gomock.NewController(b))
+	store.EXPECT().QueryComments(gomock.Any(), gomock.Any()).AnyTimes().Return(comments, nil)
+
+	service, err := NewService(testConfig, store, nil, nil, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	url := "/posts/1/comments?n_roots=100"
+	serve := func(etag string) *discardResponseWriter {
+		request := httptest.NewRequest(http.MethodGet, url, nil)
+		if etag != "" {
+			request.Header.Set("If-None-Match", etag)
+		}
+		w := &discardResponseWriter{header: make(http.Header)}
+		service.router.ServeHTTP(w, request)
+		return w
+	}
+
+	first := serve("")
+	if first.code != http.StatusOK || first.n == 0 {
+		b.Fatalf("unexpected response: %d, %d bytes", first.code, first.n)
+	}
+	etag := first.header.Get("ETag")
+
+	b.Run("OK", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			serve("")
+		}
+	})
+
+	b.Run("NotModified", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			if w := serve(etag); w.code != http.StatusNotModified {
+				b.Fatalf("unexpected status: %d", w.code)
+			}
+		}
+	})
+}
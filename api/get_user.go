@@ -37,5 +37,6 @@ func (service *Service) getUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, createPublicUserResponse(user))
+	etag := versionETag(etagUser, user.ID, user.LastModifiedAt)
+	respondWithVersionedJSON(w, r, etag, user.LastModifiedAt, cacheUser, createPublicUserResponse(user))
 }
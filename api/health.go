@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// readinessCheckTimeout caps every single dependency check of the readiness probe.
+const readinessCheckTimeout = 2 * time.Second
+
+const (
+	healthStatusOK       = "ok"
+	healthStatusReady    = "ready"
+	healthStatusNotReady = "not_ready"
+	healthStatusShutdown = "shutting_down"
+	healthCheckFailed    = "fail"
+)
+
+// HealthResponse is returned by the liveness and readiness probes.
+type HealthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// healthz is the liveness probe. It only tells that the process is able to serve HTTP,
+// dependencies are checked by the readiness probe.
+func (service *Service) healthz(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, HealthResponse{Status: healthStatusOK})
+}
+
+// readyz is the readiness probe. It reports 503 while shutting down or if Postgres or Redis
+// are unreachable, or if the database schema isn't migrated to the latest version.
+func (service *Service) readyz(w http.ResponseWriter, r *http.Request) {
+	if service.shuttingDown.Load() {
+		respondWithJSON(w, http.StatusServiceUnavailable, HealthResponse{Status: healthStatusShutdown})
+		return
+	}
+
+	ctx := r.Context()
+	checks := make(map[string]string, 3)
+	ready := true
+
+	run := func(name string, check func(ctx context.Context) error) {
+		cctx, cancel := context.WithTimeout(ctx, readinessCheckTimeout)
+		defer cancel()
+
+		if err := check(cctx); err != nil {
+			log.Warn().Err(err).Str("check", name).Msg("readiness check failed")
+			checks[name] = healthCheckFailed
+			ready = false
+			return
+		}
+
+		checks[name] = healthStatusOK
+	}
+
+	run("postgres", service.store.Ping)
+	run("redis", service.redisStore.Ping)
+	run("migrations", func(ctx context.Context) error {
+		status, err := service.store.MigrationStatus(ctx)
+		if err != nil {
+			return err
+		}
+		if !status.UpToDate() {
+			return fmt.Errorf("schema version %d (dirty: %t), latest %d", status.Current, status.Dirty, status.Latest)
+		}
+		return nil
+	})
+
+	if !ready {
+		respondWithJSON(w, http.StatusServiceUnavailable, HealthResponse{Status: healthStatusNotReady, Checks: checks})
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, HealthResponse{Status: healthStatusReady, Checks: checks})
+}
@@ -0,0 +1,233 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/Drolfothesgnir/shitposter/db/mock"
+	db "github.com/Drolfothesgnir/shitposter/db/sqlc"
+	mockst "github.com/Drolfothesgnir/shitposter/tmpstore/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHealthz(t *testing.T) {
+	service := newTestService(t, nil, nil, nil, nil)
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/healthz", nil)
+	require.NoError(t, err)
+
+	service.router.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var resp HealthResponse
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&resp))
+	require.Equal(t, healthStatusOK, resp.Status)
+}
+
+func TestReadyz(t *testing.T) {
+	upToDate := db.MigrationStatus{Current: 11, Latest: 11}
+
+	testCases := []struct {
+		name         string
+		shuttingDown bool
+		buildStubs   func(store *mockdb.MockStore, rs *mockst.MockStore)
+		wantStatus   int
+		wantResp     HealthResponse
+	}{
+		{
+			name: "Ready",
+			buildStubs: func(store *mockdb.MockStore, rs *mockst.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				rs.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().MigrationStatus(gomock.Any()).Times(1).Return(upToDate, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantResp: HealthResponse{
+				Status: healthStatusReady,
+				Checks: map[string]string{"postgres": "ok", "redis": "ok", "migrations": "ok"},
+			},
+		},
+		{
+			name: "PostgresDown",
+			buildStubs: func(store *mockdb.MockStore, rs *mockst.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(&db.OpError{Kind: db.KindInternal})
+				rs.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().MigrationStatus(gomock.Any()).Times(1).Return(db.MigrationStatus{}, &db.OpError{Kind: db.KindInternal})
+			},
+			wantStatus: http.StatusServiceUnavailable,
+			wantResp: HealthResponse{
+				Status: healthStatusNotReady,
+				Checks: map[string]string{"postgres": "fail", "redis": "ok", "migrations": "fail"},
+			},
+		},
+		{
+			name: "RedisDown",
+			buildStubs: func(store *mockdb.MockStore, rs *mockst.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				rs.EXPECT().Ping(gomock.Any()).Times(1).Return(errors.New("connection refused"))
+				store.EXPECT().MigrationStatus(gomock.Any()).Times(1).Return(upToDate, nil)
+			},
+			wantStatus: http.StatusServiceUnavailable,
+			wantResp: HealthResponse{
+				Status: healthStatusNotReady,
+				Checks: map[string]string{"postgres": "ok", "redis": "fail", "migrations": "ok"},
+			},
+		},
+		{
+			name: "MigrationsBehind",
+			buildStubs: func(store *mockdb.MockStore, rs *mockst.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				rs.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().MigrationStatus(gomock.Any()).Times(1).Return(db.MigrationStatus{Current: 10, Latest: 11}, nil)
+			},
+			wantStatus: http.StatusServiceUnavailable,
+			wantResp: HealthResponse{
+				Status: healthStatusNotReady,
+				Checks: map[string]string{"postgres": "ok", "redis": "ok", "migrations": "fail"},
+			},
+		},
+		{
+			name: "DirtyMigration",
+			buildStubs: func(store *mockdb.MockStore, rs *mockst.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				rs.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().MigrationStatus(gomock.Any()).Times(1).Return(db.MigrationStatus{Current: 11, Latest: 11, Dirty: true}, nil)
+			},
+			wantStatus: http.StatusServiceUnavailable,
+			wantResp: HealthResponse{
+				Status: healthStatusNotReady,
+				Checks: map[string]string{"postgres": "ok", "redis": "ok", "migrations": "fail"},
+			},
+		},
+		{
+			name:         "ShuttingDown",
+			shuttingDown: true,
+			buildStubs: func(store *mockdb.MockStore, rs *mockst.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(0)
+				rs.EXPECT().Ping(gomock.Any()).Times(0)
+				store.EXPECT().MigrationStatus(gomock.Any()).Times(0)
+			},
+			wantStatus: http.StatusServiceUnavailable,
+			wantResp:   HealthResponse{Status: healthStatusShutdown},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			rs := mockst.NewMockStore(ctrl)
+			tc.buildStubs(store, rs)
+
+			service := newTestService(t, store, nil, rs, nil)
+			service.shuttingDown.Store(tc.shuttingDown)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, "/readyz", nil)
+			require.NoError(t, err)
+
+			service.router.ServeHTTP(recorder, request)
+
+			require.Equal(t, tc.wantStatus, recorder.Code)
+			var resp HealthResponse
+			require.NoError(t, json.NewDecoder(recorder.Body).Decode(&resp))
+			require.Equal(t, tc.wantResp, resp)
+		})
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	t.Run("ClosesDependenciesInOrder", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		store := mockdb.NewMockStore(ctrl)
+		rs := mockst.NewMockStore(ctrl)
+
+		gomock.InOrder(
+			rs.EXPECT().Close().Times(1).Return(nil),
+			store.EXPECT().Shutdown().Times(1),
+		)
+
+		service := newTestService(t, store, nil, rs, nil)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		require.NoError(t, service.Shutdown(ctx))
+		require.True(t, service.shuttingDown.Load())
+	})
+
+	t.Run("FailsReadinessDuringDrainDelay", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		store := mockdb.NewMockStore(ctrl)
+		rs := mockst.NewMockStore(ctrl)
+
+		const delay = 50 * time.Millisecond
+		start := time.Now()
+		closedAfter := make(chan time.Duration, 1)
+		rs.EXPECT().Close().Times(1).DoAndReturn(func() error {
+			closedAfter <- time.Since(start)
+			return nil
+		})
+		store.EXPECT().Shutdown().Times(1)
+
+		config := testConfig
+		config.ShutdownDrainDelay = delay
+		service, err := NewService(config, store, nil, rs, nil)
+		require.NoError(t, err)
+
+		done := make(chan error, 1)
+		go func() { done <- service.Shutdown(context.Background()) }()
+
+		// the probe fails while the server still serves
+		require.Eventually(t, service.shuttingDown.Load, time.Second, time.Millisecond)
+		recorder := httptest.NewRecorder()
+		service.router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+
+		require.NoError(t, <-done)
+		require.GreaterOrEqual(t, <-closedAfter, delay)
+	})
+
+	t.Run("RedisCloseError", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		store := mockdb.NewMockStore(ctrl)
+		rs := mockst.NewMockStore(ctrl)
+
+		closeErr := errors.New("boom")
+		rs.EXPECT().Close().Times(1).Return(closeErr)
+		store.EXPECT().Shutdown().Times(1)
+
+		service := newTestService(t, store, nil, rs, nil)
+
+		err := service.Shutdown(context.Background())
+		require.ErrorIs(t, err, closeErr)
+	})
+
+	t.Run("PoolCloseExceedsDeadline", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		store := mockdb.NewMockStore(ctrl)
+		rs := mockst.NewMockStore(ctrl)
+
+		release := make(chan struct{})
+		defer close(release)
+
+		rs.EXPECT().Close().Times(1).Return(nil)
+		// pool close blocks on a borrowed connection which is never returned in time
+		store.EXPECT().Shutdown().Times(1).Do(func() { <-release })
+
+		service := newTestService(t, store, nil, rs, nil)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		err := service.Shutdown(ctx)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/Drolfothesgnir/shitposter/scum"
+	"github.com/Drolfothesgnir/shitposter/sml"
+)
+
+// commentBodyMaxLen is the maximum length of the comment bodies in runes, not including the surrounding whitespace.
+const commentBodyMaxLen = 500
+
+// commentBudget bounds the markup of the comment bodies.
+var commentBudget = sml.Budget{
+	MaxTextRunes: commentBodyMaxLen,
+	MaxLinks:     10,
+	MaxNodes:     300,
+	MaxDepth:     8,
+	MaxHTMLBytes: 16 << 10,
+}
+
+// commentLinkPolicy marks the outbound links of the comment bodies as the user generated content,
+// the rest is the default policy.
+var commentLinkPolicy = sml.LinkPolicy{UGC: true}
+
+// newCommentEater returns the eater of the comment bodies, bounded by the commentBudget,
+// rendering the links with the commentLinkPolicy and verifying the @username mentions with r.
+func newCommentEater(r sml.MentionResolver) (*sml.Eater, error) {
+	e, err := sml.NewEater(
+		scum.WarnOverflowNoCap,
+		0,
+		sml.WithBudget(commentBudget),
+		sml.WithLinkPolicy(commentLinkPolicy),
+		sml.WithMentionResolver(r),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// budgetIssueTags maps the issues of the exceeded [sml.Budget] to the tags of the [Issue].
+var budgetIssueTags = map[int]string{
+	int(sml.IssueTextTooLong):    validatorMaxText,
+	int(sml.IssueTooManyLinks):   validatorMaxLinks,
+	int(sml.IssueTooManyNodes):   validatorMaxNodes,
+	int(sml.IssueNestingTooDeep): validatorMaxDepth,
+	int(sml.IssueHTMLTooLarge):   validatorMaxHTML,
+}
+
+// munchMarkup munches the markup value of the field, so the handler can use the [sml.Poop] it was validated with
+// instead of munching the value again. Returns 400 with an [Issue] for each limit of the eater's budget exceeded.
+// Returns 500 if the mentioned users couldn't be verified, the other issues of the markup don't make the value invalid.
+func munchMarkup(ctx context.Context, e *sml.Eater, v, fieldName string) (sml.Poop, *Vomit) {
+	poop, syntaxIssues := e.MunchContext(ctx, v)
+
+	issues := make([]Issue, 0)
+	for _, si := range syntaxIssues {
+		if si.Code() == int(sml.IssueInternal) {
+			return poop, puke(FlavorInternal, http.StatusInternalServerError, "internal error", errors.New(si.Description()))
+		}
+
+		tag, ok := budgetIssueTags[si.Code()]
+		if !ok {
+			continue
+		}
+
+		issues = append(issues, Issue{
+			FieldName: fieldName,
+			Tag:       tag,
+			Message:   si.Description(),
+		})
+	}
+
+	return poop, barf(issues)
+}
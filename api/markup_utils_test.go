@@ -0,0 +1,69 @@
+package api
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	mockdb "github.com/Drolfothesgnir/shitposter/db/mock"
+	"github.com/Drolfothesgnir/shitposter/scum"
+	"github.com/Drolfothesgnir/shitposter/sml"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestMunchMarkup(t *testing.T) {
+	eater, err := sml.NewEater(scum.WarnOverflowNoCap, 0, sml.WithBudget(sml.Budget{MaxLinks: 1, MaxDepth: 2}))
+	require.NoError(t, err)
+
+	t.Run("Valid", func(t *testing.T) {
+		// the misplaced closing tag is the issue of the markup, not of the budget
+		poop, vErr := munchMarkup(context.Background(), &eater, "[a]!href{/a} $*b*$ ]x", "body")
+
+		require.Nil(t, vErr)
+		require.Contains(t, poop.HTML(), `<a href="/a">a</a>`)
+	})
+
+	t.Run("Exceeded", func(t *testing.T) {
+		_, vErr := munchMarkup(context.Background(), &eater, "[a]!href{/a} [b]!href{/b} $*_c_*$", "body")
+
+		require.NotNil(t, vErr)
+		require.Equal(t, ReqInvalidArguments, vErr.Reason)
+		require.Equal(t, []Issue{
+			{
+				FieldName: "body",
+				Tag:       validatorMaxLinks,
+				Message:   "number of links must be at most 1, got 2",
+			},
+			{
+				FieldName: "body",
+				Tag:       validatorMaxDepth,
+				Message:   "tags must be nested at most 2 levels deep",
+			},
+		}, vErr.Issues)
+	})
+}
+
+func TestCommentBudget_TextFitsBody(t *testing.T) {
+	eater, err := newCommentEater(mockdb.NewMockStore(gomock.NewController(t)))
+	require.NoError(t, err)
+
+	// the longest body passing strMax doesn't exceed the text budget
+	body := strings.Repeat("x", commentBodyMaxLen)
+	require.Nil(t, CreateCommentRequest{Body: body}.Validate())
+
+	_, vErr := munchMarkup(context.Background(), eater, body, "body")
+	require.Nil(t, vErr)
+}
+
+func TestCommentEater_UGCLinks(t *testing.T) {
+	eater, err := newCommentEater(mockdb.NewMockStore(gomock.NewController(t)))
+	require.NoError(t, err)
+
+	poop, vErr := munchMarkup(context.Background(), eater, "[out]!href{https://example.com} [in]!href{/posts/1}", "body")
+	require.Nil(t, vErr)
+
+	html := poop.HTML()
+	require.Contains(t, html, `<a href="https://example.com" rel="ugc nofollow">out</a>`)
+	require.Contains(t, html, `<a href="/posts/1">in</a>`)
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Drolfothesgnir/shitposter/metrics"
+)
+
+// unmatchedRoute is the route label for requests which didn't match any pattern,
+// including CORS preflights answered by the middleware.
+const unmatchedRoute = "unmatched"
+
+var httpRequestDuration = metrics.Default.NewHistogram(
+	"http_request_duration_seconds",
+	"Latency of HTTP requests by method, route pattern and response status.",
+	metrics.DefBuckets,
+	"method", "route", "status",
+)
+
+// statusRecorder captures the status code written by the downstream handlers.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rec *statusRecorder) WriteHeader(code int) {
+	if rec.status == 0 {
+		rec.status = code
+	}
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return rec.ResponseWriter.Write(b)
+}
+
+// Unwrap allows [http.ResponseController] to reach the underlying writer.
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// metricsMiddleware records latency and status of every request, labeled by
+// the route pattern rather than the raw path, to keep the label cardinality bounded.
+func metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(rec, r)
+
+		// the mux stores the matched pattern in the request it received,
+		// which is this very request, since the middlewares down the chain don't clone it
+		route := unmatchedRoute
+		if r.Pattern != "" {
+			route = r.Pattern
+			// patterns are "METHOD /path", the method is a separate label
+			if _, path, ok := strings.Cut(route, " "); ok {
+				route = path
+			}
+		}
+
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		httpRequestDuration.ObserveSince(start, r.Method, route, strconv.Itoa(status))
+	})
+}
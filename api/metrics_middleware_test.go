@@ -0,0 +1,55 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	service := newTestService(t, nil, nil, nil, nil)
+
+	testCases := []struct {
+		name       string
+		method     string
+		url        string
+		wantRoute  string
+		wantStatus string
+	}{
+		{
+			name:       "MatchedRouteUsesPattern",
+			method:     http.MethodGet,
+			url:        "/users/not-a-number",
+			wantRoute:  "/users/{id}",
+			wantStatus: "400",
+		},
+		{
+			name:       "UnknownPath",
+			method:     http.MethodGet,
+			url:        "/definitely/not/here",
+			wantRoute:  unmatchedRoute,
+			wantStatus: "404",
+		},
+		{
+			name:       "Preflight",
+			method:     http.MethodOptions,
+			url:        "/users/1",
+			wantRoute:  unmatchedRoute,
+			wantStatus: "204",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			before := httpRequestDuration.Count(tc.method, tc.wantRoute, tc.wantStatus)
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, tc.url, nil)
+			service.server.Handler.ServeHTTP(rec, req)
+
+			require.Equal(t, before+1, httpRequestDuration.Count(tc.method, tc.wantRoute, tc.wantStatus))
+		})
+	}
+}
@@ -0,0 +1,587 @@
+package api
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// The OpenAPI document is built once at router setup from the patterns registered
+// on the [routeMux] and the operation docs in [routeDocs].
+//
+// Schemas are derived from the request and response types by reflection, following
+// the encoding/json rules (tags, embedding, omitempty). Validation constraints come from
+// the `openapi` struct tag, which mirrors the validators used in the Validate methods:
+//
+//	required   - the field must be present and not blank
+//	email      - format: email
+//	url        - format: uri
+//	alphanum   - letters and digits only
+//	min=N      - minLength for strings, minimum for numbers
+//	max=N      - maxLength for strings, maximum for numbers
+//	enum=a|b   - allowed values
+//	default=V  - value used when the field is omitted
+
+const openAPIVersion = "3.1.0"
+
+const bearerSecurityScheme = "bearerAuth"
+
+// alphanumPattern is the ECMA-262 equivalent of [isAlphanumeric].
+const alphanumPattern = `^[\p{L}\p{N}]+$`
+
+// OpenAPIDocument is the root object of the OpenAPI document.
+type OpenAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       OpenAPIInfo                             `json:"info"`
+	Servers    []OpenAPIServer                         `json:"servers,omitempty"`
+	Paths      map[string]map[string]*OpenAPIOperation `json:"paths"`
+	Components OpenAPIComponents                       `json:"components"`
+}
+
+type OpenAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type OpenAPIServer struct {
+	URL string `json:"url"`
+}
+
+type OpenAPIComponents struct {
+	Schemas         map[string]*Schema               `json:"schemas"`
+	SecuritySchemes map[string]OpenAPISecurityScheme `json:"securitySchemes"`
+}
+
+type OpenAPISecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+type OpenAPIOperation struct {
+	OperationID string                      `json:"operationId"`
+	Summary     string                      `json:"summary,omitempty"`
+	Tags        []string                    `json:"tags,omitempty"`
+	Parameters  []OpenAPIParameter          `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses"`
+	Security    []map[string][]string       `json:"security,omitempty"`
+}
+
+type OpenAPIParameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required"`
+	Schema      *Schema `json:"schema"`
+}
+
+type OpenAPIRequestBody struct {
+	Description string                      `json:"description,omitempty"`
+	Required    bool                        `json:"required"`
+	Content     map[string]OpenAPIMediaType `json:"content"`
+}
+
+type OpenAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]OpenAPIMediaType `json:"content,omitempty"`
+}
+
+type OpenAPIMediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is a JSON Schema (draft 2020-12) object, as used by OpenAPI 3.1.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 any                `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Default              any                `json:"default,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+}
+
+// operationDoc describes a single route for the OpenAPI document.
+type operationDoc struct {
+	summary string
+	tag     string
+	// query is a value of the type describing the query parameters.
+	query any
+	// request is a value of the JSON request body type.
+	request any
+	// requestSchema overrides request for bodies which are not described by a Go type.
+	requestSchema *Schema
+	// params lists header and cookie parameters.
+	params []OpenAPIParameter
+	// status is the success status code.
+	status int
+	// response is a value of the success response type, nil for empty responses.
+	response any
+	// errors lists the error status codes, besides 401 which is implied by the auth middleware.
+	errors []int
+	// conditional marks routes which answer If-None-Match and If-Modified-Since with 304.
+	conditional bool
+}
+
+// routeMux is a [http.ServeMux] which remembers the registered patterns,
+// so the OpenAPI document can be checked against the actual routes.
+type routeMux struct {
+	*http.ServeMux
+	patterns []string
+	// authPatterns are the patterns registered with HandleAuthFunc.
+	authPatterns []string
+	auth         func(next http.Handler) http.HandlerFunc
+}
+
+// newRouteMux returns the mux wrapping the handlers registered with HandleAuthFunc in auth.
+func newRouteMux(auth func(next http.Handler) http.HandlerFunc) *routeMux {
+	return &routeMux{ServeMux: http.NewServeMux(), auth: auth}
+}
+
+func (m *routeMux) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	m.patterns = append(m.patterns, pattern)
+	m.ServeMux.HandleFunc(pattern, handler)
+}
+
+// HandleAuthFunc registers the handler behind the auth middleware,
+// so the OpenAPI document requires the bearer token exactly for the routes which check it.
+func (m *routeMux) HandleAuthFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	m.authPatterns = append(m.authPatterns, pattern)
+	m.HandleFunc(pattern, m.auth(http.HandlerFunc(handler)))
+}
+
+// openAPIHandler serves the OpenAPI document built at router setup.
+func (service *Service) openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, service.openAPI)
+}
+
+// buildOpenAPI creates the document for the patterns using the docs, the authPatterns require the bearer token.
+// It returns an error if a pattern has no doc or a doc has no pattern.
+func buildOpenAPI(publicOrigin string, patterns, authPatterns []string, docs map[string]operationDoc) (*OpenAPIDocument, error) {
+	gen := newSchemaGen()
+
+	doc := &OpenAPIDocument{
+		OpenAPI: openAPIVersion,
+		Info:    OpenAPIInfo{Title: "Shitposter API", Version: "0.1.0"},
+		Paths:   make(map[string]map[string]*OpenAPIOperation),
+		Components: OpenAPIComponents{
+			SecuritySchemes: map[string]OpenAPISecurityScheme{
+				bearerSecurityScheme: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+	}
+
+	if publicOrigin != "" {
+		doc.Servers = []OpenAPIServer{{URL: publicOrigin}}
+	}
+
+	errSchema := gen.schemaFor(reflect.TypeFor[Vomit](), false)
+
+	var missing []string
+	for _, pattern := range patterns {
+		opDoc, ok := docs[pattern]
+		if !ok {
+			missing = append(missing, pattern)
+			continue
+		}
+
+		method, path, _ := strings.Cut(pattern, " ")
+
+		op := &OpenAPIOperation{
+			OperationID: operationID(method, path),
+			Summary:     opDoc.summary,
+			Responses:   make(map[string]*OpenAPIResponse),
+		}
+		if opDoc.tag != "" {
+			op.Tags = []string{opDoc.tag}
+		}
+
+		op.Parameters = append(op.Parameters, pathParams(path)...)
+		if opDoc.query != nil {
+			op.Parameters = append(op.Parameters, gen.queryParams(reflect.TypeOf(opDoc.query))...)
+		}
+		op.Parameters = append(op.Parameters, opDoc.params...)
+		if opDoc.conditional {
+			op.Parameters = append(op.Parameters, ifNoneMatchParam, ifModifiedSinceParam)
+		}
+
+		switch {
+		case opDoc.requestSchema != nil:
+			op.RequestBody = jsonRequestBody(opDoc.requestSchema)
+		case opDoc.request != nil:
+			op.RequestBody = jsonRequestBody(gen.schemaFor(reflect.TypeOf(opDoc.request), true))
+		}
+
+		success := &OpenAPIResponse{Description: http.StatusText(opDoc.status)}
+		if opDoc.response != nil {
+			success.Content = map[string]OpenAPIMediaType{
+				contentJSON: {Schema: gen.schemaFor(reflect.TypeOf(opDoc.response), false)},
+			}
+		}
+		op.Responses[strconv.Itoa(opDoc.status)] = success
+		if opDoc.conditional {
+			op.Responses[strconv.Itoa(http.StatusNotModified)] = &OpenAPIResponse{
+				Description: http.StatusText(http.StatusNotModified),
+			}
+		}
+
+		errStatuses := slices.Clone(opDoc.errors)
+		if slices.Contains(authPatterns, pattern) {
+			op.Security = []map[string][]string{{bearerSecurityScheme: {}}}
+			errStatuses = append(errStatuses, http.StatusUnauthorized)
+		}
+		for _, status := range errStatuses {
+			op.Responses[strconv.Itoa(status)] = &OpenAPIResponse{
+				Description: http.StatusText(status),
+				Content:     map[string]OpenAPIMediaType{contentJSON: {Schema: errSchema}},
+			}
+		}
+
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]*OpenAPIOperation)
+		}
+		doc.Paths[path][strings.ToLower(method)] = op
+	}
+
+	var unused []string
+	for pattern := range docs {
+		if !slices.Contains(patterns, pattern) {
+			unused = append(unused, pattern)
+		}
+	}
+
+	if len(missing) > 0 || len(unused) > 0 {
+		slices.Sort(unused)
+		return nil, fmt.Errorf("OpenAPI docs are out of sync with the router: undocumented routes %q, docs without routes %q", missing, unused)
+	}
+
+	doc.Components.Schemas = gen.components
+	return doc, nil
+}
+
+func jsonRequestBody(schema *Schema) *OpenAPIRequestBody {
+	return &OpenAPIRequestBody{
+		Required: true,
+		Content:  map[string]OpenAPIMediaType{contentJSON: {Schema: schema}},
+	}
+}
+
+var pathParamRe = regexp.MustCompile(`\{([a-zA-Z_][a-zA-Z0-9_]*)\}`)
+
+// pathParams describes the wildcards of the path. All the path parameters
+// of the API are positive database IDs.
+func pathParams(path string) []OpenAPIParameter {
+	var params []OpenAPIParameter
+	for _, m := range pathParamRe.FindAllStringSubmatch(path, -1) {
+		params = append(params, OpenAPIParameter{
+			Name:     m[1],
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: "integer", Format: "int64", Minimum: ptr(1.0)},
+		})
+	}
+	return params
+}
+
+// operationID makes a stable camel-case ID like "patchPostsPostIdCommentsCommentId".
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool {
+		return r == '/' || r == '{' || r == '}' || r == '_' || r == '.'
+	}) {
+		b.WriteString(strings.ToUpper(part[:1]))
+		b.WriteString(part[1:])
+	}
+	return b.String()
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// schemaGen derives schemas from Go types. Named struct types become components
+// referenced with $ref, which also handles recursive types like [CommentNode].
+type schemaGen struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func newSchemaGen() *schemaGen {
+	return &schemaGen{
+		components: make(map[string]*Schema),
+		names:      make(map[reflect.Type]string),
+	}
+}
+
+var (
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// knownSchemas maps types with custom JSON encoding to their wire format.
+var knownSchemas = map[reflect.Type]func() *Schema{
+	reflect.TypeFor[time.Time]():          func() *Schema { return &Schema{Type: "string", Format: "date-time"} },
+	reflect.TypeFor[uuid.UUID]():          func() *Schema { return &Schema{Type: "string", Format: "uuid"} },
+	reflect.TypeFor[json.RawMessage]():    func() *Schema { return &Schema{} },
+	reflect.TypeFor[pgtype.Int8]():        func() *Schema { return &Schema{Type: []string{"integer", "null"}, Format: "int64"} },
+	reflect.TypeFor[pgtype.Int4]():        func() *Schema { return &Schema{Type: []string{"integer", "null"}, Format: "int32"} },
+	reflect.TypeFor[pgtype.Text]():        func() *Schema { return &Schema{Type: []string{"string", "null"}} },
+	reflect.TypeFor[pgtype.Bool]():        func() *Schema { return &Schema{Type: []string{"boolean", "null"}} },
+	reflect.TypeFor[pgtype.Timestamptz](): func() *Schema { return &Schema{Type: []string{"string", "null"}, Format: "date-time"} },
+}
+
+// schemaFor returns the schema of t. With request set, only the fields tagged
+// as required are required, otherwise every field without omitempty is.
+func (g *schemaGen) schemaFor(t reflect.Type, request bool) *Schema {
+	if known, ok := knownSchemas[t]; ok {
+		return known()
+	}
+
+	if t.Kind() == reflect.Pointer {
+		return nullable(g.schemaFor(t.Elem(), request))
+	}
+
+	// types with custom encoding are opaque, unless they are encoded as strings
+	if t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType) ||
+		t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+		switch {
+		case t.Kind() == reflect.String, isBytes(t), t.Implements(textMarshalerType):
+			return &Schema{Type: "string"}
+		default:
+			return &Schema{}
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if isBytes(t) {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		s := &Schema{Type: "array", Items: g.schemaFor(t.Elem(), request)}
+		if t.Kind() == reflect.Slice {
+			// nil slices are encoded as null
+			s.Type = []string{"array", "null"}
+		}
+		return s
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaFor(t.Elem(), request)}
+	case reflect.Struct:
+		return g.structRef(t, request)
+	default:
+		// interfaces and everything else which can hold any value
+		return &Schema{}
+	}
+}
+
+func isBytes(t reflect.Type) bool {
+	return (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && t.Elem().Kind() == reflect.Uint8
+}
+
+func nullable(s *Schema) *Schema {
+	switch typ := s.Type.(type) {
+	case string:
+		s.Type = []string{typ, "null"}
+		return s
+	case []string:
+		if !slices.Contains(typ, "null") {
+			s.Type = append(typ, "null")
+		}
+		return s
+	}
+
+	if s.Ref != "" {
+		return &Schema{AnyOf: []*Schema{s, {Type: "null"}}}
+	}
+
+	// already accepts anything
+	return s
+}
+
+// structRef registers the struct as a component and returns a reference to it.
+// Anonymous structs are inlined.
+func (g *schemaGen) structRef(t reflect.Type, request bool) *Schema {
+	if t.Name() == "" {
+		return g.structSchema(t, request)
+	}
+
+	name, ok := g.names[t]
+	if !ok {
+		name = t.Name()
+		if _, taken := g.components[name]; taken {
+			name = strings.ReplaceAll(t.String(), ".", "_")
+		}
+		g.names[t] = name
+		// the placeholder stops the recursion on self-referencing types
+		g.components[name] = &Schema{}
+		*g.components[name] = *g.structSchema(t, request)
+	}
+
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+func (g *schemaGen) structSchema(t reflect.Type, request bool) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.addFields(s, t, request)
+	return s
+}
+
+// addFields adds the JSON fields of the struct, promoting the fields of embedded structs.
+func (g *schemaGen) addFields(s *Schema, t reflect.Type, request bool) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				g.addFields(s, ft, request)
+				continue
+			}
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		fs := g.schemaFor(f.Type, request)
+		required := applyConstraints(fs, f.Type, f.Tag.Get("openapi"))
+
+		if request {
+			if required {
+				s.Required = append(s.Required, name)
+			}
+		} else if !slices.Contains(strings.Split(opts, ","), "omitempty") {
+			s.Required = append(s.Required, name)
+		}
+
+		s.Properties[name] = fs
+	}
+}
+
+// queryParams describes every field of the struct as a query parameter.
+func (g *schemaGen) queryParams(t reflect.Type) []OpenAPIParameter {
+	var params []OpenAPIParameter
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		s := g.schemaFor(f.Type, true)
+		required := applyConstraints(s, f.Type, f.Tag.Get("openapi"))
+
+		params = append(params, OpenAPIParameter{
+			Name:     name,
+			In:       "query",
+			Required: required,
+			Schema:   s,
+		})
+	}
+	return params
+}
+
+// applyConstraints translates the openapi tag into schema keywords.
+// It reports whether the field is required.
+func applyConstraints(s *Schema, t reflect.Type, tag string) bool {
+	if tag == "" {
+		return false
+	}
+
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	isString := t.Kind() == reflect.String
+
+	required := false
+	for _, rule := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(rule, "=")
+		switch key {
+		case validatorRequired:
+			required = true
+			if isString {
+				s.MinLength = ptr(1)
+			}
+		case validatorEmail:
+			s.Format = "email"
+		case validatorURL:
+			s.Format = "uri"
+		case validatorAlphanum:
+			s.Pattern = alphanumPattern
+		case validatorMin, validatorMax:
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				panic(fmt.Sprintf("invalid openapi tag %q: %v", tag, err))
+			}
+			switch {
+			case isString && key == validatorMin:
+				s.MinLength = ptr(n)
+			case isString:
+				s.MaxLength = ptr(n)
+			case key == validatorMin:
+				s.Minimum = ptr(float64(n))
+			default:
+				s.Maximum = ptr(float64(n))
+			}
+		case "enum":
+			for _, v := range strings.Split(value, "|") {
+				s.Enum = append(s.Enum, v)
+			}
+		case "default":
+			if isString {
+				s.Default = value
+			} else if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+				s.Default = n
+			} else {
+				panic(fmt.Sprintf("invalid openapi tag %q: %v", tag, err))
+			}
+		default:
+			panic(fmt.Sprintf("unknown openapi tag rule %q", rule))
+		}
+	}
+
+	return required
+}
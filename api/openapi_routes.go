@@ -0,0 +1,206 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+
+	db "github.com/Drolfothesgnir/shitposter/db/sqlc"
+)
+
+const (
+	tagAuth     = "auth"
+	tagComments = "comments"
+	tagUsers    = "users"
+	tagMeta     = "meta"
+)
+
+// bodyErrors are the statuses returned by [ingestJSONBody] and the Validate methods.
+var bodyErrors = []int{
+	http.StatusBadRequest,
+	http.StatusRequestEntityTooLarge,
+	http.StatusUnsupportedMediaType,
+}
+
+func withBodyErrors(statuses ...int) []int {
+	return append(slices.Clone(bodyErrors), statuses...)
+}
+
+// webauthnSessionParam is the cookie set by the start step of the passkey ceremonies.
+var webauthnSessionParam = OpenAPIParameter{
+	Name:        webauthnSessionCookie,
+	In:          "cookie",
+	Description: "Session ID set by the corresponding start step.",
+	Required:    true,
+	Schema:      &Schema{Type: "string"},
+}
+
+// Headers of the conditional requests, see etag.go.
+var (
+	ifNoneMatchParam = OpenAPIParameter{
+		Name:        "If-None-Match",
+		In:          "header",
+		Description: "ETags of the cached copies, 304 is returned if one of them is current.",
+		Schema:      &Schema{Type: "string"},
+	}
+	ifModifiedSinceParam = OpenAPIParameter{
+		Name:        "If-Modified-Since",
+		In:          "header",
+		Description: "HTTP date of the cached copy, ignored when If-None-Match is present.",
+		Schema:      &Schema{Type: "string"},
+	}
+	ifMatchParam = OpenAPIParameter{
+		Name:        "If-Match",
+		In:          "header",
+		Description: "ETag of the version being modified, as returned by the GET of the resource or in the etag field of the comments, 412 is returned if the resource has changed since.",
+		Schema:      &Schema{Type: "string"},
+	}
+)
+
+// webauthnResponseSchema describes the bodies of the finish steps, which are parsed
+// by the WebAuthn library rather than decoded into Go types.
+var webauthnResponseSchema = &Schema{
+	Type:        "object",
+	Description: "PublicKeyCredential produced by navigator.credentials, serialized with toJSON().",
+}
+
+// routeDocs documents every pattern registered in [Service.setupRouter].
+// Keep it in sync with the router: the OpenAPI document cannot be built otherwise.
+var routeDocs = map[string]operationDoc{
+	"GET /healthz": {
+		summary:  "Liveness probe",
+		tag:      tagMeta,
+		status:   http.StatusOK,
+		response: HealthResponse{},
+	},
+	"GET /readyz": {
+		summary:  "Readiness probe, checks Postgres, Redis and the schema version",
+		tag:      tagMeta,
+		status:   http.StatusOK,
+		response: HealthResponse{},
+		errors:   []int{http.StatusServiceUnavailable},
+	},
+	"GET /openapi.json": {
+		summary:  "This OpenAPI document",
+		tag:      tagMeta,
+		status:   http.StatusOK,
+		response: map[string]any{},
+	},
+
+	"POST /users/signup/start": {
+		summary:  "Start passkey registration",
+		tag:      tagAuth,
+		request:  SignupStartRequest{},
+		status:   http.StatusOK,
+		response: SignupStartResponse{},
+		errors:   withBodyErrors(http.StatusConflict, http.StatusInternalServerError),
+	},
+	"POST /users/signup/finish": {
+		summary:       "Finish passkey registration and sign in",
+		tag:           tagAuth,
+		requestSchema: webauthnResponseSchema,
+		params: []OpenAPIParameter{
+			webauthnSessionParam,
+			{
+				Name:        WebauthnTransportHeader,
+				In:          "header",
+				Description: "Comma-separated authenticator transports reported by the browser.",
+				Schema:      &Schema{Type: "string"},
+			},
+		},
+		status:   http.StatusOK,
+		response: PrivateSuccessAuthResponse{},
+		errors:   []int{http.StatusBadRequest, http.StatusInternalServerError},
+	},
+	"POST /users/signin/start": {
+		summary:  "Start passkey authentication",
+		tag:      tagAuth,
+		request:  SigninStartRequest{},
+		status:   http.StatusOK,
+		response: SigninStartResponse{},
+		errors:   withBodyErrors(http.StatusNotFound, http.StatusGone, http.StatusInternalServerError),
+	},
+	"POST /users/signin/finish": {
+		summary:       "Finish passkey authentication",
+		tag:           tagAuth,
+		requestSchema: webauthnResponseSchema,
+		params:        []OpenAPIParameter{webauthnSessionParam},
+		status:        http.StatusOK,
+		response:      PrivateSuccessAuthResponse{},
+		errors:        []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError},
+	},
+	"POST /users/renew_access": {
+		summary:  "Renew the access token with a refresh token",
+		tag:      tagAuth,
+		request:  RenewAccessTokenRequest{},
+		status:   http.StatusOK,
+		response: RenewAccessTokenResponse{},
+		errors:   withBodyErrors(http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError),
+	},
+
+	"POST /posts/{post_id}/comments": {
+		summary:  "Create a root comment",
+		tag:      tagComments,
+		request:  CreateCommentRequest{},
+		status:   http.StatusOK,
+		response: db.Comment{},
+		errors:   withBodyErrors(http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError),
+	},
+	"POST /posts/{post_id}/comments/{comment_id}": {
+		summary:  "Reply to a comment",
+		tag:      tagComments,
+		request:  CreateCommentRequest{},
+		status:   http.StatusOK,
+		response: db.Comment{},
+		errors:   withBodyErrors(http.StatusNotFound, http.StatusGone, http.StatusUnprocessableEntity, http.StatusInternalServerError),
+	},
+	"GET /posts/{post_id}/comments": {
+		summary:     "List comment trees of a post",
+		tag:         tagComments,
+		query:       GetCommentsRequest{},
+		status:      http.StatusOK,
+		response:    GetCommentsResponse{},
+		errors:      []int{http.StatusBadRequest, http.StatusInternalServerError},
+		conditional: true,
+	},
+	"PATCH /posts/{post_id}/comments/{comment_id}": {
+		summary:  "Edit own comment",
+		tag:      tagComments,
+		request:  UpdateCommentRequest{},
+		params:   []OpenAPIParameter{ifMatchParam},
+		status:   http.StatusOK,
+		response: db.UpdateCommentResult{},
+		errors:   withBodyErrors(http.StatusForbidden, http.StatusNotFound, http.StatusGone, http.StatusPreconditionFailed, http.StatusInternalServerError),
+	},
+	"DELETE /posts/{post_id}/comments/{comment_id}": {
+		summary: "Delete own comment",
+		tag:     tagComments,
+		params:  []OpenAPIParameter{ifMatchParam},
+		status:  http.StatusNoContent,
+		errors:  []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusGone, http.StatusPreconditionFailed, http.StatusInternalServerError},
+	},
+
+	"GET /users/{id}": {
+		summary:     "Get public user profile",
+		tag:         tagUsers,
+		status:      http.StatusOK,
+		response:    PublicUserResponse{},
+		errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+		conditional: true,
+	},
+	"PATCH /users": {
+		summary:  "Update own profile",
+		tag:      tagUsers,
+		request:  UpdateUserRequest{},
+		params:   []OpenAPIParameter{ifMatchParam},
+		status:   http.StatusOK,
+		response: db.UpdateUserResult{},
+		errors:   withBodyErrors(http.StatusNotFound, http.StatusGone, http.StatusConflict, http.StatusPreconditionFailed, http.StatusInternalServerError),
+	},
+	"DELETE /users": {
+		summary: "Delete own account",
+		tag:     tagUsers,
+		params:  []OpenAPIParameter{ifMatchParam},
+		status:  http.StatusNoContent,
+		errors:  []int{http.StatusNotFound, http.StatusGone, http.StatusPreconditionFailed, http.StatusInternalServerError},
+	},
+}
@@ -0,0 +1,201 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPI_CoversAllRoutes(t *testing.T) {
+	service := newTestService(t, nil, nil, nil, nil)
+
+	mux, ok := service.router.(*routeMux)
+	require.True(t, ok)
+	require.NotEmpty(t, mux.patterns)
+
+	for _, pattern := range mux.patterns {
+		method, path, _ := strings.Cut(pattern, " ")
+		ops, ok := service.openAPI.Paths[path]
+		require.True(t, ok, "path of %q is missing from the OpenAPI document", pattern)
+
+		op, ok := ops[strings.ToLower(method)]
+		require.True(t, ok, "operation %q is missing from the OpenAPI document", pattern)
+		require.NotEmpty(t, op.Responses, "operation %q has no responses", pattern)
+
+		// every wildcard is described as a path parameter
+		for _, m := range pathParamRe.FindAllStringSubmatch(path, -1) {
+			found := false
+			for _, p := range op.Parameters {
+				found = found || (p.In == "path" && p.Name == m[1])
+			}
+			require.True(t, found, "operation %q does not describe path parameter %q", pattern, m[1])
+		}
+	}
+}
+
+func TestBuildOpenAPI_OutOfSync(t *testing.T) {
+	docs := map[string]operationDoc{
+		"GET /documented": {status: http.StatusOK},
+		"GET /stale":      {status: http.StatusOK},
+	}
+
+	_, err := buildOpenAPI("", []string{"GET /documented", "POST /undocumented"}, nil, docs)
+	require.ErrorContains(t, err, `undocumented routes ["POST /undocumented"]`)
+	require.ErrorContains(t, err, `docs without routes ["GET /stale"]`)
+}
+
+func TestOpenAPI_Schemas(t *testing.T) {
+	service := newTestService(t, nil, nil, nil, nil)
+	doc := service.openAPI
+	schemas := doc.Components.Schemas
+
+	// validation constraints of the request bodies
+	signup := schemas["SignupStartRequest"]
+	require.NotNil(t, signup)
+	require.ElementsMatch(t, []string{"email", "username"}, signup.Required)
+	require.Equal(t, "email", signup.Properties["email"].Format)
+	require.Equal(t, 3, *signup.Properties["username"].MinLength)
+	require.Equal(t, 50, *signup.Properties["username"].MaxLength)
+	require.Equal(t, alphanumPattern, signup.Properties["username"].Pattern)
+
+	update := schemas["UpdateUserRequest"]
+	require.Empty(t, update.Required)
+	require.Equal(t, []string{"string", "null"}, update.Properties["profile_img_url"].Type)
+	require.Equal(t, "uri", update.Properties["profile_img_url"].Format)
+
+	require.Equal(t, 500, *schemas["CreateCommentRequest"].Properties["body"].MaxLength)
+
+	// query parameters
+	getComments := doc.Paths["/posts/{post_id}/comments"]["get"]
+	params := make(map[string]OpenAPIParameter)
+	for _, p := range getComments.Parameters {
+		params[p.Name] = p
+	}
+	require.Equal(t, "path", params["post_id"].In)
+	require.Equal(t, 1.0, *params["n_roots"].Schema.Minimum)
+	require.Equal(t, 100.0, *params["n_roots"].Schema.Maximum)
+	require.Equal(t, int64(10), params["n_roots"].Schema.Default)
+	require.Equal(t, []any{"pop", "new", "old"}, params["order"].Schema.Enum)
+
+	// recursive comment trees and flattened embedded structs
+	node := schemas["CommentNode"]
+	require.Contains(t, node.Properties, "body")
+	require.Contains(t, node.Properties, "user_display_name")
+	require.Equal(t, "#/components/schemas/CommentNode", node.Properties["replies"].Items.AnyOf[0].Ref)
+	require.NotContains(t, node.Required, "replies")
+
+	// auth and errors
+	patch := doc.Paths["/users"]["patch"]
+	require.Equal(t, []map[string][]string{{bearerSecurityScheme: {}}}, patch.Security)
+	require.Contains(t, patch.Responses, "401")
+	require.Equal(t, "#/components/schemas/Vomit", patch.Responses["409"].Content[contentJSON].Schema.Ref)
+
+	del := doc.Paths["/users"]["delete"]
+	require.Nil(t, del.Responses["204"].Content)
+
+	require.Nil(t, doc.Paths["/users/{id}"]["get"].Security)
+}
+
+func TestOpenAPI_Served(t *testing.T) {
+	service := newTestService(t, nil, nil, nil, nil)
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/openapi.json", nil)
+	require.NoError(t, err)
+
+	service.router.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, contentJSON, recorder.Header().Get("Content-Type"))
+
+	var raw map[string]any
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&raw))
+	require.Equal(t, openAPIVersion, raw["openapi"])
+	require.Contains(t, raw["paths"], "/openapi.json")
+}
+
+// TestOpenAPI_TagsMatchValidators checks the openapi tags of the request bodies against their Validate methods,
+// since the tags can't refer to the limits the validators use.
+func TestOpenAPI_TagsMatchValidators(t *testing.T) {
+	type validatable interface{ Validate() *Vomit }
+
+	for pattern, opDoc := range routeDocs {
+		req, ok := opDoc.request.(validatable)
+		if !ok {
+			continue
+		}
+		typ := reflect.TypeOf(req)
+
+		for i := range typ.NumField() {
+			field := typ.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+
+			rules := make(map[string]int)
+			for rule := range strings.SplitSeq(field.Tag.Get("openapi"), ",") {
+				key, value, _ := strings.Cut(rule, "=")
+				if key != "" {
+					n, _ := strconv.Atoi(value)
+					rules[key] = n
+				}
+			}
+
+			// tags reports the issues of the field when set to v
+			tags := func(v string) []string {
+				val := reflect.New(typ).Elem()
+				fv := val.FieldByIndex(field.Index)
+				if fv.Kind() == reflect.Pointer {
+					fv.Set(reflect.New(fv.Type().Elem()))
+					fv = fv.Elem()
+				}
+				fv.SetString(v)
+
+				var got []string
+				if vErr := val.Interface().(validatable).Validate(); vErr != nil {
+					for _, issue := range vErr.Issues {
+						if issue.FieldName == name {
+							got = append(got, issue.Tag)
+						}
+					}
+				}
+				return got
+			}
+
+			where := fmt.Sprintf("%s: %s.%s", pattern, typ.Name(), field.Name)
+
+			// every validator of the field is documented
+			for _, v := range []string{"", "!", strings.Repeat("a", 10000)} {
+				for _, tag := range tags(v) {
+					require.Contains(t, rules, tag, "%s is validated with %q, which its openapi tag lacks", where, tag)
+				}
+			}
+
+			// and every documented rule is validated with the same limit
+			if _, ok := rules[validatorRequired]; ok {
+				require.Contains(t, tags(""), validatorRequired, where)
+			}
+			if n, ok := rules[validatorMax]; ok {
+				require.Contains(t, tags(strings.Repeat("a", n+1)), validatorMax, where)
+				require.NotContains(t, tags(strings.Repeat("a", n)), validatorMax, where)
+			}
+			if n, ok := rules[validatorMin]; ok {
+				require.Contains(t, tags(strings.Repeat("a", n-1)), validatorMin, where)
+				require.NotContains(t, tags(strings.Repeat("a", n)), validatorMin, where)
+			}
+			for _, rule := range []string{validatorEmail, validatorURL, validatorAlphanum} {
+				if _, ok := rules[rule]; ok {
+					require.Contains(t, tags("!"), rule, where)
+				}
+			}
+		}
+	}
+}
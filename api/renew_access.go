@@ -7,7 +7,7 @@ import (
 )
 
 type RenewAccessTokenRequest struct {
-	RefreshToken string `json:"refresh_token"`
+	RefreshToken string `json:"refresh_token" openapi:"required"`
 }
 
 func (r RenewAccessTokenRequest) Validate() *Vomit {
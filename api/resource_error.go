@@ -72,6 +72,8 @@ func opKindToHTTPStatus(kind db.Kind) int {
 		return http.StatusUnprocessableEntity
 	case db.KindInvalid:
 		return http.StatusBadRequest
+	case db.KindPrecondition:
+		return http.StatusPreconditionFailed
 	default:
 		return http.StatusInternalServerError
 	}
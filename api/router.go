@@ -4,8 +4,8 @@ import (
 	"net/http"
 )
 
-// Establishes HTTP router.
-func (service *Service) setupRouter(server *http.Server) {
+// Establishes HTTP router and builds the OpenAPI document for the registered routes.
+func (service *Service) setupRouter(server *http.Server) error {
 	// TODO: create private user path
 
 	// privatePostGroup.DELETE("/posts/:post_id")
@@ -13,7 +13,14 @@ func (service *Service) setupRouter(server *http.Server) {
 
 	// privatePostCommentGroup.POST("/posts/:post_id/comments/:comment_id/vote", notImplemented)
 
-	router := http.NewServeMux()
+	router := newRouteMux(service.authMiddleware)
+
+	// probes
+	router.HandleFunc("GET /healthz", service.healthz)
+	router.HandleFunc("GET /readyz", service.readyz)
+
+	// API description
+	router.HandleFunc("GET /openapi.json", service.openAPIHandler)
 
 	// passkey auth
 	router.HandleFunc("POST /users/signup/start", service.signupStart)
@@ -26,18 +33,26 @@ func (service *Service) setupRouter(server *http.Server) {
 
 	// comments CRUD
 	// one for root comments
-	router.HandleFunc("POST /posts/{post_id}/comments", service.authMiddleware(http.HandlerFunc(service.createComment)))
+	router.HandleAuthFunc("POST /posts/{post_id}/comments", service.createComment)
 	// and one for replies
-	router.HandleFunc("POST /posts/{post_id}/comments/{comment_id}", service.authMiddleware(http.HandlerFunc(service.createComment)))
+	router.HandleAuthFunc("POST /posts/{post_id}/comments/{comment_id}", service.createComment)
 	router.HandleFunc("GET /posts/{post_id}/comments", service.getComments)
-	router.HandleFunc("PATCH /posts/{post_id}/comments/{comment_id}", service.authMiddleware(http.HandlerFunc(service.updateComment)))
-	router.HandleFunc("DELETE /posts/{post_id}/comments/{comment_id}", service.authMiddleware(http.HandlerFunc(service.deleteComment)))
+	router.HandleAuthFunc("PATCH /posts/{post_id}/comments/{comment_id}", service.updateComment)
+	router.HandleAuthFunc("DELETE /posts/{post_id}/comments/{comment_id}", service.deleteComment)
 
 	// users CRUD
 	router.HandleFunc("GET /users/{id}", service.getUser)
-	router.HandleFunc("PATCH /users", service.authMiddleware(http.HandlerFunc(service.updateUser)))
-	router.HandleFunc("DELETE /users", service.authMiddleware(http.HandlerFunc(service.deleteUser)))
+	router.HandleAuthFunc("PATCH /users", service.updateUser)
+	router.HandleAuthFunc("DELETE /users", service.deleteUser)
 
-	server.Handler = service.corsMiddleware(router)
+	doc, err := buildOpenAPI(service.config.PublicOrigin.String(), router.patterns, router.authPatterns, routeDocs)
+	if err != nil {
+		return err
+	}
+	service.openAPI = doc
+
+	server.Handler = metricsMiddleware(service.corsMiddleware(compressMiddleware(router)))
 	service.router = router
+
+	return nil
 }
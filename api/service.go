@@ -2,10 +2,14 @@ package api
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	db "github.com/Drolfothesgnir/shitposter/db/sqlc"
+	"github.com/Drolfothesgnir/shitposter/sml"
 	"github.com/Drolfothesgnir/shitposter/tmpstore"
 	"github.com/Drolfothesgnir/shitposter/token"
 	"github.com/Drolfothesgnir/shitposter/util"
@@ -25,6 +29,10 @@ type Service struct {
 	router         http.Handler
 	webauthnConfig wauthn.WebAuthnConfig
 	redisStore     tmpstore.Store
+	openAPI        *OpenAPIDocument
+	commentEater   *sml.Eater
+	// shuttingDown makes the readiness probe fail as soon as the shutdown begins.
+	shuttingDown atomic.Bool
 }
 
 // Returns new service instance with provided config and store.
@@ -44,6 +52,12 @@ func NewService(
 		webauthnConfig: wa,
 	}
 
+	commentEater, err := newCommentEater(store)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create comment eater: %w", err)
+	}
+	service.commentEater = commentEater
+
 	server := &http.Server{
 		Addr: config.HTTPServerAddress.String(),
 	}
@@ -57,7 +71,9 @@ func NewService(
 	// how long to keep idle keep-alive connections open.
 	server.IdleTimeout = 60 * time.Second
 
-	service.setupRouter(server)
+	if err := service.setupRouter(server); err != nil {
+		return nil, fmt.Errorf("cannot set up router: %w", err)
+	}
 
 	service.server = server
 
@@ -105,7 +121,58 @@ func clearWebauthnSessionCookie(w http.ResponseWriter, secure bool) {
 	http.SetCookie(w, cookie)
 }
 
-// TODO: shutdown db store and other sub-services
+// Shutdown stops the service in order:
+//  1. the readiness probe starts failing;
+//  2. after SHUTDOWN_DRAIN_DELAY, which gives the load balancer time to notice the probe and stop
+//     routing to this instance, the server stops accepting new connections;
+//  3. in-flight requests are drained;
+//  4. the Redis client and the database pool are closed.
+//
+// All steps must complete before ctx is done. If the delay or draining times out, the pools are still
+// closed, so the remaining requests fail fast instead of holding connections.
 func (service *Service) Shutdown(ctx context.Context) error {
-	return service.server.Shutdown(ctx)
+	service.shuttingDown.Store(true)
+
+	delay := time.NewTimer(service.config.ShutdownDrainDelay)
+	defer delay.Stop()
+
+	select {
+	case <-delay.C:
+	case <-ctx.Done():
+	}
+
+	srvErr := service.server.Shutdown(ctx)
+	if srvErr != nil {
+		srvErr = fmt.Errorf("cannot drain HTTP server: %w", srvErr)
+	}
+
+	rsErr := closeWithin(ctx, service.redisStore.Close)
+	if rsErr != nil {
+		rsErr = fmt.Errorf("cannot close Redis store: %w", rsErr)
+	}
+
+	dbErr := closeWithin(ctx, func() error {
+		service.store.Shutdown()
+		return nil
+	})
+	if dbErr != nil {
+		dbErr = fmt.Errorf("cannot close database pool: %w", dbErr)
+	}
+
+	return errors.Join(srvErr, rsErr, dbErr)
+}
+
+// closeWithin runs closeFn and waits for it until ctx is done.
+// Closing a pool blocks until all borrowed connections are returned,
+// so closeFn is left to finish in the background if the deadline is exceeded.
+func closeWithin(ctx context.Context, closeFn func() error) error {
+	done := make(chan error, 1)
+	go func() { done <- closeFn() }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
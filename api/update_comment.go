@@ -7,12 +7,12 @@ import (
 )
 
 type UpdateCommentRequest struct {
-	Body string `json:"body"`
+	Body string `json:"body" openapi:"required,max=500"`
 }
 
 func (r UpdateCommentRequest) Validate() *Vomit {
 	issues := make([]Issue, 0)
-	validate(&issues, r.Body, "body", strRequired, strMax(500))
+	validate(&issues, r.Body, "body", strRequired, strMax(commentBodyMaxLen))
 	return barf(issues)
 }
 
@@ -30,6 +30,11 @@ func (s *Service) updateComment(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 
+	if _, vErr := munchMarkup(ctx, s.commentEater, req.Body, "body"); vErr != nil {
+		abortWithError(w, vErr)
+		return
+	}
+
 	authPayload := getAuthPayload(ctx)
 
 	postID, vErr := extractPostID(r)
@@ -44,11 +49,18 @@ func (s *Service) updateComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	version, vErr := ifMatchVersion(r, etagComment, commentID)
+	if vErr != nil {
+		abortWithError(w, vErr)
+		return
+	}
+
 	result, err := s.store.UpdateComment(ctx, db.UpdateCommentParams{
-		CommentID: commentID,
-		UserID:    authPayload.UserID,
-		PostID:    postID,
-		Body:      req.Body,
+		CommentID:        commentID,
+		UserID:           authPayload.UserID,
+		PostID:           postID,
+		Body:             req.Body,
+		IfLastModifiedAt: version,
 	})
 
 	if err != nil {
@@ -57,5 +69,6 @@ func (s *Service) updateComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	w.Header().Set("ETag", versionETag(etagComment, result.ID, result.LastModifiedAt))
 	respondWithJSON(w, http.StatusOK, result)
 }
@@ -25,6 +25,8 @@ func TestUpdateComment(t *testing.T) {
 		Body:      "test",
 	}
 
+	version := time.Date(2026, 4, 4, 12, 0, 0, 123456000, time.UTC)
+
 	testCases := []struct {
 		name          string
 		body          reqBody
@@ -237,6 +239,81 @@ func TestUpdateComment(t *testing.T) {
 				require.Equal(t, "test", resp.Body)
 			},
 		},
+		{
+			name: "IfMatchOK",
+			body: reqBody{
+				"body": "test",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				conditional := arg
+				conditional.IfLastModifiedAt = &version
+				store.EXPECT().UpdateComment(gomock.Any(), conditional).Times(1).Return(
+					db.UpdateCommentResult{
+						ID:             1,
+						Body:           "test",
+						LastModifiedAt: version.Add(time.Second),
+					},
+					nil,
+				)
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				setAuthorizationHeader(t, tokenMaker, authorizationTypeBearer, 1, time.Minute, request)
+				request.Header.Set("If-Match", versionETag(etagComment, 1, version))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, versionETag(etagComment, 1, version.Add(time.Second)), recorder.Header().Get("ETag"))
+			},
+		},
+		{
+			name: "IfMatchForeignTag",
+			body: reqBody{
+				"body": "test",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateComment(gomock.Any(), gomock.Any()).Times(0)
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				setAuthorizationHeader(t, tokenMaker, authorizationTypeBearer, 1, time.Minute, request)
+				request.Header.Set("If-Match", versionETag(etagComment, 2, version))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusPreconditionFailed, recorder.Code)
+				var resp Vomit
+				err := json.NewDecoder(recorder.Body).Decode(&resp)
+				require.NoError(t, err)
+				require.Equal(t, ReqPreconditionFailed, resp.Reason)
+			},
+		},
+		{
+			name: "IfMatchStale",
+			body: reqBody{
+				"body": "test",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateComment(gomock.Any(), gomock.Any()).Times(1).Return(
+					db.UpdateCommentResult{},
+					&db.OpError{
+						Op:       "update-comment",
+						Kind:     db.KindPrecondition,
+						Entity:   "comment",
+						EntityID: "1",
+						Err:      fmt.Errorf("comment with id 1 was modified"),
+					},
+				)
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				setAuthorizationHeader(t, tokenMaker, authorizationTypeBearer, 1, time.Minute, request)
+				request.Header.Set("If-Match", versionETag(etagComment, 1, version))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusPreconditionFailed, recorder.Code)
+				var resp ResourceError
+				err := json.NewDecoder(recorder.Body).Decode(&resp)
+				require.NoError(t, err)
+				require.Equal(t, db.KindPrecondition.String(), resp.Reason)
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -7,9 +7,9 @@ import (
 )
 
 type UpdateUserRequest struct {
-	Username      *string `json:"username"`
-	Email         *string `json:"email"`
-	ProfileImgURL *string `json:"profile_img_url"`
+	Username      *string `json:"username" openapi:"min=3,max=50,alphanum"`
+	Email         *string `json:"email" openapi:"email"`
+	ProfileImgURL *string `json:"profile_img_url" openapi:"url"`
 }
 
 func (r UpdateUserRequest) Validate() *Vomit {
@@ -55,11 +55,19 @@ func (service *Service) updateUser(w http.ResponseWriter, r *http.Request) {
 		abortWithError(w, vErr)
 		return
 	}
+
+	version, vErr := ifMatchVersion(r, etagUser, authPayload.UserID)
+	if vErr != nil {
+		abortWithError(w, vErr)
+		return
+	}
+
 	arg := db.UpdateUserParams{
-		ID:            authPayload.UserID,
-		Username:      req.Username,
-		Email:         req.Email,
-		ProfileImgURL: req.ProfileImgURL,
+		ID:               authPayload.UserID,
+		Username:         req.Username,
+		Email:            req.Email,
+		ProfileImgURL:    req.ProfileImgURL,
+		IfLastModifiedAt: version,
 	}
 
 	user, err := service.store.UpdateUser(ctx, arg)
@@ -70,5 +78,6 @@ func (service *Service) updateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	w.Header().Set("ETag", versionETag(etagUser, user.ID, user.LastModifiedAt))
 	respondWithJSON(w, http.StatusOK, user)
 }
@@ -19,6 +19,11 @@ const (
 	validatorMin      = "min"
 	validatorMax      = "max"
 	validatorAlphanum = "alphanum"
+	validatorMaxText  = "max_text"
+	validatorMaxLinks = "max_links"
+	validatorMaxNodes = "max_nodes"
+	validatorMaxDepth = "max_depth"
+	validatorMaxHTML  = "max_html"
 )
 
 // barf makes a *Vomit out of list of particular field errors.
@@ -18,6 +18,7 @@ const (
 	ReqInvalidCommentID     Flavor = "REQ_INVALID_COMMENT_ID"
 	ReqIncorrectContentType Flavor = "REQ_INVALID_CONTENT_TYPE"
 	ReqMissingData          Flavor = "REQ_MISSING_DATA"
+	ReqPreconditionFailed   Flavor = "REQ_PRECONDITION_FAILED"
 )
 
 // Issue describes the error of a particular payload field
@@ -11,7 +11,7 @@ import (
 )
 
 type SigninStartRequest struct {
-	Username string `json:"username"`
+	Username string `json:"username" openapi:"required,min=3,max=50,alphanum"`
 }
 
 func (r SigninStartRequest) Validate() *Vomit {
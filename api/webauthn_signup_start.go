@@ -18,8 +18,8 @@ import (
 // 4. Server saves user data and credentials in the db and returns user object to the client
 
 type SignupStartRequest struct {
-	Email    string `json:"email"`
-	Username string `json:"username"`
+	Email    string `json:"email" openapi:"required,email"`
+	Username string `json:"username" openapi:"required,min=3,max=50,alphanum"`
 }
 
 func (r SignupStartRequest) Validate() *Vomit {
@@ -0,0 +1,201 @@
+// Command sml renders the SML markup and reports its issues, for debugging the markup
+// and moderating from the shell.
+//
+// Usage:
+//
+//	sml [flags] [file ...]
+//
+// The files are read in order, or the standard input if there are none or the file is "-".
+// The rendition of each input is printed to the standard output, and its issues to the standard error,
+// one per line, like:
+//
+//	post.sml:3:14: UNKNOWN_TAG: unknown tag "X" is rendered as text
+//
+// The exit code is 1 if any issue is found, so the command can lint the content fixtures with -q,
+// and 2 if the flags are invalid or an input can't be read.
+//
+// The flags are:
+//
+//	-format string
+//		the rendition: html, text, ansi, markdown or json, the JSON tree (default "html")
+//	-q
+//		print the issues only
+//	-linkify
+//		turn the bare URLs and emails into links
+//	-emoji
+//		replace the :shortcode: of the standard emoji
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/Drolfothesgnir/shitposter/scum"
+	"github.com/Drolfothesgnir/shitposter/sml"
+)
+
+// The exit codes of the command.
+const (
+	exitOK     = 0
+	exitIssues = 1
+	exitError  = 2
+)
+
+// stdinName is the name of the standard input in the arguments.
+const stdinName = "-"
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// config is the parsed command line.
+type config struct {
+	format  string
+	quiet   bool
+	linkify bool
+	emoji   bool
+	files   []string
+}
+
+func parseFlags(args []string, stderr io.Writer) (config, error) {
+	var cfg config
+
+	fs := flag.NewFlagSet("sml", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: sml [flags] [file ...]")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&cfg.format, "format", "html", "the rendition: html, text, ansi, markdown or json, the JSON tree")
+	fs.BoolVar(&cfg.quiet, "q", false, "print the issues only")
+	fs.BoolVar(&cfg.linkify, "linkify", false, "turn the bare URLs and emails into links")
+	fs.BoolVar(&cfg.emoji, "emoji", false, "replace the :shortcode: of the standard emoji")
+
+	if err := fs.Parse(args); err != nil {
+		return config{}, err
+	}
+
+	if _, ok := renderers[cfg.format]; !ok {
+		err := fmt.Errorf("unknown format %q", cfg.format)
+		fmt.Fprintln(stderr, err)
+		fs.Usage()
+		return config{}, err
+	}
+
+	cfg.files = fs.Args()
+	if len(cfg.files) == 0 {
+		cfg.files = []string{stdinName}
+	}
+
+	return cfg, nil
+}
+
+// renderers write the rendition of the munched input in the formats of the -format flag.
+var renderers = map[string]func(poop *sml.Poop) (string, error){
+	"html":     func(poop *sml.Poop) (string, error) { return poop.HTML(), nil },
+	"text":     func(poop *sml.Poop) (string, error) { return poop.Text(), nil },
+	"ansi":     func(poop *sml.Poop) (string, error) { return poop.ANSI(), nil },
+	"markdown": func(poop *sml.Poop) (string, error) { return poop.Markdown(), nil },
+	"json": func(poop *sml.Poop) (string, error) {
+		b, err := json.MarshalIndent(poop.Tree, "", "  ")
+		return string(b), err
+	},
+}
+
+// run runs the command with the arguments and returns its exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	cfg, err := parseFlags(args, stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	if err != nil {
+		return exitError
+	}
+
+	var opts []sml.Option
+	if cfg.linkify {
+		opts = append(opts, sml.WithLinkify())
+	}
+	if cfg.emoji {
+		opts = append(opts, sml.WithEmoji(nil))
+	}
+
+	eater, err := sml.NewEater(scum.WarnOverflowNoCap, 0, opts...)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+
+	code := exitOK
+	for _, name := range cfg.files {
+		input, err := readInput(name, stdin)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitError
+		}
+
+		poop, issues := eater.Munch(input)
+
+		if !cfg.quiet {
+			out, err := renderers[cfg.format](&poop)
+			if err != nil {
+				fmt.Fprintln(stderr, err)
+				return exitError
+			}
+
+			io.WriteString(stdout, out)
+			if !strings.HasSuffix(out, "\n") {
+				io.WriteString(stdout, "\n")
+			}
+		}
+
+		if len(issues) > 0 {
+			printIssues(stderr, name, input, issues)
+			code = exitIssues
+		}
+	}
+
+	return code
+}
+
+// readInput reads the file with the name, or stdin if the name is [stdinName].
+func readInput(name string, stdin io.Reader) (string, error) {
+	var (
+		b   []byte
+		err error
+	)
+	if name == stdinName {
+		b, err = io.ReadAll(stdin)
+	} else {
+		b, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("can't read %s: %w", name, err)
+	}
+
+	return string(b), nil
+}
+
+// printIssues writes the issues of the input, one per line, with the line and the column of their start,
+// in the order of the input.
+func printIssues(w io.Writer, name, input string, issues []sml.SyntaxIssue) {
+	if name == stdinName {
+		name = "<stdin>"
+	}
+
+	slices.SortStableFunc(issues, func(a, b sml.SyntaxIssue) int {
+		return a.Span().Start - b.Span().Start
+	})
+
+	r := scum.NewPositionResolver(input)
+	for _, issue := range issues {
+		pos := r.Resolve(issue.Span().Start)
+		fmt.Fprintf(w, "%s:%d:%d: %s: %s\n", name, pos.Line, pos.Column, issue.Codename(), issue.Description())
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Drolfothesgnir/shitposter/scum"
+	"github.com/stretchr/testify/require"
+)
+
+func runCommand(t *testing.T, stdin string, args ...string) (code int, stdout, stderr string) {
+	t.Helper()
+
+	var out, errOut bytes.Buffer
+	code = run(args, strings.NewReader(stdin), &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+func TestRun_Formats(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{format: "html", want: "<strong>hi</strong> :smile:\n"},
+		{format: "text", want: "hi :smile:\n"},
+		{format: "ansi", want: "\x1b[1mhi\x1b[22m :smile:\n"},
+		{format: "markdown", want: "**hi** :smile:\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			code, stdout, stderr := runCommand(t, "$hi$ :smile:", "-format", tt.format)
+
+			require.Equal(t, exitOK, code)
+			require.Equal(t, tt.want, stdout)
+			require.Empty(t, stderr)
+		})
+	}
+
+	t.Run("json", func(t *testing.T) {
+		code, stdout, _ := runCommand(t, "$hi$", "-format", "json")
+		require.Equal(t, exitOK, code)
+
+		var tree scum.SerializableNode
+		require.NoError(t, json.Unmarshal([]byte(stdout), &tree))
+		require.Equal(t, "BOLD", tree.Children[0].Name)
+	})
+}
+
+func TestRun_ANSIHidesSpoilers(t *testing.T) {
+	code, stdout, _ := runCommand(t, "||secret||", "-format", "ansi")
+
+	require.Equal(t, exitOK, code)
+	require.NotContains(t, stdout, "secret")
+}
+
+func TestRun_Options(t *testing.T) {
+	code, stdout, _ := runCommand(t, ":smile: https://example.com", "-emoji", "-linkify")
+
+	require.Equal(t, exitOK, code)
+	require.Equal(t, "😄 <a href=\"https://example.com\">https://example.com</a>\n", stdout)
+}
+
+func TestRun_Issues(t *testing.T) {
+	dir := t.TempDir()
+	clean := filepath.Join(dir, "clean.sml")
+	dirty := filepath.Join(dir, "dirty.sml")
+	require.NoError(t, os.WriteFile(clean, []byte("$fine$"), 0o644))
+	require.NoError(t, os.WriteFile(dirty, []byte("ok\nnot ]x"), 0o644))
+
+	code, stdout, stderr := runCommand(t, "", "-q", clean, dirty)
+
+	require.Equal(t, exitIssues, code)
+	require.Empty(t, stdout)
+	require.True(t, strings.HasPrefix(stderr, dirty+":2:"), stderr)
+	require.Equal(t, 1, strings.Count(stderr, "\n"), stderr)
+
+	code, _, stderr = runCommand(t, "", "-q", clean)
+	require.Equal(t, exitOK, code)
+	require.Empty(t, stderr)
+}
+
+func TestRun_Errors(t *testing.T) {
+	code, _, stderr := runCommand(t, "", "-format", "pdf")
+	require.Equal(t, exitError, code)
+	require.Contains(t, stderr, `unknown format "pdf"`)
+
+	code, _, stderr = runCommand(t, "", filepath.Join(t.TempDir(), "missing.sml"))
+	require.Equal(t, exitError, code)
+	require.Contains(t, stderr, "can't read")
+}
@@ -0,0 +1,100 @@
+// Command smlwasm is the WebAssembly build of the SML parser for the editor preview.
+//
+// Build it with:
+//
+//	GOOS=js GOARCH=wasm go build -o scum.wasm ./cmd/smlwasm
+//
+// and load it with wasm_exec.js from the Go distribution. It exposes the global sml object:
+//
+//	sml.munch(input: string): string         // JSON of a Result
+//	sml.munchBlocks(blocks: string[]): string // JSON array of the Results, one per block
+//	sml.dialect: string                      // the YAML spec of the SML tags
+//
+// Invalid arguments return an Error instead of the JSON.
+package main
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/Drolfothesgnir/shitposter/scum"
+	"github.com/Drolfothesgnir/shitposter/sml"
+)
+
+// warnCap bounds the warnings of a single input, the preview doesn't need more.
+const warnCap = 100
+
+// Result is the JSON contract of munch. It is kept free of the js bindings,
+// so it can be checked against [sml.Eater.Munch] in regular Go tests.
+type Result struct {
+	HTML   string                `json:"html"`
+	Tree   scum.SerializableNode `json:"tree"`
+	Issues []sml.SyntaxIssue     `json:"issues"`
+}
+
+// newResult builds the [Result] of the munched input. Issues are never null in the JSON.
+func newResult(poop *sml.Poop, issues []sml.SyntaxIssue) Result {
+	if issues == nil {
+		issues = []sml.SyntaxIssue{}
+	}
+
+	return Result{
+		HTML:   poop.HTML(),
+		Tree:   poop.Tree,
+		Issues: issues,
+	}
+}
+
+// muncher parses the inputs coming from JS.
+// The Poops are pooled, so the ASTs are reused between the keystrokes of the preview.
+type muncher struct {
+	eater sml.Eater
+	poops sync.Pool
+}
+
+func newMuncher(warnCap int) (*muncher, error) {
+	eater, err := sml.NewEater(scum.WarnOverflowTrunc, warnCap)
+	if err != nil {
+		return nil, err
+	}
+
+	return &muncher{
+		eater: eater,
+		poops: sync.Pool{
+			New: func() any {
+				return new(sml.Poop)
+			},
+		},
+	}, nil
+}
+
+// munch parses the input and returns its [Result] as JSON.
+func (m *muncher) munch(input string) ([]byte, error) {
+	poop := m.poops.Get().(*sml.Poop)
+	defer m.poops.Put(poop)
+
+	issues := m.eater.MunchInto(poop, input)
+
+	return json.Marshal(newResult(poop, issues))
+}
+
+// munchBlocks parses every text block of a post independently
+// and returns the JSON array of their [Result]s, in the order of the blocks.
+func (m *muncher) munchBlocks(blocks []string) ([]byte, error) {
+	poop := m.poops.Get().(*sml.Poop)
+	defer m.poops.Put(poop)
+
+	results := make([]json.RawMessage, len(blocks))
+	for i, block := range blocks {
+		issues := m.eater.MunchInto(poop, block)
+
+		// the Result has to be marshaled before the Poop is reused for the next block
+		res, err := json.Marshal(newResult(poop, issues))
+		if err != nil {
+			return nil, err
+		}
+		results[i] = res
+	}
+
+	return json.Marshal(results)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/Drolfothesgnir/shitposter/scum"
+	"github.com/Drolfothesgnir/shitposter/sml"
+	"github.com/stretchr/testify/require"
+)
+
+var contractInputs = []string{
+	"",
+	"plain text",
+	"pre $bold$ *italic* _under_line_ [link]!href{https://example.com} hé",
+	"$unclosed *nested $tags",
+	"[bad link]!href{javascript:alert(1)}!target{_top}",
+	"\\$escaped\\$ and a redundant \\escape",
+	strings.Repeat("$a *b* c$ ", 200),
+}
+
+// wantJSON is the contract: the JSON of what sml.Eater.Munch returns for the input.
+func wantJSON(t *testing.T, input string) []byte {
+	t.Helper()
+
+	eater, err := sml.NewEater(scum.WarnOverflowTrunc, warnCap)
+	require.NoError(t, err)
+
+	poop, issues := eater.Munch(input)
+	if issues == nil {
+		issues = []sml.SyntaxIssue{}
+	}
+
+	data, err := json.Marshal(struct {
+		HTML   string                `json:"html"`
+		Tree   scum.SerializableNode `json:"tree"`
+		Issues []sml.SyntaxIssue     `json:"issues"`
+	}{poop.HTML(), poop.Tree, issues})
+	require.NoError(t, err)
+
+	return data
+}
+
+func TestMunch_MatchesEater(t *testing.T) {
+	m, err := newMuncher(warnCap)
+	require.NoError(t, err)
+
+	// twice, so the second round runs on the reused ASTs
+	for range 2 {
+		for _, input := range contractInputs {
+			got, err := m.munch(input)
+			require.NoError(t, err)
+			require.Equal(t, string(wantJSON(t, input)), string(got), input)
+		}
+	}
+}
+
+func TestMunch_Shape(t *testing.T) {
+	m, err := newMuncher(warnCap)
+	require.NoError(t, err)
+
+	got, err := m.munch("$bold")
+	require.NoError(t, err)
+
+	var res map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(got, &res))
+	require.ElementsMatch(t, []string{"html", "tree", "issues"}, keys(res))
+
+	var issues []map[string]any
+	require.NoError(t, json.Unmarshal(res["issues"], &issues))
+	require.NotEmpty(t, issues)
+	require.Equal(t, "UNCLOSED_TAG", issues[0]["codename"])
+
+	got, err = m.munch("fine")
+	require.NoError(t, err)
+	require.Contains(t, string(got), `"issues":[]`)
+}
+
+func TestMunchBlocks_MatchesEater(t *testing.T) {
+	m, err := newMuncher(warnCap)
+	require.NoError(t, err)
+
+	// the large block goes first, so the smaller ones reuse its AST
+	blocks := []string{contractInputs[6], contractInputs[2], contractInputs[0], contractInputs[3]}
+
+	got, err := m.munchBlocks(blocks)
+	require.NoError(t, err)
+
+	var results []json.RawMessage
+	require.NoError(t, json.Unmarshal(got, &results))
+	require.Len(t, results, len(blocks))
+
+	for i, block := range blocks {
+		require.Equal(t, string(wantJSON(t, block)), string(results[i]), block)
+	}
+
+	got, err = m.munchBlocks(nil)
+	require.NoError(t, err)
+	require.Equal(t, "[]", string(got))
+}
+
+func keys(m map[string]json.RawMessage) []string {
+	ks := make([]string, 0, len(m))
+	for k := range m {
+		ks = append(ks, k)
+	}
+	return ks
+}
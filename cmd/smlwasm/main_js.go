@@ -0,0 +1,62 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"syscall/js"
+
+	"github.com/Drolfothesgnir/shitposter/sml"
+)
+
+func main() {
+	m, err := newMuncher(warnCap)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	js.Global().Set("sml", js.ValueOf(map[string]any{
+		"munch":       js.FuncOf(m.jsMunch),
+		"munchBlocks": js.FuncOf(m.jsMunchBlocks),
+		"dialect":     string(sml.Dialect()),
+	}))
+
+	// the exported functions must outlive main
+	select {}
+}
+
+func (m *muncher) jsMunch(_ js.Value, args []js.Value) any {
+	if len(args) != 1 || args[0].Type() != js.TypeString {
+		return jsError("munch expects a single string argument")
+	}
+
+	return jsResult(m.munch(args[0].String()))
+}
+
+func (m *muncher) jsMunchBlocks(_ js.Value, args []js.Value) any {
+	if len(args) != 1 || !js.Global().Get("Array").Call("isArray", args[0]).Bool() {
+		return jsError("munchBlocks expects an array of strings")
+	}
+
+	arr := args[0]
+	blocks := make([]string, arr.Length())
+	for i := range blocks {
+		v := arr.Index(i)
+		if v.Type() != js.TypeString {
+			return jsError("munchBlocks expects an array of strings")
+		}
+		blocks[i] = v.String()
+	}
+
+	return jsResult(m.munchBlocks(blocks))
+}
+
+func jsResult(data []byte, err error) any {
+	if err != nil {
+		return jsError(err.Error())
+	}
+	return string(data)
+}
+
+func jsError(msg string) js.Value {
+	return js.Global().Get("Error").New(msg)
+}
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS comment_mentions_user_id_idx;
+DROP TABLE IF EXISTS comment_mentions;
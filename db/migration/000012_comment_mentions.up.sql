@@ -0,0 +1,9 @@
+-- The users mentioned in the comments, so the mention notifications can be fanned out
+CREATE TABLE IF NOT EXISTS comment_mentions (
+  comment_id BIGINT NOT NULL REFERENCES comments (id) ON DELETE CASCADE,
+  user_id BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT (now()),
+  PRIMARY KEY (comment_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS comment_mentions_user_id_idx ON comment_mentions(user_id);
@@ -177,6 +177,35 @@ func (mr *MockStoreMockRecorder) InsertCommentTx(ctx, arg any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertCommentTx", reflect.TypeOf((*MockStore)(nil).InsertCommentTx), ctx, arg)
 }
 
+// MigrationStatus mocks base method.
+func (m *MockStore) MigrationStatus(ctx context.Context) (db.MigrationStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MigrationStatus", ctx)
+	ret0, _ := ret[0].(db.MigrationStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MigrationStatus indicates an expected call of MigrationStatus.
+func (mr *MockStoreMockRecorder) MigrationStatus(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MigrationStatus", reflect.TypeOf((*MockStore)(nil).MigrationStatus), ctx)
+}
+
+// Ping mocks base method.
+func (m *MockStore) Ping(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockStoreMockRecorder) Ping(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockStore)(nil).Ping), ctx)
+}
+
 // QueryComments mocks base method.
 func (m *MockStore) QueryComments(ctx context.Context, query db.CommentQuery) ([]db.CommentsWithAuthor, error) {
 	m.ctrl.T.Helper()
@@ -206,6 +235,21 @@ func (mr *MockStoreMockRecorder) RecordCredentialUse(ctx, arg any) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordCredentialUse", reflect.TypeOf((*MockStore)(nil).RecordCredentialUse), ctx, arg)
 }
 
+// ResolveUsernames mocks base method.
+func (m *MockStore) ResolveUsernames(ctx context.Context, usernames []string) (map[string]int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResolveUsernames", ctx, usernames)
+	ret0, _ := ret[0].(map[string]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResolveUsernames indicates an expected call of ResolveUsernames.
+func (mr *MockStoreMockRecorder) ResolveUsernames(ctx, usernames any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveUsernames", reflect.TypeOf((*MockStore)(nil).ResolveUsernames), ctx, usernames)
+}
+
 // Shutdown mocks base method.
 func (m *MockStore) Shutdown() {
 	m.ctrl.T.Helper()
@@ -219,18 +263,18 @@ func (mr *MockStoreMockRecorder) Shutdown() *gomock.Call {
 }
 
 // SoftDeleteUserTx mocks base method.
-func (m *MockStore) SoftDeleteUserTx(ctx context.Context, userID int64) (db.SoftDeleteUserTxResult, error) {
+func (m *MockStore) SoftDeleteUserTx(ctx context.Context, arg db.SoftDeleteUserTxParams) (db.SoftDeleteUserTxResult, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SoftDeleteUserTx", ctx, userID)
+	ret := m.ctrl.Call(m, "SoftDeleteUserTx", ctx, arg)
 	ret0, _ := ret[0].(db.SoftDeleteUserTxResult)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // SoftDeleteUserTx indicates an expected call of SoftDeleteUserTx.
-func (mr *MockStoreMockRecorder) SoftDeleteUserTx(ctx, userID any) *gomock.Call {
+func (mr *MockStoreMockRecorder) SoftDeleteUserTx(ctx, arg any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SoftDeleteUserTx", reflect.TypeOf((*MockStore)(nil).SoftDeleteUserTx), ctx, userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SoftDeleteUserTx", reflect.TypeOf((*MockStore)(nil).SoftDeleteUserTx), ctx, arg)
 }
 
 // UpdateComment mocks base method.
@@ -11,12 +11,23 @@ INSERT INTO comments (
   $1, $2, $3, $4, $5, $6, $7
 ) RETURNING *;
 
+-- name: createCommentMentions :exec
+INSERT INTO comment_mentions (comment_id, user_id)
+SELECT @comment_id::BIGINT, unnest(@user_ids::BIGINT[])
+ON CONFLICT DO NOTHING;
+
 -- name: getCommentWithLock :one
 SELECT * FROM comments
 WHERE id = $1 
 FOR KEY SHARE
 LIMIT 1;
 
+-- name: getCommentForUpdate :one
+SELECT * FROM comments
+WHERE id = $1
+LIMIT 1
+FOR UPDATE;
+
 -- name: deleteCommentIfLeaf :one
 SELECT
   id::BIGINT AS id,
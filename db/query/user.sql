@@ -13,6 +13,11 @@ INSERT INTO users (
 SELECT * FROM users
 WHERE id = $1 LIMIT 1;
 
+-- name: getUserForUpdate :one
+SELECT * FROM users
+WHERE id = $1 LIMIT 1
+FOR UPDATE;
+
 -- name: usernameExists :one 
 SELECT EXISTS (SELECT 1 from users WHERE username = $1) AS username_exists;
 
@@ -61,4 +66,8 @@ LIMIT $1;
 
 -- name: getUserByEmail :one
 SELECT * FROM users
-WHERE email = $1;
\ No newline at end of file
+WHERE email = $1;
+
+-- name: getActiveUserIDsByUsernames :many
+SELECT id, username FROM users
+WHERE username = ANY(@usernames::TEXT[]) AND is_deleted = FALSE;
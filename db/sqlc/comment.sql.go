@@ -65,6 +65,22 @@ func (q *Queries) createComment(ctx context.Context, arg createCommentParams) (C
 	return i, err
 }
 
+const createCommentMentions = `-- name: createCommentMentions :exec
+INSERT INTO comment_mentions (comment_id, user_id)
+SELECT $1::BIGINT, unnest($2::BIGINT[])
+ON CONFLICT DO NOTHING
+`
+
+type createCommentMentionsParams struct {
+	CommentID int64   `json:"comment_id"`
+	UserIds   []int64 `json:"user_ids"`
+}
+
+func (q *Queries) createCommentMentions(ctx context.Context, arg createCommentMentionsParams) error {
+	_, err := q.db.Exec(ctx, createCommentMentions, arg.CommentID, arg.UserIds)
+	return err
+}
+
 const deleteCommentIfLeaf = `-- name: deleteCommentIfLeaf :one
 SELECT
   id::BIGINT AS id,
@@ -138,6 +154,34 @@ func (q *Queries) getComment(ctx context.Context, id int64) (Comment, error) {
 	return i, err
 }
 
+const getCommentForUpdate = `-- name: getCommentForUpdate :one
+SELECT id, user_id, post_id, parent_id, depth, upvotes, downvotes, body, created_at, last_modified_at, is_deleted, deleted_at, popularity FROM comments
+WHERE id = $1
+LIMIT 1
+FOR UPDATE
+`
+
+func (q *Queries) getCommentForUpdate(ctx context.Context, id int64) (Comment, error) {
+	row := q.db.QueryRow(ctx, getCommentForUpdate, id)
+	var i Comment
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.PostID,
+		&i.ParentID,
+		&i.Depth,
+		&i.Upvotes,
+		&i.Downvotes,
+		&i.Body,
+		&i.CreatedAt,
+		&i.LastModifiedAt,
+		&i.IsDeleted,
+		&i.DeletedAt,
+		&i.Popularity,
+	)
+	return i, err
+}
+
 const getCommentWithAuthor = `-- name: getCommentWithAuthor :one
 SELECT id, user_id, post_id, parent_id, depth, upvotes, downvotes, body, created_at, last_modified_at, is_deleted, deleted_at, popularity, user_display_name, user_profile_img_url FROM comments_with_author
 WHERE id = $1
@@ -46,19 +46,24 @@ const (
 	// database consistency or do other harm (e.g. use of less-than-actual sign count
 	// during webauthn credential use)
 	KindSecurity
+
+	// KindPrecondition indicates that the entity was modified since the version
+	// the caller based the operation on (optimistic concurrency check failed).
+	KindPrecondition
 )
 
 var kindNames = map[Kind]string{
-	KindInternal:   "internal",
-	KindNotFound:   "not_found",
-	KindInvalid:    "invalid",
-	KindPermission: "permission",
-	KindRelation:   "relation",
-	KindConflict:   "conflict",
-	KindDeleted:    "deleted",
-	KindCorrupted:  "corrupted",
-	KindConstraint: "constraint",
-	KindSecurity:   "security",
+	KindInternal:     "internal",
+	KindNotFound:     "not_found",
+	KindInvalid:      "invalid",
+	KindPermission:   "permission",
+	KindRelation:     "relation",
+	KindConflict:     "conflict",
+	KindDeleted:      "deleted",
+	KindCorrupted:    "corrupted",
+	KindConstraint:   "constraint",
+	KindSecurity:     "security",
+	KindPrecondition: "precondition",
 }
 
 func (k Kind) String() string {
@@ -76,6 +81,8 @@ const (
 	entUser        = "user"
 	entWauthnCred  = "webauthn-credential"
 	entSession     = "session"
+	entDatabase    = "database"
+	entSchema      = "schema"
 )
 
 // OpError describes a failure of a database operation.
@@ -2,10 +2,64 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	// maxTxAttempts is the number of times a transaction is tried
+	// before a serialization failure or a deadlock is returned to the caller.
+	maxTxAttempts = 3
+	// txRetryBackoff is multiplied by the attempt number between retries.
+	txRetryBackoff = 10 * time.Millisecond
 )
 
+// execTx runs fn in a transaction. Transactions aborted because of a serialization
+// failure or a deadlock are retried up to [maxTxAttempts] times with a new transaction.
+//
+// fn must be idempotent: it can run several times for a single call, and only the effects of
+// its last run are kept by the database. So it must not have side effects outside the transaction,
+// like sending a message or appending to a slice of the caller, and it must overwrite the results
+// it captures rather than accumulate them.
 func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) error {
+	return retryTx(ctx, func() error {
+		return store.runTx(ctx, fn)
+	})
+}
+
+// retryTx calls run until it succeeds, fails with an error which is not retryable, or [maxTxAttempts] is reached,
+// waiting between the attempts. If ctx is done while waiting, the error of the last attempt is returned.
+func retryTx(ctx context.Context, run func() error) error {
+	var err error
+
+	for attempt := 1; ; attempt++ {
+		err = run()
+		if err == nil {
+			return nil
+		}
+
+		if attempt == maxTxAttempts || !isRetryableTxError(err) {
+			break
+		}
+
+		txRetries.Inc(sqlState(err))
+
+		select {
+		case <-ctx.Done():
+			txFailures.Inc(sqlState(err))
+			return err
+		case <-time.After(time.Duration(attempt) * txRetryBackoff):
+		}
+	}
+
+	txFailures.Inc(sqlState(err))
+	return err
+}
+
+func (store *SQLStore) runTx(ctx context.Context, fn func(*Queries) error) error {
 	tx, err := store.connPool.Begin(ctx)
 	if err != nil {
 		return err
@@ -15,7 +69,7 @@ func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) erro
 	err = fn(q)
 	if err != nil {
 		if rbErr := tx.Rollback(ctx); rbErr != nil {
-			return fmt.Errorf("tx err: %v, rb err: %v", err, rbErr)
+			return fmt.Errorf("tx err: %w, rb err: %v", err, rbErr)
 		}
 
 		return err
@@ -23,3 +77,20 @@ func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) erro
 
 	return tx.Commit(ctx)
 }
+
+// isRetryableTxError reports whether the transaction was aborted by Postgres
+// in a way which may succeed when simply run again.
+func isRetryableTxError(err error) bool {
+	// serialization_failure and deadlock_detected
+	code := sqlState(err)
+	return code == "40001" || code == "40P01"
+}
+
+// sqlState returns the SQLSTATE of the Postgres error in the chain, or "none" for other errors.
+func sqlState(err error) string {
+	var pgError *pgconn.PgError
+	if !errors.As(err, &pgError) {
+		return "none"
+	}
+	return pgError.Code
+}
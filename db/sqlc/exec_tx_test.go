@@ -0,0 +1,112 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryTx(t *testing.T) {
+	serializationFailure := &pgconn.PgError{Code: "40001"}
+	deadlock := &pgconn.PgError{Code: "40P01"}
+	uniqueViolation := &pgconn.PgError{Code: "23505"}
+
+	testCases := []struct {
+		name         string
+		errs         []error
+		cancelAfter  int
+		wantErr      error
+		wantAttempts int
+		wantRetries  map[string]float64
+		wantFailures map[string]float64
+	}{
+		{
+			name:         "OK",
+			errs:         []error{nil},
+			wantAttempts: 1,
+		},
+		{
+			name:         "RetriedUntilSuccess",
+			errs:         []error{serializationFailure, deadlock, nil},
+			wantAttempts: 3,
+			wantRetries:  map[string]float64{"40001": 1, "40P01": 1},
+		},
+		{
+			name:         "WrappedInOpError",
+			errs:         []error{sqlError(opVoteComment, opDetails{entity: entCommentVote}, serializationFailure), nil},
+			wantAttempts: 2,
+			wantRetries:  map[string]float64{"40001": 1},
+		},
+		{
+			name:         "AttemptsExhausted",
+			errs:         []error{serializationFailure, serializationFailure, serializationFailure, nil},
+			wantErr:      serializationFailure,
+			wantAttempts: maxTxAttempts,
+			wantRetries:  map[string]float64{"40001": maxTxAttempts - 1},
+			wantFailures: map[string]float64{"40001": 1},
+		},
+		{
+			name:         "NotRetryable",
+			errs:         []error{uniqueViolation, nil},
+			wantErr:      uniqueViolation,
+			wantAttempts: 1,
+			wantFailures: map[string]float64{"23505": 1},
+		},
+		{
+			name:         "NotPostgresError",
+			errs:         []error{errors.New("conn closed"), nil},
+			wantErr:      errors.New("conn closed"),
+			wantAttempts: 1,
+			wantFailures: map[string]float64{"none": 1},
+		},
+		{
+			name:         "CanceledDuringBackoff",
+			errs:         []error{deadlock, nil},
+			cancelAfter:  1,
+			wantErr:      deadlock,
+			wantAttempts: 1,
+			wantRetries:  map[string]float64{"40P01": 1},
+			wantFailures: map[string]float64{"40P01": 1},
+		},
+	}
+
+	labels := []string{"40001", "40P01", "23505", "none"}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			retries := make(map[string]float64)
+			failures := make(map[string]float64)
+			for _, l := range labels {
+				retries[l] = txRetries.Value(l)
+				failures[l] = txFailures.Value(l)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			attempts := 0
+			err := retryTx(ctx, func() error {
+				attempts++
+				if attempts == tc.cancelAfter {
+					cancel()
+				}
+				return tc.errs[attempts-1]
+			})
+
+			if tc.wantErr == nil {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.wantErr.Error())
+			}
+			require.Equal(t, tc.wantAttempts, attempts)
+
+			for _, l := range labels {
+				require.Equal(t, tc.wantRetries[l], txRetries.Value(l)-retries[l], "retries %s", l)
+				require.Equal(t, tc.wantFailures[l], txFailures.Value(l)-failures[l], "failures %s", l)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"github.com/Drolfothesgnir/shitposter/metrics"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	txRetries = metrics.Default.NewCounter(
+		"db_tx_retries_total",
+		"Transactions retried after a serialization failure or deadlock, by SQLSTATE.",
+		"sqlstate",
+	)
+
+	// the errors not returned by Postgres are counted as "none"
+	txFailures = metrics.Default.NewCounter(
+		"db_tx_failures_total",
+		"Transactions which finally failed, by SQLSTATE.",
+		"sqlstate",
+	)
+)
+
+// RegisterPoolMetrics exposes the connection pool statistics on the registry.
+// The values are read from the pool on every scrape.
+func RegisterPoolMetrics(r *metrics.Registry, pool *pgxpool.Pool) {
+	r.NewGaugeFunc("db_pool_total_conns", "Total connections in the pool.", func() float64 {
+		return float64(pool.Stat().TotalConns())
+	})
+	r.NewGaugeFunc("db_pool_acquired_conns", "Connections currently acquired from the pool.", func() float64 {
+		return float64(pool.Stat().AcquiredConns())
+	})
+	r.NewGaugeFunc("db_pool_idle_conns", "Idle connections in the pool.", func() float64 {
+		return float64(pool.Stat().IdleConns())
+	})
+	r.NewGaugeFunc("db_pool_constructing_conns", "Connections being established.", func() float64 {
+		return float64(pool.Stat().ConstructingConns())
+	})
+	r.NewGaugeFunc("db_pool_max_conns", "Maximum size of the pool.", func() float64 {
+		return float64(pool.Stat().MaxConns())
+	})
+	r.NewCounterFunc("db_pool_acquires_total", "Successful connection acquires.", func() float64 {
+		return float64(pool.Stat().AcquireCount())
+	})
+	r.NewCounterFunc("db_pool_empty_acquires_total", "Acquires which had to wait for a connection.", func() float64 {
+		return float64(pool.Stat().EmptyAcquireCount())
+	})
+	r.NewCounterFunc("db_pool_canceled_acquires_total", "Acquires canceled by the context.", func() float64 {
+		return float64(pool.Stat().CanceledAcquireCount())
+	})
+	r.NewCounterFunc("db_pool_acquire_duration_seconds_total", "Total time spent acquiring connections.", func() float64 {
+		return pool.Stat().AcquireDuration().Seconds()
+	})
+}
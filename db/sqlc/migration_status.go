@@ -0,0 +1,80 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/golang-migrate/migrate/v4/source"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jackc/pgx/v5"
+)
+
+const opMigrationStatus = "migration-status"
+
+// MigrationStatus describes the schema version of the database
+// compared to the migrations shipped with the service.
+type MigrationStatus struct {
+	// Current is the version recorded by golang-migrate, 0 if no migration was applied.
+	Current uint
+	// Dirty is true if the last migration failed half-way.
+	Dirty bool
+	// Latest is the highest version found in the migration source.
+	Latest uint
+}
+
+// UpToDate reports whether the latest migration is applied cleanly.
+func (s MigrationStatus) UpToDate() bool {
+	return !s.Dirty && s.Current == s.Latest
+}
+
+// MigrationStatus reads the applied schema version from the database and compares it to the latest version
+// of the migration source configured with MIGRATION_URL, which is read once by [NewStore].
+// Returns KindInternal if either of them cannot be read.
+func (store *SQLStore) MigrationStatus(ctx context.Context) (MigrationStatus, error) {
+	var status MigrationStatus
+
+	if store.latestMigrationErr != nil {
+		return status, newOpError(opMigrationStatus, KindInternal, entSchema, store.latestMigrationErr)
+	}
+	status.Latest = store.latestMigration
+
+	var version int64
+	err := store.connPool.QueryRow(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").
+		Scan(&version, &status.Dirty)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return status, newOpError(opMigrationStatus, KindInternal, entSchema, err)
+	}
+	status.Current = uint(version)
+
+	return status, nil
+}
+
+// latestMigrationVersion walks the migration source and returns its last version.
+func latestMigrationVersion(sourceURL string) (uint, error) {
+	drv, err := source.Open(sourceURL)
+	if err != nil {
+		return 0, fmt.Errorf("cannot open migration source: %w", err)
+	}
+	defer drv.Close()
+
+	version, err := drv.First()
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("cannot read first migration: %w", err)
+	}
+
+	for {
+		next, err := drv.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("cannot read migration after version %d: %w", version, err)
+		}
+		version = next
+	}
+}
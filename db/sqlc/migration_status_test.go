@@ -0,0 +1,22 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatestMigrationVersion(t *testing.T) {
+	version, err := latestMigrationVersion("file://../migration")
+	require.NoError(t, err)
+	require.Equal(t, uint(12), version)
+
+	_, err = latestMigrationVersion("file://./does-not-exist")
+	require.Error(t, err)
+}
+
+func TestMigrationStatusUpToDate(t *testing.T) {
+	require.True(t, MigrationStatus{Current: 11, Latest: 11}.UpToDate())
+	require.False(t, MigrationStatus{Current: 10, Latest: 11}.UpToDate())
+	require.False(t, MigrationStatus{Current: 11, Latest: 11, Dirty: true}.UpToDate())
+}
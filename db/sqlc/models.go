@@ -71,6 +71,12 @@ type Comment struct {
 	Popularity     pgtype.Int8 `json:"popularity"`
 }
 
+type CommentMention struct {
+	CommentID int64     `json:"comment_id"`
+	UserID    int64     `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 type CommentVote struct {
 	ID        int64 `json:"id"`
 	UserID    int64 `json:"user_id"`
@@ -0,0 +1,15 @@
+package db
+
+import "context"
+
+const opPing = "ping"
+
+// Ping acquires a connection from the pool and checks that the database responds.
+// Returns KindInternal if the database is unreachable.
+func (store *SQLStore) Ping(ctx context.Context) error {
+	if err := store.connPool.Ping(ctx); err != nil {
+		return newOpError(opPing, KindInternal, entDatabase, err)
+	}
+
+	return nil
+}
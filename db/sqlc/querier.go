@@ -12,6 +12,7 @@ import (
 
 type Querier interface {
 	createComment(ctx context.Context, arg createCommentParams) (Comment, error)
+	createCommentMentions(ctx context.Context, arg createCommentMentionsParams) error
 	createPost(ctx context.Context, arg createPostParams) (Post, error)
 	createSession(ctx context.Context, arg createSessionParams) (Session, error)
 	createUser(ctx context.Context, arg createUserParams) (User, error)
@@ -22,8 +23,10 @@ type Querier interface {
 	deleteUserCredentials(ctx context.Context, userID int64) error
 	deleteUserSessions(ctx context.Context, userID int64) error
 	emailExists(ctx context.Context, email string) (bool, error)
+	getActiveUserIDsByUsernames(ctx context.Context, usernames []string) ([]getActiveUserIDsByUsernamesRow, error)
 	getActiveUsers(ctx context.Context, limit int32) ([]User, error)
 	getComment(ctx context.Context, id int64) (Comment, error)
+	getCommentForUpdate(ctx context.Context, id int64) (Comment, error)
 	getCommentVote(ctx context.Context, arg getCommentVoteParams) (CommentVote, error)
 	getCommentWithAuthor(ctx context.Context, id int64) (CommentsWithAuthor, error)
 	getCommentWithLock(ctx context.Context, id int64) (Comment, error)
@@ -43,6 +46,7 @@ type Querier interface {
 	getUserByEmail(ctx context.Context, email string) (User, error)
 	getUserByUsername(ctx context.Context, username string) (User, error)
 	getUserCredentials(ctx context.Context, userID int64) ([]WebauthnCredential, error)
+	getUserForUpdate(ctx context.Context, id int64) (User, error)
 	listSessionsByUser(ctx context.Context, userID int64) ([]Session, error)
 	listUserCredentials(ctx context.Context, userID int64) ([]WebauthnCredential, error)
 	recordCredentialUse(ctx context.Context, arg recordCredentialUseParams) (recordCredentialUseRow, error)
@@ -15,6 +15,19 @@ type Store interface {
 	// It does not return an error; the pool is simply drained.
 	Shutdown()
 
+	// Ping checks that the database is reachable.
+	//
+	// Errors returned (*OpError):
+	//   - KindInternal – no connection could be acquired or the database didn't respond
+	Ping(ctx context.Context) error
+
+	// MigrationStatus reports the applied schema version together with the latest
+	// version available in the configured migration source.
+	//
+	// Errors returned (*OpError):
+	//   - KindInternal – the schema version or the migration source cannot be read
+	MigrationStatus(ctx context.Context) (MigrationStatus, error)
+
 	// CreateUserWithCredentialsTx creates a user and a WebAuthn credential
 	// in a single transaction. On error neither the user nor the credential
 	// is persisted.
@@ -42,22 +55,26 @@ type Store interface {
 
 	// UpdateUser applies the non-nil fields in arg to the user record.
 	// At least one optional field (Username, Email, ProfileImgURL) must be set.
+	// When IfLastModifiedAt is set the user is updated only if its version matches.
 	//
 	// Errors returned (*OpError):
-	//   - KindInvalid  – all optional fields are nil (nothing to update)
-	//   - KindNotFound – no user with the given ID exists
-	//   - KindDeleted  – user exists but has been soft-deleted
-	//   - KindConflict – new username or email conflicts with an existing active user
-	//   - KindInternal – database error or unexpected failure
+	//   - KindInvalid      – all optional fields are nil (nothing to update)
+	//   - KindNotFound     – no user with the given ID exists
+	//   - KindDeleted      – user exists but has been soft-deleted
+	//   - KindConflict     – new username or email conflicts with an existing active user
+	//   - KindPrecondition – user was modified since IfLastModifiedAt
+	//   - KindInternal     – database error or unexpected failure
 	UpdateUser(ctx context.Context, arg UpdateUserParams) (UpdateUserResult, error)
 
 	// SoftDeleteUserTx deletes the user's auth sessions and WebAuthn credentials,
 	// then marks the user as soft-deleted, all within a single transaction.
+	// When IfLastModifiedAt is set the user is deleted only if its version matches.
 	//
 	// Errors returned (*OpError):
-	//   - KindNotFound – no user with the given ID exists
-	//   - KindInternal – database error or unexpected failure
-	SoftDeleteUserTx(ctx context.Context, userID int64) (SoftDeleteUserTxResult, error)
+	//   - KindNotFound     – no user with the given ID exists
+	//   - KindPrecondition – user was modified since IfLastModifiedAt
+	//   - KindInternal     – database error or unexpected failure
+	SoftDeleteUserTx(ctx context.Context, arg SoftDeleteUserTxParams) (SoftDeleteUserTxResult, error)
 
 	// UsernameExists reports whether an active user with the given username exists.
 	//
@@ -71,6 +88,14 @@ type Store interface {
 	//   - KindInternal – database error
 	EmailExists(ctx context.Context, email string) (bool, error)
 
+	// ResolveUsernames returns the IDs of the active users with the given usernames, keyed by the username.
+	// The usernames of non-existent and soft-deleted users are missing from the map.
+	// It satisfies the mention resolver of the SML parser.
+	//
+	// Errors returned (*OpError):
+	//   - KindInternal – database error
+	ResolveUsernames(ctx context.Context, usernames []string) (map[string]int64, error)
+
 	// GetUserCredentials retrieves the list of the user's webauthn credentials.
 	//
 	// Errors returned (*OpError):
@@ -117,25 +142,29 @@ type Store interface {
 
 	// UpdateComment updates the body of a comment identified by CommentID.
 	// The caller must own the comment and the comment must belong to the given post.
+	// When IfLastModifiedAt is set the comment is updated only if its version matches.
 	//
 	// Errors returned (*OpError):
-	//   - KindNotFound   – comment does not exist
-	//   - KindPermission – comment belongs to another user
-	//   - KindDeleted    – comment has been soft-deleted
-	//   - KindRelation   – comment belongs to a different post
-	//   - KindInternal   – database error or unexpected failure
+	//   - KindNotFound     – comment does not exist
+	//   - KindPermission   – comment belongs to another user
+	//   - KindDeleted      – comment has been soft-deleted
+	//   - KindRelation     – comment belongs to a different post
+	//   - KindPrecondition – comment was modified since IfLastModifiedAt
+	//   - KindInternal     – database error or unexpected failure
 	UpdateComment(ctx context.Context, arg UpdateCommentParams) (UpdateCommentResult, error)
 
 	// DeleteCommentTx deletes a comment. Leaf comments are hard-deleted;
 	// comments with children are soft-deleted (body cleared, is_deleted flag set).
 	// Already-deleted comments are treated as a successful no-op.
+	// When IfLastModifiedAt is set the comment is deleted only if its version matches.
 	//
 	// Errors returned (*OpError):
-	//   - KindNotFound   – comment does not exist
-	//   - KindPermission – comment belongs to another user
-	//   - KindRelation   – comment belongs to a different post
-	//   - KindCorrupted  – unexpected inconsistent database state
-	//   - KindInternal   – database or transaction error
+	//   - KindNotFound     – comment does not exist
+	//   - KindPermission   – comment belongs to another user
+	//   - KindRelation     – comment belongs to a different post
+	//   - KindPrecondition – comment was modified since IfLastModifiedAt
+	//   - KindCorrupted    – unexpected inconsistent database state
+	//   - KindInternal     – database or transaction error
 	DeleteCommentTx(ctx context.Context, arg DeleteCommentTxParams) (DeleteCommentTxResult, error)
 
 	// VoteCommentTx records an upvote (+1) or downvote (-1) on a comment
@@ -167,14 +196,20 @@ type SQLStore struct {
 	*Queries
 	connPool *pgxpool.Pool
 	config   *util.Config
+	// latestMigration is read from the migration source once, since it only changes with a new build.
+	latestMigration    uint
+	latestMigrationErr error
 }
 
 func NewStore(connPool *pgxpool.Pool, config *util.Config) Store {
-	return &SQLStore{
+	store := &SQLStore{
 		connPool: connPool,
 		Queries:  New(connPool),
 		config:   config,
 	}
+	// an unreadable source is reported by MigrationStatus rather than failing the start
+	store.latestMigration, store.latestMigrationErr = latestMigrationVersion(config.MigrationURL)
+	return store
 }
 
 func (store *SQLStore) Shutdown() {
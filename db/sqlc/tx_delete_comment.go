@@ -15,6 +15,9 @@ type DeleteCommentTxParams struct {
 	CommentID int64 `json:"comment_id"`
 	UserID    int64 `json:"user_id"`
 	PostID    int64 `json:"post_id"`
+	// IfLastModifiedAt is an optional precondition: when set, the comment is deleted
+	// only if it wasn't modified since then, otherwise KindPrecondition is returned.
+	IfLastModifiedAt *time.Time `json:"if_last_modified_at"`
 }
 
 type DeleteCommentTxResult struct {
@@ -33,11 +36,18 @@ type DeleteCommentTxResult struct {
 // comments are treated as a successful no-op.
 // Returns KindNotFound if the comment does not exist, KindPermission if the comment
 // belongs to another user, KindRelation if the comment belongs to a different post,
-// KindCorrupted on unexpected inconsistent DB state, or KindInternal on database errors.
+// KindPrecondition if arg.IfLastModifiedAt is set and the comment was modified since, KindCorrupted on unexpected inconsistent DB state, or KindInternal on database errors.
 func (s *SQLStore) DeleteCommentTx(ctx context.Context, arg DeleteCommentTxParams) (DeleteCommentTxResult, error) {
 	var row deleteCommentIfLeafRow
 	var result DeleteCommentTxResult
 	err := s.execTx(ctx, func(q *Queries) error {
+		if arg.IfLastModifiedAt != nil {
+			err := checkCommentVersion(ctx, q, opDeleteComment, arg.CommentID, arg.UserID, *arg.IfLastModifiedAt)
+			if err != nil {
+				return err
+			}
+		}
+
 		deleted, err := q.deleteCommentIfLeaf(ctx, deleteCommentIfLeafParams{
 			PCommentID: arg.CommentID,
 			PUserID:    arg.UserID,
@@ -17,6 +17,9 @@ type InsertCommentTxParams struct {
 	ParentID  pgtype.Int8 `json:"parent_id"`
 	Upvotes   int64       `json:"upvotes"`
 	Downvotes int64       `json:"downvotes"`
+	// MentionedUserIDs are the users mentioned in the body, recorded with the comment
+	// so they can be notified. The author is skipped if they mention themselves.
+	MentionedUserIDs []int64 `json:"mentioned_user_ids"`
 }
 
 // InsertCommentTx creates a new comment, either a root comment or a reply to an
@@ -183,6 +186,33 @@ func (s *SQLStore) InsertCommentTx(ctx context.Context, arg InsertCommentTxParam
 			)
 		}
 
+		mentioned := make([]int64, 0, len(arg.MentionedUserIDs))
+		for _, id := range arg.MentionedUserIDs {
+			if id != arg.UserID {
+				mentioned = append(mentioned, id)
+			}
+		}
+
+		if len(mentioned) > 0 {
+			err = q.createCommentMentions(ctx, createCommentMentionsParams{
+				CommentID: comment.ID,
+				UserIds:   mentioned,
+			})
+
+			if err != nil {
+				return sqlError(
+					opInsertComment,
+					opDetails{
+						userID:    fmt.Sprint(arg.UserID),
+						postID:    fmt.Sprint(arg.PostID),
+						commentID: fmt.Sprint(comment.ID),
+						entity:    entComment,
+					},
+					err,
+				)
+			}
+		}
+
 		result = comment
 
 		return nil
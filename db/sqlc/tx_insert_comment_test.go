@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/Drolfothesgnir/shitposter/util"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/require"
 )
@@ -37,6 +38,31 @@ func TestInsertCommentTx_RootComment(t *testing.T) {
 	require.EqualValues(t, arg.Downvotes, comment.Downvotes)
 }
 
+// Mentions are recorded with the comment, without the author mentioning themselves
+func TestInsertCommentTx_Mentions(t *testing.T) {
+	ctx := context.Background()
+
+	post := createRandomPost(t)
+	mentioned := createRandomUser(t)
+
+	arg := InsertCommentTxParams{
+		UserID:           post.UserID,
+		PostID:           post.ID,
+		Body:             util.RandomString(10),
+		MentionedUserIDs: []int64{mentioned.ID, post.UserID, mentioned.ID},
+	}
+
+	comment, err := testStore.InsertCommentTx(ctx, arg)
+	require.NoError(t, err)
+
+	rows, err := testStore.connPool.Query(ctx, "SELECT user_id FROM comment_mentions WHERE comment_id = $1", comment.ID)
+	require.NoError(t, err)
+
+	userIDs, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	require.NoError(t, err)
+	require.Equal(t, []int64{mentioned.ID}, userIDs)
+}
+
 // Child comment (with parent_id) — reply from a different user
 func TestInsertCommentTx_ChildComment(t *testing.T) {
 	ctx := context.Background()
@@ -12,6 +12,13 @@ import (
 
 const opSoftDeleteUser = "soft-delete-user"
 
+type SoftDeleteUserTxParams struct {
+	UserID int64
+	// IfLastModifiedAt is an optional precondition: when set, the user is deleted
+	// only if it wasn't modified since then, otherwise KindPrecondition is returned.
+	IfLastModifiedAt *time.Time
+}
+
 // SoftDeleteUserTxResult consists of fields only relevant to the delete operation.
 type SoftDeleteUserTxResult struct {
 	ID             int64       `json:"id"`
@@ -26,13 +33,21 @@ type SoftDeleteUserTxResult struct {
 
 // SoftDeleteUserTx deletes the user's auth sessions and WebAuthn credentials,
 // then marks the user as soft-deleted, all within a single transaction.
-// Returns [KindNotFound] if the user does not exist, or [KindInternal] on database
-// errors or unexpected failure.
-func (store *SQLStore) SoftDeleteUserTx(ctx context.Context, userID int64) (SoftDeleteUserTxResult, error) {
+// Returns [KindNotFound] if the user does not exist, [KindPrecondition] if arg.IfLastModifiedAt
+// is set and the user was modified since, or [KindInternal] on database errors or unexpected failure.
+func (store *SQLStore) SoftDeleteUserTx(ctx context.Context, arg SoftDeleteUserTxParams) (SoftDeleteUserTxResult, error) {
 	var result SoftDeleteUserTxResult
+	userID := arg.UserID
 
 	err := store.execTx(ctx, func(q *Queries) error {
 		var err error
+		if arg.IfLastModifiedAt != nil {
+			err = checkUserVersion(ctx, q, opSoftDeleteUser, userID, *arg.IfLastModifiedAt)
+			if err != nil {
+				return err
+			}
+		}
+
 		err = q.deleteUserSessions(ctx, userID)
 		if err != nil {
 			return sqlError(
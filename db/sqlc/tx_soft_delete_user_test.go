@@ -18,7 +18,7 @@ func TestSoftDeleteUserTx_Success(t *testing.T) {
 
 	before := time.Now()
 
-	res, err := testStore.SoftDeleteUserTx(ctx, u.ID)
+	res, err := testStore.SoftDeleteUserTx(ctx, SoftDeleteUserTxParams{UserID: u.ID})
 	require.NoError(t, err)
 
 	// Basic identity
@@ -57,7 +57,7 @@ func TestSoftDeleteUserTx_NotFound(t *testing.T) {
 
 	nonExistingID := int64(9_999_999_999)
 
-	_, err := testStore.SoftDeleteUserTx(ctx, nonExistingID)
+	_, err := testStore.SoftDeleteUserTx(ctx, SoftDeleteUserTxParams{UserID: nonExistingID})
 	require.Error(t, err)
 
 	var opErr *OpError
@@ -76,12 +76,12 @@ func TestSoftDeleteUserTx_Idempotent(t *testing.T) {
 	u := createRandomUser(t)
 
 	// First delete.
-	first, err := testStore.SoftDeleteUserTx(ctx, u.ID)
+	first, err := testStore.SoftDeleteUserTx(ctx, SoftDeleteUserTxParams{UserID: u.ID})
 	require.NoError(t, err)
 	require.True(t, first.IsDeleted)
 
 	// Second delete on already-deleted user.
-	second, err := testStore.SoftDeleteUserTx(ctx, u.ID)
+	second, err := testStore.SoftDeleteUserTx(ctx, SoftDeleteUserTxParams{UserID: u.ID})
 	require.NoError(t, err)
 	require.True(t, second.IsDeleted)
 
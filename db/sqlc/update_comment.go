@@ -16,6 +16,9 @@ type UpdateCommentParams struct {
 	PostID    int64
 	CommentID int64
 	Body      string
+	// IfLastModifiedAt is an optional precondition: when set, the comment is updated
+	// only if it wasn't modified since then, otherwise KindPrecondition is returned.
+	IfLastModifiedAt *time.Time
 }
 
 type UpdateCommentResult struct {
@@ -29,8 +32,30 @@ type UpdateCommentResult struct {
 // Returns KindNotFound if the comment does not exist, KindPermission if the comment
 // belongs to another user, KindDeleted if the comment is soft-deleted, KindRelation
 // if the comment belongs to a different post, or KindInternal on database errors.
+// When arg.IfLastModifiedAt is set, the version check and the update run in one transaction
+// and KindPrecondition is returned if the comment was modified concurrently.
 func (s *SQLStore) UpdateComment(ctx context.Context, arg UpdateCommentParams) (UpdateCommentResult, error) {
-	updateResult, err := s.updateComment(ctx, updateCommentParams{
+	if arg.IfLastModifiedAt == nil {
+		return updateCommentWith(ctx, s.Queries, arg)
+	}
+
+	var result UpdateCommentResult
+	err := s.execTx(ctx, func(q *Queries) error {
+		err := checkCommentVersion(ctx, q, opUpdateComment, arg.CommentID, arg.UserID, *arg.IfLastModifiedAt)
+		if err != nil {
+			return err
+		}
+
+		result, err = updateCommentWith(ctx, q, arg)
+		return err
+	})
+
+	return result, err
+}
+
+// updateCommentWith performs the update with the provided queries, which may belong to a transaction.
+func updateCommentWith(ctx context.Context, q *Queries, arg UpdateCommentParams) (UpdateCommentResult, error) {
+	updateResult, err := q.updateComment(ctx, updateCommentParams{
 		PUserID:    arg.UserID,
 		PPostID:    arg.PostID,
 		PCommentID: arg.CommentID,
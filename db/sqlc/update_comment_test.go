@@ -186,3 +186,41 @@ func TestUpdateComment_PostMismatch(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, originalBody, reloaded.Body)
 }
+
+// If-Match style precondition: the update succeeds with the current version
+// and fails with KindPrecondition once the comment has changed.
+func TestUpdateComment_IfLastModifiedAt(t *testing.T) {
+	ctx := context.Background()
+
+	original := createRandomComment(t)
+	version := original.LastModifiedAt
+
+	arg := UpdateCommentParams{
+		UserID:           original.UserID,
+		PostID:           original.PostID,
+		CommentID:        original.ID,
+		Body:             util.RandomString(20),
+		IfLastModifiedAt: &version,
+	}
+
+	res, err := testStore.UpdateComment(ctx, arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Body, res.Body)
+
+	// the same version is stale now
+	arg.Body = util.RandomString(20)
+	_, err = testStore.UpdateComment(ctx, arg)
+	require.Error(t, err)
+
+	var opErr *OpError
+	require.ErrorAs(t, err, &opErr)
+	require.Equal(t, opUpdateComment, opErr.Op)
+	require.Equal(t, KindPrecondition, opErr.Kind)
+	require.Equal(t, entComment, opErr.Entity)
+	require.Equal(t, fmt.Sprint(original.ID), opErr.EntityID)
+
+	// the body from the first update is kept
+	reloaded, err := testStore.getCommentWithLock(ctx, original.ID)
+	require.NoError(t, err)
+	require.Equal(t, res.Body, reloaded.Body)
+}
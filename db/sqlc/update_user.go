@@ -18,6 +18,9 @@ type UpdateUserParams struct {
 	Username      *string
 	Email         *string
 	ProfileImgURL *string
+	// IfLastModifiedAt is an optional precondition: when set, the user is updated
+	// only if it wasn't modified since then, otherwise KindPrecondition is returned.
+	IfLastModifiedAt *time.Time
 }
 
 // empty will return true if all optional field are nil.
@@ -52,7 +55,27 @@ func (s *SQLStore) UpdateUser(ctx context.Context, arg UpdateUserParams) (Update
 		return UpdateUserResult{}, opErr
 	}
 
-	row, err := s.updateUser(ctx, updateUserParams{
+	if arg.IfLastModifiedAt == nil {
+		return updateUserWith(ctx, s.Queries, arg)
+	}
+
+	var result UpdateUserResult
+	err := s.execTx(ctx, func(q *Queries) error {
+		err := checkUserVersion(ctx, q, opUpdateUser, arg.ID, *arg.IfLastModifiedAt)
+		if err != nil {
+			return err
+		}
+
+		result, err = updateUserWith(ctx, q, arg)
+		return err
+	})
+
+	return result, err
+}
+
+// updateUserWith performs the update with the provided queries, which may belong to a transaction.
+func updateUserWith(ctx context.Context, q *Queries, arg UpdateUserParams) (UpdateUserResult, error) {
+	row, err := q.updateUser(ctx, updateUserParams{
 		PUserID:       arg.ID,
 		Username:      util.StringToPgxText(arg.Username),
 		Email:         util.StringToPgxText(arg.Email),
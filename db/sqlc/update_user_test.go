@@ -160,3 +160,33 @@ func TestUpdateUser_EmailConflict(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, user2.Email, reloaded2.Email)
 }
+
+// If-Match style precondition: a stale version results in KindPrecondition.
+func TestUpdateUser_IfLastModifiedAt(t *testing.T) {
+	ctx := context.Background()
+
+	u := createRandomUser(t)
+	stale := u.LastModifiedAt.Add(-time.Second)
+	newUsername := "updated_" + util.RandomOwner()
+
+	_, err := testStore.UpdateUser(ctx, UpdateUserParams{
+		ID:               u.ID,
+		Username:         &newUsername,
+		IfLastModifiedAt: &stale,
+	})
+	require.Error(t, err)
+
+	var opErr *OpError
+	require.ErrorAs(t, err, &opErr)
+	require.Equal(t, opUpdateUser, opErr.Op)
+	require.Equal(t, KindPrecondition, opErr.Kind)
+	require.Equal(t, entUser, opErr.Entity)
+
+	res, err := testStore.UpdateUser(ctx, UpdateUserParams{
+		ID:               u.ID,
+		Username:         &newUsername,
+		IfLastModifiedAt: &u.LastModifiedAt,
+	})
+	require.NoError(t, err)
+	require.Equal(t, newUsername, res.Username)
+}
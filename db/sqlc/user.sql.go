@@ -67,6 +67,36 @@ func (q *Queries) emailExists(ctx context.Context, email string) (bool, error) {
 	return email_exists, err
 }
 
+const getActiveUserIDsByUsernames = `-- name: getActiveUserIDsByUsernames :many
+SELECT id, username FROM users
+WHERE username = ANY($1::TEXT[]) AND is_deleted = FALSE
+`
+
+type getActiveUserIDsByUsernamesRow struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
+
+func (q *Queries) getActiveUserIDsByUsernames(ctx context.Context, usernames []string) ([]getActiveUserIDsByUsernamesRow, error) {
+	rows, err := q.db.Query(ctx, getActiveUserIDsByUsernames, usernames)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []getActiveUserIDsByUsernamesRow{}
+	for rows.Next() {
+		var i getActiveUserIDsByUsernamesRow
+		if err := rows.Scan(&i.ID, &i.Username); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getActiveUsers = `-- name: getActiveUsers :many
 SELECT id, username, webauthn_user_handle, profile_img_url, email, created_at, is_deleted, deleted_at, display_name, archived_username, archived_email, last_modified_at FROM users
 WHERE is_deleted = FALSE
@@ -181,6 +211,32 @@ func (q *Queries) getUserByUsername(ctx context.Context, username string) (User,
 	return i, err
 }
 
+const getUserForUpdate = `-- name: getUserForUpdate :one
+SELECT id, username, webauthn_user_handle, profile_img_url, email, created_at, is_deleted, deleted_at, display_name, archived_username, archived_email, last_modified_at FROM users
+WHERE id = $1 LIMIT 1
+FOR UPDATE
+`
+
+func (q *Queries) getUserForUpdate(ctx context.Context, id int64) (User, error) {
+	row := q.db.QueryRow(ctx, getUserForUpdate, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.WebauthnUserHandle,
+		&i.ProfileImgUrl,
+		&i.Email,
+		&i.CreatedAt,
+		&i.IsDeleted,
+		&i.DeletedAt,
+		&i.DisplayName,
+		&i.ArchivedUsername,
+		&i.ArchivedEmail,
+		&i.LastModifiedAt,
+	)
+	return i, err
+}
+
 const softDeleteUser = `-- name: softDeleteUser :one
 SELECT 
   id::BIGINT AS id,
@@ -1,10 +1,15 @@
 package db
 
-import "context"
+import (
+	"context"
+	"strings"
+)
 
 const (
 	opUsernameExists = "username-exists"
 	opEmailExists    = "email-exists"
+
+	opResolveUsernames = "resolve-usernames"
 )
 
 // UsernameExists reports whether an active user with the given username exists.
@@ -37,3 +42,24 @@ func (s *SQLStore) EmailExists(ctx context.Context, email string) (bool, error)
 
 	return exists, nil
 }
+
+// ResolveUsernames returns the IDs of the active users with the given usernames, keyed by the username.
+// The usernames of non-existent and soft-deleted users are missing from the map.
+// Returns KindInternal on database errors.
+func (s *SQLStore) ResolveUsernames(ctx context.Context, usernames []string) (map[string]int64, error) {
+	rows, err := s.getActiveUserIDsByUsernames(ctx, usernames)
+	if err != nil {
+		return nil, sqlError(
+			opResolveUsernames,
+			opDetails{input: strings.Join(usernames, ","), entity: entUser},
+			err,
+		)
+	}
+
+	ids := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		ids[row.Username] = row.ID
+	}
+
+	return ids, nil
+}
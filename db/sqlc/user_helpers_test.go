@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/Drolfothesgnir/shitposter/sml"
 	"github.com/Drolfothesgnir/shitposter/util"
 	"github.com/stretchr/testify/require"
 )
@@ -64,7 +65,7 @@ func TestUsernameAndEmailExists_SoftDeletedUserFreesValues(t *testing.T) {
 	require.True(t, exists)
 
 	// Soft-delete this user.
-	_, err = testStore.SoftDeleteUserTx(ctx, u.ID)
+	_, err = testStore.SoftDeleteUserTx(ctx, SoftDeleteUserTxParams{UserID: u.ID})
 	require.NoError(t, err)
 
 	// After soft delete, the original username/email should no longer be present
@@ -78,3 +79,32 @@ func TestUsernameAndEmailExists_SoftDeletedUserFreesValues(t *testing.T) {
 	require.NoError(t, err)
 	require.False(t, exists)
 }
+
+// the store verifies the mentions of the SML comments
+var _ sml.MentionResolver = (*SQLStore)(nil)
+
+func TestResolveUsernames_SkipsUnknownAndDeletedUsers(t *testing.T) {
+	ctx := context.Background()
+
+	u1 := createRandomUser(t)
+	u2 := createRandomUser(t)
+	deleted := createRandomUser(t)
+
+	_, err := testStore.SoftDeleteUserTx(ctx, SoftDeleteUserTxParams{UserID: deleted.ID})
+	require.NoError(t, err)
+
+	ids, err := testStore.ResolveUsernames(ctx, []string{
+		u1.Username,
+		u2.Username,
+		deleted.Username,
+		"non_existing_" + util.RandomOwner(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string]int64{u1.Username: u1.ID, u2.Username: u2.ID}, ids)
+}
+
+func TestResolveUsernames_EmptyInput(t *testing.T) {
+	ids, err := testStore.ResolveUsernames(context.Background(), nil)
+	require.NoError(t, err)
+	require.Empty(t, ids)
+}
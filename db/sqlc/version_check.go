@@ -0,0 +1,67 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// sameVersion compares last modification times at the precision Postgres stores them.
+func sameVersion(a, b time.Time) bool {
+	return a.UnixMicro() == b.UnixMicro()
+}
+
+// checkCommentVersion locks the comment for the rest of the transaction and returns
+// KindPrecondition if it was modified since expected.
+// The version of a comment which doesn't belong to the user is not checked, so
+// the operation reports the permission error instead of leaking the version state.
+func checkCommentVersion(ctx context.Context, q *Queries, op string, commentID, userID int64, expected time.Time) error {
+	comment, err := q.getCommentForUpdate(ctx, commentID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return notFoundError(op, entComment, fmt.Sprint(commentID))
+		}
+		return sqlError(op, opDetails{entity: entComment, entityID: fmt.Sprint(commentID)}, err)
+	}
+
+	if comment.UserID != userID || sameVersion(comment.LastModifiedAt, expected) {
+		return nil
+	}
+
+	return newOpError(
+		op,
+		KindPrecondition,
+		entComment,
+		fmt.Errorf("comment with id %d was modified at %s", commentID, comment.LastModifiedAt.UTC().Format(time.RFC3339Nano)),
+		withEntityID(fmt.Sprint(commentID)),
+		withField("last_modified_at"),
+	)
+}
+
+// checkUserVersion locks the user for the rest of the transaction and returns
+// KindPrecondition if it was modified since expected.
+func checkUserVersion(ctx context.Context, q *Queries, op string, userID int64, expected time.Time) error {
+	user, err := q.getUserForUpdate(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return notFoundError(op, entUser, fmt.Sprint(userID))
+		}
+		return sqlError(op, opDetails{entity: entUser, entityID: fmt.Sprint(userID)}, err)
+	}
+
+	if sameVersion(user.LastModifiedAt, expected) {
+		return nil
+	}
+
+	return newOpError(
+		op,
+		KindPrecondition,
+		entUser,
+		fmt.Errorf("user with id %d was modified at %s", userID, user.LastModifiedAt.UTC().Format(time.RFC3339Nano)),
+		withEntityID(fmt.Sprint(userID)),
+		withField("last_modified_at"),
+	)
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/Drolfothesgnir/shitposter/api"
 	db "github.com/Drolfothesgnir/shitposter/db/sqlc"
+	"github.com/Drolfothesgnir/shitposter/metrics"
 	"github.com/Drolfothesgnir/shitposter/tmpstore"
 	"github.com/Drolfothesgnir/shitposter/token"
 	"github.com/Drolfothesgnir/shitposter/util"
@@ -66,6 +67,7 @@ func main() {
 	}
 
 	store := db.NewStore(conn, &config)
+	db.RegisterPoolMetrics(metrics.Default, conn)
 
 	// running db migrations every time the server starts
 	// it's idempotent, so the schema establishes only once if no new versions added
@@ -75,6 +77,7 @@ func main() {
 	waitGroup, ctx := errgroup.WithContext(ctx)
 
 	RunGinServer(ctx, waitGroup, config, store)
+	RunAdminServer(ctx, waitGroup, config)
 
 	err = waitGroup.Wait()
 	if err != nil {
@@ -144,21 +147,74 @@ func RunGinServer(
 
 		log.Info().Msg("HTTP server: graceful shutdown")
 
-		// give the server 5 secs to finish all his processes
-		toCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		// give the server 5 secs after the drain delay to finish all his processes
+		toCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownDrainDelay+5*time.Second)
 		defer cancel()
 
+		// fails the readiness probe, waits for the drain delay, stops accepting requests,
+		// drains in-flight ones, then closes the Redis client and the db connection pool
 		err := service.Shutdown(toCtx)
 
 		if err != nil {
 			log.Error().Err(err).Msg("cannot shutdown HTTP server gracefully")
 		}
 
-		// closing the db connection pool
-		store.Shutdown()
-
 		log.Info().Msg("gateway server is stopped")
 
 		return err
 	})
 }
+
+// RunAdminServer starts the admin HTTP listener which exposes the metrics.
+// It is kept separate from the public server so it can be bound to a private interface.
+// The admin server is disabled when no address is configured.
+func RunAdminServer(
+	ctx context.Context,
+	waitGroup *errgroup.Group,
+	config util.Config,
+) {
+	if config.AdminServerAddress == "" {
+		log.Info().Msg("admin server address is not set, metrics are not exposed")
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics.Default.Handler())
+
+	server := &http.Server{
+		Addr:              config.AdminServerAddress.String(),
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      15 * time.Second,
+	}
+
+	waitGroup.Go(func() error {
+		log.Info().Msgf("start admin server at %s", config.AdminServerAddress)
+
+		err := server.ListenAndServe()
+		if err != nil {
+			if errors.Is(err, http.ErrServerClosed) {
+				return nil
+			}
+			log.Error().Err(err).Msg("cannot start admin server")
+		}
+
+		return err
+	})
+
+	waitGroup.Go(func() error {
+		<-ctx.Done()
+
+		toCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		err := server.Shutdown(toCtx)
+		if err != nil {
+			log.Error().Err(err).Msg("cannot shutdown admin server gracefully")
+		}
+
+		log.Info().Msg("admin server is stopped")
+
+		return err
+	})
+}
@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"bufio"
+	"math"
+	"sync/atomic"
+)
+
+// atomicFloat is a float64 which can be updated atomically.
+type atomicFloat struct {
+	bits atomic.Uint64
+}
+
+func (f *atomicFloat) add(v float64) {
+	for {
+		old := f.bits.Load()
+		if f.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+func (f *atomicFloat) set(v float64) {
+	f.bits.Store(math.Float64bits(v))
+}
+
+func (f *atomicFloat) load() float64 {
+	return math.Float64frombits(f.bits.Load())
+}
+
+// Counter is a monotonically increasing value, partitioned by labels.
+type Counter struct {
+	metricName string
+	help       string
+	vec        *vec[atomicFloat]
+}
+
+// NewCounter creates and registers a [Counter] with the provided label names.
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{
+		metricName: name,
+		help:       help,
+		vec:        newVec(labels, func() *atomicFloat { return &atomicFloat{} }),
+	}
+	r.register(c, labels)
+	return c
+}
+
+// Inc increments the counter for the label values by 1.
+func (c *Counter) Inc(labelValues ...string) {
+	c.vec.get(labelValues).add(1)
+}
+
+// Add increments the counter for the label values by v. Negative values are ignored,
+// since counters can only go up.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	c.vec.get(labelValues).add(v)
+}
+
+// Value returns the current value of the counter for the label values, 0 if it was never incremented.
+func (c *Counter) Value(labelValues ...string) float64 {
+	s := c.vec.lookup(labelValues)
+	if s == nil {
+		return 0
+	}
+	return s.load()
+}
+
+func (c *Counter) name() string { return c.metricName }
+
+func (c *Counter) write(w *bufio.Writer) {
+	writeHeader(w, c.metricName, c.help, "counter")
+	for _, l := range c.vec.sorted() {
+		writeSample(w, c.metricName, c.vec.labels, l.values, "", "", l.s.load())
+	}
+}
+
+// funcMetric is an unlabeled counter or gauge whose value is read at scrape time.
+type funcMetric struct {
+	metricName string
+	help       string
+	typ        string
+	fn         func() float64
+}
+
+// NewCounterFunc registers a counter whose value is provided by fn on every scrape.
+// The fn must be safe for concurrent use and return monotonically increasing values.
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) {
+	r.register(&funcMetric{metricName: name, help: help, typ: "counter", fn: fn}, nil)
+}
+
+// NewGaugeFunc registers a gauge whose value is provided by fn on every scrape.
+// The fn must be safe for concurrent use.
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(&funcMetric{metricName: name, help: help, typ: "gauge", fn: fn}, nil)
+}
+
+func (f *funcMetric) name() string { return f.metricName }
+
+func (f *funcMetric) write(w *bufio.Writer) {
+	writeHeader(w, f.metricName, f.help, f.typ)
+	writeSample(w, f.metricName, nil, nil, "", "", f.fn())
+}
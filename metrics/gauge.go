@@ -0,0 +1,59 @@
+package metrics
+
+import "bufio"
+
+// Gauge is a value which can go up and down, partitioned by labels.
+type Gauge struct {
+	metricName string
+	help       string
+	vec        *vec[atomicFloat]
+}
+
+// NewGauge creates and registers a [Gauge] with the provided label names.
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{
+		metricName: name,
+		help:       help,
+		vec:        newVec(labels, func() *atomicFloat { return &atomicFloat{} }),
+	}
+	r.register(g, labels)
+	return g
+}
+
+// Set sets the gauge for the label values to v.
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.vec.get(labelValues).set(v)
+}
+
+// Add adds v, which may be negative, to the gauge for the label values.
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	g.vec.get(labelValues).add(v)
+}
+
+// Inc increments the gauge for the label values by 1.
+func (g *Gauge) Inc(labelValues ...string) {
+	g.Add(1, labelValues...)
+}
+
+// Dec decrements the gauge for the label values by 1.
+func (g *Gauge) Dec(labelValues ...string) {
+	g.Add(-1, labelValues...)
+}
+
+// Value returns the current value of the gauge for the label values, 0 if it was never set.
+func (g *Gauge) Value(labelValues ...string) float64 {
+	s := g.vec.lookup(labelValues)
+	if s == nil {
+		return 0
+	}
+	return s.load()
+}
+
+func (g *Gauge) name() string { return g.metricName }
+
+func (g *Gauge) write(w *bufio.Writer) {
+	writeHeader(w, g.metricName, g.help, "gauge")
+	for _, l := range g.vec.sorted() {
+		writeSample(w, g.metricName, g.vec.labels, l.values, "", "", l.s.load())
+	}
+}
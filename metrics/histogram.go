@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefBuckets are the default histogram buckets, in seconds, tuned for request latencies
+// around the 500 ms p99 target.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Histogram counts observations in cumulative buckets, partitioned by labels.
+type Histogram struct {
+	metricName string
+	help       string
+	buckets    []float64
+	vec        *vec[histogramSeries]
+}
+
+type histogramSeries struct {
+	mu     sync.Mutex
+	counts []uint64 // per bucket, not cumulative; the last one is +Inf
+	sum    float64
+	count  uint64
+}
+
+// NewHistogram creates and registers a [Histogram] with the provided upper bucket bounds and label names.
+// Buckets must be sorted in increasing order; the +Inf bucket is always added implicitly.
+// If buckets is empty, [DefBuckets] is used.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+
+	if !slices.IsSorted(buckets) {
+		panic(fmt.Sprintf("metrics: buckets of histogram %q are not sorted", name))
+	}
+
+	buckets = slices.Clone(buckets)
+	if math.IsInf(buckets[len(buckets)-1], 1) {
+		buckets = buckets[:len(buckets)-1]
+	}
+
+	h := &Histogram{
+		metricName: name,
+		help:       help,
+		buckets:    buckets,
+	}
+	h.vec = newVec(labels, func() *histogramSeries {
+		return &histogramSeries{counts: make([]uint64, len(buckets)+1)}
+	})
+
+	r.register(h, labels)
+	return h
+}
+
+// Observe records v for the label values.
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	s := h.vec.get(labelValues)
+	i, _ := slices.BinarySearch(h.buckets, v)
+
+	s.mu.Lock()
+	s.counts[i]++
+	s.sum += v
+	s.count++
+	s.mu.Unlock()
+}
+
+// ObserveSince records the seconds elapsed since start for the label values.
+func (h *Histogram) ObserveSince(start time.Time, labelValues ...string) {
+	h.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+// Count returns the number of observations recorded for the label values.
+func (h *Histogram) Count(labelValues ...string) uint64 {
+	s := h.vec.lookup(labelValues)
+	if s == nil {
+		return 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count
+}
+
+func (h *Histogram) name() string { return h.metricName }
+
+func (h *Histogram) write(w *bufio.Writer) {
+	writeHeader(w, h.metricName, h.help, "histogram")
+
+	counts := make([]uint64, len(h.buckets)+1)
+	for _, l := range h.vec.sorted() {
+		l.s.mu.Lock()
+		copy(counts, l.s.counts)
+		sum, count := l.s.sum, l.s.count
+		l.s.mu.Unlock()
+
+		var cum uint64
+		for i, upper := range h.buckets {
+			cum += counts[i]
+			writeSample(w, h.metricName+"_bucket", h.vec.labels, l.values, "le", formatFloat(upper), float64(cum))
+		}
+		writeSample(w, h.metricName+"_bucket", h.vec.labels, l.values, "le", "+Inf", float64(count))
+		writeSample(w, h.metricName+"_sum", h.vec.labels, l.values, "", "", sum)
+		writeSample(w, h.metricName+"_count", h.vec.labels, l.values, "", "", float64(count))
+	}
+}
@@ -0,0 +1,118 @@
+// Package metrics is a small, dependency-free metrics registry which exposes
+// counters, gauges and histograms in the Prometheus text exposition format (version 0.0.4).
+//
+// It deliberately covers only what the service needs:
+//   - every metric family has a fixed set of label names declared at creation time;
+//   - label values are passed positionally on every update, e.g. counter.Inc("GET", "200");
+//   - families are registered once, usually at package initialisation, and never removed.
+//
+// Instruments are safe for concurrent use. Most packages register their instruments
+// on the [Default] registry, which is served by the admin listener under GET /metrics.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// ContentType is the content type of the text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Default is the process-wide registry used by the service packages.
+var Default = NewRegistry()
+
+var (
+	metricNameRe = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNameRe  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// family is a single named metric with all its labeled series.
+type family interface {
+	// name returns the metric name of the family.
+	name() string
+	// write writes the family in the text exposition format.
+	write(w *bufio.Writer)
+}
+
+// Registry holds metric families and renders them in the text exposition format.
+type Registry struct {
+	mu       sync.RWMutex
+	families map[string]family
+}
+
+// NewRegistry returns an empty [Registry].
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]family)}
+}
+
+// register adds the family to the registry.
+// It panics on invalid or duplicate names, since both are programming errors.
+func (r *Registry) register(f family, labels []string) {
+	if !metricNameRe.MatchString(f.name()) {
+		panic(fmt.Sprintf("metrics: invalid metric name %q", f.name()))
+	}
+
+	for _, l := range labels {
+		if !labelNameRe.MatchString(l) || l == "le" {
+			panic(fmt.Sprintf("metrics: invalid label name %q for metric %q", l, f.name()))
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.families[f.name()]; ok {
+		panic(fmt.Sprintf("metrics: metric %q is already registered", f.name()))
+	}
+
+	r.families[f.name()] = f
+}
+
+// WriteTo writes all registered families, sorted by name, in the text exposition format.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	families := make([]family, 0, len(names))
+	sort.Strings(names)
+	for _, name := range names {
+		families = append(families, r.families[name])
+	}
+	r.mu.RUnlock()
+
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, f := range families {
+		f.write(bw)
+	}
+
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// Handler returns an [http.Handler] which serves the registry contents.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		_, _ = r.WriteTo(w)
+	})
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
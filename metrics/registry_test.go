@@ -0,0 +1,172 @@
+package metrics
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func exposition(t *testing.T, r *Registry) string {
+	t.Helper()
+	var b strings.Builder
+	_, err := r.WriteTo(&b)
+	require.NoError(t, err)
+	return b.String()
+}
+
+func TestCounter_Exposition(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("requests_total", "Total requests.", "method", "code")
+
+	c.Inc("GET", "200")
+	c.Add(2, "GET", "200")
+	c.Inc("POST", "500")
+	c.Add(-5, "POST", "500") // ignored
+
+	want := `# HELP requests_total Total requests.
+# TYPE requests_total counter
+requests_total{method="GET",code="200"} 3
+requests_total{method="POST",code="500"} 1
+`
+	require.Equal(t, want, exposition(t, r))
+	require.Equal(t, 3.0, c.Value("GET", "200"))
+}
+
+func TestGauge_Exposition(t *testing.T) {
+	r := NewRegistry()
+	g := r.NewGauge("in_flight", "In-flight requests.")
+
+	g.Inc()
+	g.Inc()
+	g.Dec()
+	require.Equal(t, 1.0, g.Value())
+
+	g.Set(math.Inf(1))
+
+	want := `# HELP in_flight In-flight requests.
+# TYPE in_flight gauge
+in_flight +Inf
+`
+	require.Equal(t, want, exposition(t, r))
+}
+
+func TestFuncMetrics_Exposition(t *testing.T) {
+	r := NewRegistry()
+	r.NewGaugeFunc("b_idle", "Idle.", func() float64 { return 4 })
+	r.NewCounterFunc("a_total", "Total.", func() float64 { return 10 })
+
+	// families are sorted by name
+	want := `# HELP a_total Total.
+# TYPE a_total counter
+a_total 10
+# HELP b_idle Idle.
+# TYPE b_idle gauge
+b_idle 4
+`
+	require.Equal(t, want, exposition(t, r))
+}
+
+func TestHistogram_Exposition(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogram("latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+
+	h.Observe(0.05, "/a")
+	h.Observe(0.1, "/a") // bucket bounds are inclusive
+	h.Observe(0.5, "/a")
+	h.Observe(3, "/a")
+
+	want := `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/a",le="0.1"} 2
+latency_seconds_bucket{route="/a",le="1"} 3
+latency_seconds_bucket{route="/a",le="+Inf"} 4
+latency_seconds_sum{route="/a"} 3.65
+latency_seconds_count{route="/a"} 4
+`
+	require.Equal(t, want, exposition(t, r))
+	require.Equal(t, uint64(4), h.Count("/a"))
+}
+
+func TestReads_DontCreateSeries(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("hits_total", "Hits.", "path")
+	g := r.NewGauge("queue", "Queue length.", "name")
+	h := r.NewHistogram("latency_seconds", "Latency.", []float64{1}, "op")
+
+	require.Zero(t, c.Value("/"))
+	require.Zero(t, g.Value("jobs"))
+	require.Zero(t, h.Count("read"))
+
+	want := `# HELP hits_total Hits.
+# TYPE hits_total counter
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+# HELP queue Queue length.
+# TYPE queue gauge
+`
+	require.Equal(t, want, exposition(t, r))
+}
+
+func TestEscaping(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("escaped_total", "Line one\nback\\slash.", "v")
+	c.Inc("a \"quoted\"\nvalue\\")
+
+	want := `# HELP escaped_total Line one\nback\\slash.
+# TYPE escaped_total counter
+escaped_total{v="a \"quoted\"\nvalue\\"} 1
+`
+	require.Equal(t, want, exposition(t, r))
+}
+
+func TestRegistry_Panics(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("dup_total", "")
+
+	require.Panics(t, func() { r.NewCounter("dup_total", "") })
+	require.Panics(t, func() { r.NewCounter("bad-name", "") })
+	require.Panics(t, func() { r.NewCounter("ok_total", "", "le") })
+	require.Panics(t, func() { r.NewHistogram("unsorted", "", []float64{2, 1}) })
+
+	c := r.NewCounter("labeled_total", "", "a", "b")
+	require.Panics(t, func() { c.Inc("only-one") })
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("hits_total", "Hits.").Inc()
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, ContentType, rec.Header().Get("Content-Type"))
+	require.Contains(t, rec.Body.String(), "hits_total 1\n")
+}
+
+func TestConcurrentUpdates(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("concurrent_total", "", "worker")
+	h := r.NewHistogram("concurrent_seconds", "", nil)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 1000 {
+				c.Inc("w")
+				h.Observe(0.01)
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, 8000.0, c.Value("w"))
+	require.Equal(t, uint64(8000), h.Count())
+}
@@ -0,0 +1,167 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// labelSep joins label values into a map key. It cannot appear in valid UTF-8 text.
+const labelSep = "\xff"
+
+// vec maps label values to the series of a single family.
+type vec[S any] struct {
+	labels []string
+	mu     sync.RWMutex
+	series map[string]*labeled[S]
+	newS   func() *S
+}
+
+type labeled[S any] struct {
+	values []string
+	s      *S
+}
+
+func newVec[S any](labels []string, newS func() *S) *vec[S] {
+	return &vec[S]{
+		labels: labels,
+		series: make(map[string]*labeled[S]),
+		newS:   newS,
+	}
+}
+
+// get returns the series for the label values, creating it when needed.
+// It panics if the number of values doesn't match the declared label names.
+func (v *vec[S]) get(values []string) *S {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: expected %d label values, got %d", len(v.labels), len(values)))
+	}
+
+	key := strings.Join(values, labelSep)
+
+	v.mu.RLock()
+	l, ok := v.series[key]
+	v.mu.RUnlock()
+	if ok {
+		return l.s
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if l, ok = v.series[key]; ok {
+		return l.s
+	}
+
+	l = &labeled[S]{values: append([]string(nil), values...), s: v.newS()}
+	v.series[key] = l
+	return l.s
+}
+
+// lookup returns the series for the label values, or nil if none was recorded.
+// Unlike get it doesn't create the series, so reading a value doesn't add it to the exposition.
+func (v *vec[S]) lookup(values []string) *S {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: expected %d label values, got %d", len(v.labels), len(values)))
+	}
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	if l, ok := v.series[strings.Join(values, labelSep)]; ok {
+		return l.s
+	}
+	return nil
+}
+
+// sorted returns all series ordered by their label values.
+func (v *vec[S]) sorted() []*labeled[S] {
+	v.mu.RLock()
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]*labeled[S], 0, len(keys))
+	for _, k := range keys {
+		out = append(out, v.series[k])
+	}
+	v.mu.RUnlock()
+	return out
+}
+
+// writeHeader writes the HELP and TYPE lines of a family.
+func writeHeader(w *bufio.Writer, name, help, typ string) {
+	w.WriteString("# HELP ")
+	w.WriteString(name)
+	w.WriteByte(' ')
+	w.WriteString(escapeHelp(help))
+	w.WriteString("\n# TYPE ")
+	w.WriteString(name)
+	w.WriteByte(' ')
+	w.WriteString(typ)
+	w.WriteByte('\n')
+}
+
+// writeSample writes a single sample line. The extra label pair, if its name is not empty,
+// is appended after the regular labels (used for the histogram "le" label).
+func writeSample(w *bufio.Writer, name string, labels, values []string, extraName, extraValue string, v float64) {
+	w.WriteString(name)
+
+	if len(labels) > 0 || extraName != "" {
+		w.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			writeLabel(w, l, values[i])
+		}
+		if extraName != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			writeLabel(w, extraName, extraValue)
+		}
+		w.WriteByte('}')
+	}
+
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(v))
+	w.WriteByte('\n')
+}
+
+func writeLabel(w *bufio.Writer, name, value string) {
+	w.WriteString(name)
+	w.WriteString(`="`)
+	w.WriteString(escapeLabelValue(value))
+	w.WriteByte('"')
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
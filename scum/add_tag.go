@@ -1,7 +1,12 @@
 package scum
 
+import (
+	"errors"
+	"unicode/utf8"
+)
+
 // AddTag registers a new [Tag] in the [Dictionary] and returns [ConfigError] if the provided values are
-// inconsistent or invalid.
+// inconsistent or invalid. The IDs of the Tags starting with a multi-byte rune are given by [TagID].
 func (d *Dictionary) AddTag(name string, seq []byte, greed Greed, rule Rule, openID, closeID byte) error {
 
 	err := checkTagName(name)
@@ -18,12 +23,12 @@ func (d *Dictionary) AddTag(name string, seq []byte, greed Greed, rule Rule, ope
 	id := ts.ID()
 
 	// check if the Tag is unique
-	if d.tags[id].ID() != 0 {
-		return newDuplicateTagIDError(id)
+	if prev := &d.tags[id]; prev.ID() != 0 {
+		return newDuplicateTagError(name, ts, prev)
 	}
 
-	isSingleChar := ts.Len == 1
-	isUniversal := openID == seq[0] && closeID == seq[0]
+	isSingleChar := ts.isSingleChar()
+	isUniversal := openID == id && closeID == id
 
 	// check rules and greed
 	err = checkTagConsistency(isSingleChar, isUniversal, rule, greed)
@@ -31,6 +36,12 @@ func (d *Dictionary) AddTag(name string, seq []byte, greed Greed, rule Rule, ope
 		return err
 	}
 
+	// the Tag-Vs-Content rule counts the runs of a single byte
+	if rule == RuleTagVsContent && ts.Len > 1 {
+		return NewConfigError(IssueRuleInapplicable,
+			errors.New("rule tag-vs-content is not applicable to multi-byte runes"))
+	}
+
 	t := Tag{
 		Name:    name,
 		Greed:   greed,
@@ -42,7 +53,14 @@ func (d *Dictionary) AddTag(name string, seq []byte, greed Greed, rule Rule, ope
 
 	d.tags[id] = t
 
-	d.actions[id] = CreateAction(&t)
+	if id < utf8.RuneSelf {
+		d.actions[id] = CreateAction(&t)
+		return nil
+	}
+
+	// the multi-byte rune is dispatched from its leading byte
+	d.utf8Actions[utf8ActionIdx(id)] = CreateAction(&t)
+	d.actions[ts.Bytes[0]] = ActUTF8Tag
 
 	return nil
 }
@@ -20,6 +20,9 @@ const (
 	// NodeTag contains a parsed tag.
 	NodeTag
 
+	// NodeBlock contains a line-mode [Block], like a heading, a quote or a list item.
+	NodeBlock
+
 	// NumNodeTypes is the total number of Node types. Should be placed as last const.
 	NumNodeTypes
 )
@@ -39,7 +42,7 @@ type Node struct {
 	// Type indicates whether this node is text or a tag.
 	Type NodeType
 
-	// TagID is the tag identifier for NodeTag nodes.
+	// TagID is the tag identifier for NodeTag nodes, and the [Block] identifier for NodeBlock nodes.
 	TagID byte
 
 	// Span defines the byte range in Input covered by this node.
@@ -1,6 +1,8 @@
 package scum
 
 import (
+	"io"
+	"strings"
 	"sync"
 	"testing"
 )
@@ -31,6 +33,46 @@ func BenchmarkParse(b *testing.B) {
 	}
 }
 
+// BenchmarkTokenize_UTF8 compares the ASCII-only Dictionary with the one having Tags of multi-byte runes.
+// The ASCII input must not get slower with the rune Tags registered.
+func BenchmarkTokenize_UTF8(b *testing.B) {
+	ascii := benchDict(b)
+	utf8Dict := benchUTF8Dict(b)
+
+	cases := []struct {
+		name  string
+		d     *Dictionary
+		input string
+	}{
+		{"ASCIIDict/ASCIIInput", &ascii, "Hello *world* this is $$bold text$$ and [link text] with :[image]"},
+		{"UTF8Dict/ASCIIInput", &utf8Dict, "Hello *world* this is $$bold text$$ and [link text] with :[image]"},
+		{"ASCIIDict/UnicodeText", &ascii, "Привет *мир*, это $$жирный текст$$ и [ссылка] с :[картинкой] ©"},
+		{"UTF8Dict/UnicodeText", &utf8Dict, "Привет *мир*, это $$жирный текст$$ и [ссылка] с :[картинкой] ©"},
+		{"UTF8Dict/UnicodeTags", &utf8Dict, "Привет «мир», это §§жирный текст§§ и ·ссылка· с «картинкой» ©"},
+	}
+
+	for _, c := range cases {
+		b.Run(c.name, func(b *testing.B) {
+			b.SetBytes(int64(len(c.input)))
+			for i := 0; i < b.N; i++ {
+				warns := &Warnings{}
+				Tokenize(c.d, c.input, warns)
+			}
+		})
+	}
+}
+
+func BenchmarkParse_LongInput_UTF8Dict(b *testing.B) {
+	d := benchUTF8Dict(b)
+	input := benchLongInput()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		warns := &Warnings{}
+		Parse(input, &d, warns)
+	}
+}
+
 func BenchmarkParseOuterAST(b *testing.B) {
 	d := benchDict(b)
 	cases := []struct {
@@ -66,6 +108,78 @@ func BenchmarkParseOuterAST(b *testing.B) {
 	}
 }
 
+// BenchmarkReparse compares reparsing a 10KB document after a single-character edit with parsing it in full.
+// The edits alternate between inserting a character and deleting it, so the document stays the same.
+func BenchmarkReparse(b *testing.B) {
+	d := benchDict(b)
+	input := benchDocument(10 << 10)
+
+	// inside the word "Hello" of a paragraph
+	word := func(offset int) int {
+		return strings.LastIndex(input[:offset+1], "Hello") + 2
+	}
+
+	cases := []struct {
+		name string
+		text string
+		pos  int
+	}{
+		{"TextAtStart", "x", word(0)},
+		{"TextInMiddle", "x", word(len(input) / 2)},
+		{"TextAtEnd", "x", word(len(input) - 1)},
+		// the unclosed italic Tag changes the rest of the document
+		{"TagInMiddle", "*", word(len(input) / 2)},
+	}
+
+	for _, tc := range cases {
+		insert := Edit{Span{tc.pos, tc.pos}, tc.text}
+		remove := Edit{Span{tc.pos, tc.pos + len(tc.text)}, ""}
+		edited := input[:tc.pos] + tc.text + input[tc.pos:]
+
+		b.Run(tc.name, func(b *testing.B) {
+			b.Run("Reparse", func(b *testing.B) {
+				var warns Warnings
+				ast := Parse(input, &d, &warns)
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					edit := insert
+					if i%2 == 1 {
+						edit = remove
+					}
+
+					warns = Warnings{}
+					if _, err := Reparse(&ast, edit, &d, &warns); err != nil {
+						b.Fatal(err)
+					}
+				}
+
+				benchmarkParseSink = benchmarkASTTotal(&ast, &warns)
+			})
+
+			b.Run("ParseInto", func(b *testing.B) {
+				var ast AST
+				var warns Warnings
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					src := edited
+					if i%2 == 1 {
+						src = input
+					}
+
+					warns = Warnings{}
+					ParseInto(&ast, src, &d, &warns)
+				}
+
+				benchmarkParseSink = benchmarkASTTotal(&ast, &warns)
+			})
+		})
+	}
+}
+
 func BenchmarkSerialize(b *testing.B) {
 	d := benchDict(b)
 	warns := &Warnings{}
@@ -89,6 +203,21 @@ func BenchmarkParse_LongInput(b *testing.B) {
 	}
 }
 
+// BenchmarkWriteText extracts the text of the long input from a reader, without keeping the input in memory.
+func BenchmarkWriteText(b *testing.B) {
+	d := benchDict(b)
+	input := benchLongInput()
+
+	b.SetBytes(int64(len(input)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		warns := &Warnings{}
+		if _, err := WriteText(io.Discard, strings.NewReader(input), &d, warns); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 func BenchmarkParse_DeeplyNested(b *testing.B) {
 	d := benchDict(b)
 	input := "[$$*deeply nested content*$$]"
@@ -279,6 +408,12 @@ func benchLongInput() string {
 	return input
 }
 
+// benchDocument returns a document of at least size bytes made of paragraphs with mixed content.
+func benchDocument(size int) string {
+	chunk := "Hello *world* this is $$bold text$$ and [link text] with `code` end.\n"
+	return strings.Repeat(chunk, size/len(chunk)+1)
+}
+
 // Helper to create dictionary for benchmarks
 func benchDict(b *testing.B) Dictionary {
 	b.Helper()
@@ -298,3 +433,16 @@ func benchDict(b *testing.B) Dictionary {
 
 	return d
 }
+
+// benchUTF8Dict is benchDict with the Tags of multi-byte runes.
+func benchUTF8Dict(b *testing.B) Dictionary {
+	b.Helper()
+	d := benchDict(b)
+
+	d.AddTag("QUOTE", []byte("«"), NonGreedy, RuleNA, 0, TagID("»"))
+	d.AddTag("QUOTE_END", []byte("»"), NonGreedy, RuleNA, TagID("«"), 0)
+	d.AddUniversalTag("SECTION", []byte("§§"), NonGreedy, RuleNA)
+	d.AddUniversalTag("MARK", []byte("·"), NonGreedy, RuleInfraWord)
+
+	return d
+}
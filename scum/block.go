@@ -0,0 +1,153 @@
+package scum
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxBlocks is the maximum number of [Block]s in a [Dictionary].
+const MaxBlocks = 16
+
+// BlockKind defines how the lines starting with a [Block]'s marker are grouped into the block nodes.
+type BlockKind uint8
+
+const (
+	// BlockHeading makes each marked line a block node of its own, like "# Title".
+	BlockHeading BlockKind = iota + 1
+
+	// BlockQuote groups the consecutive marked lines into a single block node. The lines without the marker
+	// are parsed as blocks again, so the quotes can be nested, like "> > reply", and contain the other blocks.
+	BlockQuote
+
+	// BlockListItem makes each marked line a block node of its own, like "- item". The item, indented by
+	// 2 more spaces than the item before it, is nested inside that item.
+	BlockListItem
+
+	// should be the last. used for kind validation
+	numBlockKinds
+)
+
+// Block describes a line-start marker, which makes the line a block node of the [AST] with [NodeBlock] type.
+//
+// The marker is recognized only at the start of a line and must be followed by a space or the end of the line.
+// The rest of the line is parsed as inline markup inside the block node.
+type Block struct {
+	// Name is the name of the Block. Does not need to be unique.
+	Name string
+
+	// Marker is the sequence starting the line, without the space after it.
+	Marker TagSequence
+
+	// Kind defines how the marked lines are grouped.
+	Kind BlockKind
+}
+
+// AddBlock registers a new [Block] in the [Dictionary], turning the line mode on. It returns [ConfigError]
+// if the values are invalid or the marker is already registered. The ID of the Block is returned by [Dictionary.BlockID].
+//
+// The marker can't contain spaces or start with the escape symbol. The marker's first symbol can be escaped in the input,
+// so a line can start with the marker as plain text.
+func (d *Dictionary) AddBlock(name string, marker []byte, kind BlockKind) error {
+	if err := checkTagName(name); err != nil {
+		return err
+	}
+
+	ms, err := NewTagSequence(marker)
+	if err != nil {
+		return err
+	}
+
+	if strings.ContainsRune(string(marker), ' ') {
+		return NewConfigError(IssueUnprintableChar, fmt.Errorf("block marker %q must not contain spaces", marker))
+	}
+
+	if kind == 0 || kind >= numBlockKinds {
+		return NewConfigError(IssueInvalidBlockKind, fmt.Errorf("unknown block kind %d", kind))
+	}
+
+	if d.numBlocks == MaxBlocks {
+		return NewConfigError(IssueTooManyBlocks, fmt.Errorf("at most %d blocks can be registered", MaxBlocks))
+	}
+
+	if marker[0] == d.escapeTrigger {
+		return newDuplicateTagIDError(marker[0])
+	}
+
+	if d.BlockID(string(marker)) != 0 {
+		return NewConfigError(IssueDuplicateTagID, fmt.Errorf("block with marker %q is already registered", marker))
+	}
+
+	d.blocks[d.numBlocks] = Block{Name: name, Marker: ms, Kind: kind}
+	d.numBlocks++
+	d.blockLeads[marker[0]] = true
+
+	return nil
+}
+
+// Block returns a registered Block by ID.
+func (d *Dictionary) Block(id byte) (Block, bool) {
+	if id == 0 || int(id) > d.numBlocks {
+		return Block{}, false
+	}
+
+	return d.blocks[id-1], true
+}
+
+// BlockID returns the ID of the [Block] with the marker, or 0 if there is none.
+// The IDs start from 1 in the order of registration, and are stored in [Node.TagID] of the block nodes.
+func (d *Dictionary) BlockID(marker string) byte {
+	for i := range d.numBlocks {
+		b := &d.blocks[i]
+		if string(b.Marker.Bytes[:b.Marker.Len]) == marker {
+			return byte(i + 1)
+		}
+	}
+
+	return 0
+}
+
+// HasBlocks reports whether the line mode is on, that is, at least one [Block] is registered.
+func (d *Dictionary) HasBlocks() bool {
+	return d.numBlocks > 0
+}
+
+// matchBlock returns the ID of the [Block] whose marker starts s, followed by a space or the end of the line,
+// and the width of the marker with the space. The longest marker wins. It returns 0 if there is no match.
+func (d *Dictionary) matchBlock(s string) (id byte, width int) {
+	if s == "" || !d.blockLeads[s[0]] {
+		return 0, 0
+	}
+
+	best := 0
+	for i := range d.numBlocks {
+		m := &d.blocks[i].Marker
+		l := int(m.Len)
+		if l <= best || len(s) < l || s[:l] != string(m.Bytes[:l]) {
+			continue
+		}
+
+		switch {
+		case len(s) == l || s[l] == '\n':
+			id, width, best = byte(i+1), l, l
+		case s[l] == ' ':
+			id, width, best = byte(i+1), l+1, l
+		}
+	}
+
+	return id, width
+}
+
+// blockLeadWidth returns the width of the rune starting s if it starts a [Block] marker, otherwise 0.
+func (d *Dictionary) blockLeadWidth(s string) int {
+	if s == "" || !d.blockLeads[s[0]] {
+		return 0
+	}
+
+	if s[0] < utf8.RuneSelf {
+		return 1
+	}
+
+	_, w := utf8.DecodeRuneInString(s)
+	return w
+}
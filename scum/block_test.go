@@ -0,0 +1,324 @@
+package scum
+
+import (
+	"math/rand/v2"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testBlockDict returns the test Dictionary in the line mode with the Blocks:
+//
+// "HEADING"    "#"  heading
+// "SUBHEADING" "##" heading
+// "QUOTE"      ">"  quote
+// "ITEM"       "-"  list item
+func testBlockDict(t *testing.T, limits Limits) Dictionary {
+	t.Helper()
+
+	d := testDictWithLimits(t, limits)
+	require.NoError(t, d.AddBlock("HEADING", []byte("#"), BlockHeading))
+	require.NoError(t, d.AddBlock("SUBHEADING", []byte("##"), BlockHeading))
+	require.NoError(t, d.AddBlock("QUOTE", []byte(">"), BlockQuote))
+	require.NoError(t, d.AddBlock("ITEM", []byte("-"), BlockListItem))
+
+	return d
+}
+
+// randomLines returns random input of n lines, starting with the Block markers and containing the inline markup.
+func randomLines(rng *rand.Rand, n int) string {
+	prefixes := []string{"", "", "# ", "## ", "> ", "> > ", "- ", "  - ", "    - ", "> - ", `\- `, "  # "}
+
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = prefixes[rng.IntN(len(prefixes))] + randomInput(rng, 6)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func TestAddBlock(t *testing.T) {
+	d := testDict(t)
+	require.False(t, d.HasBlocks())
+
+	require.NoError(t, d.AddBlock("QUOTE", []byte(">"), BlockQuote))
+	require.True(t, d.HasBlocks())
+	require.Equal(t, byte(1), d.BlockID(">"))
+	require.Equal(t, byte(0), d.BlockID("#"))
+
+	b, ok := d.Block(1)
+	require.True(t, ok)
+	require.Equal(t, "QUOTE", b.Name)
+	require.Equal(t, BlockQuote, b.Kind)
+
+	_, ok = d.Block(2)
+	require.False(t, ok)
+}
+
+func TestAddBlock_Errors(t *testing.T) {
+	d := testDict(t)
+
+	requireConfigIssue(t, d.AddBlock("", []byte("#"), BlockHeading), IssueInvalidTagNameLen)
+	requireConfigIssue(t, d.AddBlock("HEADING", nil, BlockHeading), IssueInvalidTagSeqLen)
+	requireConfigIssue(t, d.AddBlock("HEADING", []byte("# #"), BlockHeading), IssueUnprintableChar)
+	requireConfigIssue(t, d.AddBlock("HEADING", []byte("#"), 0), IssueInvalidBlockKind)
+	requireConfigIssue(t, d.AddBlock("HEADING", []byte("#"), numBlockKinds), IssueInvalidBlockKind)
+	requireConfigIssue(t, d.AddBlock("ESCAPED", []byte(`\`), BlockHeading), IssueDuplicateTagID)
+
+	require.NoError(t, d.AddBlock("HEADING", []byte("#"), BlockHeading))
+	requireConfigIssue(t, d.AddBlock("HEADING", []byte("#"), BlockQuote), IssueDuplicateTagID)
+
+	for i := 1; i < MaxBlocks; i++ {
+		require.NoError(t, d.AddBlock("HEADING", []byte{'#', byte('0' + i%10), byte('0' + i/10)}, BlockHeading))
+	}
+	requireConfigIssue(t, d.AddBlock("ITEM", []byte("-"), BlockListItem), IssueTooManyBlocks)
+}
+
+func TestSetEscapeTrigger_BlockLead(t *testing.T) {
+	d, err := NewDictionary(Limits{})
+	require.NoError(t, err)
+	require.NoError(t, d.AddBlock("QUOTE", []byte(">"), BlockQuote))
+
+	requireConfigIssue(t, d.SetEscapeTrigger('>'), IssueDuplicateTagID)
+}
+
+func TestParse_Blocks(t *testing.T) {
+	d := testBlockDict(t, Limits{})
+
+	testCases := []struct {
+		name  string
+		input string
+		nodes []string
+	}{
+		{"Heading", "# Title", []string{"HEADING", "Title"}},
+		{"LongestMarker", "## Sub", []string{"SUBHEADING", "Sub"}},
+		{"EmptyHeading", "#", []string{"HEADING"}},
+		{"MarkerWithoutSpace", "#tag", []string{"#tag"}},
+		{"IndentedHeading", "  # h", []string{"  # h"}},
+		{"PlainLines", "a\nb", []string{"a\nb"}},
+		{"TagSpansPlainLines", "*a\nb*", []string{"ITALIC", "a\nb"}},
+		{"TagClosedAtBlockEnd", "# $$Title\nrest$$", []string{"HEADING", "BOLD", "Title", "\n", "rest", "BOLD"}},
+		{"Quote", "> a\n> b", []string{"QUOTE", "a", "\n", "b"}},
+		{"NestedQuote", "> a\n> > b\nc", []string{"QUOTE", "a", "\n", "QUOTE", "b", "\n", "c"}},
+		{"QuoteWithList", "> - a\n>   - b", []string{"QUOTE", "ITEM", "a", "\n", "ITEM", "b"}},
+		{"List", "- a\n  - b\n    - c\n- d", []string{"ITEM", "a", "\n", "ITEM", "b", "\n", "ITEM", "c", "\n", "ITEM", "d"}},
+		{"ListOverIndented", "- a\n      - b", []string{"ITEM", "a", "\n", "ITEM", "b"}},
+		{"EscapedMarkers", "\\# a\n\\- b\n\\> c", []string{"\\# a\n\\- b\n\\> c"}},
+		{"EmptyLines", "a\n\n# h\n", []string{"a\n", "\n", "HEADING", "h", "\n"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := newWarns(t)
+			ast := Parse(tc.input, &d, &w)
+
+			require.Equal(t, tc.nodes, preOrder(ast, &d))
+			require.Equal(t, Span{0, len(tc.input)}, ast.Nodes[0].Span)
+
+			for _, warn := range w.List() {
+				require.NotEqual(t, IssueRedundantEscape, warn.Issue)
+			}
+		})
+	}
+}
+
+func TestParse_BlockSpans(t *testing.T) {
+	d := testBlockDict(t, Limits{})
+	w := newWarns(t)
+	input := "- a\n  - b\nc\n> d\n> e"
+	ast := Parse(input, &d, &w)
+
+	var spans []string
+	for i := range ast.Descendants(0) {
+		if ast.Nodes[i].Type == NodeBlock {
+			spans = append(spans, input[ast.Nodes[i].Span.Start:ast.Nodes[i].Span.End])
+		}
+	}
+
+	// the item ends with its nested items, the quote spans all its lines
+	require.Equal(t, []string{"- a\n  - b", "- b", "> d\n> e"}, spans)
+}
+
+func TestParse_BlockAttributes(t *testing.T) {
+	d := testBlockDict(t, Limits{})
+	w := newWarns(t)
+	ast := Parse("# !{x}h", &d, &w)
+
+	require.Equal(t, NodeBlock, ast.Nodes[1].Type)
+	require.Equal(t, Range{0, 1}, ast.Nodes[1].Attributes)
+}
+
+func TestParse_MaxLines(t *testing.T) {
+	d := testBlockDict(t, Limits{MaxLines: 2})
+	w := newWarns(t)
+	input := "# a\n# b\n# c\n# d"
+	ast := Parse(input, &d, &w)
+
+	require.Equal(t, []string{"HEADING", "a", "\n", "HEADING", "b", "\n", "# c\n# d"}, preOrder(ast, &d))
+	require.Equal(t, []Warning{{Issue: IssueMaxLinesExceeded, Pos: 8}}, w.List())
+}
+
+func TestParse_MaxListDepth(t *testing.T) {
+	d := testBlockDict(t, Limits{MaxListDepth: 2})
+
+	w := newWarns(t)
+	ast := Parse("- a\n  - b\n    - c", &d, &w)
+	require.Equal(t, []string{"ITEM", "a", "\n", "ITEM", "b", "\n", "ITEM", "c"}, preOrder(ast, &d))
+	require.Equal(t, 1, ast.Nodes[0].ChildCount)
+	require.Equal(t, []Warning{{Issue: IssueMaxListDepthExceeded, Pos: 14}}, w.List())
+
+	w = newWarns(t)
+	ast = Parse("> > > a\n> > > b", &d, &w)
+	require.Equal(t, []string{"QUOTE", "QUOTE", "> a", "\n", "> b"}, preOrder(ast, &d))
+	require.Equal(t, []Warning{{Issue: IssueMaxListDepthExceeded, Pos: 4}}, w.List())
+}
+
+// TestParse_BlocksInlineCompatible checks that the input without the markers is parsed the same way in the line mode.
+func TestParse_BlocksInlineCompatible(t *testing.T) {
+	inline := testDict(t)
+	lines := testBlockDict(t, Limits{})
+	rng := rand.New(rand.NewPCG(39, 0))
+
+	for range 500 {
+		input := randomInput(rng, 12)
+		if strings.ContainsAny(input, "#>-") {
+			continue
+		}
+
+		w1, w2 := newWarns(t), newWarns(t)
+		want, got := Parse(input, &inline, &w1), Parse(input, &lines, &w2)
+
+		// the root span of the inline parse doesn't count the attributes, while in the line mode it is the whole input
+		want.Nodes[0].Span = got.Nodes[0].Span
+
+		require.Equal(t, want, got, input)
+		require.Equal(t, w1.List(), w2.List(), input)
+	}
+}
+
+func TestParse_BlockStats(t *testing.T) {
+	d := testBlockDict(t, Limits{})
+	rng := rand.New(rand.NewPCG(39, 1))
+
+	for range 500 {
+		w := newWarns(t)
+		ast := Parse(randomLines(rng, 5), &d, &w)
+
+		stats := nodeStats(&ast, 1, len(ast.Nodes), &d)
+		require.Equal(t, stats.TextByteLen, ast.TextByteLen, ast.Input)
+		require.Equal(t, stats.TotalTextNodes, ast.TotalTextNodes, ast.Input)
+		require.Equal(t, stats.TotalTagNodes, ast.TotalTagNodes, ast.Input)
+	}
+}
+
+func TestFormat_Blocks(t *testing.T) {
+	d := testBlockDict(t, Limits{})
+
+	testCases := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"EmptyHeading", "# ", "#"},
+		{"ClosesTagsInBlock", "# $$Title", "# $$Title$$"},
+		{"Quote", ">a? no\n> a\n>\n> > b", ">a? no\n> a\n>\n> > b"},
+		{"List", "- a\n      - b\n - c", "- a\n  - b\n- c"},
+		{"DropsRedundantEscape", "\\a\n\\# b", "a\n\\# b"},
+		{"KeepsMarkerEscape", "*a\n\\- b*", "*a\n\\- b*"},
+		{"AttributesAfterBreak", "# h\n!{x}a", "# h\n!{x}a"},
+		{"Attributes", "# !{x}h", "# !{x}h"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, requireFormatRoundTrip(t, &d, tc.input))
+		})
+	}
+
+	rng := rand.New(rand.NewPCG(39, 2))
+	for range 300 {
+		requireFormatRoundTrip(t, &d, randomLines(rng, 5))
+	}
+}
+
+func TestReparse_Blocks(t *testing.T) {
+	d := testBlockDict(t, Limits{})
+	rng := rand.New(rand.NewPCG(39, 3))
+
+	w := newWarns(t)
+	ast := Parse(randomLines(rng, 20), &d, &w)
+
+	for range 300 {
+		requireReparse(t, &d, &ast, randomEdit(rng, ast.Input))
+	}
+}
+
+func TestSerialize_Blocks(t *testing.T) {
+	d := testBlockDict(t, Limits{})
+	w := newWarns(t)
+	ast := Parse("> a", &d, &w)
+
+	root := ast.Serialize(&d)
+	require.Len(t, root.Children, 1)
+	require.Equal(t, "Block", root.Children[0].Type)
+	require.Equal(t, "QUOTE", root.Children[0].Name)
+}
+
+func TestSpec_Blocks(t *testing.T) {
+	d, err := LoadDictionary([]byte(`
+tags:
+  - name: ITALIC
+    seq: "*"
+    open_id: "*"
+    close_id: "*"
+escape: \
+blocks:
+  - name: HEADING
+    marker: "#"
+    kind: heading
+  - name: QUOTE
+    marker: ">"
+    kind: quote
+  - name: ITEM
+    marker: "*"
+    kind: list_item
+limits:
+  max_lines: 100
+  max_list_depth: 4
+`))
+	require.NoError(t, err)
+	require.Equal(t, byte(3), d.BlockID("*"))
+	require.Equal(t, 100, d.Limits.MaxLines)
+
+	spec := d.Spec()
+	require.Equal(t, []BlockSpec{{"HEADING", "#", "heading"}, {"QUOTE", ">", "quote"}, {"ITEM", "*", "list_item"}}, spec.Blocks)
+
+	reloaded, err := NewDictionaryFromSpec(spec)
+	require.NoError(t, err)
+	require.Equal(t, spec, reloaded.Spec())
+
+	// the marker takes precedence over the Tag with the same symbol
+	w := newWarns(t)
+	ast := Parse("* *a*", &d, &w)
+	require.Equal(t, []string{"ITEM", "ITALIC", "a"}, preOrder(ast, &d))
+
+	_, err = LoadDictionary([]byte(`
+tags: []
+escape: \
+blocks:
+  - name: HEADING
+    marker: "#"
+    kind: title
+  - name: QUOTE
+    marker: \
+    kind: quote
+`))
+	var errs ConfigErrors
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 2)
+	requireConfigIssue(t, errs[0], IssueInvalidBlockKind)
+	require.Equal(t, "blocks[0].kind", errs[0].Path)
+	requireConfigIssue(t, errs[1], IssueDuplicateTagID)
+	require.Equal(t, "blocks[1]", errs[1].Path)
+}
@@ -1,5 +1,7 @@
 package scum
 
+import "unicode/utf8"
+
 // CheckMultiCharTagSeq tells the ActionContext if the trigger Tag has no missing symbols.
 func CheckMultiCharTagSeq(ctx *ActionContext) {
 	i := ctx.Idx
@@ -9,6 +11,14 @@ func CheckMultiCharTagSeq(ctx *ActionContext) {
 	seqEnd := min(len(ctx.Input), expectedLastCharIdx)
 
 	contained, _, l := ctx.Tag.Seq.IsContainedIn(ctx.Input[i:seqEnd])
+
+	// the broken sequence can end inside a rune of the input, which is then
+	// left whole for the tokenizer, since it can start another Tag
+	if !contained {
+		for l > 1 && i+l < len(ctx.Input) && !utf8.RuneStart(ctx.Input[i+l]) {
+			l--
+		}
+	}
 	ctx.Bounds.SeqValid = contained
 	ctx.Bounds.Width = l
 	ctx.Bounds.Raw = NewSpan(i, l)
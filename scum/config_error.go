@@ -3,21 +3,67 @@ package scum
 import (
 	"errors"
 	"fmt"
+	"strings"
+	"unicode/utf8"
 )
 
 // ConfigError describes an error that occurs during [Dictionary] configuration.
 type ConfigError struct {
-	Issue Issue // Issue is the kind of configuration problem.
-	Err   error // Err contains the original error created during configuration.
+	Issue Issue  // Issue is the kind of configuration problem.
+	Err   error  // Err contains the original error created during configuration.
+	Path  string // Path locates the erroneous value in a [Spec], e.g. "tags[2].seq". Empty outside of specs.
 }
 
 func (e *ConfigError) Unwrap() error {
 	return e.Err
 }
 func (e *ConfigError) Error() string {
+	if e.Path != "" {
+		return fmt.Sprintf("%s: %d: %v", e.Path, e.Issue, e.Err)
+	}
 	return fmt.Sprintf("%d: %v", e.Issue, e.Err)
 }
 
+// ConfigErrors aggregates all the [ConfigError]s found in a [Spec],
+// so a data file can be fixed in one go.
+type ConfigErrors []*ConfigError
+
+func (e ConfigErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e ConfigErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// add records err at path. Errors which are not [ConfigError]s are recorded as [IssueInvalidSpec].
+func (e *ConfigErrors) add(path string, err error) {
+	var ce *ConfigError
+	if !errors.As(err, &ce) {
+		ce = NewConfigError(IssueInvalidSpec, err)
+	}
+
+	withPath := *ce
+	withPath.Path = path
+	*e = append(*e, &withPath)
+}
+
+// errOrNil returns nil for an empty list, so the result can be compared with nil.
+func (e ConfigErrors) errOrNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
 // NewConfigError is a factory function for creating a *ConfigError.
 func NewConfigError(issue Issue, err error) *ConfigError {
 	return &ConfigError{
@@ -34,6 +80,20 @@ func newDuplicateTagIDError(id byte) error {
 	return NewConfigError(IssueDuplicateTagID, fmt.Errorf("Tag with ID %d already registered", id))
 }
 
+// newDuplicateTagError is the error of the Tag whose ID is taken by the registered Tag prev. The multi-byte runes
+// sharing the last byte are explained, since their IDs are not obvious from the sequences, see [TagID].
+func newDuplicateTagError(name string, seq TagSequence, prev *Tag) error {
+	s, prevSeq := seq.Bytes[:seq.Len], prev.Seq.Bytes[:prev.Seq.Len]
+
+	err := fmt.Errorf("Tag %s %q has the same ID %d as the registered Tag %s %q", name, s, seq.ID(), prev.Name, prevSeq)
+	r, _ := utf8.DecodeRune(s)
+	if prevR, _ := utf8.DecodeRune(prevSeq); r >= utf8.RuneSelf && r != prevR {
+		err = fmt.Errorf("%w: the ID of a Tag starting with a multi-byte rune is the last byte of the rune", err)
+	}
+
+	return NewConfigError(IssueDuplicateTagID, err)
+}
+
 func newUnprintableError(ent string, char byte) error {
 	return NewConfigError(
 		IssueUnprintableChar,
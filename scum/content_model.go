@@ -0,0 +1,115 @@
+package scum
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// TagSet is a set of [Tag] IDs.
+type TagSet [4]uint64
+
+// NewTagSet returns the set containing the IDs.
+func NewTagSet(ids ...byte) TagSet {
+	var s TagSet
+	for _, id := range ids {
+		s.Add(id)
+	}
+	return s
+}
+
+// Add puts the ID into the set.
+func (s *TagSet) Add(id byte) {
+	s[id>>6] |= 1 << (id & 63)
+}
+
+// Has reports whether the ID is in the set.
+func (s *TagSet) Has(id byte) bool {
+	return s[id>>6]&(1<<(id&63)) != 0
+}
+
+// IsEmpty reports whether the set has no IDs.
+func (s *TagSet) IsEmpty() bool {
+	return s[0]|s[1]|s[2]|s[3] == 0
+}
+
+// IDs returns the IDs in the set in ascending order.
+func (s *TagSet) IDs() []byte {
+	var ids []byte
+	for i, word := range s {
+		for word != 0 {
+			b := bits.TrailingZeros64(word)
+			ids = append(ids, byte(i<<6|b))
+			word &= word - 1
+		}
+	}
+	return ids
+}
+
+// ContentModel restricts which [Tag]s can be nested inside a Tag and inside which Tags it can be nested.
+// The zero value allows any nesting, apart from the duplicate nested Tags which are never allowed.
+//
+// A Tag violating the model of its container is flattened to plain text with the [IssueTagNotAllowed] warning.
+type ContentModel struct {
+	// Children are the IDs of the Tags allowed as direct children of the Tag. Empty set allows any Tag.
+	Children TagSet
+
+	// ForbiddenAncestors are the IDs of the Tags which can't contain the Tag at any depth.
+	ForbiddenAncestors TagSet
+
+	// TextOnly forbids any Tags inside the Tag.
+	TextOnly bool
+}
+
+// allows reports whether the model lets the Tag with the id be a direct child.
+func (m *ContentModel) allows(id byte) bool {
+	return !m.TextOnly && (m.Children.IsEmpty() || m.Children.Has(id))
+}
+
+// WithContent sets the [ContentModel] for a [Tag] created by [NewTag].
+func WithContent(content ContentModel) TagDecorator {
+	return func(t *Tag) {
+		t.Content = content
+	}
+}
+
+// SetContentModel sets the [ContentModel] of the registered [Tag] with the id.
+//
+// Returns [ConfigError] with [IssueUnknownTagID] if there is no Tag with the id,
+// or if the model refers to the unregistered Tags.
+func (d *Dictionary) SetContentModel(id byte, content ContentModel) error {
+	if _, ok := d.Tag(id); !ok {
+		return NewConfigError(IssueUnknownTagID, fmt.Errorf("tag with ID %q is not registered", id))
+	}
+
+	for _, set := range []*TagSet{&content.Children, &content.ForbiddenAncestors} {
+		for _, ref := range set.IDs() {
+			if _, ok := d.Tag(ref); !ok {
+				return NewConfigError(IssueUnknownTagID, fmt.Errorf("tag with ID %q is not registered", ref))
+			}
+		}
+	}
+
+	d.tags[id].Content = content
+	return nil
+}
+
+// containerOf returns the ID of the open Tag which doesn't allow the Tag with the id at the current position,
+// either as the direct parent or as a forbidden ancestor. Returns 0 if the Tag is allowed.
+func (s *parserState) containerOf(d *Dictionary, id byte) byte {
+	if parent := s.peekStack(); parent != 0 && !d.tags[parent].Content.allows(id) {
+		return parent
+	}
+
+	forbidden := &d.tags[id].Content.ForbiddenAncestors
+	if forbidden.IsEmpty() {
+		return 0
+	}
+
+	for i := len(s.stack) - 1; i >= 0; i-- {
+		if forbidden.Has(s.stack[i]) {
+			return s.stack[i]
+		}
+	}
+
+	return 0
+}
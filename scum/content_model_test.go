@@ -0,0 +1,226 @@
+package scum
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testContentDict returns the test Dictionary with the content models:
+//
+// "LINK_TEXT_START" allows only "BOLD" and "ITALIC" children
+// "BOLD"            contains only text
+// "IMAGE"           can't be inside "LINK_TEXT_START" at any depth
+// "CODE"            can't be inside "ITALIC" at any depth
+func testContentDict(t *testing.T, limits Limits) Dictionary {
+	t.Helper()
+
+	d := testDictWithLimits(t, limits)
+	require.NoError(t, d.SetContentModel('[', ContentModel{Children: NewTagSet('$', '*')}))
+	require.NoError(t, d.SetContentModel('$', ContentModel{TextOnly: true}))
+	require.NoError(t, d.SetContentModel(':', ContentModel{ForbiddenAncestors: NewTagSet('[')}))
+	require.NoError(t, d.SetContentModel('`', ContentModel{ForbiddenAncestors: NewTagSet('*')}))
+
+	return d
+}
+
+// preOrder returns the names of the nodes in the pre-order, see nodeName.
+func preOrder(ast AST, d *Dictionary) []string {
+	var names []string
+	for i := range ast.Descendants(0) {
+		names = append(names, nodeName(ast, d, i))
+	}
+	return names
+}
+
+func TestTagSet(t *testing.T) {
+	s := NewTagSet('$', 0xAB, 255)
+	require.False(t, s.IsEmpty())
+	require.True(t, s.Has('$'))
+	require.True(t, s.Has(0xAB))
+	require.True(t, s.Has(255))
+	require.False(t, s.Has('*'))
+
+	s.Add(0)
+	require.Equal(t, []byte{0, '$', 0xAB, 255}, s.IDs())
+
+	var empty TagSet
+	require.True(t, empty.IsEmpty())
+	require.Empty(t, empty.IDs())
+}
+
+func TestParse_ContentModel(t *testing.T) {
+	d := testContentDict(t, Limits{})
+
+	testCases := []struct {
+		name   string
+		input  string
+		nodes  []string
+		issues int
+	}{
+		{"AllowedChildren", "[a $$b$$ *c*]", []string{"LINK_TEXT_START", "a ", "BOLD", "b", " ", "ITALIC", "c"}, 0},
+		{"ForbiddenChild", "[a _b_ c]", []string{"LINK_TEXT_START", "a ", "_", "b", "_", " c"}, 2},
+		{"AllowedDescendant", "[*a _b_*]", []string{"LINK_TEXT_START", "ITALIC", "a ", "UNDERLINE", "b"}, 0},
+		{"TextOnly", "$$a *b* c$$", []string{"BOLD", "a ", "*", "b", "*", " c"}, 2},
+		{"ForbiddenAncestor", "[*a :[b] c*]", []string{"LINK_TEXT_START", "ITALIC", "a ", ":[", "b", "]", " c"}, 1},
+		{"ForbiddenAncestorOutside", ":[a] *:[b]*", []string{"IMAGE", "a", " ", "ITALIC", "IMAGE", "b"}, 0},
+		{"ForbiddenGreedy", "*a `b` c* `d`", []string{"ITALIC", "a ", "`b`", " c", " ", "CODE", "d"}, 1},
+		// the closing tag after the container of the flattened opener is closed is not flattened
+		{"FlattenedOpenerOutlived", "[*:[a*]", []string{"LINK_TEXT_START", "ITALIC", ":[", "a"}, 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := newWarns(t)
+			ast := Parse(tc.input, &d, &w)
+
+			require.Equal(t, tc.nodes, preOrder(ast, &d))
+
+			var notAllowed int
+			for _, warn := range w.List() {
+				if warn.Issue == IssueTagNotAllowed {
+					notAllowed++
+				}
+			}
+			require.Equal(t, tc.issues, notAllowed, "%+v", w.List())
+		})
+	}
+}
+
+func TestParse_ContentModelWarning(t *testing.T) {
+	d := testContentDict(t, Limits{})
+	w := newWarns(t)
+	Parse("[a *b :[c]*]", &d, &w)
+
+	require.Equal(t, []Warning{{
+		Issue:          IssueTagNotAllowed,
+		Pos:            6,
+		TagID:          ':',
+		ContainerTagID: '[',
+	}}, w.List())
+
+	var sw []SerializableWarning
+	w.SerializeAll(&sw, &d)
+	require.Len(t, sw, 1)
+	require.Equal(t, "TAG_NOT_ALLOWED", sw[0].Codename)
+	require.Equal(t, "tag with name IMAGE is not allowed inside the tag with name LINK_TEXT_START and is treated as plain text.", sw[0].Description)
+	require.Equal(t, Span{6, 8}, sw[0].Span)
+}
+
+// TestParse_ContentModelStats checks that the flattened tags keep the text statistics
+// consistent with the nodes of the tree.
+func TestParse_ContentModelStats(t *testing.T) {
+	d := testContentDict(t, Limits{})
+	rng := rand.New(rand.NewPCG(38, 0))
+
+	for range 500 {
+		w := newWarns(t)
+		ast := Parse(randomInput(rng, 12), &d, &w)
+
+		stats := nodeStats(&ast, 1, len(ast.Nodes), &d)
+		require.Equal(t, stats.TextByteLen, ast.TextByteLen, ast.Input)
+		require.Equal(t, stats.TotalTextNodes, ast.TotalTextNodes, ast.Input)
+		require.Equal(t, stats.TotalTagNodes, ast.TotalTagNodes, ast.Input)
+	}
+}
+
+func TestFormat_ContentModel(t *testing.T) {
+	d := testContentDict(t, Limits{})
+
+	for _, in := range []string{"[a _b_ c]", "$$a *b* c$$", "[*a :[b] c*]", "*a `b` c*"} {
+		requireFormatRoundTrip(t, &d, in)
+	}
+}
+
+func TestReparse_ContentModel(t *testing.T) {
+	d := testContentDict(t, Limits{})
+	rng := rand.New(rand.NewPCG(38, 1))
+
+	w := newWarns(t)
+	ast := Parse(randomInput(rng, 300), &d, &w)
+
+	for range 2000 {
+		requireReparse(t, &d, &ast, randomEdit(rng, ast.Input))
+	}
+}
+
+func TestSetContentModel_Errors(t *testing.T) {
+	d := testDict(t)
+
+	err := d.SetContentModel('#', ContentModel{TextOnly: true})
+	requireConfigIssue(t, err, IssueUnknownTagID)
+
+	err = d.SetContentModel('[', ContentModel{Children: NewTagSet('#')})
+	requireConfigIssue(t, err, IssueUnknownTagID)
+
+	err = d.SetContentModel('[', ContentModel{ForbiddenAncestors: NewTagSet('[', '#')})
+	requireConfigIssue(t, err, IssueUnknownTagID)
+
+	tag, _ := d.Tag('[')
+	require.Equal(t, ContentModel{}, tag.Content)
+}
+
+func TestNewTag_WithContent(t *testing.T) {
+	content := ContentModel{Children: NewTagSet('*'), TextOnly: true}
+	tag, err := NewTag([]byte("["), "LINK", 0, ']', WithContent(content))
+	require.NoError(t, err)
+	require.Equal(t, content, tag.Content)
+}
+
+func TestSpec_ContentModel(t *testing.T) {
+	d := testContentDict(t, Limits{})
+
+	spec := d.Spec()
+	idx := slices.IndexFunc(spec.Tags, func(ts TagSpec) bool { return ts.Seq == "[" })
+	require.Equal(t, []string{"$", "*"}, spec.Tags[idx].Children)
+
+	loaded, err := LoadDictionary([]byte(`
+tags:
+  - name: ITALIC
+    seq: "*"
+    open_id: "*"
+    close_id: "*"
+  - name: BOLD
+    seq: $
+    open_id: $
+    close_id: $
+    text_only: true
+  - name: LINK
+    seq: "["
+    close_id: "]"
+    children: ["*"]
+    forbidden_ancestors: ["$"]
+  - name: LINK
+    seq: "]"
+    open_id: "["
+`))
+	require.NoError(t, err)
+
+	link, _ := loaded.Tag('[')
+	require.Equal(t, ContentModel{Children: NewTagSet('*'), ForbiddenAncestors: NewTagSet('$')}, link.Content)
+
+	spec = loaded.Spec()
+	reloaded, err := NewDictionaryFromSpec(spec)
+	require.NoError(t, err)
+	require.Equal(t, spec, reloaded.Spec())
+
+	_, err = LoadDictionary([]byte(`
+tags:
+  - name: LINK
+    seq: "["
+    close_id: "]"
+    children: ["*", "**", ""]
+  - name: LINK
+    seq: "]"
+    open_id: "["
+`))
+	var errs ConfigErrors
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 3)
+	requireConfigIssue(t, errs[0], IssueUnknownTagID)
+	require.Equal(t, "tags[0].children[0]", errs[0].Path)
+	requireConfigIssue(t, errs[1], IssueInvalidSpec)
+	requireConfigIssue(t, errs[2], IssueInvalidSpec)
+}
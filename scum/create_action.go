@@ -5,8 +5,9 @@ func CreateAction(t *Tag) Action {
 	// allocating the new Plan on the heap
 	p := new(Plan)
 
-	// choose strategy based on whether the Tag is single char or multi char
-	if t.Seq.Len == 1 {
+	// choose strategy based on whether the Tag is single char or multi char.
+	// a single multi-byte rune counts as a single char
+	if t.Seq.isSingleChar() {
 		singleCharPlan(t, p)
 	} else {
 		multiCharPlan(t, p)
@@ -17,7 +18,7 @@ func CreateAction(t *Tag) Action {
 	}
 }
 
-// singleCharPlan prepares the context with single byte opening Tag, and add
+// singleCharPlan prepares the context with single char opening Tag, and add
 // steps based on the Tag's type, greed and rule.
 func singleCharPlan(t *Tag, p *Plan) {
 	// preparing to emit the single-byte opening Tag
@@ -4,19 +4,23 @@
 // You can set opening, closing, universal and greedy tags along with the escape symbol dynamically during runtime.
 // Tags will have the tag name of your choice and the final AST will be built based on it.
 //
-// WARNING: It works exclusively with simple 1-byte long ASCII symbols as tags.
+// Tags are made of printable ASCII characters and multi-byte UTF-8 runes, like "§§" or "«" and "»".
+// The escape symbol and the attribute signature are 1-byte long ASCII symbols.
 //
 // # Notes and Policies.
 //
 //  1. This is My Toy and it was created for fun.
 //  2. Tags can contain at most [MaxTagLen] symbols. Escape and Attribute signature tags can contain only 1 symbol.
-//  3. ALL special symbols must be 1-byte long printable ASCII characters.
+//  3. The escape symbol and the attribute signature symbols must be 1-byte long printable ASCII characters.
+//     Tag sequences can also contain printable multi-byte UTF-8 runes.
 //  4. Nested tags with the same ID will have no effect. Children of the repeated descendants will become
 //     children of the "oldest" original tag and the duplicates will not end up in the final AST.
 //  5. A universal Tag uses the same Tag as opener and closer.
 //  6. The parser will try to make sense out of the User's gibberish and will not return any errors but only a slice of [Warning].
 //  7. The trigger Tag is one which starts the Action.
 //  8. The complementary Tag is one which closes the greedy Tag's body.
+//  9. Any other nesting is allowed, unless restricted by the Tags' [ContentModel]s. A Tag which is not allowed
+//     at its place is flattened to plain text, together with the closing Tag of a flattened opening Tag.
 //
 // # Parsing model.
 //
@@ -26,6 +30,17 @@
 // return an AST to a pool while any renderer, serializer, or caller is still
 // reading it.
 //
+// Use [Reparse] for the live preview while typing: it applies an [Edit] to a
+// parsed AST and tokenizes only the region around it, with the same result as
+// parsing the edited input in full.
+//
+// Use [AST.Walk], the iterators like [AST.Children] and [AST.Select] to read
+// the tree straight off the arena, without building [SerializableNode] trees.
+//
+// Use [TokenStream] to tokenize the input read from an [io.Reader] in a bounded
+// window, and [WriteText] to extract its text, for the bulk jobs over the inputs
+// which should not be kept in memory as a whole.
+//
 // # Behaviour. This will likely change in the future.
 //
 // Properties of Tags:
@@ -33,6 +48,10 @@
 //  1. ID. Each Tag has a unique byte, which triggers the tag's corresponding Action and starts the process of Tokenization.
 //     It also serves as unique ID of the Tag and is used for fast lookup for the Tag's info.
 //
+//     1.1. For a Tag starting with a multi-byte rune, like "«", the ID is the last byte of the rune, see [TagID]. The leading
+//     byte of the rune triggers [ActUTF8Tag], which dispatches the rune to the Tag's Action, so the ASCII Tags keep the direct
+//     lookup by the byte. Two Tags, whose first runes end with the same byte, like "«" and "Ϋ", can't be registered together.
+//
 //  2. Name. Each Tag has a Name string associated with it. It does not need to be unique. It is used during the parsing process
 //     for naming the AST's Node.
 //
@@ -59,15 +78,15 @@
 //
 //     3.4 Payload. The Greedy Tag's payload is considered as a text node and will be accounted as a plain text.
 //
-//  4. Sequence. You can construct your tags from at most [MaxTagLen] (4 by default). You can create tag like this "$}{|". The Sequence
+//  4. Sequence. You can construct your tags from at most [MaxTagLen] (4 by default) bytes. You can create tag like this "$}{|". The Sequence
 //     is a slice of bytes with length of the defined tag, and with indexes corresponding to indexes of chars in the tag. For tag "$}{|"
-//     Sequence will be []byte{'$', '}', '{', '|'}.
+//     Sequence will be []byte{'$', '}', '{', '|'}. A multi-byte rune takes as many bytes as its UTF-8 encoding, so "§§" is 4 bytes long.
 //
-//  5. Rule. Each UNIVERSAL SINGLE BYTE TAG can have 3 different Rules available to it:
+//  5. Rule. Each UNIVERSAL SINGLE CHAR TAG, a single ASCII character or a single rune, can have 3 different Rules available to it:
 //
 //     5.1 0 Rule - No Rule. Default Rule value. Does nothing.
 //
-//     5.2 1 - Intra-word Rule. For single-byte universal non-greedy tags, the rule is evaluated by inspecting the adjacent Unicode runes in the input string.
+//     5.2 1 - Intra-word Rule. For single-char universal non-greedy tags, the rule is evaluated by inspecting the adjacent Unicode runes in the input string.
 //     Tokenization state and previous tokens do not affect this rule.
 //
 //     5.2.1.	Example 1: '_' defines a Tag with name "UNDERLINE" and has
@@ -100,6 +119,34 @@
 //     when first encounters the "LINK_TEXT_START", saves its ID to the stack. While the '[' is at the top of the stack and the Parser encounters a closing Tag,
 //     it checks if the encountered Tag ID is equal to the "LINK_TEXT_START's" CloseID.
 //
+//  7. Content model. Each opening, universal or greedy Tag can restrict its nesting with a [ContentModel]: the Tags allowed as its children,
+//     the ancestors it can't have at any depth, and whether it can contain only text. Set it with [WithContent] or [Dictionary.SetContentModel].
+//     The Parser checks each opening Tag against the model of its parent and its own forbidden ancestors. A Tag which is not allowed
+//     becomes a text node of its raw bytes, and a Warning with [IssueTagNotAllowed] is added. The next closing Tag of a flattened opening
+//     Tag becomes text as well, unless the Tag containing the opener is closed first. Each occurrence of a universal Tag is checked on its own.
+//
+//     7.1. Example: the Tag "LINK_TEXT_START" allows only the "BOLD" and "ITALIC" Tags as its children, so in the string
+//     "[a _b_ $$c$$]" both "_" are plain text, while "$$c$$" is still bold.
+//
+// Line mode.
+//
+//   - The Tags above are inline markup. Registering a [Block] with [Dictionary.AddBlock] turns on the line mode: the markers at the starts
+//     of the lines, like "# ", "> " or "- ", make the lines [NodeBlock] nodes, with the rest of the line parsed as inline markup inside them.
+//     [BlockHeading] marks a single line, [BlockQuote] groups the consecutive marked lines and parses their content as lines again, so the quotes
+//     can be nested and contain the other blocks, and [BlockListItem] marks a single line, nested inside the previous item when indented by
+//     2 more spaces. The line mode is off by default, so the dialects without Blocks are parsed as before.
+//
+//   - A marker takes precedence over the inline Tags starting with the same symbol. The inline Tags can't span the blocks: the Tags still open
+//     at the end of a block are closed with [IssueUnclosedTag]. The adjacent plain lines are parsed together, so the Tags can span them.
+//     The line breaks between the blocks become text nodes.
+//
+//   - Escaping the first symbol of a marker makes the line plain. Such an escape is not redundant, even if the symbol is not special otherwise.
+//
+//   - [Limits.MaxLines] limits the lines checked for the markers, and [Limits.MaxListDepth] limits the nesting of the list items and quotes.
+//
+//     Example: with the Blocks "#" heading and ">" quote, the input "# $$Title\n> a\n> > b" is a heading with an unclosed bold Tag,
+//     followed by a quote containing the text "a" and a nested quote with the text "b".
+//
 // Attributes.
 //
 //   - Each Tag can have valued or flag attributes. You can define a Tag's attribute by creating other special Tags. To do this you need to define a
@@ -156,6 +203,12 @@
 //   - [Limits.MaxParseDepth] limits the number of simultaneously open tag nodes. The root node is not counted.
 //     When the limit is reached, deeper opening tags are omitted and [IssueMaxParseDepthExceeded] is added.
 //
+//   - [Limits.MaxLines] limits the number of lines checked for the [Block] markers. The rest of the input is parsed as plain lines
+//     and [IssueMaxLinesExceeded] is added.
+//
+//   - [Limits.MaxListDepth] limits the nesting of the list items and quotes. Deeper items are moved up and
+//     [IssueMaxListDepthExceeded] is added.
+//
 // Zero resource limits mean unlimited. Unlike the scanning limits, resource
 // limits are not replaced with default values by [NewDictionary].
 //
@@ -176,22 +229,41 @@
 //     Returns [IssueUnprintableChar] if any of the three symbols (trigger, payload start, payload end) is not a printable ASCII character.
 //
 //   - [NewTagSequence]: Returns [IssueInvalidTagSeqLen] if the byte sequence is empty or longer than [MaxTagLen].
-//     Returns [IssueUnprintableChar] if any byte in the sequence is not a printable ASCII character.
+//     Returns [IssueUnprintableChar] if the sequence contains a character which is neither printable ASCII
+//     nor a printable multi-byte UTF-8 rune, or is not valid UTF-8.
 //
 //   - Tag name validation: Returns [IssueInvalidTagNameLen] if the tag name is empty or longer than [MaxTagNameLen] UTF-8 characters.
 //
 //   - Tag consistency validation: Returns [IssueInvalidRule] if the rule value exceeds [MaxRule], or if the rule is incompatible with
 //     the tag's greed level (e.g., [RuleInfraWord] requires [NonGreedy], [RuleTagVsContent] requires greedy tag).
 //     Returns [IssueInvalidGreedLevel] if the greed level exceeds [MaxGreedLevel].
-//     Returns [IssueRuleInapplicable] if a rule other than [RuleNA] is applied to a non-single-char or non-universal tag.
+//     Returns [IssueRuleInapplicable] if a rule other than [RuleNA] is applied to a non-single-char or non-universal tag,
+//     or if [RuleTagVsContent] is applied to a multi-byte rune.
 //
 //   - Tag registration ([Dictionary.AddTag], [Dictionary.AddUniversalTag]): Returns [IssueDuplicateTagID] if the tag ID is already registered.
 //
+//   - [Dictionary.AddBlock]: Returns [IssueInvalidBlockKind] if the kind is unknown, [IssueTooManyBlocks] if [MaxBlocks] Blocks are
+//     already registered, and [IssueDuplicateTagID] if the marker is already registered or starts with the escape symbol.
+//     The marker is validated like a Tag sequence and can't contain spaces.
+//
+//   - Declarative configuration ([NewDictionaryFromSpec], [LoadDictionary]): Returns [ConfigErrors] with every problem found
+//     in the [Spec], each carrying the path of the erroneous value, e.g. "tags[1].seq". Returns [IssueInvalidSpec] if the data
+//     is malformed or a value can't be represented, like a multi-character symbol.
+//
+// # Declarative configuration.
+//
+// Instead of calling the setup methods one by one, a Dictionary can be described by a [Spec] and loaded from JSON or YAML
+// with [LoadDictionary]. [Dictionary.Spec] exports an existing Dictionary back, so the same definition can be shared
+// between the server and the frontend.
+//
 // # Warnings.
 //
 // A [Warning] is added during tokenization or parsing when the input contains problematic but recoverable patterns. Warnings do not stop
 // processing; instead, the tokenizer and parser attempt to make sense of the input. Each Warning contains an [Issue] and a position in the input.
 //
+// Positions are byte offsets. A [PositionResolver] maps them to lines, columns and UTF-16 offsets for the editors,
+// see [SerializableWarning.Locate].
+//
 //   - [IssueUnexpectedEOL] Added when a special symbol is found at the very end of the input where more content is expected.
 //     This includes: escape symbol at EOL, opening tag at EOL, attribute trigger at EOL, and attribute payload start at EOL.
 //
@@ -239,6 +311,10 @@
 //   - [IssueMaxAttributesExceeded] Added when [Limits.MaxAttributes] is reached and further attributes are omitted.
 //
 //   - [IssueMaxParseDepthExceeded] Added when [Limits.MaxParseDepth] is reached and deeper opening tags are omitted.
+//
+//   - [IssueMaxLinesExceeded] Added when the input has more lines than [Limits.MaxLines] in the line mode.
+//
+//   - [IssueMaxListDepthExceeded] Added once when the list items or quotes are nested deeper than [Limits.MaxListDepth].
 package scum
 
 // Dictionary manages registration of Tags and their corresponding Actions.
@@ -253,6 +329,11 @@ type Dictionary struct {
 	// tags maps particular Tag's ID to its Tag's info.
 	tags [256]Tag
 
+	// utf8Actions keeps the Actions of the Tags starting with a multi-byte rune, see [TagID].
+	// Their IDs are UTF-8 continuation bytes, which are found inside the runes in the input, so these Actions are
+	// not kept in actions, but dispatched by [ActUTF8Tag] from the leading byte of the rune.
+	utf8Actions [64]Action
+
 	// attrTrigger is a special symbol, which starts Attribute Action.
 	attrTrigger byte
 
@@ -264,6 +345,15 @@ type Dictionary struct {
 
 	// escapeTrigger is a symbol which makes the tokenizer treat the next symbol after it as non-special.
 	escapeTrigger byte
+
+	// blocks keeps the registered line-start [Block]s. The ID of a Block is its index + 1.
+	blocks [MaxBlocks]Block
+
+	// numBlocks is the number of the registered Blocks. The line mode is on if it is not 0.
+	numBlocks int
+
+	// blockLeads marks the first bytes of the Block markers.
+	blockLeads [256]bool
 }
 
 // Tag returns a registered Tag by ID.
@@ -272,14 +362,16 @@ func (d *Dictionary) Tag(id byte) (Tag, bool) {
 	return t, t.Seq.Len != 0
 }
 
-// Action returns a registered [Action] by Tag ID.
+// Action returns the [Action] triggered by the byte: the Action of the ASCII Tag or special symbol
+// with this ID, or [ActUTF8Tag] for the leading byte of a multi-byte rune starting a Tag.
 func (d *Dictionary) Action(id byte) (Action, bool) {
 	a := d.actions[id]
 	return a, a != nil
 }
 
 // IsSpecial returns true if the provided char is registered inside the [Dictionary]
-// as either a [Tag], an attribute signature part, or an escape symbol.
+// as either a [Tag], an attribute signature part, or an escape symbol. It is also true
+// for the leading byte of a multi-byte rune starting a Tag.
 func (d *Dictionary) IsSpecial(char byte) bool {
 	if char == 0 {
 		return false
@@ -0,0 +1,46 @@
+package scum
+
+// flattenTag demotes the tag token to a [NodeText] node of its raw bytes.
+//
+// The text statistics are corrected for the [AST]: the raw bytes of the token become text,
+// minus the payload of a greedy token which the tokenizer has already counted as a text token.
+func flattenTag(state *parserState, tok Token, warns *Warnings) {
+	payload := tok.Payload.End - tok.Payload.Start
+	state.ast.TextByteLen += tok.Width - payload
+	if payload == 0 {
+		state.textNodes++
+	}
+
+	// processText uses Payload as the text node span. For a demoted tag, the
+	// text is the raw tag bytes, not the tag payload.
+	tok.Type = TokenText
+	tok.Payload = NewSpan(tok.Pos, tok.Width)
+	processText(state, tok, warns)
+}
+
+// rejectByContentModel checks the tag token against the [ContentModel]s of the open Tags and the Tag itself.
+// If the Tag is not allowed, it adds the [IssueTagNotAllowed] warning, flattens the token and returns true.
+//
+// The next closing tag of a flattened non-universal, non-greedy opening Tag is flattened as well, as long as
+// the Tag containing the opener is open. The occurrences of a universal Tag are checked on their own.
+func rejectByContentModel(state *parserState, d *Dictionary, warns *Warnings, tok Token) bool {
+	container := state.containerOf(d, tok.Trigger)
+	if container == 0 {
+		return false
+	}
+
+	warns.Add(Warning{
+		Issue:          IssueTagNotAllowed,
+		Pos:            tok.Pos,
+		TagID:          tok.Trigger,
+		ContainerTagID: container,
+	})
+
+	tag := &d.tags[tok.Trigger]
+	if tag.Greed == NonGreedy && tag.IsOpening() {
+		state.pushFlattened(tag.CloseID)
+	}
+
+	flattenTag(state, tok, warns)
+	return true
+}
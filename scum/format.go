@@ -0,0 +1,417 @@
+package scum
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+// Format re-emits the [AST] as the canonical source in the markup of the [Dictionary] it was parsed with.
+//
+// Parsing the result builds the same tree as the AST, while the equivalent ways to write it collapse to one:
+//   - tags closed implicitly by the parser get their closing tags,
+//   - duplicate nested tags and other sequences omitted by the parser are dropped,
+//   - redundant escapes are dropped, and special symbols in text are escaped only where they would be parsed as markup,
+//   - attributes are moved after the closing tag of the node they belong to.
+//
+// Greedy tags and attributes are emitted verbatim. In the rare cases the tree can't be written
+// canonically, like an empty intra-word tag right after a letter, or text which would be parsed as markup
+// while the Dictionary has no escape symbol, the input is returned as is.
+//
+// In the line mode the blocks are written with their markers followed by a space, the nested list items
+// are indented by 2 spaces per level, and the markers at the starts of the plain lines are escaped.
+func Format(ast AST, d *Dictionary) string {
+	if len(ast.Nodes) == 0 {
+		return ""
+	}
+
+	if d.HasBlocks() {
+		if out, ok := formatLines(&ast, d); ok {
+			return out
+		}
+		return ast.Input
+	}
+
+	f := formatter{ast: &ast, d: d}
+	f.emitNode(0)
+
+	out, ok := f.resolve()
+	if !ok {
+		return ast.Input
+	}
+
+	return out
+}
+
+// formatSegKind is the kind of a piece of the formatted source.
+type formatSegKind uint8
+
+const (
+	// segText is plain text without the symbols starting Actions.
+	segText formatSegKind = iota
+
+	// segSymbol is a symbol starting an Action found in text. It is escaped if it would be parsed as markup.
+	segSymbol
+
+	// segToken is a tag or an attribute, which must be tokenized as a single [Token].
+	segToken
+)
+
+// formatSeg is a piece of the formatted source.
+type formatSeg struct {
+	kind formatSegKind
+
+	// text is the source of segText and segToken.
+	text string
+
+	// sym is the symbol of segSymbol, a single byte or the multi-byte rune of a Tag.
+	sym string
+
+	// escaped is true if the segSymbol or the first rune of segText are escaped.
+	escaped bool
+
+	// escapedLast is true if the last rune of segText is escaped.
+	escapedLast bool
+}
+
+// formatter collects the source pieces of the AST and escapes the text until
+// the tokenizer sees the same Tokens as were emitted.
+type formatter struct {
+	ast  *AST
+	d    *Dictionary
+	segs []formatSeg
+
+	// starts are the offsets of the rendered segs in the output
+	starts []int
+}
+
+// emitNode emits the node with its subtree.
+func (f *formatter) emitNode(idx int) {
+	n := &f.ast.Nodes[idx]
+
+	switch n.Type {
+	case NodeRoot:
+		f.emitAttributes(n.Attributes)
+		f.emitChildren(n)
+
+	case NodeText:
+		f.emitText(f.ast.Input[n.Span.Start:n.Span.End])
+		f.emitAttributes(n.Attributes)
+
+	case NodeTag:
+		tag := &f.d.tags[n.TagID]
+		if tag.Greed > NonGreedy {
+			f.emitGreedyTag(n, tag)
+			return
+		}
+
+		f.emitToken(string(tag.Seq.Bytes[:tag.Seq.Len]))
+		f.emitChildren(n)
+		closeTag := &f.d.tags[tag.CloseID]
+		f.emitToken(string(closeTag.Seq.Bytes[:closeTag.Seq.Len]))
+		f.emitAttributes(n.Attributes)
+	}
+}
+
+func (f *formatter) emitChildren(n *Node) {
+	for c := n.FirstChild; c != -1; c = f.ast.Nodes[c].NextSibling {
+		f.emitNode(c)
+	}
+}
+
+// emitGreedyTag emits the greedy tag verbatim, adding the closing tag if the tag was unclosed.
+// The attributes following a greedy tag belong to its payload node.
+func (f *formatter) emitGreedyTag(n *Node, tag *Tag) {
+	raw := f.ast.Input[n.Span.Start:n.Span.End]
+
+	if n.FirstChild == -1 {
+		f.emitToken(raw)
+		f.emitAttributes(n.Attributes)
+		return
+	}
+
+	payload := &f.ast.Nodes[n.FirstChild]
+
+	// nothing after the payload means the tag consumed the rest of the input
+	if payload.Span.End == n.Span.End {
+		if tag.Rule == RuleTagVsContent {
+			for range payload.Span.Start - n.Span.Start {
+				raw += string(tag.ID())
+			}
+		} else if closeTag, ok := f.d.Tag(tag.CloseID); ok {
+			raw += string(closeTag.Seq.Bytes[:closeTag.Seq.Len])
+		}
+	}
+
+	f.emitToken(raw)
+	f.emitAttributes(n.Attributes)
+	f.emitAttributes(payload.Attributes)
+}
+
+func (f *formatter) emitAttributes(r Range) {
+	for _, a := range f.ast.Attributes[r.Start : r.Start+r.Len] {
+		src := make([]byte, 0, a.Name.End-a.Name.Start+a.Payload.End-a.Payload.Start+3)
+		src = append(src, f.d.attrTrigger)
+		if !a.IsFlag {
+			src = append(src, f.ast.Input[a.Name.Start:a.Name.End]...)
+		}
+		src = append(src, f.d.attrPayloadStart)
+		src = append(src, f.ast.Input[a.Payload.Start:a.Payload.End]...)
+		src = append(src, f.d.attrPayloadEnd)
+
+		f.emitToken(string(src))
+	}
+}
+
+func (f *formatter) emitToken(src string) {
+	f.segs = append(f.segs, formatSeg{kind: segToken, text: src})
+}
+
+// emitText emits the unescaped raw text, splitting it into plain text and symbols.
+func (f *formatter) emitText(raw string) {
+	text := Unescape(raw, f.d.escapeTrigger)
+
+	plainStart := 0
+	for i := 0; i < len(text); i++ {
+		w := f.d.specialWidth(text[i:])
+		if w == 0 {
+			continue
+		}
+
+		f.emitPlain(text[plainStart:i])
+		// the escape symbol is always escaped, otherwise it would escape the next symbol
+		f.segs = append(f.segs, formatSeg{kind: segSymbol, sym: text[i : i+w], escaped: text[i] == f.d.escapeTrigger})
+		i += w - 1
+		plainStart = i + 1
+	}
+
+	f.emitPlain(text[plainStart:])
+}
+
+func (f *formatter) emitPlain(s string) {
+	if s == "" {
+		return
+	}
+
+	// adjacent text nodes are merged
+	if last := len(f.segs) - 1; last >= 0 && f.segs[last].kind == segText {
+		f.segs[last].text += s
+		return
+	}
+
+	f.segs = append(f.segs, formatSeg{kind: segText, text: s})
+}
+
+// resolve renders the segs, escaping the text until it is tokenized as intended.
+// It returns false if escaping can't fix the output. The escapes are only ever added, so the loop ends.
+func (f *formatter) resolve() (string, bool) {
+	// the warnings are already reported for the parsed input
+	warns, _ := NewWarnings(WarnOverflowNoRec, 0)
+
+	for {
+		out := f.render()
+		tokens := Tokenize(f.d, out, &warns)
+
+		fixed, ok := f.fix(tokens.Tokens)
+		if ok {
+			return out, true
+		}
+
+		if !fixed {
+			return "", false
+		}
+	}
+}
+
+func (f *formatter) render() string {
+	size := 0
+	for _, s := range f.segs {
+		size += len(s.text) + 2
+	}
+
+	out := make([]byte, 0, size)
+	f.starts = f.starts[:0]
+	esc := f.d.escapeTrigger
+
+	for _, s := range f.segs {
+		f.starts = append(f.starts, len(out))
+
+		switch s.kind {
+		case segText:
+			text := s.text
+			if s.escaped {
+				out = append(out, esc)
+			}
+			if s.escapedLast {
+				_, w := utf8.DecodeLastRuneInString(text)
+				out = append(out, text[:len(text)-w]...)
+				out = append(out, esc)
+				text = text[len(text)-w:]
+			}
+			out = append(out, text...)
+
+		case segSymbol:
+			if s.escaped {
+				out = append(out, esc)
+			}
+			out = append(out, s.sym...)
+
+		case segToken:
+			out = append(out, s.text...)
+		}
+	}
+
+	return string(out)
+}
+
+// fix compares the tokens of the rendered output with the emitted ones and escapes the text causing
+// the difference. It returns ok if there is no difference, and fixed if anything was escaped.
+//
+// The symbols parsed as markup are escaped first. Only if there are none, the tags and attributes
+// which failed to be parsed get their neighboring text escaped, since the failure can be caused by the
+// symbols before them.
+func (f *formatter) fix(tokens []Token) (fixed, ok bool) {
+	unexpected := false
+	var missing []int
+
+	seg := 0
+	for _, t := range tokens {
+		if t.Type == TokenText {
+			continue
+		}
+
+		for ; seg < len(f.segs) && (f.segs[seg].kind != segToken || f.starts[seg] < t.Pos); seg++ {
+			if f.segs[seg].kind == segToken {
+				missing = append(missing, seg)
+			}
+		}
+
+		if seg < len(f.segs) && f.starts[seg] == t.Pos {
+			if len(f.segs[seg].text) != t.Width {
+				missing = append(missing, seg)
+			}
+			seg++
+			continue
+		}
+
+		unexpected = true
+		if f.escapeSymbolAt(t.Pos) {
+			fixed = true
+		}
+	}
+
+	for ; seg < len(f.segs); seg++ {
+		if f.segs[seg].kind == segToken {
+			missing = append(missing, seg)
+		}
+	}
+
+	if !unexpected && len(missing) == 0 {
+		return false, true
+	}
+
+	if fixed {
+		return true, false
+	}
+
+	for _, seg := range missing {
+		if f.escapeNeighbor(seg+1, true) || f.escapeNeighbor(seg-1, false) {
+			fixed = true
+		}
+	}
+
+	return fixed, false
+}
+
+// escapeSymbolAt escapes the text symbol rendered at the offset.
+func (f *formatter) escapeSymbolAt(offset int) bool {
+	if f.d.escapeTrigger == 0 {
+		return false
+	}
+
+	i := sort.SearchInts(f.starts, offset+1) - 1
+	if i < 0 || f.segs[i].kind != segSymbol || f.segs[i].escaped {
+		return false
+	}
+
+	f.segs[i].escaped = true
+	return true
+}
+
+// escapeNeighbor escapes the text adjacent to a tag or attribute: the first rune of the seg
+// following it if first is true, otherwise the last rune of the seg preceding it.
+func (f *formatter) escapeNeighbor(i int, first bool) bool {
+	if f.d.escapeTrigger == 0 || i < 0 || i >= len(f.segs) {
+		return false
+	}
+
+	s := &f.segs[i]
+	switch s.kind {
+	case segSymbol:
+		if s.escaped {
+			return false
+		}
+		s.escaped = true
+		return true
+
+	case segText:
+		_, w := utf8.DecodeLastRuneInString(s.text)
+		if first || w == len(s.text) {
+			if s.escaped {
+				return false
+			}
+			s.escaped = true
+			return true
+		}
+
+		if s.escapedLast {
+			return false
+		}
+		s.escapedLast = true
+		return true
+	}
+
+	return false
+}
+
+// Unescape returns the text with the escape symbols removed, the way the tokenizer reads them:
+// each escape symbol makes the next UTF-8 code point plain text. The escape symbol at the end stays.
+// It lets the users of the [AST] show the text the way it reads, since the text nodes keep their escapes.
+func Unescape(raw string, esc byte) string {
+	if esc == 0 {
+		return raw
+	}
+
+	first := -1
+	for i := 0; i < len(raw)-1; i++ {
+		if raw[i] == esc {
+			first = i
+			break
+		}
+	}
+
+	if first == -1 {
+		return raw
+	}
+
+	out := make([]byte, 0, len(raw))
+	out = append(out, raw[:first]...)
+
+	for i := first; i < len(raw); {
+		b := raw[i]
+		if b != esc || i+1 == len(raw) {
+			out = append(out, b)
+			i++
+			continue
+		}
+
+		width := 1
+		if raw[i+1] >= utf8.RuneSelf {
+			_, width = utf8.DecodeRuneInString(raw[i+1:])
+		}
+
+		out = append(out, raw[i+1:i+1+width]...)
+		i += 1 + width
+	}
+
+	return string(out)
+}
@@ -0,0 +1,173 @@
+package scum
+
+import "strings"
+
+// lineFormatter formats the [AST] parsed in the line mode: the blocks are written with their markers,
+// and the runs of inline nodes between them are formatted on their own, like they were parsed.
+type lineFormatter struct {
+	ast *AST
+	d   *Dictionary
+}
+
+// formatLines is [Format] in the line mode. It returns false if the tree can't be written canonically.
+func formatLines(ast *AST, d *Dictionary) (string, bool) {
+	f := lineFormatter{ast: ast, d: d}
+	return f.container(0, 0, true)
+}
+
+// container formats the children of the node. level is the number of the list items the node is nested in,
+// and atLineStart reports whether the first child starts a line, so a marker there must be escaped.
+// The attributes of the node are written before its first child.
+func (f *lineFormatter) container(idx, level int, atLineStart bool) (string, bool) {
+	var b strings.Builder
+
+	parent := &f.ast.Nodes[idx]
+	attrs := parent.Attributes
+	if attrs.Len > 0 {
+		atLineStart = false
+	}
+
+	var run []int
+	flush := func() bool {
+		if len(run) == 0 && attrs.Len == 0 {
+			return true
+		}
+
+		s, ok := f.inline(attrs, run, atLineStart)
+		b.WriteString(s)
+		run, attrs = run[:0], Range{}
+		return ok
+	}
+
+	for c := parent.FirstChild; c != -1; c = f.ast.Nodes[c].NextSibling {
+		n := &f.ast.Nodes[c]
+
+		switch {
+		case n.Type == NodeText && n.Span.End-n.Span.Start == 1 && f.ast.Input[n.Span.Start] == '\n':
+			// the line break between the blocks and the plain lines, its attributes start the next line
+			if !flush() {
+				return "", false
+			}
+			b.WriteByte('\n')
+			attrs = n.Attributes
+			atLineStart = attrs.Len == 0
+
+		case n.Type == NodeBlock:
+			// the attributes of the container can't be written before a block
+			if attrs.Len > 0 {
+				return "", false
+			}
+			if !flush() {
+				return "", false
+			}
+
+			s, ok := f.block(c, level)
+			if !ok {
+				return "", false
+			}
+			b.WriteString(s)
+			atLineStart = false
+
+		default:
+			run = append(run, c)
+		}
+	}
+
+	if !flush() {
+		return "", false
+	}
+
+	return b.String(), true
+}
+
+// block formats the block node with its marker.
+func (f *lineFormatter) block(idx, level int) (string, bool) {
+	n := &f.ast.Nodes[idx]
+	block := &f.d.blocks[n.TagID-1]
+	marker := string(block.Marker.Bytes[:block.Marker.Len])
+
+	switch block.Kind {
+	case BlockQuote:
+		content, ok := f.container(idx, 0, true)
+		if !ok {
+			return "", false
+		}
+
+		lines := strings.Split(content, "\n")
+		for i, l := range lines {
+			lines[i] = withMarker(marker, l)
+		}
+		return strings.Join(lines, "\n"), true
+
+	case BlockListItem:
+		content, ok := f.container(idx, level+1, false)
+		if !ok {
+			return "", false
+		}
+		return strings.Repeat("  ", level) + withMarker(marker, content), true
+
+	default:
+		content, ok := f.container(idx, level, false)
+		if !ok {
+			return "", false
+		}
+		return withMarker(marker, content), true
+	}
+}
+
+// inline formats the run of inline nodes, preceded by the attributes, and escapes the markers
+// at the starts of its lines.
+func (f *lineFormatter) inline(attrs Range, run []int, atLineStart bool) (string, bool) {
+	inner := formatter{ast: f.ast, d: f.d}
+	inner.emitAttributes(attrs)
+	for _, c := range run {
+		inner.emitNode(c)
+	}
+
+	out, ok := inner.resolve()
+	if !ok {
+		return "", false
+	}
+
+	var b strings.Builder
+	for i, l := range strings.Split(out, "\n") {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+
+		if pos := f.markerPos(l); pos >= 0 && (i > 0 || atLineStart) {
+			if f.d.escapeTrigger == 0 {
+				return "", false
+			}
+			b.WriteString(l[:pos])
+			b.WriteByte(f.d.escapeTrigger)
+			l = l[pos:]
+		}
+
+		b.WriteString(l)
+	}
+
+	return b.String(), true
+}
+
+// markerPos returns the offset of the [Block] marker starting the line, or -1 if the line is plain.
+func (f *lineFormatter) markerPos(line string) int {
+	indent := len(line) - len(strings.TrimLeft(line, " "))
+
+	id, _ := f.d.matchBlock(line[indent:])
+	if id == 0 || (indent > 0 && f.d.blocks[id-1].Kind != BlockListItem) {
+		return -1
+	}
+
+	return indent
+}
+
+// withMarker returns the marker followed by the content, separated by a space unless the content is empty
+// or starts with a line break.
+func withMarker(marker, content string) string {
+	if content == "" || content[0] == '\n' {
+		return marker + content
+	}
+
+	return marker + " " + content
+}
@@ -0,0 +1,187 @@
+package scum
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// structNode is the structure of a parsed tree, free of the source details: text is unescaped
+// and merged like the tokenizer would read it, tags are compared by name and ID.
+type structNode struct {
+	Name     string
+	ID       byte
+	Text     string
+	Attrs    []structAttr
+	Children []structNode
+}
+
+type structAttr struct {
+	Name    string
+	Payload string
+	IsFlag  bool
+}
+
+func structure(ast AST, d *Dictionary) structNode {
+	return structureOf(&ast, d, 0, false)
+}
+
+func structureOf(ast *AST, d *Dictionary, idx int, inGreedy bool) structNode {
+	n := ast.Nodes[idx]
+
+	var s structNode
+	switch n.Type {
+	case NodeRoot:
+		s.Name = "ROOT"
+	case NodeText:
+		s.Name = "TEXT"
+		s.Text = ast.Input[n.Span.Start:n.Span.End]
+		if !inGreedy {
+			s.Text = Unescape(s.Text, d.escapeTrigger)
+		}
+	case NodeTag:
+		s.Name = d.tags[n.TagID].Name
+		s.ID = n.TagID
+	case NodeBlock:
+		s.Name = d.blocks[n.TagID-1].Name
+		s.ID = n.TagID
+	}
+
+	for _, a := range ast.Attributes[n.Attributes.Start : n.Attributes.Start+n.Attributes.Len] {
+		s.Attrs = append(s.Attrs, structAttr{
+			Name:    ast.Input[a.Name.Start:a.Name.End],
+			Payload: ast.Input[a.Payload.Start:a.Payload.End],
+			IsFlag:  a.IsFlag,
+		})
+	}
+
+	greedy := n.Type == NodeTag && d.tags[n.TagID].Greed > NonGreedy
+	for c := n.FirstChild; c != -1; c = ast.Nodes[c].NextSibling {
+		child := structureOf(ast, d, c, greedy)
+
+		// adjacent text is one text for the tokenizer, unless the attributes of the first one separate them
+		if last := len(s.Children) - 1; !greedy && last >= 0 && child.Name == "TEXT" &&
+			s.Children[last].Name == "TEXT" && len(s.Children[last].Attrs) == 0 {
+			s.Children[last].Text += child.Text
+			s.Children[last].Attrs = child.Attrs
+			continue
+		}
+
+		s.Children = append(s.Children, child)
+	}
+
+	return s
+}
+
+// requireFormatRoundTrip checks that Parse(Format(Parse(x))) is structurally equal to Parse(x)
+// and that the formatted source is a fixed point of Format.
+func requireFormatRoundTrip(t *testing.T, d *Dictionary, input string) string {
+	t.Helper()
+
+	w1 := newWarns(t)
+	ast := Parse(input, d, &w1)
+	formatted := Format(ast, d)
+
+	w2 := newWarns(t)
+	reparsed := Parse(formatted, d, &w2)
+	require.Equal(t, structure(ast, d), structure(reparsed, d), "input: %q\nformatted: %q", input, formatted)
+	require.Equal(t, formatted, Format(reparsed, d), "input: %q", input)
+
+	return formatted
+}
+
+func TestFormat(t *testing.T) {
+	d := testDict(t)
+
+	testCases := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"Empty", "", ""},
+		{"Plain", "hello world", "hello world"},
+		{"Tags", "$$bold *it*$$ _under_", "$$bold *it*$$ _under_"},
+		{"ClosesUnclosed", "$$bold *it", "$$bold *it*$$"},
+		{"DropsDuplicateNested", "*a $$b *c* d$$", "*a $$b c d$$*"},
+		{"DropsRedundantEscape", `\a\b \$`, `ab $`},
+		{"KeepsNeededEscape", `\$$ \* \\`, `\$$ \* \\`},
+		{"IntraWordNeedsNoEscape", "snake_case_name", "snake_case_name"},
+		{"MismatchedClosingIsText", "[a *b]", `[a *b\]*]`},
+		{"MisplacedClosingDropped", "a] b", "a b"},
+		{"Attributes", "[link]!href{https://x.y}!{nofollow}", "[link]!href{https://x.y}!{nofollow}"},
+		{"AttributesAfterClosing", "[!href{x}link", "[link]!href{x}"},
+		{"EscapedAttribute", `a\!b{c}`, `a\!b{c}`},
+		{"GreedyVerbatim", "``a `b` c`` d", "``a `b` c`` d"},
+		{"GreedyNextToTrigger", "`a`\\`", "`a`\\`"},
+		{"IntraWordAfterDroppedTag", "a]_b_", "a_\\b_"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := requireFormatRoundTrip(t, &d, tc.input)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+// formatAlphabet is biased towards the special symbols of testDict.
+var formatAlphabet = []string{
+	"$", "$$", "*", "_", "[", "]", ":[", "`", "``", "\\", "!", "{", "}", "!k{v}", "!{f}",
+	"a", "b", " ", "\n", "é", "😀", "\xff",
+}
+
+func TestFormat_RoundTripRandom(t *testing.T) {
+	d := testDict(t)
+	rng := rand.New(rand.NewPCG(34, 2026))
+
+	for range 5000 {
+		n := rng.IntN(24)
+		input := ""
+		for range n {
+			input += formatAlphabet[rng.IntN(len(formatAlphabet))]
+		}
+
+		requireFormatRoundTrip(t, &d, input)
+	}
+}
+
+func TestFormat_RoundTripWithLimits(t *testing.T) {
+	d, err := NewDictionary(Limits{MaxNodes: 6, MaxParseDepth: 2, MaxAttributes: 1})
+	require.NoError(t, err)
+	require.NoError(t, d.AddUniversalTag("BOLD", []byte("$"), NonGreedy, RuleNA))
+	require.NoError(t, d.AddUniversalTag("ITALIC", []byte("*"), NonGreedy, RuleNA))
+	require.NoError(t, d.AddTag("LINK", []byte("["), NonGreedy, RuleNA, 0, ']'))
+	require.NoError(t, d.AddTag("LINK", []byte("]"), NonGreedy, RuleNA, '[', 0))
+	require.NoError(t, d.SetAttributeSignature('!', '{', '}'))
+	require.NoError(t, d.SetEscapeTrigger('\\'))
+
+	for _, input := range []string{
+		"$a *b [c $d$ e] f* g$ h i j k",
+		"[a]!x{1}!y{2} [b]!z{3}",
+		"$*[deep]*$ $*[deep]*$",
+	} {
+		requireFormatRoundTrip(t, &d, input)
+	}
+}
+
+func FuzzFormat_RoundTrip(f *testing.F) {
+	seeds := []string{
+		"",
+		"$$bold *it*$$ _under_",
+		"*a $$b *c* d$$",
+		`\a\b \$ \\ \`,
+		"[a *b] c]",
+		"[!href{x}link",
+		"``a `b` c`` d `e",
+		"a]_b_ :[img] !{f}",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		d := testDict(t)
+		requireFormatRoundTrip(t, &d, input)
+	})
+}
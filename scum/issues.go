@@ -109,6 +109,31 @@ const (
 	// omits additional nested tag structure.
 	IssueMaxParseDepthExceeded
 
+	// IssueInvalidSpec occurs when a [Spec] cannot be decoded, or has a value which
+	// doesn't map onto the [Dictionary] configuration, e.g. an unknown greed name or a multi-char symbol.
+	IssueInvalidSpec
+
+	// IssueUnknownTagID occurs during configuration when the ID doesn't belong to any registered [Tag].
+	IssueUnknownTagID
+
+	// IssueTagNotAllowed occurs when the Tag violates the [ContentModel] of the open Tag containing it.
+	// The Tag is treated as plain text.
+	IssueTagNotAllowed
+
+	// IssueInvalidBlockKind occurs during configuration when the [BlockKind] is not one of the kinds defined in this package.
+	IssueInvalidBlockKind
+
+	// IssueTooManyBlocks occurs during configuration when more than [MaxBlocks] Blocks are registered.
+	IssueTooManyBlocks
+
+	// IssueMaxLinesExceeded occurs when the input has more lines than [Limits.MaxLines].
+	// The lines after the limit are parsed as plain text, without the Block markers.
+	IssueMaxLinesExceeded
+
+	// IssueMaxListDepthExceeded occurs when the list items or quotes are nested deeper than [Limits.MaxListDepth].
+	// A nested list item is moved up to the maximum depth, while the markers of a list or a quote starting deeper are treated as plain text.
+	IssueMaxListDepthExceeded
+
 	maxIssueCode
 )
 
@@ -12,7 +12,7 @@ type Limits struct {
 	// is treated as plain text and [IssueAttrKeyTooLong] is recorded.
 	//
 	// Measured in bytes, not UTF-8 runes.
-	MaxAttrKeyLen int
+	MaxAttrKeyLen int `json:"max_attr_key_len,omitempty" yaml:"max_attr_key_len,omitempty"`
 
 	// MaxAttrPayloadLen defines the maximum number of bytes scanned for an attribute payload
 	// (from the payload start symbol up to the payload end symbol).
@@ -21,19 +21,19 @@ type Limits struct {
 	// is treated as plain text and [IssueAttrPayloadTooLong] is recorded.
 	//
 	// Measured in bytes, not UTF-8 runes.
-	MaxAttrPayloadLen int
+	MaxAttrPayloadLen int `json:"max_attr_payload_len,omitempty" yaml:"max_attr_payload_len,omitempty"`
 
 	// MaxPayloadLen defines the maximum number of bytes scanned for a Greedy
 	// Tag's body. It also applies to the Tag-Vs-Content based search.
 	//
 	// A value of 0 is replaced with [DefaultMaxPayloadLen] by [NewDictionary].
-	MaxPayloadLen int
+	MaxPayloadLen int `json:"max_payload_len,omitempty" yaml:"max_payload_len,omitempty"`
 
 	// MaxKeyLen defines the maximum number of bytes scanned for opening and
 	// closing sequences used by the Tag-Vs-Content rule.
 	//
 	// A value of 0 is replaced with [DefaultMaxKeyLen] by [NewDictionary].
-	MaxKeyLen int
+	MaxKeyLen int `json:"max_key_len,omitempty" yaml:"max_key_len,omitempty"`
 
 	// MaxNodes defines the maximum number of [Node] entries [ParseInto] may keep
 	// in the returned [AST]. The root node counts toward this limit.
@@ -41,7 +41,7 @@ type Limits struct {
 	// When the limit is reached, further nodes are omitted and
 	// [IssueMaxNodesExceeded] is recorded. A value of 0 means no node count
 	// limit.
-	MaxNodes int
+	MaxNodes int `json:"max_nodes,omitempty" yaml:"max_nodes,omitempty"`
 
 	// MaxAttributes defines the maximum number of [Attribute] entries [ParseInto]
 	// may keep in the returned [AST].
@@ -49,7 +49,7 @@ type Limits struct {
 	// When the limit is reached, further attributes are omitted and
 	// [IssueMaxAttributesExceeded] is recorded. A value of 0 means no attribute
 	// count limit.
-	MaxAttributes int
+	MaxAttributes int `json:"max_attributes,omitempty" yaml:"max_attributes,omitempty"`
 
 	// MaxParseDepth defines the maximum number of simultaneously open tag nodes.
 	// The root node is not counted.
@@ -57,39 +57,55 @@ type Limits struct {
 	// When the limit is reached, further opening tags are omitted and
 	// [IssueMaxParseDepthExceeded] is recorded. A value of 0 means no parse
 	// depth limit.
-	MaxParseDepth int
-}
+	MaxParseDepth int `json:"max_parse_depth,omitempty" yaml:"max_parse_depth,omitempty"`
 
-// Validate checks that all limits are non-negative.
-// It returns [ConfigError] if at least one value is negative.
-func (l Limits) Validate() error {
+	// MaxLines defines the maximum number of lines checked for the [Block] markers in the line mode.
+	//
+	// When the limit is reached, the rest of the input is parsed as plain text and
+	// [IssueMaxLinesExceeded] is recorded. A value of 0 means no line count limit.
+	MaxLines int `json:"max_lines,omitempty" yaml:"max_lines,omitempty"`
 
-	values := [...]int{
-		l.MaxAttrKeyLen,
-		l.MaxAttrPayloadLen,
-		l.MaxPayloadLen,
-		l.MaxKeyLen,
-		l.MaxNodes,
-		l.MaxAttributes,
-		l.MaxParseDepth,
-	}
+	// MaxListDepth defines the maximum nesting depth of the list items and quotes in the line mode.
+	//
+	// When the limit is reached, deeper list items are kept at the maximum depth, deeper quote markers
+	// are treated as plain text, and [IssueMaxListDepthExceeded] is recorded. A value of 0 means no depth limit.
+	MaxListDepth int `json:"max_list_depth,omitempty" yaml:"max_list_depth,omitempty"`
+}
 
-	names := [...]string{
-		"MaxAttrKeyLen",
-		"MaxAttrPayloadLen",
-		"MaxPayloadLen",
-		"MaxKeyLen",
-		"MaxNodes",
-		"MaxAttributes",
-		"MaxParseDepth",
+// limitField is a single limit along with its names in Go and in [Spec].
+type limitField struct {
+	name  string
+	key   string
+	value int
+}
+
+func (l Limits) fields() [9]limitField {
+	return [...]limitField{
+		{"MaxAttrKeyLen", "max_attr_key_len", l.MaxAttrKeyLen},
+		{"MaxAttrPayloadLen", "max_attr_payload_len", l.MaxAttrPayloadLen},
+		{"MaxPayloadLen", "max_payload_len", l.MaxPayloadLen},
+		{"MaxKeyLen", "max_key_len", l.MaxKeyLen},
+		{"MaxNodes", "max_nodes", l.MaxNodes},
+		{"MaxAttributes", "max_attributes", l.MaxAttributes},
+		{"MaxParseDepth", "max_parse_depth", l.MaxParseDepth},
+		{"MaxLines", "max_lines", l.MaxLines},
+		{"MaxListDepth", "max_list_depth", l.MaxListDepth},
 	}
+}
 
-	for i := range values {
-		if values[i] < 0 {
-			err := fmt.Errorf("%s must be >= 0, got %d", names[i], values[i])
-			return NewConfigError(IssueNegativeLimit, err)
+// Validate checks that all limits are non-negative.
+// It returns [ConfigError] if at least one value is negative.
+func (l Limits) Validate() error {
+	for _, f := range l.fields() {
+		if f.value < 0 {
+			return newNegativeLimitError(f)
 		}
 	}
 
 	return nil
 }
+
+func newNegativeLimitError(f limitField) error {
+	err := fmt.Errorf("%s must be >= 0, got %d", f.name, f.value)
+	return NewConfigError(IssueNegativeLimit, err)
+}
@@ -38,7 +38,15 @@ func Parse(input string, d *Dictionary, warns *Warnings) AST {
 //  3. Finalization: any tags still open at end-of-input are force-closed from
 //     innermost to outermost (with [IssueUnclosedTag] warnings), the root
 //     node's span is set, and AST-level statistics are populated.
+//
+// If the Dictionary has [Block]s, the input is first split into the lines, and the
+// phases run for each block and each run of plain lines on its own, see [Dictionary.AddBlock].
 func ParseInto(dst *AST, input string, d *Dictionary, warns *Warnings) {
+	if d.HasBlocks() {
+		parseLinesInto(dst, input, d, warns)
+		return
+	}
+
 	// Phase 1: tokenize
 	out := Tokenize(d, input, warns)
 
@@ -51,19 +59,44 @@ func ParseInto(dst *AST, input string, d *Dictionary, warns *Warnings) {
 
 	// Phase 2: build the tree by dispatching each token
 	for _, t := range out.Tokens {
-		switch t.Type {
-		case TokenText:
-			processText(state, t, warns)
+		processToken(state, d, warns, t)
+	}
+
+	// Phase 3: force-close any remaining open tags (innermost first)
+	closeOpenTags(state, d, warns)
+
+	// Finalize root span and collect statistics
+	state.ast.Nodes[0].Span.End = state.peekCumWidth()
+	state.ast.MaxDepth = state.maxDepth
+	// TokenizerOutput.TextByteLen is a byte count, so AST.TextByteLen remains byte-based.
+	state.ast.TextByteLen += out.TextByteLen
+	state.ast.TotalTextNodes = state.textNodes + out.TextTokens
+	state.ast.TotalTagNodes = state.totalTagNodes
+
+	*dst = state.ast
+
+	state.Reset()
+
+	statePool.Put(state)
+}
+
+// processToken dispatches the token to its handler by the token type.
+func processToken(state *parserState, d *Dictionary, warns *Warnings, t Token) {
+	switch t.Type {
+	case TokenText:
+		processText(state, t, warns)
 
-		case TokenAttributeFlag, TokenAttributeKV:
-			processAttribute(state, t, warns)
+	case TokenAttributeFlag, TokenAttributeKV:
+		processAttribute(state, t, warns)
 
-		case TokenTag:
-			processTag(state, d, warns, t)
-		}
+	case TokenTag:
+		processTag(state, d, warns, t)
 	}
+}
 
-	// Phase 3: force-close any remaining open tags (innermost first)
+// closeOpenTags force-closes the tags still open at the end of the input, innermost first,
+// adding an [IssueUnclosedTag] warning for each of them.
+func closeOpenTags(state *parserState, d *Dictionary, warns *Warnings) {
 	for len(state.breadcrumbs) > 1 {
 		idx := state.popCrumb()
 		childWidth := state.popCumWidth(0) // 0 because there is no closing tag
@@ -80,20 +113,6 @@ func ParseInto(dst *AST, input string, d *Dictionary, warns *Warnings) {
 			CloseTagID: d.tags[openTagID].CloseID,
 		})
 	}
-
-	// Finalize root span and collect statistics
-	state.ast.Nodes[0].Span.End = state.peekCumWidth()
-	state.ast.MaxDepth = state.maxDepth
-	// TokenizerOutput.TextByteLen is a byte count, so AST.TextByteLen remains byte-based.
-	state.ast.TextByteLen += out.TextByteLen
-	state.ast.TotalTextNodes = state.textNodes + out.TextTokens
-	state.ast.TotalTagNodes = state.totalTagNodes
-
-	*dst = state.ast
-
-	state.Reset()
-
-	statePool.Put(state)
 }
 
 func resetAST(dst *AST, input string, out TokenizerOutput, limits Limits) {
@@ -0,0 +1,322 @@
+package scum
+
+import "strings"
+
+// lineSeg is a line of the input in the line mode: the content of the line after the markers of the
+// enclosing quotes, up to the line break, which is not included.
+type lineSeg struct {
+	start, end int
+
+	// plain is true for the rest of the input after [Limits.MaxLines], which is not checked for the markers.
+	plain bool
+}
+
+// lineClass is the [Block] a line starts with. kind is 0 for a plain line.
+type lineClass struct {
+	kind BlockKind
+	id   byte
+
+	// indent is the number of spaces before the marker.
+	indent int
+
+	// markerStart and contentStart are the offsets of the marker and of the content after it.
+	markerStart  int
+	contentStart int
+}
+
+// openItem is a list item which can still get the nested items.
+type openItem struct {
+	idx   int
+	level int
+}
+
+// lineParser builds the [AST] in the line mode: it groups the lines into the block nodes, and parses
+// the inline markup of each block, or of each run of plain lines, on its own with the [parserState].
+// The inline Tags are closed at the end of the block they were opened in.
+type lineParser struct {
+	state *parserState
+	d     *Dictionary
+	warns *Warnings
+	input string
+
+	// out is reused for the tokens of each inline run.
+	out TokenizerOutput
+
+	// items is reused for the open items of each list.
+	items []openItem
+
+	textByteLen int
+	textTokens  int
+	maxDepth    int
+
+	warnedMaxListDepth bool
+}
+
+// parseLinesInto builds the AST of the input in dst in the line mode, see [ParseInto].
+func parseLinesInto(dst *AST, input string, d *Dictionary, warns *Warnings) {
+	resetAST(dst, input, TokenizerOutput{}, d.Limits)
+
+	state := statePool.Get().(*parserState)
+	state.ast = *dst
+	state.limits = d.Limits
+
+	p := lineParser{
+		state:    state,
+		d:        d,
+		warns:    warns,
+		input:    input,
+		maxDepth: 1,
+	}
+
+	p.parseContainer(0, p.splitLines(), 0)
+
+	state.ast.Nodes[0].Span.End = len(input)
+	state.ast.MaxDepth = p.maxDepth
+	state.ast.TextByteLen += p.textByteLen
+	state.ast.TotalTextNodes = state.textNodes + p.textTokens
+	state.ast.TotalTagNodes = state.totalTagNodes
+
+	*dst = state.ast
+
+	state.Reset()
+
+	statePool.Put(state)
+}
+
+// splitLines splits the input into the lines, up to [Limits.MaxLines]. The rest of the input becomes a single plain line.
+func (p *lineParser) splitLines() []lineSeg {
+	lines := make([]lineSeg, 0, strings.Count(p.input, "\n")+1)
+
+	start := 0
+	for {
+		if limit := p.d.Limits.MaxLines; limit > 0 && len(lines) == limit {
+			p.warns.Add(Warning{
+				Issue: IssueMaxLinesExceeded,
+				Pos:   start,
+			})
+
+			return append(lines, lineSeg{start: start, end: len(p.input), plain: true})
+		}
+
+		i := strings.IndexByte(p.input[start:], '\n')
+		if i < 0 {
+			return append(lines, lineSeg{start: start, end: len(p.input)})
+		}
+
+		lines = append(lines, lineSeg{start: start, end: start + i})
+		start += i + 1
+	}
+}
+
+// classify returns the Block the line starts with at the depth. Only the list items can be indented.
+func (p *lineParser) classify(l lineSeg, depth int) lineClass {
+	if l.plain {
+		return lineClass{}
+	}
+
+	s := p.input[l.start:l.end]
+
+	indent := 0
+	for indent < len(s) && s[indent] == ' ' {
+		indent++
+	}
+
+	id, width := p.d.matchBlock(s[indent:])
+	if id == 0 {
+		return lineClass{}
+	}
+
+	kind := p.d.blocks[id-1].Kind
+	if indent > 0 && kind != BlockListItem {
+		return lineClass{}
+	}
+
+	if kind != BlockHeading {
+		if limit := p.d.Limits.MaxListDepth; limit > 0 && depth >= limit {
+			p.warnMaxListDepth(l.start + indent)
+			return lineClass{}
+		}
+	}
+
+	return lineClass{
+		kind:         kind,
+		id:           id,
+		indent:       indent,
+		markerStart:  l.start + indent,
+		contentStart: l.start + indent + width,
+	}
+}
+
+// parseContainer builds the blocks of the lines as the children of the parent node, which has depth
+// quotes and list items above it. The line breaks between the blocks and the plain lines become text nodes.
+func (p *lineParser) parseContainer(parent int, lines []lineSeg, depth int) {
+	for i := 0; i < len(lines); {
+		if i > 0 {
+			p.appendBreak(parent, lines[i-1].end)
+		}
+
+		l := lines[i]
+		c := p.classify(l, depth)
+
+		switch c.kind {
+		case BlockHeading:
+			if idx, ok := p.appendBlock(parent, c.id, c.markerStart, l.end, depth); ok {
+				p.parseInline(idx, c.contentStart, l.end, depth+1)
+			}
+			i++
+
+		case BlockQuote:
+			// the consecutive lines of the same quote, without its marker
+			sub := []lineSeg{{start: c.contentStart, end: l.end}}
+			j := i + 1
+			for ; j < len(lines); j++ {
+				next := p.classify(lines[j], depth)
+				if next.kind != BlockQuote || next.id != c.id {
+					break
+				}
+				sub = append(sub, lineSeg{start: next.contentStart, end: lines[j].end})
+			}
+
+			if idx, ok := p.appendBlock(parent, c.id, c.markerStart, lines[j-1].end, depth); ok {
+				p.parseContainer(idx, sub, depth+1)
+			}
+			i = j
+
+		case BlockListItem:
+			j := i + 1
+			for j < len(lines) && p.classify(lines[j], depth).kind == BlockListItem {
+				j++
+			}
+
+			p.parseList(parent, lines[i:j], depth)
+			i = j
+
+		default:
+			// the adjacent plain lines are parsed together, so the inline Tags can span them
+			j := i + 1
+			for j < len(lines) && lines[j].start == lines[j-1].end+1 && p.classify(lines[j], depth).kind == 0 {
+				j++
+			}
+
+			p.parseInline(parent, l.start, lines[j-1].end, depth)
+			i = j
+		}
+	}
+}
+
+// parseList builds the list items of the lines. An item indented by 2 more spaces than the previous one is nested inside it.
+func (p *lineParser) parseList(parent int, lines []lineSeg, depth int) {
+	items := p.items[:0]
+	prevLevel := -1
+
+	for k, l := range lines {
+		c := p.classify(l, depth)
+
+		level := min(c.indent/2, prevLevel+1)
+		if limit := p.d.Limits.MaxListDepth; limit > 0 && depth+level >= limit {
+			level = limit - depth - 1
+			p.warnMaxListDepth(c.markerStart)
+		}
+
+		for len(items) > level {
+			items = items[:len(items)-1]
+		}
+
+		itemParent := parent
+		if len(items) > 0 {
+			itemParent = items[len(items)-1].idx
+		}
+
+		if k > 0 {
+			p.appendBreak(itemParent, lines[k-1].end)
+		}
+
+		prevLevel = level
+
+		idx, ok := p.appendBlock(itemParent, c.id, c.markerStart, l.end, depth+level)
+		if !ok {
+			continue
+		}
+
+		p.parseInline(idx, c.contentStart, l.end, depth+level+1)
+
+		// the open items end with their last nested item
+		for _, it := range items {
+			p.state.ast.Nodes[it.idx].Span.End = l.end
+		}
+
+		items = append(items, openItem{idx: idx, level: level})
+	}
+
+	p.items = items
+}
+
+// appendBlock appends the block node at the depth, and makes it the node the attributes at the start of its content are attached to.
+func (p *lineParser) appendBlock(parent int, id byte, start, end, depth int) (int, bool) {
+	node := NewNode()
+	node.Type = NodeBlock
+	node.TagID = id
+	node.Span = Span{start, end}
+
+	idx, ok := appendStateNode(p.state, parent, node, start, p.warns)
+	if ok {
+		p.state.lastNodeIdx = idx
+		p.maxDepth = max(p.maxDepth, depth+1)
+	}
+
+	return idx, ok
+}
+
+// appendBreak appends the line break at the offset as a text node. The attributes at the start of the next line are attached to it.
+func (p *lineParser) appendBreak(parent, pos int) {
+	p.textByteLen++
+	p.textTokens++
+
+	node := NewNode()
+	node.Type = NodeText
+	node.Span = NewSpan(pos, 1)
+
+	if idx, ok := appendStateNode(p.state, parent, node, pos, p.warns); ok {
+		p.state.lastNodeIdx = idx
+	}
+}
+
+// parseInline tokenizes and parses the inline markup of input[start:end] as the children of the parent node at the depth.
+// The Tags still open at the end are closed.
+func (p *lineParser) parseInline(parent, start, end, depth int) {
+	if start >= end {
+		return
+	}
+
+	s := p.state
+	s.breadcrumbs = append(s.breadcrumbs[:0], parent)
+	s.cumWidth = append(s.cumWidth[:0], 0)
+	clear(s.skip[:])
+	s.flattened = s.flattened[:0]
+	s.maxDepth = 0
+
+	p.out = TokenizerOutput{Tokens: p.out.Tokens[:0]}
+	tokenizeFrom(&p.out, p.d, p.input[:end], start, p.warns)
+
+	for _, t := range p.out.Tokens {
+		processToken(s, p.d, p.warns, t)
+	}
+
+	closeOpenTags(s, p.d, p.warns)
+
+	p.textByteLen += p.out.TextByteLen
+	p.textTokens += p.out.TextTokens
+	p.maxDepth = max(p.maxDepth, depth+s.maxDepth)
+}
+
+func (p *lineParser) warnMaxListDepth(pos int) {
+	if p.warnedMaxListDepth {
+		return
+	}
+	p.warnedMaxListDepth = true
+
+	p.warns.Add(Warning{
+		Issue: IssueMaxListDepthExceeded,
+		Pos:   pos,
+	})
+}
@@ -44,6 +44,14 @@ type parserState struct {
 	// orphaned closing token is consumed without error.
 	skip [256]int
 
+	// flattened records the close tag IDs of the opening tags flattened to
+	// text because a [ContentModel] didn't allow them. The next matching
+	// closing token is flattened as well, while the tag containing the
+	// flattened opener is open. Each entry keeps the depth it was added at,
+	// and is dropped when the tag at that depth closes, so there are no
+	// entries when the stack is empty.
+	flattened []flattenMark
+
 	// openedTags tracks which tag IDs are currently open anywhere in the
 	// ancestor chain. Indexed by tag byte value. Used to detect duplicate
 	// nesting: if openedTags[id] is true, a second opening of the same tag
@@ -67,8 +75,8 @@ type parserState struct {
 	// Duplicate nested tags and greedy tags are excluded from this count.
 	totalTagNodes int
 
-	// textNodes counts closing tags that were demoted to text nodes due to
-	// an open/close mismatch. These are added to the tokenizer's TextTokens
+	// textNodes counts tags that were demoted to text nodes due to
+	// an open/close mismatch or a [ContentModel]. These are added to the tokenizer's TextTokens
 	// count to produce [AST.TotalTextNodes].
 	textNodes int
 
@@ -111,6 +119,12 @@ func (s *parserState) popStack() byte {
 	lastItemIdx := len(s.stack) - 1
 	lastItem := s.stack[lastItemIdx]
 	s.stack = s.stack[:lastItemIdx]
+
+	// the closed tag takes the flattened openers inside it along
+	for len(s.flattened) > 0 && s.flattened[len(s.flattened)-1].depth > lastItemIdx {
+		s.flattened = s.flattened[:len(s.flattened)-1]
+	}
+
 	return lastItem
 }
 
@@ -144,6 +158,30 @@ func (s *parserState) popCumWidth(w int) int {
 	return delta
 }
 
+// flattenMark is the close tag ID of a flattened opening tag and the depth of the stack it was flattened at.
+type flattenMark struct {
+	depth   int
+	closeID byte
+}
+
+// pushFlattened records the close tag ID of the flattened opening tag at the current depth.
+func (s *parserState) pushFlattened(closeID byte) {
+	s.flattened = append(s.flattened, flattenMark{depth: len(s.stack), closeID: closeID})
+}
+
+// popFlattened removes the latest record of the flattened opening tag closed by the tag with the id,
+// and reports whether there was one.
+func (s *parserState) popFlattened(id byte) bool {
+	for i := len(s.flattened) - 1; i >= 0; i-- {
+		if s.flattened[i].closeID == id {
+			s.flattened = append(s.flattened[:i], s.flattened[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
 // peekCumWidth returns the cumulative byte width at the current depth without
 // modifying the stack. Used to finalize the root node's Span.End.
 func (s *parserState) peekCumWidth() int {
@@ -159,7 +197,8 @@ func (s *parserState) Reset() {
 	s.cumWidth = s.cumWidth[:0]
 	s.cumWidth = append(s.cumWidth, 0) // assigning the initial root cumulative width
 	clear(s.skip[:])                   // removing any skipped tags
-	clear(s.openedTags[:])             // removing any opened tags
+	s.flattened = s.flattened[:0]
+	clear(s.openedTags[:]) // removing any opened tags
 	s.stack = s.stack[:0]
 	s.maxDepth = 1
 	s.lastNodeIdx = 0
@@ -0,0 +1,115 @@
+package scum
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+// Position is the location of a byte offset in the input, in the units the editors use.
+type Position struct {
+	// Offset is the byte offset in the input.
+	Offset int `json:"offset"`
+
+	// Line is the 1-based line number. Lines are separated by '\n'.
+	Line int `json:"line"`
+
+	// Column is the 1-based column in the line, counted in runes.
+	Column int `json:"column"`
+
+	// Rune is the offset in runes from the start of the input.
+	Rune int `json:"rune"`
+
+	// UTF16 is the offset in UTF-16 code units from the start of the input,
+	// which is the string index in JavaScript.
+	UTF16 int `json:"utf16"`
+}
+
+// lineStart is the position of the first byte of a line.
+type lineStart struct {
+	offset int
+	rune   int
+	utf16  int
+}
+
+// PositionResolver maps byte offsets in the input to [Position]s.
+//
+// The input is scanned once on creation, so resolving an offset only walks
+// the line containing it. Invalid UTF-8 bytes count as one rune and one UTF-16 code unit each,
+// like the replacement character they are decoded to.
+type PositionResolver struct {
+	input string
+	lines []lineStart
+}
+
+// NewPositionResolver creates a [PositionResolver] for the input.
+func NewPositionResolver(input string) *PositionResolver {
+	r := &PositionResolver{
+		input: input,
+		lines: []lineStart{{}},
+	}
+
+	var runes, units int
+	for i := 0; i < len(input); {
+		b := input[i]
+		width := 1
+		if b >= utf8.RuneSelf {
+			var c rune
+			c, width = utf8.DecodeRuneInString(input[i:])
+			units += utf16Len(c)
+		} else {
+			units++
+		}
+
+		runes++
+		i += width
+
+		if b == '\n' {
+			r.lines = append(r.lines, lineStart{offset: i, rune: runes, utf16: units})
+		}
+	}
+
+	return r
+}
+
+// Resolve returns the [Position] of the byte offset, clamped to the input bounds.
+// An offset in the middle of a multi-byte character resolves to the start of the next one.
+func (r *PositionResolver) Resolve(offset int) Position {
+	offset = max(0, min(offset, len(r.input)))
+
+	// the last line starting at or before the offset
+	idx := sort.Search(len(r.lines), func(i int) bool {
+		return r.lines[i].offset > offset
+	}) - 1
+
+	line := r.lines[idx]
+	pos := Position{
+		Offset: offset,
+		Line:   idx + 1,
+		Column: 1,
+		Rune:   line.rune,
+		UTF16:  line.utf16,
+	}
+
+	for i := line.offset; i < offset; {
+		c, width := utf8.DecodeRuneInString(r.input[i:])
+		pos.Column++
+		pos.Rune++
+		pos.UTF16 += utf16Len(c)
+		i += width
+	}
+
+	return pos
+}
+
+// ResolveSpan returns the [Position]s of the start and the end of the span.
+func (r *PositionResolver) ResolveSpan(s Span) (start, end Position) {
+	return r.Resolve(s.Start), r.Resolve(s.End)
+}
+
+// utf16Len returns the number of UTF-16 code units encoding c.
+func utf16Len(c rune) int {
+	if c >= 0x10000 && c <= utf8.MaxRune {
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,71 @@
+package scum
+
+import (
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPositionResolver_Resolve(t *testing.T) {
+	// é is 2 bytes and 1 UTF-16 unit, 😀 is 4 bytes and 2 UTF-16 units
+	input := "ab\néx😀y\n\n\xffz"
+	r := NewPositionResolver(input)
+
+	testCases := []struct {
+		name   string
+		offset int
+		want   Position
+	}{
+		{"Start", 0, Position{Offset: 0, Line: 1, Column: 1, Rune: 0, UTF16: 0}},
+		{"SameLine", 1, Position{Offset: 1, Line: 1, Column: 2, Rune: 1, UTF16: 1}},
+		{"Newline", 2, Position{Offset: 2, Line: 1, Column: 3, Rune: 2, UTF16: 2}},
+		{"SecondLine", 3, Position{Offset: 3, Line: 2, Column: 1, Rune: 3, UTF16: 3}},
+		{"AfterTwoByteRune", 5, Position{Offset: 5, Line: 2, Column: 2, Rune: 4, UTF16: 4}},
+		{"Emoji", 6, Position{Offset: 6, Line: 2, Column: 3, Rune: 5, UTF16: 5}},
+		{"AfterEmoji", 10, Position{Offset: 10, Line: 2, Column: 4, Rune: 6, UTF16: 7}},
+		{"EmptyLine", 12, Position{Offset: 12, Line: 3, Column: 1, Rune: 8, UTF16: 9}},
+		{"InvalidByte", 14, Position{Offset: 14, Line: 4, Column: 2, Rune: 10, UTF16: 11}},
+		{"End", len(input), Position{Offset: 15, Line: 4, Column: 3, Rune: 11, UTF16: 12}},
+		{"Negative", -5, Position{Offset: 0, Line: 1, Column: 1}},
+		{"PastEnd", 100, Position{Offset: 15, Line: 4, Column: 3, Rune: 11, UTF16: 12}},
+		{"MidRune", 4, Position{Offset: 4, Line: 2, Column: 2, Rune: 4, UTF16: 4}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, r.Resolve(tc.offset))
+		})
+	}
+}
+
+func TestPositionResolver_UTF16MatchesEncoding(t *testing.T) {
+	input := "pre $bold$ 𝕏 hé\n日本語 [link]!href{https://example.com} 😀"
+	r := NewPositionResolver(input)
+
+	for i := range input {
+		want := len(utf16.Encode([]rune(input[:i])))
+		require.Equal(t, want, r.Resolve(i).UTF16, i)
+	}
+}
+
+func TestSerializableWarning_Locate(t *testing.T) {
+	d := testDict(t)
+	warns := newWarnings(t)
+
+	input := "hé\n*unclosed"
+	Parse(input, &d, warns)
+
+	var list []SerializableWarning
+	warns.SerializeAll(&list, &d)
+	require.Len(t, list, 1)
+
+	w := list[0]
+	require.Equal(t, IssueUnclosedTag, w.Code)
+	require.Equal(t, Span{4, 5}, w.Span)
+
+	w.Locate(NewPositionResolver(input))
+	require.Equal(t, Position{Offset: 4, Line: 2, Column: 1, Rune: 3, UTF16: 3}, w.Start)
+	require.Equal(t, Position{Offset: 5, Line: 2, Column: 2, Rune: 4, UTF16: 4}, w.End)
+	require.Equal(t, 3, w.SymbolIdx)
+}
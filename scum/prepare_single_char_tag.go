@@ -1,10 +1,11 @@
 package scum
 
-// PrepareSingleCharTag sets bounds for the single byte Tag.
+// PrepareSingleCharTag sets bounds for the single char Tag, which is a single byte or a single multi-byte rune.
 func PrepareSingleCharTag(ctx *ActionContext) {
-	ctx.Bounds.Width = 1
-	ctx.Bounds.Raw = NewSpan(ctx.Idx, 1)
-	ctx.Bounds.Inner = NewSpan(ctx.Idx+1, 0)
+	w := int(ctx.Tag.Seq.Len)
+	ctx.Bounds.Width = w
+	ctx.Bounds.Raw = NewSpan(ctx.Idx, w)
+	ctx.Bounds.Inner = NewSpan(ctx.Idx+w, 0)
 	// since single char Tags have valid sequence by default
 	ctx.Bounds.SeqValid = true
 }
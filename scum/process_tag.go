@@ -3,8 +3,9 @@ package scum
 // processTag is the top-level dispatcher for tag tokens.
 //
 // It first checks if the token should be silently consumed (skip counter > 0,
-// set when a duplicate nested tag's closer needs to be discarded). Otherwise
-// it routes to the appropriate handler based on the tag's properties:
+// set when a duplicate nested tag's closer needs to be discarded), or demoted
+// to text (its opener was flattened because a [ContentModel] didn't allow it). Then it checks the token against the content models,
+// see [rejectByContentModel]. Otherwise it routes to the appropriate handler based on the tag's properties:
 //   - Greedy tags (e.g. backtick code): handled atomically by [appendGreedyNode].
 //   - Universal tags (same byte opens and closes, e.g. $$ or *): [processUniversalTag].
 //   - Opening-only tags (e.g. [): [processOpeningTag].
@@ -15,7 +16,18 @@ func processTag(state *parserState, d *Dictionary, warns *Warnings, tok Token) {
 		return
 	}
 
-	tag := d.tags[tok.Trigger]
+	if len(state.flattened) > 0 && state.popFlattened(tok.Trigger) {
+		flattenTag(state, tok, warns)
+		return
+	}
+
+	tag := &d.tags[tok.Trigger]
+
+	// the closing Tags are checked with their opening Tags
+	if !tag.IsClosing() && !(tag.IsUniversal() && state.peekStack() == tok.Trigger) &&
+		rejectByContentModel(state, d, warns, tok) {
+		return
+	}
 
 	if tag.Greed > NonGreedy {
 		appendGreedyNode(state, tok, warns)
@@ -0,0 +1,613 @@
+package scum
+
+import (
+	"errors"
+	"sync"
+	"unicode/utf8"
+)
+
+// ErrEditOutOfRange is returned by [Reparse] when the span of the [Edit] is not within the input.
+var ErrEditOutOfRange = errors.New("edit span is out of the input bounds")
+
+// Edit describes a change of the input, like a keystroke in an editor:
+// the bytes of the input inside Span are replaced with Text.
+type Edit struct {
+	// Span is the replaced byte range of the previous input. It is empty for an insertion.
+	Span Span
+
+	// Text is the replacement. It is empty for a deletion.
+	Text string
+}
+
+// lookaheadSlack covers the bytes an Action reads around its scanned windows:
+// the Tag sequences, the runes after an escape symbol or a Tag, and the attribute payload symbols.
+const lookaheadSlack = 4 * MaxTagLen
+
+// reparsePool keeps the scratch arenas of the reparsed regions between the reparses.
+var reparsePool = sync.Pool{
+	New: func() any {
+		return new(AST)
+	},
+}
+
+// Reparse applies the edit to the input of ast and updates ast to the [AST] of the edited input,
+// reusing the parts of the tree the edit can't affect. The result is identical to parsing
+// the edited input with [ParseInto].
+//
+// ast must be the result of [Parse], [ParseInto] or Reparse with the same [Dictionary].
+// ast, d and warns must be non-nil.
+//
+// Only the region around the edit is tokenized and parsed again:
+//   - It starts at the nearest top-level text node, which is far enough from the edit that no Action
+//     before it could have read the edited bytes. The parser has no open Tags there,
+//     so its state is restored from the tree.
+//   - It ends at the first top-level text node after the edit, where the parser has no open Tags again
+//     and the previous tree has the same node. From there on the previous tree is moved into place
+//     with its spans and indices shifted. If there is no such node, the input is parsed to the end.
+//
+// If the previous tree might have reached the node, attribute or depth [Limits], or the edited one might reach them,
+// the edited input is parsed in full, since the omitted nodes depend on the whole input.
+// The input is parsed in full in the line mode as well, see [Dictionary.AddBlock].
+//
+// warns receives only the Warnings found in the reparsed region, which is returned as a span of the edited input.
+// Use [ParseInto] when the complete list is needed.
+//
+// Reparse returns [ErrEditOutOfRange] and leaves ast untouched if the span of the edit is not within the input.
+func Reparse(ast *AST, edit Edit, d *Dictionary, warns *Warnings) (Span, error) {
+	old := ast.Input
+	if edit.Span.Start < 0 || edit.Span.End < edit.Span.Start || edit.Span.End > len(old) {
+		return Span{}, ErrEditOutOfRange
+	}
+
+	input := old[:edit.Span.Start] + edit.Text + old[edit.Span.End:]
+
+	if d.HasBlocks() || !reusable(ast, d.Limits) {
+		ParseInto(ast, input, d, warns)
+		return Span{0, len(input)}, nil
+	}
+
+	// the Warnings are collected aside, since the region might turn out to reach the limits
+	var local Warnings
+
+	r := newReparser(ast, edit, input, d)
+	r.parse(&local)
+
+	span, ok := r.splice()
+	r.release()
+
+	if !ok {
+		ParseInto(ast, input, d, warns)
+		return Span{0, len(input)}, nil
+	}
+
+	for _, w := range local.List() {
+		warns.Add(w)
+	}
+
+	return span, nil
+}
+
+// reusable reports whether the AST can be reused, that is, it is not empty and
+// none of the parse limits could have omitted its nodes.
+func reusable(ast *AST, l Limits) bool {
+	return len(ast.Nodes) > 0 &&
+		(l.MaxNodes == 0 || len(ast.Nodes) < l.MaxNodes) &&
+		(l.MaxAttributes == 0 || len(ast.Attributes) < l.MaxAttributes) &&
+		(l.MaxParseDepth == 0 || ast.MaxDepth < l.MaxParseDepth)
+}
+
+// reparser holds the state of a single [Reparse].
+//
+// The reparsed region is parsed into a scratch AST. Its root stands in for the root of the tree, and
+// if the parser would attach the attributes at the start of the region to another node, the node
+// after the root stands in for it. The scratch nodes after the stand-ins are moved
+// into the tree between the kept prefix and suffix.
+type reparser struct {
+	// ast is the previous AST, updated in place.
+	ast *AST
+
+	d *Dictionary
+
+	// input is the edited input.
+	input string
+
+	// delta is the shift of the bytes after the edit.
+	delta int
+
+	// editEnd is the end of the edit in the edited input.
+	editEnd int
+
+	// oldRoot is the root of the previous AST.
+	oldRoot Node
+
+	// start is the offset the region starts at.
+	start int
+
+	// base is the index of the first reparsed node in the tree.
+	base int
+
+	// attrBase is the index of the first reparsed attribute in the tree.
+	attrBase int
+
+	// prev is the last top-level node kept before the region, or -1.
+	prev int
+
+	// lastNode is the node the attributes at the start of the region are attached to.
+	lastNode int
+
+	// rootCount and rootWidth are the number of the top-level nodes before the region and the cumulative root width.
+	rootCount int
+	rootWidth int
+
+	// oldChild is the next top-level node of the previous AST to resync with, or -1.
+	// oldCount and oldWidth are the number of the top-level nodes before it and the cumulative root width.
+	oldChild int
+	oldCount int
+	oldWidth int
+
+	// resynced is true if the region ended at the oldChild node, at the offset end.
+	resynced bool
+	end      int
+
+	// resyncWidth is the cumulative root width at the end of the region.
+	resyncWidth int
+
+	state   *parserState
+	scratch *AST
+
+	// off is the index of the first reparsed node in the scratch AST.
+	off int
+}
+
+func newReparser(ast *AST, edit Edit, input string, d *Dictionary) *reparser {
+	r := &reparser{
+		ast:      ast,
+		d:        d,
+		input:    input,
+		delta:    len(edit.Text) - (edit.Span.End - edit.Span.Start),
+		editEnd:  edit.Span.Start + len(edit.Text),
+		oldRoot:  ast.Nodes[0],
+		base:     1,
+		prev:     -1,
+		oldChild: ast.Nodes[0].FirstChild,
+	}
+
+	r.findStart(edit.Span.Start)
+
+	return r
+}
+
+// findStart finds the last top-level text node no Action before which could read the edited bytes.
+// Without one the region starts at the beginning of the input.
+func (r *reparser) findStart(editStart int) {
+	limit := dirtyStart(r.ast.Input, editStart, r.d) - r.d.Limits.lookahead()
+
+	nodes := r.ast.Nodes
+	width, count, prev := 0, 0, -1
+	for c := r.oldRoot.FirstChild; c != -1; c = nodes[c].NextSibling {
+		n := &nodes[c]
+		if n.Span.Start > limit {
+			break
+		}
+
+		if n.Type == NodeText && n.Span.Start > 0 {
+			r.start, r.base, r.prev = n.Span.Start, c, prev
+			r.rootWidth, r.rootCount = width, count
+		}
+
+		width += n.Span.End - n.Span.Start
+		prev = c
+		count++
+	}
+
+	if r.start == 0 {
+		return
+	}
+
+	r.oldChild, r.oldCount, r.oldWidth = r.base, r.rootCount, r.rootWidth
+	r.attrBase = firstAttribute(r.ast, r.base)
+
+	// the last node the parser created or closed before the region
+	r.lastNode = 0
+	if r.prev != -1 {
+		r.lastNode = r.prev
+		if p := &nodes[r.prev]; p.Type == NodeTag && r.d.tags[p.TagID].Greed > NonGreedy && p.FirstChild != -1 {
+			r.lastNode = p.FirstChild
+		}
+	}
+}
+
+// parse tokenizes and parses the region into the scratch AST until it resyncs with the previous tree.
+// It mirrors [Tokenize] and [ParseInto], checking for the resync before every text token.
+func (r *reparser) parse(warns *Warnings) {
+	s := reparsePool.Get().(*AST)
+	s.Input = r.input
+	s.Nodes = append(s.Nodes[:0], NewNode())
+	s.Attributes = s.Attributes[:0]
+	r.scratch = s
+
+	r.off = 1
+	if r.lastNode != 0 {
+		s.Nodes = append(s.Nodes, NewNode())
+		r.off = 2
+	}
+
+	state := statePool.Get().(*parserState)
+	state.ast = *s
+	// the node and attribute limits are checked on the whole tree in splice,
+	// while the parser has no open Tags at the start of the region, so its depth is exact
+	state.limits = Limits{MaxParseDepth: r.d.Limits.MaxParseDepth}
+	state.cumWidth[0] = r.rootWidth
+	state.lastNodeIdx = r.off - 1
+	r.state = state
+
+	r.scan(warns)
+	*s = state.ast
+}
+
+// scan runs the tokenizer and the parser from the start of the region.
+func (r *reparser) scan(warns *Warnings) {
+	state := r.state
+
+	var ts TokenizerState
+	ac := ActionContext{
+		Dictionary: r.d,
+		State:      &ts,
+		Input:      r.input,
+		Warns:      warns,
+	}
+
+	n := len(r.input)
+	textStart := r.start
+
+	for i := r.start; i < n; {
+		act, isSpecial := r.d.Action(r.input[i])
+		if !isSpecial {
+			i++
+			continue
+		}
+
+		ac.Reset(r.input[i], i)
+		token, stride, skip := act(&ac)
+		if skip {
+			i += stride
+			continue
+		}
+
+		if i > textStart {
+			if r.resync(textStart) {
+				return
+			}
+
+			processText(state, Token{
+				Type:    TokenText,
+				Pos:     textStart,
+				Width:   i - textStart,
+				Payload: Span{textStart, i},
+			}, warns)
+		}
+
+		i += stride
+		textStart = i
+
+		processToken(state, r.d, warns, token)
+	}
+
+	if textStart < n {
+		if r.resync(textStart) {
+			return
+		}
+
+		processText(state, Token{
+			Type:    TokenText,
+			Pos:     textStart,
+			Width:   n - textStart,
+			Payload: Span{textStart, n},
+		}, warns)
+	}
+
+	closeOpenTags(state, r.d, warns)
+}
+
+// resync reports whether the region can end with the text token at the offset: it is after the edit
+// and the bytes the Actions read behind their position, the parser has no open or skipped Tags,
+// and the previous AST has a top-level text node at the same place.
+// From there on the tokens and the parser state are the same as in the previous parse, shifted by the edit.
+func (r *reparser) resync(offset int) bool {
+	if offset < r.editEnd+utf8.UTFMax || len(r.state.stack) > 0 {
+		return false
+	}
+
+	nodes := r.ast.Nodes
+	target := offset - r.delta
+	for r.oldChild != -1 && nodes[r.oldChild].Span.Start < target {
+		n := &nodes[r.oldChild]
+		r.oldWidth += n.Span.End - n.Span.Start
+		r.oldCount++
+		r.oldChild = n.NextSibling
+	}
+
+	if r.oldChild == -1 || nodes[r.oldChild].Span.Start != target || nodes[r.oldChild].Type != NodeText {
+		return false
+	}
+
+	for _, c := range r.state.skip {
+		if c > 0 {
+			return false
+		}
+	}
+
+	r.resynced = true
+	r.end = offset
+	r.resyncWidth = r.state.peekCumWidth()
+	return true
+}
+
+// splice moves the reparsed nodes and attributes into the tree between the kept prefix and suffix, and
+// updates the root and the statistics. It returns false, leaving the tree untouched, if the edited tree might reach the limits.
+func (r *reparser) splice() (Span, bool) {
+	ast, s, state := r.ast, r.scratch, r.state
+
+	// the removed nodes and attributes of the previous AST
+	suffix, attrSuffix := len(ast.Nodes), len(ast.Attributes)
+	end := len(r.input)
+	if r.resynced {
+		suffix = r.oldChild
+		attrSuffix = firstAttribute(ast, suffix)
+		end = r.end
+	}
+
+	removed := nodeStats(ast, r.base, suffix, r.d)
+	added := nodeStats(s, r.off, len(s.Nodes), r.d)
+
+	// the removed nodes are whole top-level subtrees
+	removedDepth := 0
+	for c := r.base; c < suffix; c = subtreeEnd(ast, c) {
+		removedDepth = max(removedDepth, nestingDepth(ast, c, r.d))
+	}
+
+	maxDepth := max(ast.MaxDepth, state.maxDepth)
+	if removedDepth >= ast.MaxDepth && state.maxDepth < removedDepth {
+		// the deepest nesting might be removed, so the kept nodes are measured
+		maxDepth = state.maxDepth
+		for c := r.oldRoot.FirstChild; c != -1; c = ast.Nodes[c].NextSibling {
+			if c >= r.base && c < suffix {
+				continue
+			}
+			maxDepth = max(maxDepth, nestingDepth(ast, c, r.d))
+		}
+	}
+
+	added.TextByteLen -= removed.TextByteLen
+	added.TotalTagNodes -= removed.TotalTagNodes
+	added.TotalTextNodes -= removed.TotalTextNodes
+
+	m, ma := len(s.Nodes)-r.off, len(s.Attributes)
+	nodeCount := r.base + m + len(ast.Nodes) - suffix
+	attrCount := r.attrBase + ma + len(ast.Attributes) - attrSuffix
+
+	l := r.d.Limits
+	if state.warnedMaxParseDepth ||
+		(l.MaxNodes > 0 && nodeCount >= l.MaxNodes) ||
+		(l.MaxAttributes > 0 && attrCount >= l.MaxAttributes) ||
+		(l.MaxParseDepth > 0 && maxDepth >= l.MaxParseDepth) {
+		return Span{}, false
+	}
+
+	shift := r.base + m - suffix
+	attrShift := r.attrBase + ma - attrSuffix
+
+	// 1. Move the suffix into place and shift it
+	ast.Nodes = spliceSlice(ast.Nodes, r.base, suffix, m)
+	for i := r.base + m; i < len(ast.Nodes); i++ {
+		n := &ast.Nodes[i]
+		n.FirstChild = shiftIdx(n.FirstChild, shift)
+		n.LastChild = shiftIdx(n.LastChild, shift)
+		n.NextSibling = shiftIdx(n.NextSibling, shift)
+		n.Span.Start += r.delta
+		n.Span.End += r.delta
+		if n.Attributes.Len > 0 {
+			n.Attributes.Start += attrShift
+		}
+	}
+
+	ast.Attributes = spliceSlice(ast.Attributes, r.attrBase, attrSuffix, ma)
+	for i := r.attrBase + ma; i < len(ast.Attributes); i++ {
+		a := &ast.Attributes[i]
+		if !a.IsFlag {
+			a.Name.Start += r.delta
+			a.Name.End += r.delta
+		}
+		a.Payload.Start += r.delta
+		a.Payload.End += r.delta
+	}
+
+	// 2. Copy the reparsed nodes and attributes
+	shift = r.base - r.off
+	for j, n := range s.Nodes[r.off:] {
+		n.FirstChild = shiftIdx(n.FirstChild, shift)
+		n.LastChild = shiftIdx(n.LastChild, shift)
+		n.NextSibling = shiftIdx(n.NextSibling, shift)
+		if n.Attributes.Len > 0 {
+			n.Attributes.Start += r.attrBase
+		}
+		ast.Nodes[r.base+j] = n
+	}
+
+	copy(ast.Attributes[r.attrBase:], s.Attributes)
+
+	// 3. Link the top-level nodes and attach the attributes of the stand-ins
+	root := &ast.Nodes[0]
+	if r.start == 0 {
+		root.Attributes = Range{}
+	}
+
+	r.attachAttributes(root, &s.Nodes[0])
+	if r.off == 2 {
+		r.attachAttributes(&ast.Nodes[r.lastNode], &s.Nodes[1])
+	}
+
+	first, last := -1, -1
+	if sr := &s.Nodes[0]; sr.FirstChild != -1 {
+		first, last = sr.FirstChild+shift, sr.LastChild+shift
+	}
+	count := r.rootCount + s.Nodes[0].ChildCount
+
+	if r.resynced {
+		suffixFirst := r.base + m
+		if last != -1 {
+			ast.Nodes[last].NextSibling = suffixFirst
+		} else {
+			first = suffixFirst
+		}
+
+		last = r.oldRoot.LastChild + r.base + m - suffix
+		count += r.oldRoot.ChildCount - r.oldCount
+		root.Span.End = r.resyncWidth + r.oldRoot.Span.End - r.oldWidth
+	} else {
+		root.Span.End = state.peekCumWidth()
+	}
+
+	if r.prev != -1 {
+		ast.Nodes[r.prev].NextSibling = first
+	} else {
+		root.FirstChild = first
+	}
+
+	if last == -1 {
+		last = r.prev
+	}
+
+	root.LastChild = last
+	root.ChildCount = count
+
+	ast.Input = r.input
+	ast.MaxDepth = maxDepth
+	ast.TextByteLen += added.TextByteLen
+	ast.TotalTagNodes += added.TotalTagNodes
+	ast.TotalTextNodes += added.TotalTextNodes
+
+	return Span{r.start, end}, true
+}
+
+// attachAttributes appends the attributes attached to the scratch stand-in to the node.
+// Both are contiguous: the node's attributes are the last ones before the region.
+func (r *reparser) attachAttributes(n *Node, standIn *Node) {
+	if standIn.Attributes.Len == 0 {
+		return
+	}
+
+	if n.Attributes.Len == 0 {
+		n.Attributes.Start = r.attrBase + standIn.Attributes.Start
+	}
+
+	n.Attributes.Len += standIn.Attributes.Len
+}
+
+// release returns the parser state and the scratch AST to their pools.
+func (r *reparser) release() {
+	r.scratch.Input = ""
+	reparsePool.Put(r.scratch)
+
+	r.state.Reset()
+	statePool.Put(r.state)
+}
+
+// lookahead returns the max number of bytes after its position an Action can read,
+// not counting the runs of the Tag-Vs-Content symbols.
+func (l Limits) lookahead() int {
+	return max(l.MaxAttrKeyLen+l.MaxAttrPayloadLen, l.MaxKeyLen+l.MaxPayloadLen) + lookaheadSlack
+}
+
+// dirtyStart returns the offset, reading from which an Action might read the edit at the offset start.
+// Tag-Vs-Content sequences are read until their symbol run ends, so a run of a special symbol right before
+// the edit counts as a part of it.
+func dirtyStart(input string, start int, d *Dictionary) int {
+	if start == 0 || d.actions[input[start-1]] == nil {
+		return start
+	}
+
+	c := input[start-1]
+	for start > 0 && input[start-1] == c {
+		start--
+	}
+
+	return start
+}
+
+// firstAttribute returns the index of the first attribute attached to the nodes from the index on,
+// which is the number of the attributes attached to the nodes before it. The attributes of a Tag follow
+// the attributes of its children, so all the nodes are checked.
+func firstAttribute(ast *AST, from int) int {
+	first := len(ast.Attributes)
+	for _, n := range ast.Nodes[from:] {
+		if n.Attributes.Len > 0 {
+			first = min(first, n.Attributes.Start)
+		}
+	}
+
+	return first
+}
+
+// nodeStats returns the statistics of the nodes in the index range the way [ParseInto] counts them,
+// except for MaxDepth.
+func nodeStats(ast *AST, from, to int, d *Dictionary) AST {
+	var stats AST
+	for _, n := range ast.Nodes[from:to] {
+		switch n.Type {
+		case NodeTag:
+			if d.tags[n.TagID].Greed == NonGreedy {
+				stats.TotalTagNodes++
+			}
+
+		case NodeText:
+			stats.TextByteLen += n.Span.End - n.Span.Start
+			// only the payload of a greedy Tag can be empty, and it is not counted
+			if n.Span.End > n.Span.Start {
+				stats.TotalTextNodes++
+			}
+		}
+	}
+
+	return stats
+}
+
+// nestingDepth returns the number of the nested non-greedy Tags in the subtree, including its root.
+func nestingDepth(ast *AST, idx int, d *Dictionary) int {
+	n := &ast.Nodes[idx]
+	if n.Type != NodeTag || d.tags[n.TagID].Greed > NonGreedy {
+		return 0
+	}
+
+	deepest := 0
+	for c := n.FirstChild; c != -1; c = ast.Nodes[c].NextSibling {
+		deepest = max(deepest, nestingDepth(ast, c, d))
+	}
+
+	return deepest + 1
+}
+
+func shiftIdx(idx, shift int) int {
+	if idx == -1 {
+		return -1
+	}
+
+	return idx + shift
+}
+
+// spliceSlice replaces the elements in [from, to) with n elements, moving the rest in place.
+// The new elements are left for the caller to fill.
+func spliceSlice[T any](s []T, from, to, n int) []T {
+	size := len(s) - (to - from) + n
+	if size > cap(s) {
+		grown := make([]T, size, size+size/4)
+		copy(grown, s[:from])
+		copy(grown[from+n:], s[to:])
+		return grown
+	}
+
+	tail := len(s) - to
+	s = s[:max(size, len(s))]
+	copy(s[from+n:], s[to:to+tail])
+	return s[:size]
+}
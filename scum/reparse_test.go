@@ -0,0 +1,200 @@
+package scum
+
+import (
+	"math/rand/v2"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// requireReparse applies the edit with Reparse and checks the result against the full parse of the edited input.
+func requireReparse(t *testing.T, d *Dictionary, ast *AST, edit Edit) Span {
+	t.Helper()
+
+	before := ast.Input
+	w := newWarns(t)
+	span, err := Reparse(ast, edit, d, &w)
+	require.NoError(t, err)
+
+	input := before[:edit.Span.Start] + edit.Text + before[edit.Span.End:]
+	fw := newWarns(t)
+	want := Parse(input, d, &fw)
+	// a fresh AST has no attribute arena, while a reused one keeps an empty one
+	if len(want.Attributes) == 0 && len(ast.Attributes) == 0 {
+		want.Attributes = ast.Attributes
+	}
+	require.Equal(t, want, *ast, "input: %q\nedit: %+v", before, edit)
+	require.LessOrEqual(t, 0, span.Start)
+	require.LessOrEqual(t, span.End, len(input))
+
+	return span
+}
+
+func TestReparse(t *testing.T) {
+	// the small limits let the reparsed region start and end inside the short inputs
+	d := testDictWithLimits(t, Limits{MaxAttrKeyLen: 4, MaxAttrPayloadLen: 4, MaxPayloadLen: 4, MaxKeyLen: 2})
+	long := strings.Repeat("plain text ", 8)
+
+	testCases := []struct {
+		name  string
+		input string
+		edit  Edit
+	}{
+		{"EmptyInput", "", Edit{Span{0, 0}, "$$a"}},
+		{"InsertText", long + "*it*" + long, Edit{Span{50, 50}, "x"}},
+		{"OpenTag", long + "*it*" + long, Edit{Span{50, 50}, "*"}},
+		{"CloseTag", long + "*it" + long, Edit{Span{91, 91}, "*"}},
+		{"DeleteClosingTag", long + "[link]" + long, Edit{Span{93, 94}, ""}},
+		{"ReplaceTag", long + "$$bold$$" + long, Edit{Span{88, 90}, "*"}},
+		{"AttributeAfterTag", long + "[link] " + long, Edit{Span{94, 94}, "!href{x}"}},
+		{"AttributeAtRegionStart", "*a*" + long + "!f{x}" + long, Edit{Span{120, 121}, "y"}},
+		{"GreedyTag", long + "`code` " + long, Edit{Span{90, 90}, "`"}},
+		{"TagVsContentRun", long + "``" + long + "``" + long, Edit{Span{178, 178}, "`"}},
+		{"Escape", long + `\*a*` + long, Edit{Span{88, 89}, ""}},
+		{"IntraWord", long + "snake_case_" + long, Edit{Span{98, 99}, " "}},
+		{"AtStart", long, Edit{Span{0, 0}, "$$"}},
+		{"AtEnd", long, Edit{Span{len(long), len(long)}, "$$"}},
+		{"DeleteAll", long + "*it*", Edit{Span{0, len(long) + 4}, ""}},
+		{"UTF8", long + "é😀*é*" + long, Edit{Span{94, 94}, "😀"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := newWarns(t)
+			ast := Parse(tc.input, &d, &w)
+			requireReparse(t, &d, &ast, tc.edit)
+		})
+	}
+}
+
+func TestReparse_ReparsesOnlyAroundTheEdit(t *testing.T) {
+	d := testDict(t)
+	input := strings.Repeat("Hello *world* this is $$bold text$$ and [link text] end. ", 180)
+
+	w := newWarns(t)
+	ast := Parse(input, &d, &w)
+
+	mid := len(input) / 2
+	span := requireReparse(t, &d, &ast, Edit{Span{mid, mid}, "x"})
+	require.Less(t, span.End-span.Start, 2*d.Limits.lookahead())
+	require.Greater(t, span.Start, 0)
+	require.Less(t, span.End, len(ast.Input))
+
+	span = requireReparse(t, &d, &ast, Edit{Span{mid, mid + 1}, ""})
+	require.Less(t, span.End-span.Start, 2*d.Limits.lookahead())
+	require.Equal(t, input, ast.Input)
+}
+
+func TestReparse_Warnings(t *testing.T) {
+	d := testDict(t)
+
+	w := newWarns(t)
+	ast := Parse("plain text", &d, &w)
+
+	w = newWarns(t)
+	_, err := Reparse(&ast, Edit{Span{0, 0}, "$$"}, &d, &w)
+	require.NoError(t, err)
+	requireWarningIssue(t, &w, IssueUnclosedTag)
+}
+
+func TestReparse_EditOutOfRange(t *testing.T) {
+	d := testDict(t)
+
+	w := newWarns(t)
+	ast := Parse("text", &d, &w)
+	want := Parse("text", &d, &w)
+
+	for _, s := range []Span{{-1, 0}, {3, 2}, {0, 5}} {
+		_, err := Reparse(&ast, Edit{Span: s}, &d, &w)
+		require.ErrorIs(t, err, ErrEditOutOfRange)
+		require.Equal(t, want, ast)
+	}
+}
+
+func TestReparse_Limits(t *testing.T) {
+	d := testDictWithLimits(t, Limits{MaxNodes: 12, MaxAttributes: 2, MaxParseDepth: 2, MaxPayloadLen: 4, MaxKeyLen: 2})
+	input := strings.Repeat("text *a $$b [c]$$* ", 3) + strings.Repeat("x!{f} ", 3)
+
+	w := newWarns(t)
+	ast := Parse("", &d, &w)
+	for i := range len(input) {
+		requireReparse(t, &d, &ast, Edit{Span{i, i}, input[i : i+1]})
+	}
+
+	for range len(input) {
+		requireReparse(t, &d, &ast, Edit{Span{0, 1}, ""})
+	}
+}
+
+// randomEdit returns an edit, deleting up to two bytes and inserting up to one piece of formatAlphabet at a random place.
+func randomEdit(rng *rand.Rand, input string) Edit {
+	start := rng.IntN(len(input) + 1)
+	end := min(start+rng.IntN(3), len(input))
+
+	text := ""
+	if rng.IntN(3) > 0 {
+		text = formatAlphabet[rng.IntN(len(formatAlphabet))]
+	}
+
+	return Edit{Span{start, end}, text}
+}
+
+func randomInput(rng *rand.Rand, pieces int) string {
+	var b strings.Builder
+	for range pieces {
+		b.WriteString(formatAlphabet[rng.IntN(len(formatAlphabet))])
+		// the plain text makes the top-level text nodes more likely
+		b.WriteString(" text ")
+	}
+
+	return b.String()
+}
+
+func TestReparse_Keystrokes(t *testing.T) {
+	limits := []Limits{
+		{},
+		{MaxAttrKeyLen: 8, MaxAttrPayloadLen: 8, MaxPayloadLen: 16, MaxKeyLen: 4},
+		{MaxAttrKeyLen: 8, MaxAttrPayloadLen: 8, MaxPayloadLen: 16, MaxKeyLen: 4, MaxNodes: 400, MaxAttributes: 20, MaxParseDepth: 3},
+	}
+
+	for i, l := range limits {
+		d := testDictWithLimits(t, l)
+		rng := rand.New(rand.NewPCG(35, uint64(i)))
+
+		w := newWarns(t)
+		ast := Parse(randomInput(rng, 300), &d, &w)
+
+		for range 2000 {
+			requireReparse(t, &d, &ast, randomEdit(rng, ast.Input))
+		}
+	}
+}
+
+func FuzzReparse(f *testing.F) {
+	seeds := []struct {
+		input      string
+		start, end int
+		text       string
+	}{
+		{"", 0, 0, "$$a"},
+		{"plain *it* text [link]!href{x} more", 8, 8, "*"},
+		{"a ``b`` c `d` e", 4, 5, ""},
+		{`x \*y* z $$w$$`, 2, 3, "_"},
+		{"é😀 :[img] ]!{f} *$$*", 3, 7, "["},
+	}
+	for _, s := range seeds {
+		f.Add(s.input, s.start, s.end, s.text)
+	}
+
+	f.Fuzz(func(t *testing.T, input string, start, end int, text string) {
+		d := testDictWithLimits(t, Limits{MaxAttrKeyLen: 4, MaxAttrPayloadLen: 4, MaxPayloadLen: 4, MaxKeyLen: 2})
+
+		start = min(max(start, 0), len(input))
+		end = min(max(end, start), len(input))
+
+		w := newWarns(t)
+		ast := Parse(input, &d, &w)
+		requireReparse(t, &d, &ast, Edit{Span{start, end}, text})
+	})
+}
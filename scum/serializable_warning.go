@@ -10,10 +10,21 @@ type SerializableWarning struct {
 	Codename string `json:"codename"`
 	// ByteIdx is the position of the starting byte of the erroneous sequence in the input.
 	ByteIdx int `json:"byte_idx"`
-	// SymbolIdx is the position of the symbol/letter causing the issue.
+	// SymbolIdx is the position of the symbol/letter causing the issue. It is set by [SerializableWarning.Locate].
 	SymbolIdx int `json:"symbol_idx"`
 	// Description is a human-readable description of the issue.
 	Description string `json:"description"`
+	// Span is the byte range of the erroneous sequence in the input.
+	Span Span `json:"span"`
+	// Start and End are the positions of the Span in the input. They are set by [SerializableWarning.Locate].
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Locate resolves the Span of the warning into the Start and End positions and the SymbolIdx.
+func (w *SerializableWarning) Locate(r *PositionResolver) {
+	w.Start, w.End = r.ResolveSpan(w.Span)
+	w.SymbolIdx = w.Start.Rune
 }
 
 var (
@@ -78,6 +89,13 @@ func init() {
 	mapIssueToCodename[issueIndex(IssueMaxNodesExceeded)] = "MAX_NODES_EXCEEDED"
 	mapIssueToCodename[issueIndex(IssueMaxAttributesExceeded)] = "MAX_ATTRIBUTES_EXCEEDED"
 	mapIssueToCodename[issueIndex(IssueMaxParseDepthExceeded)] = "MAX_PARSE_DEPTH_EXCEEDED"
+	mapIssueToCodename[issueIndex(IssueInvalidSpec)] = "INVALID_SPEC"
+	mapIssueToCodename[issueIndex(IssueUnknownTagID)] = "UNKNOWN_TAG_ID"
+	mapIssueToCodename[issueIndex(IssueTagNotAllowed)] = "TAG_NOT_ALLOWED"
+	mapIssueToCodename[issueIndex(IssueInvalidBlockKind)] = "INVALID_BLOCK_KIND"
+	mapIssueToCodename[issueIndex(IssueTooManyBlocks)] = "TOO_MANY_BLOCKS"
+	mapIssueToCodename[issueIndex(IssueMaxLinesExceeded)] = "MAX_LINES_EXCEEDED"
+	mapIssueToCodename[issueIndex(IssueMaxListDepthExceeded)] = "MAX_LIST_DEPTH_EXCEEDED"
 
 	serializers[issueIndex(IssueUnexpectedEOL)] = serializeUnexpectedEOL
 	serializers[issueIndex(IssueUnexpectedSymbol)] = serializeUnexpectedSymbol
@@ -108,6 +126,13 @@ func init() {
 	serializers[issueIndex(IssueMaxNodesExceeded)] = serializeMaxNodesExceeded
 	serializers[issueIndex(IssueMaxAttributesExceeded)] = serializeMaxAttributesExceeded
 	serializers[issueIndex(IssueMaxParseDepthExceeded)] = serializeMaxParseDepthExceeded
+	serializers[issueIndex(IssueInvalidSpec)] = serializeGeneric
+	serializers[issueIndex(IssueUnknownTagID)] = serializeGeneric
+	serializers[issueIndex(IssueTagNotAllowed)] = serializeTagNotAllowed
+	serializers[issueIndex(IssueInvalidBlockKind)] = serializeGeneric
+	serializers[issueIndex(IssueTooManyBlocks)] = serializeGeneric
+	serializers[issueIndex(IssueMaxLinesExceeded)] = serializeMaxLinesExceeded
+	serializers[issueIndex(IssueMaxListDepthExceeded)] = serializeMaxListDepthExceeded
 }
 
 type warnSerializer func(w Warning, d *Dictionary) SerializableWarning
@@ -116,11 +141,26 @@ var serializers [NumIssues]warnSerializer
 
 // serialize converts a Warning to a SerializableWarning using the appropriate serializer.
 func serialize(w Warning, d *Dictionary) SerializableWarning {
+	var sw SerializableWarning
 	idx := issueIndex(w.Issue)
 	if serializers[idx] != nil {
-		return serializers[idx](w, d)
+		sw = serializers[idx](w, d)
+	} else {
+		sw = serializeGeneric(w, d)
 	}
-	return serializeGeneric(w, d)
+
+	sw.Span = warningSpan(w, d)
+	return sw
+}
+
+// warningSpan returns the bounds of the sequence causing the Warning: the Tag if there is one,
+// otherwise the single symbol at the position.
+func warningSpan(w Warning, d *Dictionary) Span {
+	width := 1
+	if w.TagID != 0 && d.tags[w.TagID].Seq.Len > 0 {
+		width = int(d.tags[w.TagID].Seq.Len)
+	}
+	return NewSpan(w.Pos, width)
 }
 
 // WarnCount returns total number of Warnings in the list.
@@ -181,6 +221,24 @@ func serializeMaxParseDepthExceeded(w Warning, d *Dictionary) SerializableWarnin
 	}
 }
 
+func serializeMaxLinesExceeded(w Warning, d *Dictionary) SerializableWarning {
+	return SerializableWarning{
+		Code:        w.Issue,
+		Codename:    mapIssueToCodename[issueIndex(w.Issue)],
+		ByteIdx:     w.Pos,
+		Description: "maximum number of lines reached; further lines were parsed as plain text.",
+	}
+}
+
+func serializeMaxListDepthExceeded(w Warning, d *Dictionary) SerializableWarning {
+	return SerializableWarning{
+		Code:        w.Issue,
+		Codename:    mapIssueToCodename[issueIndex(w.Issue)],
+		ByteIdx:     w.Pos,
+		Description: "maximum list depth reached; the block was not nested deeper.",
+	}
+}
+
 func serializeUnclosedTag(w Warning, d *Dictionary) SerializableWarning {
 	desc := "unclosed tag with name " +
 		d.tags[w.TagID].Name +
@@ -322,3 +380,19 @@ func serializeDuplicateNestedTag(w Warning, d *Dictionary) SerializableWarning {
 		Description: desc,
 	}
 }
+
+func serializeTagNotAllowed(w Warning, d *Dictionary) SerializableWarning {
+	desc := "tag with name " +
+		d.tags[w.TagID].Name +
+		" is not allowed inside the tag with name " +
+		d.tags[w.ContainerTagID].Name +
+		" and is treated as plain text."
+
+	return SerializableWarning{
+		Code:     w.Issue,
+		Codename: mapIssueToCodename[issueIndex(w.Issue)],
+		ByteIdx:  w.Pos,
+		// Summary:     mapIssueToSumm[w.Issue],
+		Description: desc,
+	}
+}
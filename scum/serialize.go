@@ -6,14 +6,27 @@ func init() {
 	mapNodeTypeToName[NodeRoot] = "Root"
 	mapNodeTypeToName[NodeTag] = "Tag"
 	mapNodeTypeToName[NodeText] = "Text"
+	mapNodeTypeToName[NodeBlock] = "Block"
 }
 
 // SerializableAttribute is a JSON-friendly view of an [Attribute].
-// Unlike [Attribute], it stores resolved strings instead of spans into [AST.Input].
+// Unlike [Attribute], it stores resolved strings, and keeps the spans into [AST.Input]
+// only to point at the attribute in the input.
 type SerializableAttribute struct {
-	Name    string `json:"name"`
-	Payload string `json:"payload"`
-	IsFlag  bool   `json:"is_flag"`
+	Name        string `json:"name"`
+	Payload     string `json:"payload"`
+	IsFlag      bool   `json:"is_flag"`
+	NameSpan    Span   `json:"name_span"`
+	PayloadSpan Span   `json:"payload_span"`
+}
+
+// Span returns the bounds of the attribute's name and payload in the input.
+// The attribute symbols around them are not included.
+func (a SerializableAttribute) Span() Span {
+	if a.IsFlag {
+		return a.PayloadSpan
+	}
+	return Span{Start: a.NameSpan.Start, End: max(a.NameSpan.End, a.PayloadSpan.End)}
 }
 
 // serializeAttributes resolves attribute spans into strings and appends them to dest.
@@ -24,6 +37,9 @@ func serializeAttributes(dest *[]SerializableAttribute, ast *AST, r Range) {
 			Name:    ast.Input[a.Name.Start:a.Name.End],
 			Payload: ast.Input[a.Payload.Start:a.Payload.End],
 			IsFlag:  a.IsFlag,
+
+			NameSpan:    a.Name,
+			PayloadSpan: a.Payload,
 		})
 	}
 }
@@ -36,11 +52,11 @@ func serializeAttributes(dest *[]SerializableAttribute, ast *AST, r Range) {
 type SerializableNode struct {
 	// Name is the semantic node name.
 	//
-	// For tag nodes it comes from the [Dictionary]. For non-tag nodes serializer
+	// For tag and block nodes it comes from the [Dictionary]. For non-tag nodes serializer
 	// uses conventional names such as "ROOT" and "TEXT".
 	Name string `json:"name"`
 
-	// Type is the coarse node kind: "Root", "Tag" or "Text".
+	// Type is the coarse node kind: "Root", "Tag", "Block" or "Text".
 	Type string `json:"type"`
 
 	// Attributes contains attributes attached directly to this node.
@@ -53,10 +69,13 @@ type SerializableNode struct {
 	// on the tag's meaning and may also rely on Children.
 	Content string `json:"content"`
 
+	// Span is the bounds of Content in the original input.
+	Span Span `json:"span"`
+
 	// Children contains this node's parsed descendants in source order.
 	Children []SerializableNode `json:"children"`
 
-	// ID is the numeric trigger byte of the tag.
+	// ID is the numeric trigger byte of the tag, or the ID of the block.
 	//
 	// It is 0 for nodes that are not backed by a concrete tag, such as the root.
 	ID byte `json:"id"`
@@ -88,6 +107,7 @@ func (ast AST) Serialize(d *Dictionary) (tree SerializableNode) {
 		Name:       "ROOT",
 		Type:       mapNodeTypeToName[NodeRoot],
 		Content:    ast.Input[root.Span.Start:root.Span.End],
+		Span:       root.Span,
 		Children:   allChildren[childrenUsed : childrenUsed+root.ChildCount],
 		ID:         root.TagID,
 		Attributes: rootAttrs,
@@ -116,9 +136,12 @@ func (ast AST) Serialize(d *Dictionary) (tree SerializableNode) {
 		serializeAttributes(&nodeAttrs, &ast, node.Attributes)
 
 		var name string
-		if node.Type == NodeTag {
+		switch node.Type {
+		case NodeTag:
 			name = d.tags[node.TagID].Name
-		} else {
+		case NodeBlock:
+			name = d.blocks[node.TagID-1].Name
+		default:
 			name = "TEXT"
 		}
 
@@ -126,6 +149,7 @@ func (ast AST) Serialize(d *Dictionary) (tree SerializableNode) {
 			Name:       name,
 			Type:       mapNodeTypeToName[node.Type],
 			Content:    ast.Input[node.Span.Start:node.Span.End],
+			Span:       node.Span,
 			Children:   children,
 			ID:         node.TagID,
 			Attributes: nodeAttrs,
@@ -176,15 +176,18 @@ func TestSerialize_TagAttributes(t *testing.T) {
 	require.Len(t, tagNode.Attributes, 2)
 
 	require.Equal(t, SerializableAttribute{
-		Name:    "lang",
-		Payload: "ru",
-		IsFlag:  false,
+		Name:        "lang",
+		Payload:     "ru",
+		IsFlag:      false,
+		NameSpan:    Span{9, 13},
+		PayloadSpan: Span{14, 16},
 	}, tagNode.Attributes[0])
 
 	require.Equal(t, SerializableAttribute{
-		Name:    "",
-		Payload: "featured",
-		IsFlag:  true,
+		Name:        "",
+		Payload:     "featured",
+		IsFlag:      true,
+		PayloadSpan: Span{19, 27},
 	}, tagNode.Attributes[1])
 }
 
@@ -207,14 +210,17 @@ func TestSerialize_TextAttributes(t *testing.T) {
 	require.Len(t, textNode.Attributes, 2)
 
 	require.Equal(t, SerializableAttribute{
-		Name:    "lang",
-		Payload: "en",
-		IsFlag:  false,
+		Name:        "lang",
+		Payload:     "en",
+		IsFlag:      false,
+		NameSpan:    Span{6, 10},
+		PayloadSpan: Span{11, 13},
 	}, textNode.Attributes[0])
 
 	require.Equal(t, SerializableAttribute{
-		Name:    "",
-		Payload: "plain",
-		IsFlag:  true,
+		Name:        "",
+		Payload:     "plain",
+		IsFlag:      true,
+		PayloadSpan: Span{16, 21},
 	}, textNode.Attributes[1])
 }
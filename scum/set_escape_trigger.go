@@ -6,7 +6,7 @@ import (
 
 // SetEscapeTrigger sets the char as an escape symbol. Escaping means
 // treating the next UTF-8 code point after the trigger as a plain text, whether
-// it is special or not.
+// it is special or not. The escape symbol can't start a [Block] marker.
 // NOTE: escape symbol can be only 1-byte long ASCII char.
 func (d *Dictionary) SetEscapeTrigger(char byte) error {
 	// if the char is not printable, abort with error
@@ -15,7 +15,7 @@ func (d *Dictionary) SetEscapeTrigger(char byte) error {
 	}
 
 	// if some action is already registered for this ID/char, abort with error
-	if d.actions[char] != nil {
+	if d.actions[char] != nil || d.blockLeads[char] {
 		return newDuplicateTagIDError(char)
 	}
 
@@ -53,11 +53,17 @@ func ActEscape(ac *ActionContext) (token Token, stride int, skip bool) {
 
 	nextByte := ac.Input[i+1]
 
-	// width of the next code-point
-	nextWidth := 1
+	// width of the next code-point, which is the whole multi-byte rune of a Tag
+	nextWidth := ac.Dictionary.specialWidth(ac.Input[i+1:])
+
+	// the first symbol of a Block marker can be escaped to keep the marker as plain text at the start of the line
+	if nextWidth == 0 {
+		nextWidth = ac.Dictionary.blockLeadWidth(ac.Input[i+1:])
+	}
 
 	// in this case we add a Warning of redundant escape
-	if ac.Dictionary.actions[nextByte] == nil {
+	if nextWidth == 0 {
+		nextWidth = 1
 
 		next := rune(nextByte)
 		ok := true
@@ -0,0 +1,412 @@
+package scum
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Spec is the declarative description of a [Dictionary]. It can be stored as JSON or YAML,
+// so dialects can ship as data files and be shared between the server and the frontend.
+//
+// Symbols are strings of exactly one printable ASCII character. The Tag sequences and the
+// Tag IDs can also contain multi-byte UTF-8 runes, like "«". In YAML, quote the
+// symbols which have a meaning in YAML itself, like '*', '[', '{', '!' or '`'.
+type Spec struct {
+	Tags      []TagSpec      `json:"tags" yaml:"tags"`
+	Attribute *AttributeSpec `json:"attribute,omitempty" yaml:"attribute,omitempty"`
+	Escape    string         `json:"escape,omitempty" yaml:"escape,omitempty"`
+	Blocks    []BlockSpec    `json:"blocks,omitempty" yaml:"blocks,omitempty"`
+	Limits    Limits         `json:"limits" yaml:"limits"`
+}
+
+// TagSpec describes a single [Tag], see [Dictionary.AddTag].
+type TagSpec struct {
+	Name string `json:"name" yaml:"name"`
+	// Seq is the Tag's string representation, at most [MaxTagLen] bytes long.
+	Seq string `json:"seq" yaml:"seq"`
+	// Greed is one of "non_greedy" (default), "greedy" or "grasping".
+	Greed string `json:"greed,omitempty" yaml:"greed,omitempty"`
+	// Rule is one of "none" (default), "intra_word" or "tag_vs_content".
+	Rule string `json:"rule,omitempty" yaml:"rule,omitempty"`
+	// OpenID is the ID of the opening Tag for closing and universal Tags,
+	// written as the first character of the opening Tag's sequence.
+	OpenID string `json:"open_id,omitempty" yaml:"open_id,omitempty"`
+	// CloseID is the ID of the closing Tag for opening and universal Tags,
+	// written as the first character of the closing Tag's sequence.
+	CloseID string `json:"close_id,omitempty" yaml:"close_id,omitempty"`
+	// Children are the IDs of the Tags allowed inside the Tag, see [ContentModel]. Empty allows any Tag.
+	Children []string `json:"children,omitempty" yaml:"children,omitempty"`
+	// ForbiddenAncestors are the IDs of the Tags which can't contain the Tag at any depth.
+	ForbiddenAncestors []string `json:"forbidden_ancestors,omitempty" yaml:"forbidden_ancestors,omitempty"`
+	// TextOnly forbids any Tags inside the Tag.
+	TextOnly bool `json:"text_only,omitempty" yaml:"text_only,omitempty"`
+}
+
+// BlockSpec describes a single [Block], see [Dictionary.AddBlock].
+type BlockSpec struct {
+	Name   string `json:"name" yaml:"name"`
+	Marker string `json:"marker" yaml:"marker"`
+	// Kind is one of "heading", "quote" or "list_item".
+	Kind string `json:"kind" yaml:"kind"`
+}
+
+// AttributeSpec describes the attribute signature, see [Dictionary.SetAttributeSignature].
+type AttributeSpec struct {
+	Trigger      string `json:"trigger" yaml:"trigger"`
+	PayloadStart string `json:"payload_start" yaml:"payload_start"`
+	PayloadEnd   string `json:"payload_end" yaml:"payload_end"`
+}
+
+var (
+	greedNames = [...]string{NonGreedy: "non_greedy", Greedy: "greedy", Grasping: "grasping"}
+	ruleNames  = [...]string{RuleNA: "none", RuleInfraWord: "intra_word", RuleTagVsContent: "tag_vs_content"}
+	blockNames = [...]string{BlockHeading: "heading", BlockQuote: "quote", BlockListItem: "list_item"}
+)
+
+// ParseSpec decodes a [Spec] from JSON or YAML. Unknown fields are rejected.
+// It returns [ConfigErrors] with a single [IssueInvalidSpec] if the data is malformed.
+func ParseSpec(data []byte) (Spec, error) {
+	var spec Spec
+
+	// YAML is a superset of JSON, so one decoder handles both
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+
+	if err := dec.Decode(&spec); err != nil {
+		var errs ConfigErrors
+		errs.add("", err)
+		return Spec{}, errs
+	}
+
+	return spec, nil
+}
+
+// LoadDictionary decodes the [Spec] from JSON or YAML and creates a [Dictionary] from it.
+func LoadDictionary(data []byte) (Dictionary, error) {
+	spec, err := ParseSpec(data)
+	if err != nil {
+		return Dictionary{}, err
+	}
+
+	return NewDictionaryFromSpec(spec)
+}
+
+// NewDictionaryFromSpec creates a [Dictionary] from the spec. Unlike the imperative setup, it doesn't
+// stop at the first problem: all of them are returned as [ConfigErrors], each with the path of the erroneous value.
+//
+// Tags are registered first, then their content models, the attribute signature, the escape symbol and the Blocks.
+func NewDictionaryFromSpec(spec Spec) (Dictionary, error) {
+	var errs ConfigErrors
+
+	limits := spec.Limits
+	for _, f := range limits.fields() {
+		if f.value < 0 {
+			errs.add("limits."+f.key, newNegativeLimitError(f))
+		}
+	}
+
+	// the negative limits are reported above, the dictionary is still built to collect the rest
+	if len(errs) > 0 {
+		limits = Limits{}
+	}
+
+	d, err := NewDictionary(limits)
+	if err != nil {
+		// this should not happen
+		panic(err.Error())
+	}
+
+	for i, ts := range spec.Tags {
+		path := fmt.Sprintf("tags[%d]", i)
+
+		greed, ok := lookupName(greedNames[:], ts.Greed, greedNames[NonGreedy])
+		if !ok {
+			errs.add(path+".greed", NewConfigError(IssueInvalidGreedLevel, fmt.Errorf("unknown greed %q", ts.Greed)))
+		}
+
+		rule, rok := lookupName(ruleNames[:], ts.Rule, ruleNames[RuleNA])
+		if !rok {
+			errs.add(path+".rule", NewConfigError(IssueInvalidRule, fmt.Errorf("unknown rule %q", ts.Rule)))
+		}
+
+		openID, oerr := tagSymbol(ts.OpenID)
+		if oerr != nil {
+			errs.add(path+".open_id", oerr)
+		}
+
+		closeID, cerr := tagSymbol(ts.CloseID)
+		if cerr != nil {
+			errs.add(path+".close_id", cerr)
+		}
+
+		nerr := checkTagName(ts.Name)
+		if nerr != nil {
+			errs.add(path+".name", nerr)
+		}
+
+		_, serr := NewTagSequence([]byte(ts.Seq))
+		if serr != nil {
+			errs.add(path+".seq", serr)
+		}
+
+		if !ok || !rok || oerr != nil || cerr != nil || nerr != nil || serr != nil {
+			continue
+		}
+
+		if err := d.AddTag(ts.Name, []byte(ts.Seq), Greed(greed), Rule(rule), openID, closeID); err != nil {
+			errs.add(path+tagErrorField(err), err)
+		}
+	}
+
+	// the content models can refer to any Tag, so they are set once all the Tags are registered
+	for i, ts := range spec.Tags {
+		if len(ts.Children) == 0 && len(ts.ForbiddenAncestors) == 0 && !ts.TextOnly {
+			continue
+		}
+
+		path := fmt.Sprintf("tags[%d]", i)
+		content := ContentModel{TextOnly: ts.TextOnly}
+		ok := tagSet(&d, &content.Children, ts.Children, path+".children", &errs)
+		ok = tagSet(&d, &content.ForbiddenAncestors, ts.ForbiddenAncestors, path+".forbidden_ancestors", &errs) && ok
+
+		id := TagID(ts.Seq)
+		if t, registered := d.Tag(id); !ok || !registered || t.Name != ts.Name {
+			// the Tag itself is invalid and is already reported
+			continue
+		}
+
+		if err := d.SetContentModel(id, content); err != nil {
+			errs.add(path, err)
+		}
+	}
+
+	if a := spec.Attribute; a != nil {
+		trigger, terr := symbol(a.Trigger)
+		if terr != nil {
+			errs.add("attribute.trigger", terr)
+		}
+
+		start, serr := symbol(a.PayloadStart)
+		if serr != nil {
+			errs.add("attribute.payload_start", serr)
+		}
+
+		end, eerr := symbol(a.PayloadEnd)
+		if eerr != nil {
+			errs.add("attribute.payload_end", eerr)
+		}
+
+		if terr == nil && serr == nil && eerr == nil {
+			if err := d.SetAttributeSignature(trigger, start, end); err != nil {
+				errs.add("attribute", err)
+			}
+		}
+	}
+
+	if spec.Escape != "" {
+		esc, err := symbol(spec.Escape)
+		if err == nil {
+			err = d.SetEscapeTrigger(esc)
+		}
+		if err != nil {
+			errs.add("escape", err)
+		}
+	}
+
+	for i, bs := range spec.Blocks {
+		path := fmt.Sprintf("blocks[%d]", i)
+
+		kind, ok := lookupName(blockNames[:], bs.Kind, "")
+		if !ok || kind == 0 {
+			errs.add(path+".kind", NewConfigError(IssueInvalidBlockKind, fmt.Errorf("unknown block kind %q", bs.Kind)))
+			continue
+		}
+
+		if err := d.AddBlock(bs.Name, []byte(bs.Marker), BlockKind(kind)); err != nil {
+			errs.add(path, err)
+		}
+	}
+
+	if err := errs.errOrNil(); err != nil {
+		return Dictionary{}, err
+	}
+
+	return d, nil
+}
+
+// Spec exports the [Dictionary] configuration. The Tags are listed in the order of their IDs,
+// and the Limits are the effective ones, with the defaults filled in.
+func (d *Dictionary) Spec() Spec {
+	spec := Spec{
+		Tags:   make([]TagSpec, 0),
+		Limits: d.Limits,
+	}
+
+	for _, t := range d.tags {
+		if t.Seq.Len == 0 {
+			continue
+		}
+
+		ts := TagSpec{
+			Name:    t.Name,
+			Seq:     string(t.Seq.Bytes[:t.Seq.Len]),
+			OpenID:  d.tagSymbolString(t.OpenID),
+			CloseID: d.tagSymbolString(t.CloseID),
+		}
+		if t.Greed != NonGreedy {
+			ts.Greed = greedNames[t.Greed]
+		}
+		if t.Rule != RuleNA {
+			ts.Rule = ruleNames[t.Rule]
+		}
+		ts.Children = d.tagSymbolStrings(&t.Content.Children)
+		ts.ForbiddenAncestors = d.tagSymbolStrings(&t.Content.ForbiddenAncestors)
+		ts.TextOnly = t.Content.TextOnly
+
+		spec.Tags = append(spec.Tags, ts)
+	}
+
+	if d.attrTrigger != 0 {
+		spec.Attribute = &AttributeSpec{
+			Trigger:      symbolString(d.attrTrigger),
+			PayloadStart: symbolString(d.attrPayloadStart),
+			PayloadEnd:   symbolString(d.attrPayloadEnd),
+		}
+	}
+
+	spec.Escape = symbolString(d.escapeTrigger)
+
+	for _, b := range d.blocks[:d.numBlocks] {
+		spec.Blocks = append(spec.Blocks, BlockSpec{
+			Name:   b.Name,
+			Marker: string(b.Marker.Bytes[:b.Marker.Len]),
+			Kind:   blockNames[b.Kind],
+		})
+	}
+
+	return spec
+}
+
+// lookupName returns the index of name in names, or of def if name is empty.
+func lookupName(names []string, name, def string) (int, bool) {
+	if name == "" {
+		name = def
+	}
+
+	for i, n := range names {
+		if n == name {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
+
+// symbol converts the spec's one-char string into a byte.
+func symbol(s string) (byte, error) {
+	if len(s) != 1 {
+		return 0, NewConfigError(IssueInvalidSpec, fmt.Errorf("expected exactly one ASCII character, got %q", s))
+	}
+
+	if !isASCIIPrintable(s[0]) {
+		return 0, newUnprintableError("symbol", s[0])
+	}
+
+	return s[0], nil
+}
+
+// tagSymbol converts the spec's Tag ID, which is either one ASCII character or one multi-byte rune,
+// into the ID, see [TagID]. It maps the empty string to 0.
+func tagSymbol(s string) (byte, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	if s[0] < utf8.RuneSelf {
+		return symbol(s)
+	}
+
+	r, w := utf8.DecodeRuneInString(s)
+	if w != len(s) || r == utf8.RuneError {
+		return 0, NewConfigError(IssueInvalidSpec, fmt.Errorf("expected exactly one character, got %q", s))
+	}
+
+	return TagID(s), nil
+}
+
+// tagSymbolString is the reverse of [tagSymbol]: the ID of a Tag starting with a multi-byte rune
+// is written as the rune.
+func (d *Dictionary) tagSymbolString(id byte) string {
+	if id < utf8.RuneSelf {
+		return symbolString(id)
+	}
+
+	seq := d.tags[id].Seq
+	_, w := utf8.DecodeRune(seq.Bytes[:seq.Len])
+
+	return string(seq.Bytes[:w])
+}
+
+// tagSet fills the set with the spec's Tag IDs. It reports each invalid or unknown ID and returns false if there are any.
+func tagSet(d *Dictionary, set *TagSet, symbols []string, path string, errs *ConfigErrors) bool {
+	ok := true
+	for j, s := range symbols {
+		id, err := tagSymbol(s)
+		if err == nil && id == 0 {
+			err = NewConfigError(IssueInvalidSpec, errors.New("expected exactly one character, got empty string"))
+		}
+		if err == nil {
+			if _, registered := d.Tag(id); !registered {
+				err = NewConfigError(IssueUnknownTagID, fmt.Errorf("tag with ID %q is not registered", s))
+			}
+		}
+		if err != nil {
+			errs.add(fmt.Sprintf("%s[%d]", path, j), err)
+			ok = false
+			continue
+		}
+		set.Add(id)
+	}
+	return ok
+}
+
+// tagSymbolStrings returns the spec's Tag IDs of the set, or nil if it is empty.
+func (d *Dictionary) tagSymbolStrings(set *TagSet) []string {
+	var symbols []string
+	for _, id := range set.IDs() {
+		symbols = append(symbols, d.tagSymbolString(id))
+	}
+	return symbols
+}
+
+func symbolString(b byte) string {
+	if b == 0 {
+		return ""
+	}
+	return string(b)
+}
+
+// tagErrorField points the error returned by [Dictionary.AddTag] at the responsible field of [TagSpec].
+func tagErrorField(err error) string {
+	var ce *ConfigError
+	if !errors.As(err, &ce) {
+		return ""
+	}
+
+	switch ce.Issue {
+	case IssueInvalidTagNameLen:
+		return ".name"
+	case IssueInvalidTagSeqLen, IssueUnprintableChar, IssueDuplicateTagID:
+		return ".seq"
+	case IssueInvalidGreedLevel:
+		return ".greed"
+	case IssueInvalidRule, IssueRuleInapplicable:
+		return ".rule"
+	default:
+		return ""
+	}
+}
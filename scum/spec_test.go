@@ -0,0 +1,183 @@
+package scum
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+const testSpecYAML = `
+tags:
+  - name: BOLD
+    seq: $
+    open_id: $
+    close_id: $
+  - name: UNDERLINE
+    seq: _
+    rule: intra_word
+    open_id: _
+    close_id: _
+  - name: CODE
+    seq: "` + "`" + `"
+    greed: greedy
+    rule: tag_vs_content
+    open_id: "` + "`" + `"
+    close_id: "` + "`" + `"
+  - name: LINK
+    seq: "["
+    close_id: "]"
+  - name: LINK
+    seq: "]"
+    open_id: "["
+  - name: SPOILER
+    seq: "||"
+    open_id: "|"
+    close_id: "|"
+attribute:
+  trigger: "!"
+  payload_start: "{"
+  payload_end: "}"
+escape: \
+limits:
+  max_payload_len: 64
+  max_nodes: 100
+`
+
+// imperativeTestDictionary builds the same dictionary as testSpecYAML with the imperative API.
+func imperativeTestDictionary(t *testing.T) Dictionary {
+	d, err := NewDictionary(Limits{MaxPayloadLen: 64, MaxNodes: 100})
+	require.NoError(t, err)
+
+	require.NoError(t, d.AddUniversalTag("BOLD", []byte("$"), NonGreedy, RuleNA))
+	require.NoError(t, d.AddUniversalTag("UNDERLINE", []byte("_"), NonGreedy, RuleInfraWord))
+	require.NoError(t, d.AddUniversalTag("CODE", []byte("`"), Greedy, RuleTagVsContent))
+	require.NoError(t, d.AddTag("LINK", []byte("["), NonGreedy, RuleNA, 0, ']'))
+	require.NoError(t, d.AddTag("LINK", []byte("]"), NonGreedy, RuleNA, '[', 0))
+	require.NoError(t, d.AddUniversalTag("SPOILER", []byte("||"), NonGreedy, RuleNA))
+	require.NoError(t, d.SetAttributeSignature('!', '{', '}'))
+	require.NoError(t, d.SetEscapeTrigger('\\'))
+
+	return d
+}
+
+func TestLoadDictionary_MatchesImperative(t *testing.T) {
+	loaded, err := LoadDictionary([]byte(testSpecYAML))
+	require.NoError(t, err)
+
+	built := imperativeTestDictionary(t)
+
+	require.Equal(t, built.Spec(), loaded.Spec())
+	require.Equal(t, built.Limits, loaded.Limits)
+
+	inputs := []string{
+		"$bold$ _under_line_ ```co`de``` [link]!href{https://x.y} ||spoiler|| \\$",
+		"[unclosed $ and `code",
+	}
+	for _, input := range inputs {
+		wantWarns := newWarns(t)
+		gotWarns := newWarns(t)
+
+		want := Parse(input, &built, &wantWarns)
+		got := Parse(input, &loaded, &gotWarns)
+
+		require.Equal(t, want.Serialize(&built), got.Serialize(&loaded), input)
+		require.Equal(t, wantWarns.List(), gotWarns.List(), input)
+	}
+}
+
+func TestDictionarySpec_RoundTrip(t *testing.T) {
+	d := imperativeTestDictionary(t)
+	spec := d.Spec()
+
+	// the defaults are exported along with the explicit limits
+	require.Equal(t, DefaultMaxKeyLen, spec.Limits.MaxKeyLen)
+	require.Equal(t, 64, spec.Limits.MaxPayloadLen)
+	require.Len(t, spec.Tags, 6)
+	require.Equal(t, `\`, spec.Escape)
+
+	t.Run("JSON", func(t *testing.T) {
+		data, err := json.Marshal(spec)
+		require.NoError(t, err)
+
+		loaded, err := LoadDictionary(data)
+		require.NoError(t, err)
+		require.Equal(t, spec, loaded.Spec())
+	})
+
+	t.Run("YAML", func(t *testing.T) {
+		data, err := yaml.Marshal(spec)
+		require.NoError(t, err)
+
+		loaded, err := LoadDictionary(data)
+		require.NoError(t, err)
+		require.Equal(t, spec, loaded.Spec())
+	})
+}
+
+func TestNewDictionaryFromSpec_AggregatesErrors(t *testing.T) {
+	spec := Spec{
+		Tags: []TagSpec{
+			{Name: "OK", Seq: "$", OpenID: "$", CloseID: "$"},
+			{Name: "", Seq: "toolong", Greed: "hungry"},
+			{Name: "DUP", Seq: "$", OpenID: "$", CloseID: "$"},
+			{Name: "BAD_RULE", Seq: "**", Rule: "intra_word", OpenID: "*", CloseID: "*"},
+			{Name: "BAD_ID", Seq: "[", CloseID: "]]"},
+		},
+		Attribute: &AttributeSpec{Trigger: "!", PayloadStart: "\x01", PayloadEnd: "}"},
+		Escape:    "$",
+		Limits:    Limits{MaxNodes: -1},
+	}
+
+	_, err := NewDictionaryFromSpec(spec)
+	require.Error(t, err)
+
+	var errs ConfigErrors
+	require.ErrorAs(t, err, &errs)
+
+	got := make(map[string]Issue, len(errs))
+	for _, e := range errs {
+		got[e.Path] = e.Issue
+	}
+
+	require.Equal(t, map[string]Issue{
+		"limits.max_nodes":        IssueNegativeLimit,
+		"tags[1].greed":           IssueInvalidGreedLevel,
+		"tags[1].name":            IssueInvalidTagNameLen,
+		"tags[1].seq":             IssueInvalidTagSeqLen,
+		"tags[2].seq":             IssueDuplicateTagID,
+		"tags[3].rule":            IssueRuleInapplicable,
+		"tags[4].close_id":        IssueInvalidSpec,
+		"attribute.payload_start": IssueUnprintableChar,
+		"escape":                  IssueDuplicateTagID,
+	}, got)
+
+	// single errors are still reachable with errors.As
+	var ce *ConfigError
+	require.ErrorAs(t, err, &ce)
+	require.Equal(t, "limits.max_nodes", ce.Path)
+	require.Contains(t, err.Error(), "tags[2].seq: ")
+}
+
+func TestParseSpec_Malformed(t *testing.T) {
+	testCases := []struct {
+		name string
+		data string
+	}{
+		{"Syntax", `{"tags": [`},
+		{"UnknownField", `{"tags": [], "colour": "red"}`},
+		{"WrongType", `{"tags": {"name": "BOLD"}}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := LoadDictionary([]byte(tc.data))
+			require.Error(t, err)
+
+			var ce *ConfigError
+			require.ErrorAs(t, err, &ce)
+			require.Equal(t, IssueInvalidSpec, ce.Issue)
+		})
+	}
+}
@@ -8,10 +8,13 @@ import (
 // StepInfraWordCheck checks if the current char is a real [Tag], according to the [RuleInfraWord].
 // If the char is a real Tag, it returns false and allows the next steps to handle the Tag properly.
 // If the char is considered a plain text, it returns true and sets Skip to true.
+//
+// The Tag's char is either a single ASCII byte or a single multi-byte rune.
 func StepInfraWordCheck(ctx *ActionContext) bool {
 	leftIsWordPart := false
 
 	i := ctx.Idx
+	w := int(ctx.Tag.Seq.Len)
 
 	// if the current index is not at the beginning of the string
 	if i > 0 {
@@ -19,37 +22,33 @@ func StepInfraWordCheck(ctx *ActionContext) bool {
 		b := ctx.Input[i-1]
 
 		// if the previous byte is ASCII char, check the byte directly
-		if b < 128 {
+		if b < utf8.RuneSelf {
 			leftIsWordPart = b == ctx.Tag.ID() ||
 				(!ctx.Dictionary.IsSpecial(b) && (isASCIIAlphanum(b) || isASCIIPunct(b)))
 		} else {
 			// else, decode the previous UTF-8 code point
-			prev, _ := utf8.DecodeLastRuneInString(ctx.Input[:i])
+			prev, pw := utf8.DecodeLastRuneInString(ctx.Input[:i])
 
-			leftIsWordPart = unicode.IsLetter(prev) || // is a letter or
-				unicode.IsNumber(prev) || // a number or
-				unicode.IsPunct(prev) // a punctuation
+			leftIsWordPart = isRuneWordPart(ctx, prev, ctx.Input[i-pw:])
 		}
 	}
 
 	rightIsWordPart := false
 
 	// if the char is not the last in the input
-	if i+1 < len(ctx.Input) {
+	if i+w < len(ctx.Input) {
 		// extracting the next byte
-		b := ctx.Input[i+1]
+		b := ctx.Input[i+w]
 
 		// if the next byte is ASCII char, check the byte directly
-		if b < 128 {
+		if b < utf8.RuneSelf {
 			rightIsWordPart = b == ctx.Tag.ID() ||
 				(!ctx.Dictionary.IsSpecial(b) && (isASCIIAlphanum(b) || isASCIIPunct(b)))
 		} else {
 			// else, decode the next UTF-8 code point
-			next, _ := utf8.DecodeRuneInString(ctx.Input[i+1:])
+			next, _ := utf8.DecodeRuneInString(ctx.Input[i+w:])
 
-			rightIsWordPart = unicode.IsLetter(next) || // is a letter or
-				unicode.IsNumber(next) || // a number or
-				unicode.IsPunct(next) // a punctuation
+			rightIsWordPart = isRuneWordPart(ctx, next, ctx.Input[i+w:])
 		}
 	}
 
@@ -58,8 +57,22 @@ func StepInfraWordCheck(ctx *ActionContext) bool {
 		return false
 	}
 
-	// else return true, set Skip to true and Stride to 1
-	ctx.Stride = 1
+	// else return true, set Skip to true and Stride to the width of the char
+	ctx.Stride = w
 	ctx.Skip = true
 	return true
 }
+
+// isRuneWordPart reports whether the multi-byte rune r, found at the start of s, is a part of the word
+// for the [RuleInfraWord]: the Tag's own rune, or a letter, a number or a punctuation which does not start another Tag.
+func isRuneWordPart(ctx *ActionContext, r rune, s string) bool {
+	seq := ctx.Tag.Seq.Bytes[:ctx.Tag.Seq.Len]
+	if len(s) >= len(seq) && s[:len(seq)] == string(seq) {
+		return true
+	}
+
+	return ctx.Dictionary.specialWidth(s) == 0 &&
+		(unicode.IsLetter(r) || // is a letter or
+			unicode.IsNumber(r) || // a number or
+			unicode.IsPunct(r)) // a punctuation
+}
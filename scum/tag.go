@@ -18,9 +18,11 @@ type Tag struct {
 	Greed Greed
 
 	// Seq defines the sequence of bytes from which the Tag's string consists.
+	// It can contain multi-byte UTF-8 runes.
 	Seq TagSequence
 
-	// Rule defines optional behaviour for the universal single-byte Tags during the tokenization process.
+	// Rule defines optional behaviour for the universal single-char Tags during the tokenization process.
+	// A single multi-byte rune counts as a single char, but can't have the Tag-VS-Content rule.
 	//
 	// Possible values are 0, 1 and 2.
 	//
@@ -52,9 +54,14 @@ type Tag struct {
 	// Set both OpenID and CloseID to the ID of this Tag to make it universal.
 	// WARNING: You have to set at least one of OpenID or CloseID for the Parser to consider the Tag valid.
 	CloseID byte
+
+	// Content restricts the nesting of the Tag and the Tags inside it. The zero value allows any nesting.
+	// Only the opening Tag's model is used, see [ContentModel].
+	Content ContentModel
 }
 
-// ID returns the unique byte value used as the opening byte for the Tag's sequence.
+// ID returns the unique byte value identifying the Tag. It is the opening byte of an ASCII Tag's sequence,
+// see [TagID] for the Tags starting with a multi-byte rune.
 func (t *Tag) ID() byte {
 	return t.Seq.ID()
 }
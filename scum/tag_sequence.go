@@ -2,6 +2,8 @@ package scum
 
 import (
 	"fmt"
+	"unicode"
+	"unicode/utf8"
 )
 
 // TagSequence maintains the Tag's string representation and its length.
@@ -10,8 +12,16 @@ type TagSequence struct {
 	Len   uint8
 }
 
-// ID returns the first byte of the Tag's byte sequence.
-func (seq TagSequence) ID() byte { return seq.Bytes[0] }
+// ID returns the ID of the Tag with this sequence, see [TagID].
+func (seq TagSequence) ID() byte {
+	// the ASCII fast path
+	if b := seq.Bytes[0]; b < utf8.RuneSelf {
+		return b
+	}
+
+	_, w := utf8.DecodeRune(seq.Bytes[:seq.Len])
+	return seq.Bytes[w-1]
+}
 
 // IsContainedIn checks if src contains TagSequence. It returns true when contained,
 // plus the start index and length of the sequence part that was found.
@@ -19,7 +29,35 @@ func (seq TagSequence) IsContainedIn(src string) (contained bool, startIdx, leng
 	return longestCommonSubPrefix(src, seq.Bytes[:seq.Len])
 }
 
+// isSingleChar returns true if the sequence consists of a single ASCII character or a single multi-byte rune.
+func (seq TagSequence) isSingleChar() bool {
+	return seq.Len == 1 || utf8.RuneCount(seq.Bytes[:seq.Len]) == 1
+}
+
+// TagID returns the ID of the Tag with the sequence seq, or 0 if seq is empty or is not valid UTF-8.
+//
+// The ID of a sequence starting with an ASCII character is that character. The ID of a sequence
+// starting with a multi-byte rune is the last byte of the rune, which is a UTF-8 continuation byte
+// and never clashes with the ASCII IDs. For example, TagID("»") can be used as the CloseID of the Tag "«".
+func TagID(seq string) byte {
+	if seq == "" {
+		return 0
+	}
+
+	if b := seq[0]; b < utf8.RuneSelf {
+		return b
+	}
+
+	r, w := utf8.DecodeRuneInString(seq)
+	if r == utf8.RuneError {
+		return 0
+	}
+
+	return seq[w-1]
+}
+
 // NewTagSequence creates a [TagSequence] from the provided byte sequence and possibly returns a [ConfigError].
+// The sequence can contain printable ASCII characters and printable multi-byte UTF-8 runes.
 func NewTagSequence(src []byte) (TagSequence, error) {
 	n := len(src)
 
@@ -37,14 +75,30 @@ func NewTagSequence(src []byte) (TagSequence, error) {
 
 	var ts TagSequence
 
-	for i, b := range src {
-		// check if the series contains unprintable characters
-		if !isASCIIPrintable(b) {
+	for i := 0; i < n; {
+		b := src[i]
+
+		if b < utf8.RuneSelf {
+			// check if the series contains unprintable characters
+			if !isASCIIPrintable(b) {
+				return TagSequence{}, NewConfigError(IssueUnprintableChar,
+					fmt.Errorf("provided Tag byte sequence has unprintable character %q at index %d.", b, i))
+			}
+
+			ts.Bytes[i] = b
+			i++
+			continue
+		}
+
+		// the multi-byte runes must be valid, visible and not spaces
+		r, w := utf8.DecodeRune(src[i:])
+		if r == utf8.RuneError || !unicode.IsGraphic(r) || unicode.IsSpace(r) {
 			return TagSequence{}, NewConfigError(IssueUnprintableChar,
-				fmt.Errorf("provided Tag byte sequence has unprintable character %q at index %d.", b, i))
+				fmt.Errorf("provided Tag byte sequence has unprintable or invalid UTF-8 character %q at index %d.", src[i:i+w], i))
 		}
 
-		ts.Bytes[i] = b
+		copy(ts.Bytes[i:], src[i:i+w])
+		i += w
 	}
 
 	ts.Len = uint8(n)
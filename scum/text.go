@@ -1,6 +1,9 @@
 package scum
 
-import "strings"
+import (
+	"io"
+	"strings"
+)
 
 // Text returns all text node content concatenated in source order.
 //
@@ -18,3 +21,37 @@ func (ast AST) Text() string {
 	}
 	return b.String()
 }
+
+// WriteText writes the text of the input read from r to w, reading it with a [TokenStream], so the memory
+// doesn't grow with the input. It is meant for the bulk jobs, like indexing the stored posts for search.
+// It returns the number of bytes written and the first error of r or w.
+//
+// The text is the same as [AST.Text] of the parsed input, except that the Tags the parser turns into text, like a closing
+// Tag without its opening Tag or a Tag not allowed by a [ContentModel], are dropped, the [Limits] of the tree are not applied,
+// and an unclosed [Grasping] Tag with the payload longer than [Limits.MaxPayloadLen] is written as text, see [TokenStream].
+// d and warns must be non-nil.
+func WriteText(w io.Writer, r io.Reader, d *Dictionary, warns *Warnings) (int64, error) {
+	s := NewTokenStream(r, d, warns)
+
+	var written int64
+	var err error
+
+	for t := range s.Tokens() {
+		if t.Type == TokenTag && d.tags[t.Trigger].Greed == NonGreedy || t.Type > TokenTag {
+			continue
+		}
+
+		if t.Payload.End == t.Payload.Start {
+			continue
+		}
+
+		var n int
+		n, err = io.WriteString(w, s.Source(t.Payload))
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+
+	return written, s.Err()
+}
@@ -3,10 +3,10 @@ package scum
 // Span defines bounds of the window view of a string.
 type Span struct {
 	// Start defines the inclusive start of the view.
-	Start int
+	Start int `json:"start"`
 
 	// End defines the exclusive end of the view.
-	End int
+	End int `json:"end"`
 }
 
 // NewSpan creates new Span from the startIdx and the width.
@@ -22,10 +22,11 @@ type Token struct {
 	Type TokenType
 
 	// Trigger is the leading special byte that started this Token.
-	// It is always a 1-byte printable ASCII character from the input that matched a registered Action.
+	// It is a 1-byte printable ASCII character from the input that matched a registered Action, or the ID of the Tag
+	// starting with a multi-byte rune, see [TagID].
 	//
 	// Examples:
-	//   - for a Tag token, Trigger is the first byte of the tag sequence (its ID).
+	//   - for a Tag token, Trigger is the ID of the Tag, which is the first byte of an ASCII tag sequence.
 	//   - for an Attribute token, Trigger is the attribute signature symbol.
 	Trigger byte
 
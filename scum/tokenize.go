@@ -34,8 +34,15 @@ type TokenizerState struct {
 // It can emit Warnings during the process.
 // warns must be non-nil.
 func Tokenize(d *Dictionary, input string, warns *Warnings) (out TokenizerOutput) {
+	out.Tokens = make([]Token, 0, len(input)/(ByteToTokenRatio+1))
+	tokenizeFrom(&out, d, input, 0, warns)
+	return
+}
+
+// tokenizeFrom appends the Tokens of the input, starting at the offset, to out and adds up its counters.
+// The Actions can still read the input before the offset, but not after its end.
+func tokenizeFrom(out *TokenizerOutput, d *Dictionary, input string, offset int, warns *Warnings) {
 	n := len(input)
-	out.Tokens = make([]Token, 0, n/(ByteToTokenRatio+1))
 
 	var s TokenizerState
 
@@ -47,9 +54,9 @@ func Tokenize(d *Dictionary, input string, warns *Warnings) (out TokenizerOutput
 	}
 
 	// the place where current plain string started
-	textStart := 0
+	textStart := offset
 
-	for i := 0; i < n; {
+	for i := offset; i < n; {
 
 		b := input[i]
 
@@ -123,11 +130,9 @@ func Tokenize(d *Dictionary, input string, warns *Warnings) (out TokenizerOutput
 		out.TextByteLen += n - textStart
 	}
 
-	out.TagsTotal = s.TagsTotal
-	out.UniversalTags = s.UniversalTags
-	out.OpenTags = s.OpenTags
-	out.CloseTags = s.CloseTags
-	out.Attributes = s.Attributes
-
-	return
+	out.TagsTotal += s.TagsTotal
+	out.UniversalTags += s.UniversalTags
+	out.OpenTags += s.OpenTags
+	out.CloseTags += s.CloseTags
+	out.Attributes += s.Attributes
 }
@@ -0,0 +1,231 @@
+package scum
+
+import (
+	"errors"
+	"io"
+	"iter"
+	"unicode/utf8"
+)
+
+// DefaultStreamChunk is the default number of bytes a [TokenStream] reads from its reader at once.
+const DefaultStreamChunk = 32 * 1024
+
+// TokenStream tokenizes the input read from an [io.Reader] in a bounded window, so the input doesn't have to be
+// kept in memory as a whole. The Tokens are the same as [Tokenize] returns for the whole input, with the positions
+// in the whole input, except that a long text run can be split into several consecutive text Tokens.
+//
+// The window keeps the bytes an Action can read around its position, which is bounded by the scanning [Limits],
+// so the greedy Tags and the attributes spanning the reads are tokenized as usual. The only exception is an
+// unclosed [Grasping] Tag, which takes the rest of the input as its payload: the stream doesn't read the rest
+// past [Limits.MaxPayloadLen], so the opening Tag with a longer payload is read as text with [IssueTagPayloadTooLong].
+//
+// Like [Tokenize], the stream knows nothing about the line mode: the [Block] markers are read as text.
+type TokenStream struct {
+	r     io.Reader
+	d     *Dictionary
+	warns *Warnings
+
+	// chunk is the number of bytes read at once, buf is reused for the reads.
+	chunk int
+	buf   []byte
+
+	// win is the window of the input, starting at the offset base.
+	win  string
+	base int
+
+	eof bool
+	err error
+
+	// scratch collects the Warnings of an Action until its Token is accepted, see [TokenStream.act].
+	scratch Warnings
+	state   TokenizerState
+	ac      ActionContext
+}
+
+// NewTokenStream creates a [TokenStream] reading from r. d and warns must be non-nil.
+func NewTokenStream(r io.Reader, d *Dictionary, warns *Warnings) *TokenStream {
+	s := &TokenStream{
+		r:       r,
+		d:       d,
+		warns:   warns,
+		chunk:   DefaultStreamChunk,
+		scratch: Warnings{policy: WarnOverflowNoCap},
+	}
+
+	s.ac = ActionContext{
+		Dictionary: d,
+		State:      &s.state,
+		Warns:      &s.scratch,
+	}
+
+	return s
+}
+
+// Tokens returns the iterator over the Tokens of the input. The iteration stops early if reading fails, see [TokenStream.Err].
+// The stream can be iterated only once.
+func (s *TokenStream) Tokens() iter.Seq[Token] {
+	return func(yield func(Token) bool) {
+		// the bytes an Action can read after and before its position
+		ahead := s.d.Limits.lookahead() + s.d.Limits.MaxKeyLen
+		behind := lookaheadSlack
+
+		i, textStart := 0, 0
+
+		for {
+			// the Actions starting closer than ahead to the end of the window wait for the next read
+			limit := len(s.win)
+			if !s.eof {
+				limit -= ahead
+			}
+
+			for i < limit {
+				act, isSpecial := s.d.Action(s.win[i])
+				if !isSpecial {
+					i++
+					continue
+				}
+
+				token, stride, skip := s.act(act, i)
+				if skip {
+					i += stride
+					continue
+				}
+
+				if i > textStart && !s.yieldText(yield, textStart, i) {
+					return
+				}
+
+				i += stride
+				textStart = i
+
+				if !yield(s.shift(token)) {
+					return
+				}
+			}
+
+			if s.eof {
+				if len(s.win) > textStart {
+					s.yieldText(yield, textStart, len(s.win))
+				}
+				return
+			}
+
+			// the long text is emitted in parts, split at a rune boundary, so the window doesn't grow with it
+			if cut := runeStart(s.win, i-behind); cut-textStart > s.chunk {
+				if !s.yieldText(yield, textStart, cut) {
+					return
+				}
+				textStart = cut
+			}
+
+			// dropping the bytes no Action can read anymore, apart from the pending text
+			keep := max(min(textStart, i-behind), 0)
+			s.win = s.win[keep:]
+			s.base += keep
+			i -= keep
+			textStart -= keep
+
+			s.fill(s.chunk)
+			if s.err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Err returns the first error returned by the reader, other than [io.EOF].
+func (s *TokenStream) Err() error {
+	return s.err
+}
+
+// Source returns the input inside the span, given in the positions of the whole input. It is valid for the spans
+// of the Token being yielded by [TokenStream.Tokens], and panics for the bytes already dropped from the window.
+func (s *TokenStream) Source(span Span) string {
+	return s.win[span.Start-s.base : span.End-s.base]
+}
+
+// act runs the Action at the window position i and adds its Warnings.
+func (s *TokenStream) act(act Action, i int) (token Token, stride int, skip bool) {
+	s.ac.Input = s.win
+	s.ac.Reset(s.win[i], i)
+	token, stride, skip = act(&s.ac)
+
+	// the unclosed Grasping Tag takes the rest of the input, which is only read up to the payload limit ahead of i,
+	// so the opening of the Tag past the limit is read as text
+	if !skip && s.graspsPastLimit() {
+		s.scratch.list = append(s.scratch.list[:0], Warning{Issue: IssueTagPayloadTooLong, Pos: i})
+		token, stride, skip = Token{}, s.ac.Bounds.Width, true
+	}
+
+	for _, w := range s.scratch.list {
+		w.Pos += s.base
+		s.warns.Add(w)
+	}
+	s.scratch.list = s.scratch.list[:0]
+
+	return token, stride, skip
+}
+
+// graspsPastLimit reports whether the Action has emitted an unclosed [Grasping] Tag with the payload longer than [Limits.MaxPayloadLen].
+func (s *TokenStream) graspsPastLimit() bool {
+	b := &s.ac.Bounds
+	if s.ac.Tag.Greed != Grasping || s.ac.Tag.IsClosing() || b.Closed {
+		return false
+	}
+	return b.PayloadLimitReached || b.Inner.End-b.Inner.Start > s.d.Limits.MaxPayloadLen
+}
+
+// yieldText yields the text between the window positions.
+func (s *TokenStream) yieldText(yield func(Token) bool, start, end int) bool {
+	return yield(Token{
+		Type:    TokenText,
+		Pos:     s.base + start,
+		Width:   end - start,
+		Payload: Span{s.base + start, s.base + end},
+	})
+}
+
+// shift moves the Token from the window positions to the positions of the whole input.
+func (s *TokenStream) shift(t Token) Token {
+	t.Pos += s.base
+	t.Payload.Start += s.base
+	t.Payload.End += s.base
+
+	if t.Type == TokenAttributeKV {
+		t.AttrKey.Start += s.base
+		t.AttrKey.End += s.base
+	}
+
+	return t
+}
+
+// fill appends n bytes of the input to the window, or the rest of the input if it's shorter.
+func (s *TokenStream) fill(n int) {
+	if cap(s.buf) < n {
+		s.buf = make([]byte, n)
+	}
+	n, err := io.ReadFull(s.r, s.buf[:n])
+	s.win += string(s.buf[:n])
+
+	switch {
+	case err == nil:
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		s.eof = true
+	default:
+		s.err = err
+	}
+}
+
+// runeStart returns the start of the UTF-8 code point containing the byte at i, or 0 if i is negative.
+func runeStart(s string, i int) int {
+	if i <= 0 {
+		return 0
+	}
+
+	j := i
+	for j > 0 && j > i-utf8.UTFMax && !utf8.RuneStart(s[j]) {
+		j--
+	}
+
+	return j
+}
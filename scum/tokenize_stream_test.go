@@ -0,0 +1,215 @@
+package scum
+
+import (
+	"errors"
+	"io"
+	"math/rand/v2"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+)
+
+// streamLimits make the window of the stream small, so the Tags often span the reads.
+var streamLimits = Limits{MaxAttrKeyLen: 8, MaxAttrPayloadLen: 8, MaxPayloadLen: 16, MaxKeyLen: 4}
+
+// randomStreamInput returns random input with the long plain text runs and greedy payloads.
+func randomStreamInput(rng *rand.Rand, pieces int) string {
+	var b strings.Builder
+	for range pieces {
+		switch rng.IntN(10) {
+		case 0:
+			b.WriteString(strings.Repeat("long é text ", rng.IntN(20)))
+		case 1:
+			b.WriteString("`" + strings.Repeat("c", rng.IntN(24)) + "`")
+		default:
+			b.WriteString(formatAlphabet[rng.IntN(len(formatAlphabet))])
+		}
+	}
+
+	return b.String()
+}
+
+// streamTokens collects the Tokens of the stream, merging the adjacent text Tokens like [Tokenize] emits them.
+func streamTokens(t *testing.T, r io.Reader, d *Dictionary, chunk int, warns *Warnings) []Token {
+	t.Helper()
+
+	s := NewTokenStream(r, d, warns)
+	s.chunk = chunk
+
+	tokens := make([]Token, 0)
+	for tok := range s.Tokens() {
+		require.Equal(t, tok.Width, len(s.Source(NewSpan(tok.Pos, tok.Width))))
+
+		if last := len(tokens) - 1; last >= 0 && tok.Type == TokenText && tokens[last].Type == TokenText {
+			require.Equal(t, tokens[last].Pos+tokens[last].Width, tok.Pos)
+			tokens[last].Width += tok.Width
+			tokens[last].Payload.End = tok.Payload.End
+			continue
+		}
+
+		tokens = append(tokens, tok)
+	}
+	require.NoError(t, s.Err())
+
+	return tokens
+}
+
+func TestTokenStream_MatchesTokenize(t *testing.T) {
+	d := testDictWithLimits(t, streamLimits)
+	require.NoError(t, d.AddUniversalTag("SPOILER", []byte("|"), Grasping, RuleNA))
+
+	rng := rand.New(rand.NewPCG(40, 0))
+
+	for range 1000 {
+		input := randomStreamInput(rng, rng.IntN(60))
+		if rng.IntN(4) == 0 {
+			// the unclosed grasping Tag takes the rest of the input
+			input += "|" + randomStreamInput(rng, 30)
+		}
+
+		w1 := newWarns(t)
+		want := Tokenize(&d, input, &w1).Tokens
+
+		// the stream reads the unclosed grasping Tag past the payload limit as text,
+		// so the stream reading the whole input at once is the reference
+		if last := len(want) - 1; last >= 0 && want[last].Trigger == '|' && want[last].Payload.End-want[last].Payload.Start > streamLimits.MaxPayloadLen {
+			w1 = newWarns(t)
+			want = streamTokens(t, strings.NewReader(input), &d, len(input)+1, &w1)
+		}
+
+		chunk := 1 + rng.IntN(64)
+
+		var r io.Reader = strings.NewReader(input)
+		if rng.IntN(2) == 0 {
+			r = iotest.OneByteReader(r)
+		}
+
+		w2 := newWarns(t)
+		got := streamTokens(t, r, &d, chunk, &w2)
+
+		require.Equal(t, want, got, "input: %q, chunk: %d", input, chunk)
+		require.Equal(t, w1.List(), w2.List(), "input: %q, chunk: %d", input, chunk)
+	}
+}
+
+func TestTokenStream_SplitsLongText(t *testing.T) {
+	d := testDict(t)
+	input := strings.Repeat("😀 plain text ", 10000) + "*end*"
+
+	s := NewTokenStream(strings.NewReader(input), &d, &Warnings{})
+	s.chunk = 1024
+	window := 2*s.chunk + d.Limits.lookahead() + d.Limits.MaxKeyLen
+
+	var text strings.Builder
+	var texts int
+	for tok := range s.Tokens() {
+		if tok.Type == TokenText {
+			texts++
+			text.WriteString(s.Source(tok.Payload))
+			require.LessOrEqual(t, tok.Width, window)
+		}
+	}
+
+	require.NoError(t, s.Err())
+	require.Greater(t, texts, 10)
+	require.Equal(t, strings.Repeat("😀 plain text ", 10000)+"end", text.String())
+	require.LessOrEqual(t, len(s.win), window)
+}
+
+// failingReader returns the input, failing once n bytes of it are read.
+type failingReader struct {
+	r   io.Reader
+	n   int
+	err error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.n <= 0 {
+		return 0, r.err
+	}
+	n, err := r.r.Read(p[:min(len(p), r.n)])
+	r.n -= n
+	return n, err
+}
+
+func TestTokenStream_UnclosedGraspingTagPastLimit(t *testing.T) {
+	d := testDictWithLimits(t, streamLimits)
+	require.NoError(t, d.AddUniversalTag("SPOILER", []byte("|"), Grasping, RuleNA))
+
+	errRead := errors.New("read failed")
+	input := "a |b *c* " + strings.Repeat("x", 10_000)
+	r := &failingReader{r: strings.NewReader(input), n: 1024, err: errRead}
+
+	warns := newWarns(t)
+	s := NewTokenStream(r, &d, &warns)
+	s.chunk = 64
+
+	var tags []Token
+	for tok := range s.Tokens() {
+		if tok.Type == TokenTag {
+			tags = append(tags, tok)
+		}
+	}
+
+	// the Tag is read as text before the reader fails, without reading the rest of the input
+	require.ErrorIs(t, s.Err(), errRead)
+	require.Len(t, tags, 2)
+	require.Equal(t, byte('*'), tags[0].Trigger)
+	require.Equal(t, 5, tags[0].Pos)
+	require.Equal(t, []Warning{{Issue: IssueTagPayloadTooLong, Pos: 2}}, warns.List())
+
+	// the payload which fits the limit at the end of the input is taken as usual
+	w1, w2 := newWarns(t), newWarns(t)
+	tokens := streamTokens(t, strings.NewReader("a |b *c*"), &d, 2, &w2)
+	require.Equal(t, Tokenize(&d, "a |b *c*", &w1).Tokens, tokens)
+	require.Equal(t, NewSpan(3, 5), tokens[len(tokens)-1].Payload)
+}
+
+func TestTokenStream_ReadError(t *testing.T) {
+	d := testDict(t)
+	errRead := errors.New("read failed")
+	r := io.MultiReader(strings.NewReader("*a* b"), iotest.ErrReader(errRead))
+
+	s := NewTokenStream(r, &d, &Warnings{})
+	for range s.Tokens() {
+	}
+	require.ErrorIs(t, s.Err(), errRead)
+
+	var b strings.Builder
+	_, err := WriteText(&b, io.MultiReader(strings.NewReader("*a* b"), iotest.ErrReader(errRead)), &d, &Warnings{})
+	require.ErrorIs(t, err, errRead)
+}
+
+func TestWriteText(t *testing.T) {
+	d := testDictWithLimits(t, streamLimits)
+	rng := rand.New(rand.NewPCG(40, 1))
+
+	for range 500 {
+		input := randomStreamInput(rng, rng.IntN(60))
+
+		// the text of the Tokens the parser keeps as text
+		var want strings.Builder
+		for _, tok := range Tokenize(&d, input, &Warnings{}).Tokens {
+			if tok.Type == TokenText || tok.Type == TokenTag && d.tags[tok.Trigger].Greed > NonGreedy {
+				want.WriteString(input[tok.Payload.Start:tok.Payload.End])
+			}
+		}
+
+		var got strings.Builder
+		n, err := WriteText(&got, iotest.HalfReader(strings.NewReader(input)), &d, &Warnings{})
+		require.NoError(t, err)
+		require.Equal(t, want.String(), got.String(), input)
+		require.Equal(t, int64(got.Len()), n)
+	}
+
+	// without the Tags turned into text by the parser, the text is the same as of the tree
+	input := "Hello *world* this is $$bold `code`$$ and [link text]!href{x}"
+	var got strings.Builder
+	_, err := WriteText(&got, strings.NewReader(input), &d, &Warnings{})
+	require.NoError(t, err)
+
+	w := newWarns(t)
+	require.Equal(t, Parse(input, &d, &w).Text(), got.String())
+}
@@ -52,7 +52,11 @@ func requireTokenizeInvariants(t *testing.T, inp string, out TokenizerOutput, d
 }
 
 func testDict(t *testing.T) Dictionary {
-	d, err := NewDictionary(Limits{})
+	return testDictWithLimits(t, Limits{})
+}
+
+func testDictWithLimits(t *testing.T, limits Limits) Dictionary {
+	d, err := NewDictionary(limits)
 	require.NoError(t, err)
 
 	err = d.AddUniversalTag("BOLD", []byte("$$"), NonGreedy, RuleNA)
@@ -0,0 +1,307 @@
+package scum
+
+import (
+	"math/rand/v2"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+)
+
+// initUTF8Dict registers a set of tags with multi-byte runes along with the ASCII ones.
+//
+// "§§" : universal, non-greedy, two 2-byte runes
+// "«"  : opening tag, closed by "»"
+// "»"  : closing tag
+// "·"  : universal, non-greedy, infra-word rule
+// "‹"  : opening greedy tag, closed by "›", 3-byte runes
+// "*"  : universal, non-greedy ASCII tag
+// '\\' : escape symbol
+func initUTF8Dict(t *testing.T) *Dictionary {
+	t.Helper()
+
+	d, err := NewDictionary(Limits{})
+	require.NoError(t, err)
+	dp := &d
+
+	mustAddTag(t, dp, "SECTION", "§§", NonGreedy, RuleNA, TagID("§"), TagID("§"))
+	mustAddTag(t, dp, "QUOTE", "«", NonGreedy, RuleNA, 0, TagID("»"))
+	mustAddTag(t, dp, "QUOTE_END", "»", NonGreedy, RuleNA, TagID("«"), 0)
+	require.NoError(t, d.AddUniversalTag("MARK", []byte("·"), NonGreedy, RuleInfraWord))
+	mustAddTag(t, dp, "KBD", "‹", Greedy, RuleNA, 0, TagID("›"))
+	mustAddTag(t, dp, "KBD_END", "›", NonGreedy, RuleNA, TagID("‹"), 0)
+	require.NoError(t, d.AddUniversalTag("ITALIC", []byte("*"), NonGreedy, RuleNA))
+	require.NoError(t, d.SetEscapeTrigger('\\'))
+
+	return dp
+}
+
+// tagRaws returns the raw values of the tag tokens.
+func tagRaws(in string, toks []Token) []string {
+	var raws []string
+	for _, tok := range toks {
+		if tok.Type == TokenTag {
+			raws = append(raws, in[tok.Pos:tok.Pos+tok.Width])
+		}
+	}
+	return raws
+}
+
+func TestTagID(t *testing.T) {
+	require.Equal(t, byte('*'), TagID("*"))
+	require.Equal(t, byte('$'), TagID("$$"))
+	require.Equal(t, byte(0xAB), TagID("«"))
+	require.Equal(t, byte(0xA7), TagID("§§"))
+	require.Equal(t, byte(0xB9), TagID("‹"))
+	require.Equal(t, byte(0), TagID(""))
+	require.Equal(t, byte(0), TagID("\xc2"))
+
+	s, err := NewTagSequence([]byte("«"))
+	require.NoError(t, err)
+	require.Equal(t, TagID("«"), s.ID())
+}
+
+func TestNewTagSequence_UTF8(t *testing.T) {
+	for _, seq := range []string{"§§", "«", "a«", "«a", "😀", "‹"} {
+		_, err := NewTagSequence([]byte(seq))
+		require.NoError(t, err, seq)
+	}
+
+	for _, seq := range []string{" ", "\xc2", "\xc2\xc2", "a​", " "} {
+		_, err := NewTagSequence([]byte(seq))
+		requireConfigIssue(t, err, IssueUnprintableChar)
+	}
+
+	_, err := NewTagSequence([]byte("§§a"))
+	requireConfigIssue(t, err, IssueInvalidTagSeqLen)
+}
+
+func TestAddTag_UTF8(t *testing.T) {
+	d, err := NewDictionary(Limits{})
+	require.NoError(t, err)
+
+	require.NoError(t, d.AddUniversalTag("QUOTE", []byte("«"), NonGreedy, RuleNA))
+
+	// "Ϋ" ends with the same byte as "«"
+	err = d.AddUniversalTag("OTHER", []byte("Ϋ"), NonGreedy, RuleNA)
+	requireConfigIssue(t, err, IssueDuplicateTagID)
+	require.ErrorContains(t, err, `Tag OTHER "Ϋ" has the same ID 171 as the registered Tag QUOTE "«": `+
+		`the ID of a Tag starting with a multi-byte rune is the last byte of the rune`)
+
+	// so do "→" (E2 86 92) and "Ғ" (D2 92)
+	require.NoError(t, d.AddUniversalTag("ARROW", []byte("→"), NonGreedy, RuleNA))
+	err = d.AddUniversalTag("GHE", []byte("Ғ"), NonGreedy, RuleNA)
+	requireConfigIssue(t, err, IssueDuplicateTagID)
+	require.ErrorContains(t, err, `Tag GHE "Ғ" has the same ID 146 as the registered Tag ARROW "→"`)
+
+	// the same rune is a plain duplicate
+	err = d.AddUniversalTag("QUOTE2", []byte("«"), NonGreedy, RuleNA)
+	require.ErrorContains(t, err, `Tag QUOTE2 "«" has the same ID 171 as the registered Tag QUOTE "«"`)
+	require.NotContains(t, err.Error(), "multi-byte rune")
+
+	// the Tag-Vs-Content rule counts single bytes
+	err = d.AddUniversalTag("CODE", []byte("·"), Greedy, RuleTagVsContent)
+	requireConfigIssue(t, err, IssueRuleInapplicable)
+
+	// the rules are for the single chars only
+	err = d.AddUniversalTag("MARKS", []byte("··"), NonGreedy, RuleInfraWord)
+	requireConfigIssue(t, err, IssueRuleInapplicable)
+
+	tag, ok := d.Tag(TagID("«"))
+	require.True(t, ok)
+	require.True(t, tag.IsUniversal())
+
+	act, ok := d.Action("«"[0])
+	require.True(t, ok)
+	require.NotNil(t, act)
+
+	// the ID itself is a continuation byte, which must not trigger anything
+	_, ok = d.Action(TagID("«"))
+	require.False(t, ok)
+}
+
+func TestTokenizeUTF8_OpenClose(t *testing.T) {
+	d := initUTF8Dict(t)
+	in := "a «b» c"
+
+	toks, warns := tokenize(t, d, in)
+	assertTokenInvariants(t, in, toks)
+	require.Empty(t, warns.List())
+
+	require.Equal(t, []Token{
+		{Type: TokenText, Pos: 0, Width: 2, Payload: Span{0, 2}},
+		{Type: TokenTag, Trigger: TagID("«"), Pos: 2, Width: 2, Payload: Span{4, 4}},
+		{Type: TokenText, Pos: 4, Width: 1, Payload: Span{4, 5}},
+		{Type: TokenTag, Trigger: TagID("»"), Pos: 5, Width: 2, Payload: Span{7, 7}},
+		{Type: TokenText, Pos: 7, Width: 2, Payload: Span{7, 9}},
+	}, toks)
+
+	w := newWarns(t)
+	ast := Parse(in, d, &w)
+	require.Empty(t, w.List())
+	require.Equal(t, "QUOTE", nodeName(ast, d, 2))
+	require.Equal(t, "b", nodeName(ast, d, 3))
+}
+
+func TestTokenizeUTF8_RunesSharingLeadingByteAreText(t *testing.T) {
+	d := initUTF8Dict(t)
+	// "¢" and "©" start with the same byte as "«", "€" as "‹"
+	in := "¢ © € é ü"
+
+	toks, warns := tokenize(t, d, in)
+	require.Empty(t, warns.List())
+	require.Equal(t, []Token{{Type: TokenText, Pos: 0, Width: len(in), Payload: Span{0, len(in)}}}, toks)
+}
+
+func TestTokenizeUTF8_MultiRuneSequence(t *testing.T) {
+	d := initUTF8Dict(t)
+
+	in := "§§x§§ *y*"
+	toks, warns := tokenize(t, d, in)
+	assertTokenInvariants(t, in, toks)
+	require.Empty(t, warns.List())
+	require.Equal(t, []string{"§§", "§§", "*", "*"}, tagRaws(in, toks))
+
+	// the broken sequence is skipped by whole runes, so the next rune still starts a Tag
+	in = "§«x»"
+	toks, warns = tokenize(t, d, in)
+	assertTokenInvariants(t, in, toks)
+	require.Equal(t, in, sliceByRaw(in, toks))
+	require.True(t, hasIssue(&warns, IssueUnexpectedSymbol))
+	require.Equal(t, []string{"«", "»"}, tagRaws(in, toks))
+}
+
+func TestTokenizeUTF8_InfraWord(t *testing.T) {
+	d := initUTF8Dict(t)
+
+	testCases := []struct {
+		in   string
+		tags []string
+	}{
+		{"a·b·c", nil},
+		{"é·ü", nil},
+		{"·word·", []string{"·", "·"}},
+		{"x ·y· z", []string{"·", "·"}},
+		// the same rune on the side makes it a part of the word
+		{"a··", []string{"·"}},
+		// other Tag runes are not parts of the word
+		{"«·x·»", []string{"«", "·", "·", "»"}},
+	}
+
+	for _, tc := range testCases {
+		toks, warns := tokenize(t, d, tc.in)
+		assertTokenInvariants(t, tc.in, toks)
+		require.Empty(t, warns.List(), tc.in)
+		require.Equal(t, tc.tags, tagRaws(tc.in, toks), tc.in)
+	}
+}
+
+func TestTokenizeUTF8_Greedy(t *testing.T) {
+	d := initUTF8Dict(t)
+	in := "press ‹ctrl›!"
+
+	toks, warns := tokenize(t, d, in)
+	assertTokenInvariants(t, in, toks)
+	require.Empty(t, warns.List())
+	require.Equal(t, []string{"‹ctrl›"}, tagRaws(in, toks))
+	require.Equal(t, "ctrl", in[toks[1].Payload.Start:toks[1].Payload.End])
+
+	in = "press ‹ctrl"
+	toks, warns = tokenize(t, d, in)
+	require.True(t, hasIssue(&warns, IssueUnclosedTag))
+	require.Empty(t, tagRaws(in, toks))
+}
+
+func TestTokenizeUTF8_Escape(t *testing.T) {
+	d := initUTF8Dict(t)
+	in := `\«x» \¢`
+
+	toks, warns := tokenize(t, d, in)
+	assertTokenInvariants(t, in, toks)
+	require.Equal(t, []string{"»"}, tagRaws(in, toks))
+
+	// only the escape before "¢" is redundant
+	require.Len(t, warns.List(), 1)
+	require.Equal(t, IssueRedundantEscape, warns.List()[0].Issue)
+	require.Equal(t, 7, warns.List()[0].Pos)
+}
+
+func TestFormat_UTF8(t *testing.T) {
+	d := initUTF8Dict(t)
+
+	testCases := []struct{ in, want string }{
+		{"«a» §§b§§", "«a» §§b§§"},
+		{"«a", "«a»"},
+		{`x \«y`, `x \«y`},
+		{`\¢ ·a·`, `¢ ·a·`},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.want, requireFormatRoundTrip(t, d, tc.in), tc.in)
+	}
+}
+
+func TestReparse_UTF8(t *testing.T) {
+	d := initUTF8Dict(t)
+	alphabet := []string{"«", "»", "§", "§§", "·", "‹", "›", "*", `\`, "é", "¢", "a", " "}
+	rng := rand.New(rand.NewPCG(37, 0))
+
+	w := newWarns(t)
+	ast := Parse("", d, &w)
+
+	for range 3000 {
+		start := rng.IntN(len(ast.Input) + 1)
+		end := min(start+rng.IntN(3), len(ast.Input))
+		requireReparse(t, d, &ast, Edit{Span{start, end}, alphabet[rng.IntN(len(alphabet))]})
+	}
+}
+
+func TestSpec_UTF8(t *testing.T) {
+	d := initUTF8Dict(t)
+
+	spec := d.Spec()
+	require.Contains(t, spec.Tags, TagSpec{Name: "QUOTE", Seq: "«", CloseID: "»"})
+	require.Contains(t, spec.Tags, TagSpec{Name: "MARK", Seq: "·", Rule: "intra_word", OpenID: "·", CloseID: "·"})
+
+	loaded, err := NewDictionaryFromSpec(spec)
+	require.NoError(t, err)
+	require.Equal(t, spec, loaded.Spec())
+
+	spec.Tags[0].OpenID = "«»"
+	_, err = NewDictionaryFromSpec(spec)
+	require.Error(t, err)
+}
+
+func FuzzTokenizeUTF8_NoPanic_ValidSpans(f *testing.F) {
+	seeds := []string{
+		"",
+		"«quote» §§section§§ ·mark· ‹kbd›",
+		"§«x»",
+		"a·b ¢ © € é",
+		`\«x» \¢ \`,
+		"‹unclosed «",
+		"\xc2\xab\xc2",
+		"§",
+	}
+	for _, s := range seeds {
+		f.Add(s)
+	}
+
+	f.Fuzz(func(t *testing.T, in string) {
+		d := initUTF8Dict(t)
+		toks, warns := tokenize(t, d, in)
+
+		assertTokenInvariants(t, in, toks)
+		assertWarningInvariants(t, in, &warns)
+
+		require.Equal(t, in, sliceByRaw(in, toks), "round-trip mismatch; toks=%#v warns=%#v", toks, warns)
+
+		// the tags never start or end inside a rune of the valid input
+		for _, tok := range toks {
+			if tok.Type != TokenText && utf8.ValidString(in) {
+				require.True(t, utf8.RuneStart(in[tok.Pos]), "token %#v", tok)
+				require.True(t, tok.Pos+tok.Width == len(in) || utf8.RuneStart(in[tok.Pos+tok.Width]), "token %#v", tok)
+			}
+		}
+	})
+}
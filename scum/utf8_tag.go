@@ -0,0 +1,58 @@
+package scum
+
+import "unicode/utf8"
+
+// ActUTF8Tag is the [Action] of the leading byte of the multi-byte runes which start Tags. It dispatches
+// the rune to the Action of its Tag. Other runes sharing the leading byte are skipped as plain text.
+//
+// The ASCII Tags are triggered directly by their IDs and never go through this Action.
+func ActUTF8Tag(ac *ActionContext) (token Token, stride int, skip bool) {
+	id, w, ok := ac.Dictionary.runeTag(ac.Input[ac.Idx:])
+	if !ok {
+		return Token{}, w, true
+	}
+
+	ac.Reset(id, ac.Idx)
+	return ac.Dictionary.utf8Actions[utf8ActionIdx(id)](ac)
+}
+
+// runeTag returns the ID of the Tag, whose sequence starts with the multi-byte rune at the start of s,
+// and the width of the rune. ok is false if there is no such Tag; the width is then at least 1.
+func (d *Dictionary) runeTag(s string) (id byte, width int, ok bool) {
+	_, width = utf8.DecodeRuneInString(s)
+	if width < 2 {
+		return 0, 1, false
+	}
+
+	id = s[width-1]
+	t := &d.tags[id]
+
+	return id, width, int(t.Seq.Len) >= width && string(t.Seq.Bytes[:width]) == s[:width]
+}
+
+// specialWidth returns the width in bytes of the special symbol at the start of the non-empty s,
+// which is 1 for the ASCII symbols and the width of the rune for the multi-byte Tag runes, or 0 if
+// s doesn't start with a special symbol.
+func (d *Dictionary) specialWidth(s string) int {
+	b := s[0]
+	if d.actions[b] == nil {
+		return 0
+	}
+
+	if b < utf8.RuneSelf {
+		return 1
+	}
+
+	_, w, ok := d.runeTag(s)
+	if !ok {
+		return 0
+	}
+
+	return w
+}
+
+// utf8ActionIdx maps the ID of a Tag starting with a multi-byte rune, which is a UTF-8 continuation byte,
+// to the index in [Dictionary.utf8Actions].
+func utf8ActionIdx(id byte) byte {
+	return id & 0x3F
+}
@@ -0,0 +1,206 @@
+package scum
+
+import "iter"
+
+// WalkAction tells [AST.Walk] how to continue after entering a node.
+type WalkAction uint8
+
+const (
+	// WalkContinue descends into the children of the node.
+	WalkContinue WalkAction = iota
+
+	// WalkSkipChildren skips the children of the node. The node is still left.
+	WalkSkipChildren
+
+	// WalkStop ends the walk without leaving the entered nodes.
+	WalkStop
+)
+
+// Walk traverses the subtree of the node at idx in source order, calling enter before
+// the children of each node and leave after them. The nodes are passed as indices into [AST.Nodes]
+// along with the pointers to them, so no copies are made. leave can be nil.
+//
+// It returns false if the walk was stopped with [WalkStop].
+func (ast AST) Walk(idx int, enter func(idx int, n *Node) WalkAction, leave func(idx int, n *Node)) bool {
+	// the path of the entered nodes above the current one
+	var buf [16]int
+	path := buf[:0]
+
+	cur := idx
+	for {
+		n := &ast.Nodes[cur]
+		act := enter(cur, n)
+		if act == WalkStop {
+			return false
+		}
+
+		if act == WalkContinue && n.FirstChild != -1 {
+			path = append(path, cur)
+			cur = n.FirstChild
+			continue
+		}
+
+		// leaving the node and its ancestors, until one of them has a next sibling
+		for {
+			if leave != nil {
+				leave(cur, &ast.Nodes[cur])
+			}
+
+			if len(path) == 0 {
+				return true
+			}
+
+			if next := ast.Nodes[cur].NextSibling; next != -1 {
+				cur = next
+				break
+			}
+
+			cur = path[len(path)-1]
+			path = path[:len(path)-1]
+		}
+	}
+}
+
+// Children returns an iterator over the indices of the children of the node at idx, in source order.
+func (ast AST) Children(idx int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for c := ast.Nodes[idx].FirstChild; c != -1; c = ast.Nodes[c].NextSibling {
+			if !yield(c) {
+				return
+			}
+		}
+	}
+}
+
+// Descendants returns an iterator over the indices of the descendants of the node at idx, in source order.
+//
+// The parser appends the nodes in pre-order, so the descendants of a node are the nodes
+// right after it in [AST.Nodes] and are iterated without a stack.
+func (ast AST) Descendants(idx int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		end := subtreeEnd(&ast, idx)
+		for i := idx + 1; i < end; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+}
+
+// Ancestors returns an iterator over the indices of the ancestors of the node at idx,
+// from its parent up to the root.
+//
+// Nodes don't link to their parents, so the path is found by descending from the root
+// into the child whose subtree contains the node.
+func (ast AST) Ancestors(idx int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		path := ast.path(idx)
+		for i := len(path) - 1; i >= 0; i-- {
+			if !yield(path[i]) {
+				return
+			}
+		}
+	}
+}
+
+// Parent returns the index of the parent of the node at idx, or -1 for the root.
+func (ast AST) Parent(idx int) int {
+	path := ast.path(idx)
+	if len(path) == 0 {
+		return -1
+	}
+
+	return path[len(path)-1]
+}
+
+// path returns the indices of the ancestors of the node at idx, starting from the root.
+func (ast AST) path(idx int) []int {
+	var path []int
+
+	cur := 0
+	for cur != idx {
+		path = append(path, cur)
+
+		// the last child starting at or before the node contains it
+		next := -1
+		for c := ast.Nodes[cur].FirstChild; c != -1 && c <= idx; c = ast.Nodes[c].NextSibling {
+			next = c
+		}
+
+		cur = next
+	}
+
+	return path
+}
+
+// NodeAttributes returns the attributes attached to the node at idx.
+func (ast AST) NodeAttributes(idx int) []Attribute {
+	r := ast.Nodes[idx].Attributes
+	return ast.Attributes[r.Start : r.Start+r.Len]
+}
+
+// Attribute returns the first attribute of the node at idx with the name.
+// A flag attribute is named by its payload.
+func (ast AST) Attribute(idx int, name string) (Attribute, bool) {
+	for _, a := range ast.NodeAttributes(idx) {
+		span := a.Name
+		if a.IsFlag {
+			span = a.Payload
+		}
+
+		if ast.Input[span.Start:span.End] == name {
+			return a, true
+		}
+	}
+
+	return Attribute{}, false
+}
+
+// Selector matches the nodes of an [AST] by the Tag name and the attributes,
+// like "all LINK nodes with attribute href".
+type Selector struct {
+	// Tag is the name of the Tag of the matched nodes. Empty Tag matches any node.
+	Tag string
+
+	// Attributes are the names of the attributes the matched nodes must have. See [AST.Attribute].
+	Attributes []string
+}
+
+// Match reports whether the node at idx matches the selector.
+// d must be the [Dictionary] the AST was parsed with.
+func (s Selector) Match(ast AST, d *Dictionary, idx int) bool {
+	n := &ast.Nodes[idx]
+	if s.Tag != "" && (n.Type != NodeTag || d.tags[n.TagID].Name != s.Tag) {
+		return false
+	}
+
+	for _, name := range s.Attributes {
+		if _, ok := ast.Attribute(idx, name); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Select returns an iterator over the indices of the nodes matching the selector, in source order.
+// d must be the [Dictionary] the AST was parsed with.
+func (ast AST) Select(d *Dictionary, s Selector) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for i := range ast.Nodes {
+			if s.Match(ast, d, i) && !yield(i) {
+				return
+			}
+		}
+	}
+}
+
+// subtreeEnd returns the index after the last node of the subtree. The nodes are stored in pre-order,
+// so the subtree occupies the indices up to the last descendant.
+func subtreeEnd(ast *AST, idx int) int {
+	for ast.Nodes[idx].LastChild != -1 {
+		idx = ast.Nodes[idx].LastChild
+	}
+
+	return idx + 1
+}
@@ -0,0 +1,201 @@
+package scum
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// nodeName returns the Tag name of the node, or the content of the text node.
+func nodeName(ast AST, d *Dictionary, idx int) string {
+	n := ast.Nodes[idx]
+	switch n.Type {
+	case NodeRoot:
+		return "ROOT"
+	case NodeTag:
+		return d.tags[n.TagID].Name
+	case NodeBlock:
+		return d.blocks[n.TagID-1].Name
+	default:
+		return ast.Input[n.Span.Start:n.Span.End]
+	}
+}
+
+func TestWalk(t *testing.T) {
+	d := testDict(t)
+	w := newWarns(t)
+	ast := Parse("a *b $$c$$* [d]!href{x}", &d, &w)
+
+	var events []string
+	ok := ast.Walk(0, func(idx int, n *Node) WalkAction {
+		require.Same(t, &ast.Nodes[idx], n)
+		events = append(events, "+"+nodeName(ast, &d, idx))
+		return WalkContinue
+	}, func(idx int, n *Node) {
+		events = append(events, "-"+nodeName(ast, &d, idx))
+	})
+
+	require.True(t, ok)
+	require.Equal(t, []string{
+		"+ROOT",
+		"+a ", "-a ",
+		"+ITALIC", "+b ", "-b ", "+BOLD", "+c", "-c", "-BOLD", "-ITALIC",
+		"+ ", "- ",
+		"+LINK_TEXT_START", "+d", "-d", "-LINK_TEXT_START",
+		"-ROOT",
+	}, events)
+}
+
+func TestWalk_SkipChildrenAndStop(t *testing.T) {
+	d := testDict(t)
+	w := newWarns(t)
+	ast := Parse("*a* [b] $$c$$", &d, &w)
+
+	var entered, left []string
+	ok := ast.Walk(0, func(idx int, _ *Node) WalkAction {
+		name := nodeName(ast, &d, idx)
+		entered = append(entered, name)
+		switch name {
+		case "ITALIC":
+			return WalkSkipChildren
+		case "LINK_TEXT_START":
+			return WalkStop
+		}
+		return WalkContinue
+	}, func(idx int, _ *Node) {
+		left = append(left, nodeName(ast, &d, idx))
+	})
+
+	require.False(t, ok)
+	require.Equal(t, []string{"ROOT", "ITALIC", " ", "LINK_TEXT_START"}, entered)
+	require.Equal(t, []string{"ITALIC", " "}, left)
+}
+
+func TestWalk_Subtree(t *testing.T) {
+	d := testDict(t)
+	w := newWarns(t)
+	ast := Parse("*a $$b$$* c", &d, &w)
+
+	var entered []string
+	ast.Walk(1, func(idx int, _ *Node) WalkAction {
+		entered = append(entered, nodeName(ast, &d, idx))
+		return WalkContinue
+	}, nil)
+
+	// the sibling of the walked node is not visited
+	require.Equal(t, []string{"ITALIC", "a ", "BOLD", "b"}, entered)
+}
+
+func TestAST_Children(t *testing.T) {
+	d := testDict(t)
+	w := newWarns(t)
+	ast := Parse("a *b $$c$$* [d]", &d, &w)
+
+	var names []string
+	for c := range ast.Children(0) {
+		names = append(names, nodeName(ast, &d, c))
+	}
+	require.Equal(t, []string{"a ", "ITALIC", " ", "LINK_TEXT_START"}, names)
+
+	// stopping early
+	for c := range ast.Children(0) {
+		require.Equal(t, 1, c)
+		break
+	}
+}
+
+func TestAST_DescendantsAndAncestors(t *testing.T) {
+	d := testDict(t)
+	w := newWarns(t)
+	ast := Parse("a *b $$c$$* [d]", &d, &w)
+
+	italic := 2
+	require.Equal(t, "ITALIC", nodeName(ast, &d, italic))
+
+	var names []string
+	for i := range ast.Descendants(italic) {
+		names = append(names, nodeName(ast, &d, i))
+	}
+	require.Equal(t, []string{"b ", "BOLD", "c"}, names)
+
+	c := 5
+	require.Equal(t, "c", nodeName(ast, &d, c))
+
+	names = names[:0]
+	for i := range ast.Ancestors(c) {
+		names = append(names, nodeName(ast, &d, i))
+	}
+	require.Equal(t, []string{"BOLD", "ITALIC", "ROOT"}, names)
+
+	require.Equal(t, 4, ast.Parent(c))
+	require.Equal(t, 0, ast.Parent(italic))
+	require.Equal(t, -1, ast.Parent(0))
+	require.Empty(t, slices.Collect(ast.Ancestors(0)))
+}
+
+// TestAST_TraversalsAgree checks the iterators against Walk on random inputs.
+func TestAST_TraversalsAgree(t *testing.T) {
+	d := testDict(t)
+	rng := rand.New(rand.NewPCG(36, 2026))
+
+	for range 500 {
+		w := newWarns(t)
+		ast := Parse(randomInput(rng, 12), &d, &w)
+
+		var walked, path []int
+		parents := make([]int, len(ast.Nodes))
+		ast.Walk(0, func(idx int, _ *Node) WalkAction {
+			walked = append(walked, idx)
+			parents[idx] = -1
+			if len(path) > 0 {
+				parents[idx] = path[len(path)-1]
+			}
+			path = append(path, idx)
+			return WalkContinue
+		}, func(int, *Node) {
+			path = path[:len(path)-1]
+		})
+
+		require.Equal(t, walked[1:], slices.Collect(ast.Descendants(0)))
+
+		for idx := range ast.Nodes {
+			require.Equal(t, parents[idx], ast.Parent(idx))
+
+			var children []int
+			for i := range ast.Descendants(idx) {
+				if parents[i] == idx {
+					children = append(children, i)
+				}
+			}
+			require.Equal(t, children, slices.Collect(ast.Children(idx)))
+		}
+	}
+}
+
+func TestAST_Select(t *testing.T) {
+	d := testDict(t)
+	w := newWarns(t)
+	ast := Parse("[a]!href{x} [b] [c]!{href} *d*!href{y} [e]!title{t}!href{z}", &d, &w)
+
+	links := Selector{Tag: "LINK_TEXT_START", Attributes: []string{"href"}}
+
+	var names []string
+	for i := range ast.Select(&d, links) {
+		names = append(names, nodeName(ast, &d, ast.Nodes[i].FirstChild))
+	}
+	require.Equal(t, []string{"a", "c", "e"}, names)
+
+	require.Len(t, slices.Collect(ast.Select(&d, Selector{Attributes: []string{"href"}})), 4)
+	require.Len(t, slices.Collect(ast.Select(&d, Selector{Tag: "ITALIC"})), 1)
+	require.Empty(t, slices.Collect(ast.Select(&d, Selector{Tag: "BOLD"})))
+
+	a, ok := ast.Attribute(ast.Nodes[0].LastChild, "href")
+	require.True(t, ok)
+	require.Equal(t, "z", ast.Input[a.Payload.Start:a.Payload.End])
+	require.Len(t, ast.NodeAttributes(ast.Nodes[0].LastChild), 2)
+
+	_, ok = ast.Attribute(0, "href")
+	require.False(t, ok)
+}
@@ -24,6 +24,9 @@ type Warning struct {
 
 	// Got is the symbol that was found instead of the expected one.
 	Got byte
+
+	// ContainerTagID is the ID of the open Tag whose [ContentModel] doesn't allow the Tag causing the issue.
+	ContainerTagID byte
 }
 
 // WarningOverflowPolicy determines what happens when the maximum Warning capacity is reached.
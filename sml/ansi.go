@@ -0,0 +1,116 @@
+package sml
+
+import (
+	"unicode/utf8"
+
+	"github.com/Drolfothesgnir/shitposter/scum"
+)
+
+const (
+	// csi starts the SGR sequences styling the text.
+	csi = "\x1b["
+	// osc8 starts the OSC 8 sequences of the hyperlinks, which are terminated by st.
+	osc8 = "\x1b]8;;"
+	st   = "\x1b\\"
+)
+
+// ANSI returns the parsed input as text for the terminals, with the [Bold], [Italic], [Underline] and [Strike] tags
+// styled with the SGR escape sequences and the links written as the OSC 8 hyperlinks. The terminals which don't
+// support a sequence ignore it, so the text stays readable anyway.
+//
+// The [Spoiler] is written as the "[spoiler]" placeholder in reverse video, so its content is not revealed,
+// and the [Superscript] as ^(text).
+//
+// The control characters of the text, like ESC, are replaced with U+FFFD, so the input can't
+// inject escape sequences of its own.
+func (p Poop) ANSI() string {
+	r := Renderer{tags: p.handlers(), mode: renderANSI}
+	r.Grow(p.AST.TextByteLen)
+	r.Children(&p.Tree)
+	return r.String()
+}
+
+// ansiTag returns the ANSI renderer wrapping the children in the SGR sequences with the on and off parameters.
+// The off parameters reset only their own style, so the nested tags keep the styles of the outer ones.
+func ansiTag(on, off string) func(r *Renderer, n *scum.SerializableNode) {
+	onSeq := csi + on + "m"
+	offSeq := csi + off + "m"
+
+	return func(r *Renderer, n *scum.SerializableNode) {
+		r.WriteString(onSeq)
+		r.Children(n)
+		r.WriteString(offSeq)
+	}
+}
+
+// ansiSpoiler writes the placeholder instead of the content of the spoiler. The concealed text (SGR 8)
+// is not used, the terminals which don't support it would show the content.
+func ansiSpoiler(r *Renderer, _ *scum.SerializableNode) {
+	r.WriteString(csi + "7m[spoiler]" + csi + "27m")
+}
+
+// ansiSuperscript writes the superscript as ^(text), there is no widely supported SGR for it.
+func ansiSuperscript(r *Renderer, n *scum.SerializableNode) {
+	r.WriteString("^(")
+	r.Children(n)
+	r.WriteByte(')')
+}
+
+// ansiLink writes the link, or the mention, as the OSC 8 hyperlink. The link without href is written as its text.
+func ansiLink(r *Renderer, n *scum.SerializableNode) {
+	href, _ := linkAttributes(n)
+	if href == "" {
+		r.Children(n)
+		return
+	}
+
+	r.WriteString(osc8)
+	writeANSIURI(r, href)
+	r.WriteString(st)
+	r.Children(n)
+	r.WriteString(osc8)
+	r.WriteString(st)
+}
+
+// writeANSIURI writes the URL percent-encoding the bytes outside of the printable ASCII,
+// which OSC 8 doesn't allow and which would end the sequence.
+func writeANSIURI(r *Renderer, url string) {
+	const hex = "0123456789ABCDEF"
+	for i := 0; i < len(url); i++ {
+		c := url[i]
+		if c <= ' ' || c >= 0x7f {
+			r.WriteByte('%')
+			r.WriteByte(hex[c>>4])
+			r.WriteByte(hex[c&0xF])
+			continue
+		}
+		r.WriteByte(c)
+	}
+}
+
+// ansiText writes the text replacing the control characters, except the newlines and the tabs, with U+FFFD.
+func (r *Renderer) ansiText(s string) {
+	last := 0
+	for i, c := range s {
+		if !isANSIControl(c) {
+			continue
+		}
+
+		r.WriteString(s[last:i])
+		r.WriteRune(utf8.RuneError)
+		last = i + utf8.RuneLen(c)
+	}
+	r.WriteString(s[last:])
+}
+
+// isANSIControl reports whether c is a C0 or C1 control character which could start or alter an escape sequence.
+func isANSIControl(c rune) bool {
+	switch {
+	case c == '\n' || c == '\t':
+		return false
+	case c < 0x20 || c == 0x7f:
+		return true
+	default:
+		return 0x80 <= c && c <= 0x9f
+	}
+}
@@ -0,0 +1,63 @@
+package sml
+
+import (
+	"testing"
+
+	"github.com/Drolfothesgnir/shitposter/scum"
+	"github.com/stretchr/testify/require"
+)
+
+func TestANSI(t *testing.T) {
+	eater, err := NewEater(scum.WarnOverflowNoCap, 0)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "styles",
+			input: "$bold *both*$ _under_ ~~gone~~",
+			want:  "\x1b[1mbold \x1b[3mboth\x1b[23m\x1b[22m \x1b[4munder\x1b[24m \x1b[9mgone\x1b[29m",
+		},
+		{
+			name:  "unstyled tags",
+			input: "`$code$` x^2^",
+			want:  "$code$ x^(2)",
+		},
+		{
+			name:  "spoiler",
+			input: "the end: ||$he$ was the butler||",
+			want:  "the end: \x1b[7m[spoiler]\x1b[27m",
+		},
+		{
+			name:  "link",
+			input: "[$the$ docs]!href{https://example.com/a b}",
+			want:  "\x1b]8;;https://example.com/a%20b\x1b\\\x1b[1mthe\x1b[22m docs\x1b]8;;\x1b\\",
+		},
+		{
+			name:  "control characters",
+			input: "a\x1b(0red\x07b\u009bc\n\td",
+			want:  "a�(0red�b�c\n\td",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			poop, _ := eater.Munch(tt.input)
+
+			require.Equal(t, tt.want, poop.ANSI())
+		})
+	}
+}
+
+func TestANSI_Mention(t *testing.T) {
+	eater, err := NewEater(scum.WarnOverflowNoCap, 0, WithMentionResolver(&fakeResolver{users: map[string]int64{"alice": 1}}))
+	require.NoError(t, err)
+
+	poop, issues := eater.Munch("hi @alice")
+
+	require.Empty(t, issues)
+	require.Equal(t, "hi \x1b]8;;/users/1\x1b\\@alice\x1b]8;;\x1b\\", poop.ANSI())
+}
@@ -49,6 +49,11 @@ func benchDeeplyNested(level int) string {
 	return b.String()
 }
 
+func benchBareURLInput(repeat int) string {
+	chunk := `see https://example.com/a?b=1, (www.example.org/wiki/Go_(language)) or mail bob@example.com. `
+	return strings.Repeat(chunk, repeat)
+}
+
 func benchChaosInput(repeat int) string {
 	chunk := `$*_[broken link]!href{//evil.com}!target{popup}!title{` + strings.Repeat("x", MaxTitleLength+8) + `} plain\\*text`
 	return strings.Repeat(chunk, repeat)
@@ -74,6 +79,42 @@ func BenchmarkMunch_LongInput(b *testing.B) {
 	}
 }
 
+func BenchmarkMunch_LongInput_Linkify(b *testing.B) {
+	e, err := NewEater(scum.WarnOverflowTrunc, 256, WithLinkify())
+	if err != nil {
+		b.Fatal(err)
+	}
+	input := benchLongInput(64)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		benchSinkPoop, benchSinkIssues = e.Munch(input)
+	}
+}
+
+func BenchmarkMunch_BareURLs(b *testing.B) {
+	e := benchEater(b)
+	input := benchBareURLInput(64)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		benchSinkPoop, benchSinkIssues = e.Munch(input)
+	}
+}
+
+func BenchmarkMunch_BareURLs_Linkify(b *testing.B) {
+	e, err := NewEater(scum.WarnOverflowTrunc, 256, WithLinkify())
+	if err != nil {
+		b.Fatal(err)
+	}
+	input := benchBareURLInput(64)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		benchSinkPoop, benchSinkIssues = e.Munch(input)
+	}
+}
+
 func BenchmarkMunch_DeeplyNested(b *testing.B) {
 	e := benchEater(b)
 	input := benchDeeplyNested(20)
@@ -0,0 +1,139 @@
+package sml
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/Drolfothesgnir/shitposter/scum"
+)
+
+// Budget bounds the size and the complexity of the munched inputs, see [WithBudget].
+// The zero fields are not limited.
+type Budget struct {
+	// MaxTextRunes is the maximum length of the visible text in runes, that is of [Poop.Text].
+	MaxTextRunes int
+	// MaxLinks is the maximum number of the links, including the linkified bare URLs.
+	// The mentions are limited by [MaxMentions] instead.
+	MaxLinks int
+	// MaxNodes is the maximum number of the nodes of the tree, the tags and the texts.
+	MaxNodes int
+	// MaxDepth is the maximum nesting of the tags, 1 for the tags which are not inside another tag.
+	MaxDepth int
+	// MaxHTMLBytes is the maximum length of [Poop.HTML] in bytes.
+	MaxHTMLBytes int
+}
+
+// WithBudget makes the [Eater] check the munched inputs against the budget, adding an issue for each exceeded limit:
+// [IssueTextTooLong], [IssueTooManyLinks], [IssueTooManyNodes], [IssueNestingTooDeep] or [IssueHTMLTooLarge].
+// The input is munched as a whole anyway, it's up to the caller to reject it.
+// If any of the limits is negative, [NewEater] returns a *[ConfigError].
+func WithBudget(b Budget) Option {
+	return func(e *Eater) {
+		if b.MaxTextRunes < 0 || b.MaxLinks < 0 || b.MaxNodes < 0 || b.MaxDepth < 0 || b.MaxHTMLBytes < 0 {
+			if e.err == nil {
+				e.err = errors.New("budget limits must not be negative")
+			}
+			return
+		}
+		e.budget = b
+	}
+}
+
+// checkBudget adds the issues of the limits of the budget exceeded by the tree of the input.
+// textByteLen is the [Poop.TextByteLen] of the input.
+func (p *Eater) checkBudget(input string, tree *scum.SerializableNode, textByteLen int, issues *Issues) {
+	b := p.budget
+	whole := scum.Span{Start: 0, End: len(input)}
+
+	// the text can't have more runes than bytes
+	if b.MaxTextRunes > 0 && textByteLen > b.MaxTextRunes {
+		r := Renderer{tags: p.tags, mode: renderText}
+		r.Children(tree)
+		if n := utf8.RuneCountInString(r.String()); n > b.MaxTextRunes {
+			issues.Add(NewSyntaxIssueDescriptor(
+				IssueTextTooLong,
+				whole,
+				fmt.Sprintf("text must be at most %d characters long, got %d", b.MaxTextRunes, n),
+			))
+		}
+	}
+
+	if b.MaxLinks > 0 || b.MaxNodes > 0 || b.MaxDepth > 0 {
+		var c budgetCounter
+		c.count(tree, 0, b)
+
+		if b.MaxLinks > 0 && c.links > b.MaxLinks {
+			issues.Add(NewSyntaxIssueDescriptor(
+				IssueTooManyLinks,
+				c.extraLink,
+				fmt.Sprintf("number of links must be at most %d, got %d", b.MaxLinks, c.links),
+			))
+		}
+
+		if b.MaxNodes > 0 && c.nodes > b.MaxNodes {
+			issues.Add(NewSyntaxIssueDescriptor(
+				IssueTooManyNodes,
+				c.extraNode,
+				fmt.Sprintf("number of text and markup pieces must be at most %d, got %d", b.MaxNodes, c.nodes),
+			))
+		}
+
+		if b.MaxDepth > 0 && c.tooDeep {
+			issues.Add(NewSyntaxIssueDescriptor(
+				IssueNestingTooDeep,
+				c.deepNode,
+				fmt.Sprintf("tags must be nested at most %d levels deep", b.MaxDepth),
+			))
+		}
+	}
+
+	if b.MaxHTMLBytes > 0 {
+		r := Renderer{tags: p.tags, mode: renderHTML}
+		r.Children(tree)
+		if r.Len() > b.MaxHTMLBytes {
+			issues.Add(NewSyntaxIssueDescriptor(
+				IssueHTMLTooLarge,
+				whole,
+				fmt.Sprintf("rendered HTML must be at most %d bytes long, got %d", b.MaxHTMLBytes, r.Len()),
+			))
+		}
+	}
+}
+
+// budgetCounter counts the nodes of the tree, keeping the spans of the first ones over the limits.
+type budgetCounter struct {
+	links, nodes         int
+	extraLink, extraNode scum.Span
+	tooDeep              bool
+	deepNode             scum.Span
+}
+
+func (c *budgetCounter) count(n *scum.SerializableNode, depth int, b Budget) {
+	for i := range n.Children {
+		child := &n.Children[i]
+
+		c.nodes++
+		if c.nodes == b.MaxNodes+1 {
+			c.extraNode = child.Span
+		}
+
+		if child.Type != "Tag" {
+			continue
+		}
+
+		if child.Name == Link {
+			c.links++
+			if c.links == b.MaxLinks+1 {
+				c.extraLink = child.Span
+			}
+		}
+
+		if depth+1 > b.MaxDepth && !c.tooDeep {
+			c.tooDeep = true
+			c.deepNode = child.Span
+		}
+
+		c.count(child, depth+1, b)
+	}
+}
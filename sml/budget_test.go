@@ -0,0 +1,134 @@
+package sml
+
+import (
+	"testing"
+
+	"github.com/Drolfothesgnir/shitposter/scum"
+	"github.com/stretchr/testify/require"
+)
+
+func budgetEater(t *testing.T, b Budget, opts ...Option) Eater {
+	t.Helper()
+
+	eater, err := NewEater(scum.WarnOverflowNoCap, 0, append(opts, WithBudget(b))...)
+	require.NoError(t, err)
+	return eater
+}
+
+func TestBudget_WithinLimits(t *testing.T) {
+	eater := budgetEater(t, Budget{MaxTextRunes: 9, MaxLinks: 1, MaxNodes: 7, MaxDepth: 2, MaxHTMLBytes: 64})
+
+	// the escapes and the markup don't count as the text
+	_, issues := eater.Munch(`\$ä $[b]!href{/x}$ ~~c~~`)
+	require.Empty(t, issues)
+}
+
+func TestBudget_Exceeded(t *testing.T) {
+	tests := []struct {
+		name     string
+		budget   Budget
+		input    string
+		codename string
+		desc     string
+		span     scum.Span
+	}{
+		{
+			name:     "text",
+			budget:   Budget{MaxTextRunes: 3},
+			input:    "$ää$ää",
+			codename: "TEXT_TOO_LONG",
+			desc:     "text must be at most 3 characters long, got 4",
+			span:     scum.Span{Start: 0, End: 10},
+		},
+		{
+			name:     "links",
+			budget:   Budget{MaxLinks: 1},
+			input:    "[a]!href{/a} [b]!href{/b} [c]",
+			codename: "TOO_MANY_LINKS",
+			desc:     "number of links must be at most 1, got 3",
+			span:     scum.Span{Start: 13, End: 16},
+		},
+		{
+			name:     "nodes",
+			budget:   Budget{MaxNodes: 2},
+			input:    "a $b$ c",
+			codename: "TOO_MANY_NODES",
+			desc:     "number of text and markup pieces must be at most 2, got 4",
+			span:     scum.Span{Start: 3, End: 4},
+		},
+		{
+			name:     "depth",
+			budget:   Budget{MaxDepth: 2},
+			input:    "$*a* *_b_*$",
+			codename: "NESTING_TOO_DEEP",
+			desc:     "tags must be nested at most 2 levels deep",
+			span:     scum.Span{Start: 6, End: 9},
+		},
+		{
+			name:     "html",
+			budget:   Budget{MaxHTMLBytes: 20},
+			input:    "||a|| b",
+			codename: "HTML_TOO_LARGE",
+			desc:     "rendered HTML must be at most 20 bytes long, got 94",
+			span:     scum.Span{Start: 0, End: 7},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eater := budgetEater(t, tt.budget)
+
+			_, issues := eater.Munch(tt.input)
+
+			require.Len(t, issues, 1)
+			require.Equal(t, tt.codename, issues[0].Codename())
+			require.Equal(t, tt.desc, issues[0].Description())
+			require.Equal(t, tt.span, issues[0].Span())
+		})
+	}
+}
+
+func TestBudget_CountsLinkifiedURLsAndEmoji(t *testing.T) {
+	eater := budgetEater(t, Budget{MaxTextRunes: 3, MaxLinks: 1}, WithLinkify(), WithEmoji(nil))
+
+	_, issues := eater.Munch("https://a.example www.b.example")
+	require.Equal(t, []string{"TEXT_TOO_LONG", "TOO_MANY_LINKS"}, issueCodenames(issues))
+
+	// the shortcode is longer than its emoji
+	_, issues = eater.Munch(":+1::+1::+1:")
+	require.Empty(t, issues)
+}
+
+func TestBudget_RejectsNegativeLimits(t *testing.T) {
+	_, err := NewEater(scum.WarnOverflowNoCap, 0, WithBudget(Budget{MaxLinks: -1}))
+
+	var cfgErr *ConfigError
+	require.ErrorAs(t, err, &cfgErr)
+}
+
+func BenchmarkMunch_LongInput_Budget(b *testing.B) {
+	e, err := NewEater(scum.WarnOverflowTrunc, 256, WithBudget(Budget{
+		MaxTextRunes: 10_000,
+		MaxLinks:     100,
+		MaxNodes:     1_000,
+		MaxDepth:     10,
+		MaxHTMLBytes: 64 << 10,
+	}))
+	if err != nil {
+		b.Fatal(err)
+	}
+	input := benchLongInput(64)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		benchSinkPoop, benchSinkIssues = e.Munch(input)
+	}
+}
+
+func issueCodenames(issues []SyntaxIssue) []string {
+	names := make([]string, len(issues))
+	for i, issue := range issues {
+		names[i] = issue.Codename()
+	}
+	return names
+}
@@ -1,7 +1,7 @@
 // # Shitposter's Markup Language
 //
 // It is created for the Shitposter users to be able to create tag-based rich text in their posts.
-// The parsed rich text can be transformed to an HTML or plain text string.
+// The parsed rich text can be transformed to an HTML, Markdown or plain text string.
 // Attribute names are case-insensetive.
 //
 // # Tags
@@ -26,9 +26,41 @@
 //   - Accepts no attributes.
 //   - Written as _text_ and rendered in HTML as <span class="sml-internal-underline">text</span>.
 //
+// ~~ - Strike:
+//
+//   - Represents crossed out text.
+//   - Accepts no attributes.
+//   - Written as ~~text~~ and rendered in HTML as <s>text</s>.
+//
+// || - Spoiler:
+//
+//   - Represents text hidden until the reader reveals it.
+//   - Accepts no attributes.
+//   - Written as ||text|| and rendered in HTML as
+//     <span class="sml-internal-spoiler" role="button" tabindex="0" aria-expanded="false">text</span>,
+//     the client reveals the text and sets aria-expanded to "true" on toggle.
+//   - The plain text contains the hidden text as is.
+//
+// ` - Code:
+//
+//   - Represents inline code. The content is taken as written, without any tags, attributes or escapes.
+//   - To put backticks inside, open the code with a longer backtick run and close it with the same run,
+//     like a `quoted` word wrapped in double backticks.
+//   - Accepts no attributes.
+//   - Written as `text` and rendered in HTML as <code>text</code>.
+//
+// ^ - Superscript:
+//
+//   - Represents raised text, like the power in x^2^.
+//   - Accepts no attributes.
+//   - Written as ^text^ and rendered in HTML as <sup>text</sup>.
+//
 // [...] - Link:
 //
-//   - Represents hyperlink. In case of multiple attributes with the same name (case-insensetive), the first one will be used
+//   - Represents hyperlink. Can contain only Bold, Italic, Underline, Strike, Code and Superscript tags,
+//     any other tag inside it is treated as plain text, so the links are never nested.
+//
+//   - In case of multiple attributes with the same name (case-insensetive), the first one will be used
 //     and others discarded.
 //
 //   - Accepts attributes:
@@ -39,6 +71,7 @@
 //     Must not contain forbidden control characters.
 //     Must not be protocol-relative.
 //     Scheme must be one of "http", "https" or "mailto".
+//     Host must not be an IDN homograph, like "pаypal.com" with the Cyrillic "а".
 //
 //     target - Optional.
 //     Must be one of "_blank" or "_self".
@@ -48,13 +81,46 @@
 //     Must not contain forbidden control characters.
 //     Must be at most [MaxTitleLength] characters long.
 //
+//   - The schemes, the targets and the title length can be changed, and the domains restricted,
+//     with [WithLinkPolicy].
+//
+//   - The bare URLs and emails in the text, like https://example.com or bob@example.com, become links
+//     when the [Eater] is created with [WithLinkify].
+//
 //   - Written as [text]!href{https://address.com}!target{_blank}!title{this is a link} and
 //     rendered in HTML as <a href="https://address.com" target="_blank" rel="noopener noreferrer" title="this is a link">text</a>.
 //     When rendered as plain text, only the text in the [] will be rendered.
+//
+// @username - Mention:
+//
+//   - Refers to a user by the username, which is 3 to 50 letters or digits.
+//   - Recognized only in the text outside of the links and the inline code, when the [Eater] is created with
+//     [WithMentionResolver]. The "@" must not follow a letter or a digit, so emails are not mentions.
+//   - The mentions of the unknown users stay plain text.
+//   - Written as @username and rendered in HTML as <a href="/users/42" class="sml-internal-mention">@username</a>.
+//
+// :shortcode: - Emoji:
+//
+//   - Refers to an emoji by its shortcode, like :smile: or :+1:.
+//   - Recognized only in the text outside of the mentions and the inline code, when the [Eater] is created with
+//     [WithEmoji]. The unknown shortcodes stay plain text.
+//   - The standard emoji are rendered as their Unicode characters. The custom emoji of the [EmojiRegistry]
+//     are rendered in HTML as <img class="sml-internal-emoji" src="/emoji/parrot.gif" alt=":parrot:" title=":parrot:">,
+//     and as :parrot: in the plain text.
+//
+// # Escapes
+//
+// The \ makes the next character plain text, so the special characters can be written as is, like \$5.
+// The escapes are removed from the text and from the attribute payloads, except in the inline code.
 package sml
 
 import (
+	"bytes"
+	"context"
+	_ "embed"
+	"maps"
 	"slices"
+	"time"
 
 	"github.com/Drolfothesgnir/shitposter/scum"
 )
@@ -65,25 +131,70 @@ const (
 	Italic    = "ITALIC"
 	Underline = "UNDERLINE"
 	Link      = "LINK"
+
+	Strike      = "STRIKE"
+	Spoiler     = "SPOILER"
+	Code        = "CODE"
+	Superscript = "SUPERSCRIPT"
+
+	// Mention is not a part of the dialect, its tags are created for the resolved mentions, see [WithMentionResolver].
+	Mention = "MENTION"
+	// Emoji is not a part of the dialect, its tags are created for the known shortcodes, see [WithEmoji].
+	Emoji = "EMOJI"
 )
 
+// dialect is the [scum.Spec] of the SML tags.
+//
+//go:embed dialect.yaml
+var dialect []byte
+
+// escapeSymbol is the escape symbol of the dialect.
+const escapeSymbol byte = '\\'
+
+// Dialect returns the [scum.Spec] of the SML tags as YAML, for the clients to load the same definition
+// with [scum.LoadDictionary].
+func Dialect() []byte {
+	return bytes.Clone(dialect)
+}
+
 // Poop is the result of the input parsing, returned by [Eater.Munch].
-// It contains the parsed tree and methods for rendering it as HTML or plain text.
+// It contains the parsed tree and methods for rendering it as HTML, Markdown or plain text.
 // Syntax issues are returned separately by [Eater.Munch].
 type Poop struct {
 	Input string
 	AST   scum.AST
 	Tree  scum.SerializableNode
+	// Canonical is the input normalized with [scum.Format]. It is set only if the [Eater]
+	// is created with [WithCanonicalSource].
+	Canonical string
+	// Mentions lists the mentioned users once each, in the order of their first mention. It is set only
+	// if the [Eater] is created with [WithMentionResolver].
+	Mentions []MentionedUser
+
+	// tags are the handlers of the [Eater] which munched the Poop.
+	tags tagHandlers
+	// textShrink is the number of the bytes of the text of the input removed by the [Eater], like the escapes.
+	textShrink int
 }
 
-// Text returns the parsed input as plain text string.
+// Text returns the parsed input as plain text string. The tags are dropped and their text is kept,
+// including the content of the code and of the spoilers, unless their [TagHandler] renders it differently.
 func (p Poop) Text() string {
-	return p.AST.Text()
+	return p.PlainText(PlainTextOptions{})
+}
+
+// handlers returns the tag handlers of the Poop, or the built-in ones for the Poop created by hand.
+func (p Poop) handlers() tagHandlers {
+	if p.tags == nil {
+		return builtinTags
+	}
+	return p.tags
 }
 
-// TextByteLength returns the byte count of the plain text in the input, that is the non-tag and non-attribute parts.
+// TextByteLength returns the byte count of the plain text in the input, that is the non-tag and non-attribute parts,
+// without the removed escapes and with the emoji in place of their shortcodes. It is the length of [Poop.Text].
 func (p Poop) TextByteLen() int {
-	return p.AST.TextByteLen
+	return p.AST.TextByteLen - p.textShrink
 }
 
 // Eater is the main SML parser object.
@@ -93,16 +204,80 @@ type Eater struct {
 	warningOverflowPolicy scum.WarningOverflowPolicy
 	// warnCap is the maximum number of warnings which will be processed during parsing.
 	warnCap int
+	// canonical enables the normalization of the input, see [WithCanonicalSource].
+	canonical bool
+	// linkify enables the links of the bare URLs, see [WithLinkify].
+	linkify bool
+	// mentions verifies the mentioned usernames, see [WithMentionResolver].
+	mentions MentionResolver
+	// emoji enables the shortcodes, customEmoji are the emoji of the site, see [WithEmoji].
+	emoji       bool
+	customEmoji *EmojiRegistry
+	// budget bounds the munched inputs, see [WithBudget].
+	budget Budget
+	// tags are the handlers of the tags by name, see [TagHandler].
+	tags tagHandlers
+	// extraTags are added to the dialect, see [WithTag].
+	extraTags []scum.TagSpec
+	// err is the first error of the options, returned by [NewEater].
+	err error
+}
+
+// Option configures optional [Eater] behavior.
+type Option func(*Eater)
+
+// WithCanonicalSource makes the [Eater] normalize the input into [Poop.Canonical], so equivalent
+// markup written in different ways is stored in a single form. The normalization runs after parsing
+// and doesn't affect the tree or the issues.
+func WithCanonicalSource() Option {
+	return func(e *Eater) {
+		e.canonical = true
+	}
 }
 
 // Munch parses and normalizes the input, returning a [Poop] and all syntax issues
 // found while parsing, validating and normalizing.
 func (p *Eater) Munch(input string) (Poop, []SyntaxIssue) {
+	return p.MunchContext(context.Background(), input)
+}
+
+// MunchContext is like [Eater.Munch], with the context passed to the [MentionResolver].
+func (p *Eater) MunchContext(ctx context.Context, input string) (Poop, []SyntaxIssue) {
+	var poop Poop
+	issues := p.MunchIntoContext(ctx, &poop, input)
+	return poop, issues
+}
+
+// MunchInto is like [Eater.Munch], but parses into dst with [scum.ParseInto], reusing the backing
+// arrays of dst.AST. It lets the callers parsing many inputs, like the editor preview, pool their Poops.
+// The previous content of dst is invalid after the call.
+func (p *Eater) MunchInto(dst *Poop, input string) []SyntaxIssue {
+	return p.MunchIntoContext(context.Background(), dst, input)
+}
+
+// MunchIntoContext is like [Eater.MunchInto], with the context passed to the [MentionResolver].
+func (p *Eater) MunchIntoContext(ctx context.Context, dst *Poop, input string) []SyntaxIssue {
+	start := time.Now()
 	w, _ := scum.NewWarnings(p.warningOverflowPolicy, p.warnCap)
-	ast := scum.Parse(input, &p.dict, &w)
-	tree := ast.Serialize(&p.dict)
+	scum.ParseInto(&dst.AST, input, &p.dict, &w)
+	tree := dst.AST.Serialize(&p.dict)
 	issues := NewIssues(len(input) / 10)
-	normalizeRenderTree(&tree, &issues)
+	normalizeRenderTree(&tree, p.tags, &issues)
+	if p.linkify {
+		// before the mentions, so the @ in the URLs is not taken for one
+		linkify(&tree, p.tags)
+	}
+	dst.Mentions = dst.Mentions[:0]
+	if p.mentions != nil {
+		resolveMentions(ctx, p.mentions, input, &tree, &dst.Mentions, &issues)
+	}
+	shrink := 0
+	if p.emoji {
+		shrink += replaceEmoji(&tree, newEmojiLookup(p.customEmoji), p.tags)
+	}
+	// the mentions are found by the spans of the text, so the escapes are removed after them
+	shrink += unescapeTree(&tree, p.tags)
+	p.checkBudget(input, &tree, dst.AST.TextByteLen-shrink, &issues)
 	scumWarns := make([]scum.SerializableWarning, 0, w.WarnCount())
 	w.SerializeAll(&scumWarns, &p.dict)
 	warns := make([]SyntaxIssue, 0, w.WarnCount())
@@ -110,67 +285,55 @@ func (p *Eater) Munch(input string) (Poop, []SyntaxIssue) {
 		warns = append(warns, Warning{w})
 	}
 	allIssues := slices.Concat(warns, issues.List)
-	return Poop{
-		Input: input,
-		AST:   ast,
-		Tree:  tree,
-	}, allIssues
+	locateIssues(allIssues, input)
+	recordMunch(start, allIssues)
+	dst.Input = input
+	dst.Tree = tree
+	dst.tags = p.tags
+	dst.textShrink = shrink
+	dst.Canonical = ""
+	if p.canonical {
+		dst.Canonical = scum.Format(dst.AST, &p.dict)
+	}
+	return allIssues
 }
 
 // It will return a *[ConfigError] if invalid arguments passed.
-func NewEater(warnPol scum.WarningOverflowPolicy, warnCap int) (Eater, error) {
-	d, err := scum.NewDictionary(scum.Limits{})
-	if err != nil {
-		// this should not happen
-		panic(err.Error())
-	}
-
+func NewEater(warnPol scum.WarningOverflowPolicy, warnCap int, opts ...Option) (Eater, error) {
 	// checking if provided arguments are valid.
 	// this helps to avoid error check in the Munch method
-	_, err = scum.NewWarnings(warnPol, warnCap)
+	_, err := scum.NewWarnings(warnPol, warnCap)
 	if err != nil {
 		return Eater{}, NewConfigError("SML Parser", ReasonInvalidParams, err)
 	}
 
-	err = d.AddUniversalTag(Bold, []byte{'$'}, scum.NonGreedy, scum.RuleNA)
-	if err != nil {
-		// this should not happen
-		panic(err.Error())
-	}
-	err = d.AddUniversalTag(Italic, []byte{'*'}, scum.NonGreedy, scum.RuleNA)
-	if err != nil {
-		// this should not happen
-		panic(err.Error())
+	e := Eater{
+		warningOverflowPolicy: warnPol,
+		warnCap:               warnCap,
+		tags:                  maps.Clone(builtinTags),
 	}
-	err = d.AddUniversalTag(Underline, []byte{'_'}, scum.NonGreedy, scum.RuleInfraWord)
-	if err != nil {
-		// this should not happen
-		panic(err.Error())
+
+	for _, opt := range opts {
+		opt(&e)
 	}
-	err = d.AddTag(Link, []byte{'['}, scum.NonGreedy, scum.RuleNA, 0, ']')
-	if err != nil {
-		// this should not happen
-		panic(err.Error())
+	if e.err != nil {
+		return Eater{}, NewConfigError("SML Parser", ReasonInvalidParams, e.err)
 	}
-	err = d.AddTag(Link, []byte{']'}, scum.NonGreedy, scum.RuleNA, '[', 0)
-	if err != nil {
-		// this should not happen
-		panic(err.Error())
-	}
-	err = d.SetAttributeSignature('!', '{', '}')
+
+	spec, err := scum.ParseSpec(dialect)
 	if err != nil {
-		// this should not happen
+		// this should not happen, the dialect is covered by the tests
 		panic(err.Error())
 	}
-	err = d.SetEscapeTrigger('\\')
+	spec.Tags = append(spec.Tags, e.extraTags...)
+
+	e.dict, err = scum.NewDictionaryFromSpec(spec)
 	if err != nil {
-		// this should not happen
-		panic(err.Error())
+		if len(e.extraTags) == 0 {
+			panic(err.Error())
+		}
+		return Eater{}, NewConfigError("SML Parser", ReasonInvalidParams, err)
 	}
 
-	return Eater{
-		dict:                  d,
-		warningOverflowPolicy: warnPol,
-		warnCap:               warnCap,
-	}, nil
+	return e, nil
 }
@@ -19,6 +19,27 @@ func TestPoopText(t *testing.T) {
 	require.Equal(t, len("pre bold link hé"), poop.TextByteLen())
 }
 
+func TestPoopText_FormattingTags(t *testing.T) {
+	eater, err := NewEater(scum.WarnOverflowNoCap, 0)
+	require.NoError(t, err)
+
+	poop, issues := eater.Munch("~~old~~ ||twist|| x^2^ ``a `$b$` \\c``")
+
+	require.Empty(t, issues)
+	require.Equal(t, "old twist x2 a `$b$` \\c", poop.Text())
+	require.Equal(t, len(poop.Text()), poop.TextByteLen())
+}
+
+func TestPoopTextByteLen_WithoutEscapesAndShortcodes(t *testing.T) {
+	eater, err := NewEater(scum.WarnOverflowNoCap, 0, WithEmoji(nil))
+	require.NoError(t, err)
+
+	poop, _ := eater.Munch("\\$5 $\\*$ :smile: `\\$` :nope:")
+
+	require.Equal(t, "$5 * 😄 \\$ :nope:", poop.Text())
+	require.Equal(t, len(poop.Text()), poop.TextByteLen())
+}
+
 func TestEaterMunch_ReturnsParserIssues(t *testing.T) {
 	eater, err := NewEater(scum.WarnOverflowNoCap, 0)
 	require.NoError(t, err)
@@ -52,3 +73,67 @@ func requireIssueCodename(t *testing.T, issues []SyntaxIssue, codename string) {
 
 	require.Failf(t, "missing issue codename", "expected issue codename %q in %#v", codename, issues)
 }
+
+func TestDialect_DefinesTheSMLTags(t *testing.T) {
+	d, err := scum.LoadDictionary(Dialect())
+	require.NoError(t, err)
+
+	names := make(map[string]bool)
+	for _, tag := range d.Spec().Tags {
+		names[tag.Name] = true
+	}
+
+	require.Equal(t, map[string]bool{
+		Bold:        true,
+		Italic:      true,
+		Underline:   true,
+		Strike:      true,
+		Spoiler:     true,
+		Code:        true,
+		Superscript: true,
+		Link:        true,
+	}, names)
+}
+
+func TestEaterMunch_IssuePositions(t *testing.T) {
+	eater, err := NewEater(scum.WarnOverflowNoCap, 0)
+	require.NoError(t, err)
+
+	input := "héllo\n[link]!href{javascript:alert(1)} $bold"
+	_, issues := eater.Munch(input)
+	require.Len(t, issues, 2)
+
+	// the parser warning comes first
+	warn, ok := issues[0].(Warning)
+	require.True(t, ok)
+	require.Equal(t, "UNCLOSED_TAG", warn.Codename())
+	require.Equal(t, "$", input[warn.Span().Start:warn.Span().End])
+	require.Equal(t, 2, warn.Start.Line)
+	require.Equal(t, 34, warn.Start.Column)
+
+	desc, ok := issues[1].(SyntaxIssueDescriptor)
+	require.True(t, ok)
+	require.Equal(t, "ATTRIBUTE_INVALID_PAYLOAD", desc.Codename())
+	require.Equal(t, "javascript:alert(1)", input[desc.Span().Start:desc.Span().End])
+	require.Equal(t, scum.Position{Offset: 19, Line: 2, Column: 13, Rune: 18, UTF16: 18}, desc.Start)
+	require.Equal(t, 38, desc.End.Offset)
+}
+
+func TestEaterMunch_CanonicalSource(t *testing.T) {
+	eater, err := NewEater(scum.WarnOverflowNoCap, 0, WithCanonicalSource())
+	require.NoError(t, err)
+
+	poop, _ := eater.Munch(`$bold *it \$ [link $x]!href{https://example.com}`)
+	require.Equal(t, `$bold *it \$ [link x]!href{https://example.com}*$`, poop.Canonical)
+
+	canonical, issues := eater.Munch(poop.Canonical)
+	require.Empty(t, issues)
+	require.Equal(t, poop.HTML(), canonical.HTML())
+	require.Equal(t, poop.Canonical, canonical.Canonical)
+
+	plain, err := NewEater(scum.WarnOverflowNoCap, 0)
+	require.NoError(t, err)
+
+	poop, _ = plain.Munch("$bold")
+	require.Empty(t, poop.Canonical)
+}
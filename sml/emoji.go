@@ -0,0 +1,360 @@
+package sml
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Drolfothesgnir/shitposter/scum"
+)
+
+//go:generate go run gen_emoji.go
+
+// The bounds of the shortcode names, without the colons. The longest standard one,
+// like :flag_south_georgia_south_sandwich_islands:, is shorter than maxShortcodeLen.
+const (
+	maxShortcodeLen       = 64
+	maxCustomShortcodeLen = 32
+)
+
+// emojiClass is the class of the images of the custom emoji.
+const emojiClass = "sml-internal-emoji"
+
+// WithEmoji makes the [Eater] replace the :shortcode: of the emoji in the text with [Emoji] tags,
+// looking them up in the custom emoji of r first, and then in the standard Unicode emoji, like :smile: or :+1:.
+// The registry can be nil for the standard emoji only. The unknown shortcodes stay plain text.
+//
+// The text inside the mentions and the verbatim tags, like [Code], is not replaced.
+func WithEmoji(r *EmojiRegistry) Option {
+	return func(e *Eater) {
+		e.emoji = true
+		e.customEmoji = r
+	}
+}
+
+// CustomEmoji is an emoji of the site, rendered as an image, see [EmojiRegistry].
+type CustomEmoji struct {
+	// Name is the shortcode without the colons: 1 to 32 lowercase letters, digits, "_", "+" or "-".
+	Name string `json:"name"`
+	// URL is the address of the image: absolute with the http or https scheme, or a path of the site.
+	URL string `json:"url"`
+}
+
+// validate returns the error describing why the emoji can't be registered, if it can't.
+func (e CustomEmoji) validate() error {
+	if e.Name == "" || len(e.Name) > maxCustomShortcodeLen {
+		return fmt.Errorf("custom emoji name %q must be 1 to %d characters long", e.Name, maxCustomShortcodeLen)
+	}
+	for i := 0; i < len(e.Name); i++ {
+		if c := e.Name[i]; !isShortcodeByte(c) || 'A' <= c && c <= 'Z' {
+			return fmt.Errorf("custom emoji name %q must contain only lowercase letters, digits, \"_\", \"+\" or \"-\"", e.Name)
+		}
+	}
+
+	if strings.ContainsAny(e.URL, "\x00\r\n\t") || strings.HasPrefix(e.URL, "//") {
+		return fmt.Errorf("custom emoji %q URL %q is not allowed", e.Name, e.URL)
+	}
+	u, err := url.Parse(e.URL)
+	if err != nil {
+		return fmt.Errorf("custom emoji %q URL is invalid: %w", e.Name, err)
+	}
+
+	switch {
+	case u.Scheme == "http" || u.Scheme == "https":
+		if u.Host == "" {
+			return fmt.Errorf("custom emoji %q URL %q has no host", e.Name, e.URL)
+		}
+	case u.Scheme != "" || !strings.HasPrefix(e.URL, "/"):
+		return fmt.Errorf("custom emoji %q URL %q must be an http or https URL, or a path of the site", e.Name, e.URL)
+	}
+
+	return nil
+}
+
+// EmojiRegistry holds the custom emoji of the site, see [WithEmoji]. It is safe for concurrent use,
+// so the emoji can be changed at runtime, like when a moderator adds one, and the inputs munched after
+// the change use them. Each input is munched with the emoji of a single moment.
+type EmojiRegistry struct {
+	// mu serializes the changes, the readers load the current map without locking
+	mu    sync.Mutex
+	emoji atomic.Pointer[map[string]CustomEmoji]
+}
+
+// NewEmojiRegistry returns the registry of the emoji. It returns an error if any of them is invalid.
+func NewEmojiRegistry(emoji ...CustomEmoji) (*EmojiRegistry, error) {
+	r := &EmojiRegistry{}
+	if err := r.Replace(emoji); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Get returns the custom emoji with the name.
+func (r *EmojiRegistry) Get(name string) (CustomEmoji, bool) {
+	m := r.emoji.Load()
+	if m == nil {
+		return CustomEmoji{}, false
+	}
+
+	e, ok := (*m)[name]
+	return e, ok
+}
+
+// List returns the custom emoji sorted by name.
+func (r *EmojiRegistry) List() []CustomEmoji {
+	m := r.emoji.Load()
+	if m == nil {
+		return nil
+	}
+
+	return slices.SortedFunc(maps.Values(*m), func(a, b CustomEmoji) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+}
+
+// Set adds the emoji, replacing the one with the same name.
+func (r *EmojiRegistry) Set(e CustomEmoji) error {
+	if err := e.validate(); err != nil {
+		return err
+	}
+
+	r.update(func(m map[string]CustomEmoji) {
+		m[e.Name] = e
+	})
+	return nil
+}
+
+// Remove removes the emoji with the name, if there is one.
+func (r *EmojiRegistry) Remove(name string) {
+	r.update(func(m map[string]CustomEmoji) {
+		delete(m, name)
+	})
+}
+
+// Replace replaces all the emoji. If any of them is invalid, the registry is not changed.
+func (r *EmojiRegistry) Replace(emoji []CustomEmoji) error {
+	m := make(map[string]CustomEmoji, len(emoji))
+	for _, e := range emoji {
+		if err := e.validate(); err != nil {
+			return err
+		}
+		m[e.Name] = e
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.emoji.Store(&m)
+	return nil
+}
+
+// Load replaces all the emoji with the ones of the JSON array, like [{"name": "party_parrot", "url": "/emoji/parrot.gif"}].
+// If the JSON or any of the emoji is invalid, the registry is not changed.
+func (r *EmojiRegistry) Load(src io.Reader) error {
+	var emoji []CustomEmoji
+	if err := json.NewDecoder(src).Decode(&emoji); err != nil {
+		return fmt.Errorf("custom emoji can't be decoded: %w", err)
+	}
+	return r.Replace(emoji)
+}
+
+// update changes the copy of the emoji with fn and makes it the current one.
+func (r *EmojiRegistry) update(fn func(m map[string]CustomEmoji)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m := make(map[string]CustomEmoji)
+	if cur := r.emoji.Load(); cur != nil {
+		m = maps.Clone(*cur)
+	}
+	fn(m)
+	r.emoji.Store(&m)
+}
+
+// emojiLookup finds the emoji by the shortcode name, in the custom ones first.
+type emojiLookup struct {
+	custom map[string]CustomEmoji
+}
+
+// newEmojiLookup returns the lookup of the current emoji of the registry, which can be nil.
+func newEmojiLookup(r *EmojiRegistry) emojiLookup {
+	var l emojiLookup
+	if r != nil {
+		if m := r.emoji.Load(); m != nil {
+			l.custom = *m
+		}
+	}
+	return l
+}
+
+// node returns the [Emoji] tag of the shortcode, or false if it's unknown.
+func (l emojiLookup) node(content string, span scum.Span) (scum.SerializableNode, bool) {
+	name := content[1 : len(content)-1]
+	n := scum.SerializableNode{
+		Name:       Emoji,
+		Type:       "Tag",
+		Attributes: []scum.SerializableAttribute{},
+		Content:    content,
+		Span:       span,
+		Children:   []scum.SerializableNode{},
+	}
+
+	if e, ok := l.custom[name]; ok {
+		n.Attributes = append(n.Attributes, scum.SerializableAttribute{Name: "src", Payload: e.URL})
+		return n, true
+	}
+
+	if e, ok := standardEmoji[name]; ok {
+		n.Children = append(n.Children, textNode(e, span))
+		return n, true
+	}
+
+	return scum.SerializableNode{}, false
+}
+
+// replaceEmoji replaces the known shortcodes in the text nodes of the tree with the [Emoji] tags.
+// It returns the number of the bytes the text got shorter by, the Unicode emoji are shorter than their shortcodes.
+func replaceEmoji(n *scum.SerializableNode, l emojiLookup, tags tagHandlers) (removed int) {
+	// out is allocated only when the first text node is split
+	var out []scum.SerializableNode
+
+	for i := range n.Children {
+		c := &n.Children[i]
+
+		if c.Type != "Text" {
+			if c.Type == "Tag" && c.Name != Mention && c.Name != Emoji && !tags[c.Name].Verbatim {
+				removed += replaceEmoji(c, l, tags)
+			}
+			if out != nil {
+				out = append(out, *c)
+			}
+			continue
+		}
+
+		split := false
+		last := 0
+
+		for start, end := nextShortcode(c.Content, 0); start >= 0; start, end = nextShortcode(c.Content, end) {
+			span := scum.Span{Start: c.Span.Start + start, End: c.Span.Start + end}
+			emoji, ok := l.node(c.Content[start:end], span)
+			if !ok {
+				// the closing colon can open the next shortcode, like in :unknown:smile:
+				end--
+				continue
+			}
+
+			if out == nil {
+				out = make([]scum.SerializableNode, 0, len(n.Children)+2)
+				out = append(out, n.Children[:i]...)
+			}
+
+			if start > last {
+				out = append(out, textNode(c.Content[last:start], scum.Span{Start: c.Span.Start + last, End: span.Start}))
+			}
+			out = append(out, emoji)
+			if len(emoji.Children) > 0 {
+				removed += len(emoji.Content) - len(emoji.Children[0].Content)
+			}
+
+			last = end
+			split = true
+		}
+
+		switch {
+		case !split:
+			if out != nil {
+				out = append(out, *c)
+			}
+		case last < len(c.Content):
+			out = append(out, textNode(c.Content[last:], scum.Span{Start: c.Span.Start + last, End: c.Span.End}))
+		}
+	}
+
+	if out != nil {
+		n.Children = out
+	}
+	return removed
+}
+
+// nextShortcode returns the offsets of the first :shortcode: in s at or after i, including the colons,
+// or -1 if there is none. The escaped colon doesn't open a shortcode.
+func nextShortcode(s string, i int) (start, end int) {
+	for {
+		j := strings.IndexByte(s[i:], ':')
+		if j < 0 {
+			return -1, -1
+		}
+
+		start = i + j
+		end = start + 1
+		for end < len(s) && end-start <= maxShortcodeLen && isShortcodeByte(s[end]) {
+			end++
+		}
+
+		if end < len(s) && s[end] == ':' && end > start+1 && end-start-1 <= maxShortcodeLen && !isEscaped(s, start) {
+			return start, end + 1
+		}
+
+		i = end
+	}
+}
+
+func isShortcodeByte(c byte) bool {
+	return isASCIIAlnum(c) || c == '_' || c == '+' || c == '-'
+}
+
+// emojiSrc returns the image of the custom emoji, or "" for the Unicode one.
+func emojiSrc(n *scum.SerializableNode) string {
+	for _, a := range n.Attributes {
+		if a.Name == "src" {
+			return a.Payload
+		}
+	}
+	return ""
+}
+
+// renderEmoji writes the Unicode emoji as its character, and the custom one as the image with the shortcode as its alt text.
+func renderEmoji(r *Renderer, n *scum.SerializableNode) {
+	src := emojiSrc(n)
+	if src == "" {
+		r.Children(n)
+		return
+	}
+
+	r.WriteString(`<img class="` + emojiClass + `" src="`)
+	r.Text(src)
+	r.WriteString(`" alt="`)
+	r.Text(n.Content)
+	r.WriteString(`" title="`)
+	r.Text(n.Content)
+	r.WriteString(`">`)
+}
+
+// textEmoji writes the Unicode emoji as its character, and the custom one as its shortcode.
+func textEmoji(r *Renderer, n *scum.SerializableNode) {
+	if emojiSrc(n) == "" {
+		r.Children(n)
+		return
+	}
+	r.Text(n.Content)
+}
+
+// markdownEmoji writes the Unicode emoji as its character, and the custom one as the image.
+func markdownEmoji(r *Renderer, n *scum.SerializableNode) {
+	src := emojiSrc(n)
+	if src == "" {
+		r.Children(n)
+		return
+	}
+
+	r.WriteString("![")
+	r.Text(n.Content)
+	r.WriteString("](")
+	writeMarkdownDestination(r, src)
+	r.WriteByte(')')
+}
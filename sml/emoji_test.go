@@ -0,0 +1,175 @@
+package sml
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Drolfothesgnir/shitposter/scum"
+	"github.com/stretchr/testify/require"
+)
+
+func emojiEater(t *testing.T, r *EmojiRegistry) Eater {
+	t.Helper()
+
+	eater, err := NewEater(scum.WarnOverflowNoCap, 0, WithEmoji(r))
+	require.NoError(t, err)
+	return eater
+}
+
+func TestEmoji_Standard(t *testing.T) {
+	eater := emojiEater(t, nil)
+
+	tests := []struct {
+		name  string
+		input string
+		html  string
+		text  string
+	}{
+		{
+			name:  "shortcodes",
+			input: "hi :smile: :+1: $:tada:$",
+			html:  "hi 😄 👍 <strong>🎉</strong>",
+			text:  "hi 😄 👍 🎉",
+		},
+		{
+			name:  "unknown shortcodes stay text",
+			input: ":nope: 10:30:45 :octocat: ::",
+			html:  ":nope: 10:30:45 :octocat: ::",
+			text:  ":nope: 10:30:45 :octocat: ::",
+		},
+		{
+			name:  "closing colon opens the next shortcode",
+			input: ":nope:smile:",
+			html:  ":nope😄",
+			text:  ":nope😄",
+		},
+		{
+			name:  "not in code",
+			input: "`:smile:` [:smile:]!href{/x}",
+			html:  `<code>:smile:</code> <a href="/x">😄</a>`,
+			text:  ":smile: 😄",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			poop, issues := eater.Munch(tt.input)
+
+			require.Empty(t, issues)
+			require.Equal(t, tt.html, poop.HTML())
+			require.Equal(t, tt.text, poop.Text())
+		})
+	}
+}
+
+func TestEmoji_EscapedColon(t *testing.T) {
+	eater := emojiEater(t, nil)
+
+	// the escape is redundant for the parser, but it still keeps the colon plain
+	poop, _ := eater.Munch(`\:smile: \\:smile:`)
+	require.Equal(t, `:smile: \😄`, poop.HTML())
+}
+
+func TestEmoji_Disabled(t *testing.T) {
+	eater, err := NewEater(scum.WarnOverflowNoCap, 0)
+	require.NoError(t, err)
+
+	poop, _ := eater.Munch(":smile:")
+	require.Equal(t, ":smile:", poop.HTML())
+}
+
+func TestEmoji_Custom(t *testing.T) {
+	r, err := NewEmojiRegistry(
+		CustomEmoji{Name: "party_parrot", URL: "/emoji/parrot.gif?v=1&x=<y>"},
+		CustomEmoji{Name: "smile", URL: "https://cdn.example.com/smile.png"},
+	)
+	require.NoError(t, err)
+	eater := emojiEater(t, r)
+
+	poop, issues := eater.Munch("yay :party_parrot: :smile: :wave:")
+
+	require.Empty(t, issues)
+	require.Equal(t, `yay <img class="sml-internal-emoji" src="/emoji/parrot.gif?v=1&amp;x=&lt;y&gt;" alt=":party_parrot:" title=":party_parrot:"> `+
+		`<img class="sml-internal-emoji" src="https://cdn.example.com/smile.png" alt=":smile:" title=":smile:"> 👋`, poop.HTML())
+	require.Equal(t, "yay :party_parrot: :smile: 👋", poop.Text())
+	require.Equal(t, `yay ![:party\_parrot:](/emoji/parrot.gif?v=1&x=%3Cy%3E) ![:smile:](https://cdn.example.com/smile.png) 👋`, poop.Markdown())
+}
+
+func TestEmoji_Spans(t *testing.T) {
+	eater := emojiEater(t, nil)
+
+	poop, _ := eater.Munch("a :smile: b")
+
+	emoji := poop.Tree.Children[1]
+	require.Equal(t, Emoji, emoji.Name)
+	require.Equal(t, scum.Span{Start: 2, End: 9}, emoji.Span)
+	require.Equal(t, scum.Span{Start: 9, End: 11}, poop.Tree.Children[2].Span)
+}
+
+func TestEmojiRegistry_ChangesAtRuntime(t *testing.T) {
+	r, err := NewEmojiRegistry()
+	require.NoError(t, err)
+	eater := emojiEater(t, r)
+
+	poop, _ := eater.Munch(":blob:")
+	require.Equal(t, ":blob:", poop.Text())
+
+	require.NoError(t, r.Set(CustomEmoji{Name: "blob", URL: "/emoji/blob.png"}))
+	poop, _ = eater.Munch(":blob:")
+	require.Contains(t, poop.HTML(), `src="/emoji/blob.png"`)
+
+	require.NoError(t, r.Load(strings.NewReader(`[{"name": "cat", "url": "/emoji/cat.png"}]`)))
+	require.Equal(t, []CustomEmoji{{Name: "cat", URL: "/emoji/cat.png"}}, r.List())
+
+	r.Remove("cat")
+	require.Empty(t, r.List())
+}
+
+func TestEmojiRegistry_RejectsInvalid(t *testing.T) {
+	r, err := NewEmojiRegistry(CustomEmoji{Name: "ok", URL: "/ok.png"})
+	require.NoError(t, err)
+
+	invalid := []CustomEmoji{
+		{Name: "", URL: "/a.png"},
+		{Name: "Upper", URL: "/a.png"},
+		{Name: "with space", URL: "/a.png"},
+		{Name: strings.Repeat("a", maxCustomShortcodeLen+1), URL: "/a.png"},
+		{Name: "js", URL: "javascript:alert(1)"},
+		{Name: "proto", URL: "//evil.example/a.png"},
+		{Name: "relative", URL: "a.png"},
+		{Name: "nohost", URL: "https:///a.png"},
+	}
+	for _, e := range invalid {
+		require.Error(t, r.Set(e), e.Name)
+		require.Error(t, r.Replace([]CustomEmoji{{Name: "fine", URL: "/f.png"}, e}), e.Name)
+	}
+
+	require.Error(t, r.Load(strings.NewReader(`{"name": "ok"}`)))
+	require.Equal(t, []CustomEmoji{{Name: "ok", URL: "/ok.png"}}, r.List())
+}
+
+func TestEmojiRegistry_ConcurrentUse(t *testing.T) {
+	r, err := NewEmojiRegistry()
+	require.NoError(t, err)
+	eater := emojiEater(t, r)
+
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				require.NoError(t, r.Set(CustomEmoji{Name: "e" + string(rune('a'+i)), URL: "/e.png"}))
+				r.Remove("e" + string(rune('a'+i)))
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				eater.Munch(":ea: :eb: :smile:")
+			}
+		}()
+	}
+	wg.Wait()
+}
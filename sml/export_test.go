@@ -0,0 +1,103 @@
+package sml
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Drolfothesgnir/shitposter/scum"
+	"github.com/stretchr/testify/require"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files of the exporters")
+
+// goldenPlainText are the options of the plain text golden files.
+var goldenPlainText = PlainTextOptions{LinkFootnotes: true, Width: 40}
+
+// TestPoopExport_Golden compares the exports of each testdata/export/*.sml input with its .md and .txt golden files.
+// Run with -update to rewrite them.
+func TestPoopExport_Golden(t *testing.T) {
+	inputs, err := filepath.Glob("testdata/export/*.sml")
+	require.NoError(t, err)
+	require.NotEmpty(t, inputs)
+
+	eater, err := NewEater(scum.WarnOverflowNoCap, 0)
+	require.NoError(t, err)
+
+	for _, path := range inputs {
+		base := strings.TrimSuffix(path, ".sml")
+
+		t.Run(filepath.Base(base), func(t *testing.T) {
+			input, err := os.ReadFile(path)
+			require.NoError(t, err)
+
+			poop, _ := eater.Munch(string(input))
+
+			requireGolden(t, base+".md", poop.Markdown())
+			requireGolden(t, base+".txt", poop.PlainText(goldenPlainText))
+		})
+	}
+}
+
+func requireGolden(t *testing.T, path, got string) {
+	t.Helper()
+
+	if *updateGolden {
+		require.NoError(t, os.WriteFile(path, []byte(got), 0o644))
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, string(want), got, path)
+}
+
+func TestPoopMarkdown_CodeSpans(t *testing.T) {
+	eater, err := NewEater(scum.WarnOverflowNoCap, 0)
+	require.NoError(t, err)
+
+	cases := []struct {
+		input string
+		want  string
+	}{
+		{"`plain`", "`plain`"},
+		{"`*not* [markdown]`", "`*not* [markdown]`"},
+		{"``a ` b``", "``a ` b``"},
+		{"``` `` ```", "```  ``  ```"},
+		{"`` `tick``", "`` `tick``"},
+		{"`  spaced  `", "`   spaced   `"},
+		{"`   `", "`   `"},
+	}
+
+	for _, tc := range cases {
+		poop, issues := eater.Munch(tc.input)
+		require.Empty(t, issues, tc.input)
+		require.Equal(t, tc.want, poop.Markdown(), tc.input)
+	}
+}
+
+func TestPoopMarkdown_Mention(t *testing.T) {
+	eater := mentionEater(t, &fakeResolver{users: map[string]int64{"alice": 1}})
+
+	poop, _ := eater.Munch("hi <@alice>")
+	require.Equal(t, `hi \<[@alice](/users/1)\>`, poop.Markdown())
+}
+
+func TestPoopPlainText_ZeroOptionsIsText(t *testing.T) {
+	eater, err := NewEater(scum.WarnOverflowNoCap, 0)
+	require.NoError(t, err)
+
+	poop, _ := eater.Munch("a [link]!href{https://example.com} and $bold$ text")
+
+	require.Equal(t, poop.Text(), poop.PlainText(PlainTextOptions{}))
+	require.Equal(t, "a link and bold text", poop.Text())
+}
+
+func TestWrapText(t *testing.T) {
+	require.Equal(t, "aaa bbb\nccc", wrapText("aaa bbb ccc", 7))
+	require.Equal(t, "aaa\nbbbbbbbbbb\nc", wrapText("aaa bbbbbbbbbb c", 5))
+	require.Equal(t, "short  spaced\n\nnext", wrapText("short  spaced\n\nnext", 20))
+	require.Equal(t, "ää ää\nää", wrapText("ää ää ää", 5))
+}
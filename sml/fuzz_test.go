@@ -1,6 +1,7 @@
 package sml
 
 import (
+	"html"
 	"strings"
 	"testing"
 
@@ -18,6 +19,10 @@ func FuzzPoopHTML_DeterministicAndTextLengthInvariant(f *testing.F) {
 		`[link]!href{javascript:alert(1)}!target{_parent}`,
 		`$unclosed *nested [link]!href{//evil.example}`,
 		`slashes \ \$ \* \_ \[ \]`,
+		`~~strike~~ ||spoiler|| x^2^`,
+		"`code $x$ [y]!href{z}` ``a `b` c``",
+		"~~||`^nested^`||~~ ^||unclosed ~~",
+		"[~~a~~ ||b|| `c`]!href{/x}",
 		string([]byte{'[', 'x', ']', '!', 'h', 'r', 'e', 'f', '{', 0xff, '}'}),
 	}
 	for _, seed := range seeds {
@@ -51,6 +56,44 @@ func FuzzPoopHTML_DeterministicAndTextLengthInvariant(f *testing.F) {
 	})
 }
 
+func FuzzInlineCode_RendersContentVerbatim(f *testing.F) {
+	seeds := []string{
+		"x",
+		"$bold$ *italic* [link]!href{https://example.com}",
+		`\$ \\ \`,
+		"<script>&amp;</script>",
+		"  spaced  ",
+		"~~ || ^",
+		string([]byte{0xff, 'a'}),
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	eater, err := NewEater(scum.WarnOverflowNoCap, 0)
+	if err != nil {
+		f.Fatalf("NewEater: %v", err)
+	}
+
+	f.Fuzz(func(t *testing.T, content string) {
+		if content == "" || strings.Contains(content, "`") || len(content) > scum.DefaultMaxPayloadLen {
+			return
+		}
+
+		poop, issues := eater.Munch("`" + content + "`")
+		if len(issues) != 0 {
+			t.Fatalf("code %q got issues: %v", content, issues)
+		}
+
+		if got, want := poop.HTML(), "<code>"+html.EscapeString(content)+"</code>"; got != want {
+			t.Fatalf("code is not rendered verbatim:\ngot:  %q\nwant: %q", got, want)
+		}
+		if poop.Text() != content {
+			t.Fatalf("code text mismatch: got %q, want %q", poop.Text(), content)
+		}
+	})
+}
+
 func FuzzAttrHref_Invariants(f *testing.F) {
 	seeds := []string{
 		"",
@@ -94,6 +137,39 @@ func FuzzAttrHref_Invariants(f *testing.F) {
 	})
 }
 
+func FuzzLinkify_KeepsTextAndIssues(f *testing.F) {
+	seeds := []string{
+		"see https://example.com/a?b=1&c=2.",
+		"(https://en.wikipedia.org/wiki/Go_(language)) www.example.com, bob@example.com",
+		`$https://example.com/\*x\*$ [https://a.example]!href{/x} ` + "`www.b.example`",
+		"https://bank.com@evil.com https://pаypal.com @alice a@b.c",
+		"https://example.com/@alice_x_ www.",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	plain, err := NewEater(scum.WarnOverflowNoCap, 0)
+	if err != nil {
+		f.Fatalf("NewEater: %v", err)
+	}
+	linked, err := NewEater(scum.WarnOverflowNoCap, 0, WithLinkify())
+	if err != nil {
+		f.Fatalf("NewEater: %v", err)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		want, wantIssues := plain.Munch(input)
+		got, gotIssues := linked.Munch(input)
+
+		// the bare links only wrap the text, so the text and the issues stay the same
+		if want.Text() != got.Text() {
+			t.Fatalf("linkify changed the text:\nplain:  %q\nlinked: %q", want.Text(), got.Text())
+		}
+		assertSameIssues(t, wantIssues, gotIssues)
+	})
+}
+
 func assertSameIssues(t *testing.T, a, b []SyntaxIssue) {
 	t.Helper()
 
@@ -0,0 +1,97 @@
+//go:build ignore
+
+// gen_emoji generates emoji_table.go, the shortcodes of the standard emoji, from the emoji database
+// of gemoji, the library GitHub uses for its shortcodes. Run it with:
+//
+//	go generate ./sml
+//
+// or, to generate the table from a local copy of the database:
+//
+//	go run gen_emoji.go -src emoji.json
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// gemojiURL is the database of the pinned gemoji release.
+const gemojiURL = "https://raw.githubusercontent.com/github/gemoji/v4.1.0/db/emoji.json"
+
+// gemoji is an entry of the database, the fields not used by the table are skipped.
+type gemoji struct {
+	Emoji   string   `json:"emoji"`
+	Aliases []string `json:"aliases"`
+}
+
+func main() {
+	src := flag.String("src", gemojiURL, "the URL or the path of the gemoji database")
+	out := flag.String("o", "emoji_table.go", "the path of the generated file")
+	flag.Parse()
+
+	data, err := read(*src)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var db []gemoji
+	if err := json.Unmarshal(data, &db); err != nil {
+		log.Fatalf("can't decode %s: %v", *src, err)
+	}
+
+	table := make(map[string]string)
+	for _, e := range db {
+		for _, alias := range e.Aliases {
+			if _, ok := table[alias]; !ok {
+				table[alias] = e.Emoji
+			}
+		}
+	}
+
+	var b bytes.Buffer
+	b.WriteString("// Code generated by gen_emoji.go from the gemoji database. DO NOT EDIT.\n\n")
+	b.WriteString("package sml\n\n")
+	b.WriteString("// standardEmoji maps the shortcodes of the standard emoji, without the colons, to their characters.\n")
+	b.WriteString("var standardEmoji = map[string]string{\n")
+	for _, name := range slices.Sorted(maps.Keys(table)) {
+		fmt.Fprintf(&b, "\t%s: %s,\n", strconv.Quote(name), strconv.QuoteToGraphic(table[name]))
+	}
+	b.WriteString("}\n")
+
+	code, err := format.Source(b.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, code, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// read returns the content of the URL or of the file.
+func read(src string) ([]byte, error) {
+	if !strings.HasPrefix(src, "https://") {
+		return os.ReadFile(src)
+	}
+
+	resp, err := http.Get(src)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("can't download %s: %s", src, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -1,79 +1,30 @@
 package sml
 
 import (
-	"fmt"
-	"html"
-	"strings"
-
 	"github.com/Drolfothesgnir/shitposter/scum"
 )
 
+// spoilerStart is the opening of the spoiler element. The content is hidden until the reader toggles it,
+// so the element is focusable and announced as a collapsed button, the client flips aria-expanded on toggle.
+const spoilerStart = `span class="sml-internal-spoiler" role="button" tabindex="0" aria-expanded="false"`
+
 // HTML returns the parsed input as an HTML.
 func (p Poop) HTML() string {
-	var b strings.Builder
-	for _, n := range p.Tree.Children {
-		renderNode(&b, n)
-	}
-	return b.String()
-}
-
-func renderTextNode(b *strings.Builder, n scum.SerializableNode) {
-	b.WriteString(html.EscapeString(n.Content))
-}
-
-func renderTagNode(b *strings.Builder, n scum.SerializableNode) {
-	switch n.Name {
-	case Bold:
-		renderTag(b, n, "strong", "strong")
-	case Italic:
-		renderTag(b, n, "em", "em")
-	case Underline:
-		renderTag(b, n, "span class=\"sml-internal-underline\"", "span")
-	case Link:
-		renderLink(b, n)
-	default:
-		panic(fmt.Sprintf("AAAAAAAAAAAA - data corruption; SML encountered unknown tag %q", n.Name))
-	}
-}
-
-func renderNode(b *strings.Builder, n scum.SerializableNode) {
-	if n.Type == "Tag" {
-		renderTagNode(b, n)
-		return
-	}
-
-	if n.Type == "Text" {
-		renderTextNode(b, n)
-		return
-	}
-
-	panic(fmt.Sprintf("AAAAAAAAAAAAA - data corruption; SML encountered unknown node type %q", n.Type))
+	r := Renderer{tags: p.handlers(), mode: renderHTML}
+	r.Children(&p.Tree)
+	return r.String()
 }
 
-func renderTag(b *strings.Builder, n scum.SerializableNode, start, end string) {
-	b.WriteByte('<')
-	b.WriteString(start)
-	b.WriteByte('>')
-	for _, c := range n.Children {
-		renderNode(b, c)
-	}
-	b.WriteString("</")
-	b.WriteString(end)
-	b.WriteByte('>')
-}
-
-func renderLink(b *strings.Builder, n scum.SerializableNode) {
-	b.WriteString("<a")
+func renderLink(r *Renderer, n *scum.SerializableNode) {
+	r.WriteString("<a")
 	for _, a := range n.Attributes {
-		b.WriteByte(' ')
-		b.WriteString(a.Name)
-		b.WriteString(`="`)
-		b.WriteString(html.EscapeString(a.Payload))
-		b.WriteByte('"')
-	}
-	b.WriteByte('>')
-	for _, c := range n.Children {
-		renderNode(b, c)
+		r.WriteByte(' ')
+		r.WriteString(a.Name)
+		r.WriteString(`="`)
+		r.Text(a.Payload)
+		r.WriteByte('"')
 	}
-	b.WriteString("</a>")
+	r.WriteByte('>')
+	r.Children(n)
+	r.WriteString("</a>")
 }
@@ -1,6 +1,7 @@
 package sml
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/Drolfothesgnir/shitposter/scum"
@@ -43,35 +44,50 @@ func TestPoopHTML_AttributesOnNonLinkTagsAreRejected(t *testing.T) {
 	requireIssueDescription(t, Issues{List: issues}, `unknown attribute "target" for the tag "ITALIC"`)
 }
 
-func TestRenderNode_DefensiveUnknownNodeAndTagPanics(t *testing.T) {
-	require.Panics(t, func() {
-		renderNode(nil, scum.SerializableNode{
-			Type: "Tag",
-			Name: "CHAOS",
-		})
+func TestRenderer_UnknownNodeAndTagRenderAsText(t *testing.T) {
+	r := Renderer{tags: builtinTags}
+	r.Node(&scum.SerializableNode{
+		Type:    "Tag",
+		Name:    "CHAOS",
+		Content: "<chaos>",
 	})
-	require.Panics(t, func() {
-		renderNode(nil, scum.SerializableNode{
-			Type: "Portal",
-			Name: "TEXT_BUT_SIDEWAYS",
-		})
+	r.Node(&scum.SerializableNode{
+		Type:    "Portal",
+		Name:    "TEXT_BUT_SIDEWAYS",
+		Content: "&",
 	})
+
+	require.Equal(t, "&lt;chaos&gt;&amp;", r.String())
 }
 
-func TestNormalizeNode_DefensiveUnknownNodeAndTagPanics(t *testing.T) {
+func TestNormalizeNode_UnknownNodeAndTagDegradeToText(t *testing.T) {
 	issues := Issues{}
-	require.Panics(t, func() {
-		normalizeNode(&scum.SerializableNode{
-			Type: "Tag",
-			Name: "CHAOS",
-		}, &issues)
-	})
-	require.Panics(t, func() {
-		normalizeNode(&scum.SerializableNode{
-			Type: "Portal",
-			Name: "TEXT_BUT_SIDEWAYS",
-		}, &issues)
-	})
+
+	tag := scum.SerializableNode{
+		Type:     "Tag",
+		Name:     "CHAOS",
+		Content:  "~x~",
+		Span:     scum.Span{Start: 2, End: 5},
+		Children: []scum.SerializableNode{{Type: "Text", Name: "TEXT", Content: "x"}},
+	}
+	normalizeNode(&tag, builtinTags, &issues)
+
+	portal := scum.SerializableNode{
+		Type:    "Portal",
+		Name:    "TEXT_BUT_SIDEWAYS",
+		Content: "y",
+	}
+	normalizeNode(&portal, builtinTags, &issues)
+
+	require.Equal(t, "Text", tag.Type)
+	require.Equal(t, "~x~", tag.Content)
+	require.Empty(t, tag.Children)
+	require.Equal(t, "Text", portal.Type)
+
+	require.Len(t, issues.List, 2)
+	require.Equal(t, "UNKNOWN_TAG", issues.List[0].Codename())
+	require.Equal(t, scum.Span{Start: 2, End: 5}, issues.List[0].Span())
+	require.Equal(t, "UNKNOWN_NODE_TYPE", issues.List[1].Codename())
 }
 
 func TestPoopHTML_NestedLinkAttributesAreNormalized(t *testing.T) {
@@ -85,6 +101,25 @@ func TestPoopHTML_NestedLinkAttributesAreNormalized(t *testing.T) {
 	require.Equal(t, `<strong>bold <a href="https://example.com?q=1&amp;x=&lt;y&gt;" target="_blank" rel="noopener noreferrer">link</a></strong>`, html)
 }
 
+func TestPoopHTML_LinksAreNeverNested(t *testing.T) {
+	eater, err := NewEater(scum.WarnOverflowNoCap, 0)
+	require.NoError(t, err)
+
+	poop, issues := eater.Munch(`[a $b [c]!href{https://c.com}$ d]!href{https://a.com}`)
+	html := poop.HTML()
+
+	require.NotEmpty(t, issues)
+	require.Equal(t, 1, strings.Count(html, "<a"))
+
+	spec, err := scum.ParseSpec(Dialect())
+	require.NoError(t, err)
+	for _, ts := range spec.Tags {
+		if ts.Seq == "[" {
+			require.Equal(t, []string{"$", "*", "_", "~", "`", "^"}, ts.Children)
+		}
+	}
+}
+
 func TestNormalizeNode_StripsNestedTextAttributes(t *testing.T) {
 	issues := Issues{}
 	node := scum.SerializableNode{
@@ -102,7 +137,7 @@ func TestNormalizeNode_StripsNestedTextAttributes(t *testing.T) {
 		},
 	}
 
-	normalizeNode(&node, &issues)
+	normalizeNode(&node, builtinTags, &issues)
 
 	require.Empty(t, node.Children[0].Attributes)
 	requireIssueDescription(t, issues, `unknown attribute "style" for the text node`)
@@ -174,3 +209,76 @@ func TestPoopHTML_LinkFlagCountsAsDuplicateAttributeName(t *testing.T) {
 	require.Equal(t, `<a href="https://example.com">link</a>`, html)
 	requireIssueDescription(t, Issues{List: issues}, "attribute href must have a value")
 }
+
+func TestPoopHTML_FormattingTags(t *testing.T) {
+	eater, err := NewEater(scum.WarnOverflowNoCap, 0)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"Strike", "~~old <b>~~ new", "<s>old &lt;b&gt;</s> new"},
+		{"Spoiler", "||he *dies*||", `<span class="sml-internal-spoiler" role="button" tabindex="0" aria-expanded="false">he <em>dies</em></span>`},
+		{"Superscript", "E = mc^2^", "E = mc<sup>2</sup>"},
+		{"Code", "run `rm -rf $HOME <x>`", "run <code>rm -rf $HOME &lt;x&gt;</code>"},
+		{"CodeKeepsMarkupAndEscapes", "$b$ `*a* \\\\* [x]!href{y}`", `<strong>b</strong> <code>*a* \\* [x]!href{y}</code>`},
+		{"CodeWithBackticks", "``a `quoted` word``", "<code>a `quoted` word</code>"},
+		{"InsideLink", "[~~a~~ `b` ^c^]!href{/x}", `<a href="/x"><s>a</s> <code>b</code> <sup>c</sup></a>`},
+		{"SpoilerInsideLinkIsText", "[||a||]!href{/x}", `<a href="/x">||a||</a>`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			poop, _ := eater.Munch(tc.input)
+			require.Equal(t, tc.want, poop.HTML())
+		})
+	}
+}
+
+func TestPoopHTML_FormattingTagsRejectAttributes(t *testing.T) {
+	eater, err := NewEater(scum.WarnOverflowNoCap, 0)
+	require.NoError(t, err)
+
+	poop, issues := eater.Munch("~~a~~!x{1} ||b||!y{2} `c`!z{3} ^d^!w{4}")
+
+	require.Len(t, issues, 4)
+	for _, issue := range issues {
+		require.Equal(t, "ATTRIBUTE_NOT_ALLOWED", issue.Codename())
+	}
+	require.Equal(t, `<s>a</s> <span class="sml-internal-spoiler" role="button" tabindex="0" aria-expanded="false">b</span> <code>c</code> <sup>d</sup>`, poop.HTML())
+}
+
+func TestPoopHTML_EscapesAreRemoved(t *testing.T) {
+	eater, err := NewEater(scum.WarnOverflowNoCap, 0)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"Text", `costs \$5 \* 2, \[not a link\] \\o/`, `costs $5 * 2, [not a link] \o/`},
+		{"InsideTags", `$a \$ b$ [c \] d]!href{/x}`, `<strong>a $ b</strong> <a href="/x">c ] d</a>`},
+		{"Payload", `[x]!href{/a\}b}!title{\{t\}}`, `<a href="/a}b" title="{t}">x</a>`},
+		{"CodeKeepsEscapes", "`\\$x`", `<code>\$x</code>`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			poop, _ := eater.Munch(tc.input)
+			require.Equal(t, tc.want, poop.HTML())
+		})
+	}
+}
+
+func TestPoopHTML_EscapedSchemeIsValidatedUnescaped(t *testing.T) {
+	eater, err := NewEater(scum.WarnOverflowNoCap, 0)
+	require.NoError(t, err)
+
+	poop, issues := eater.Munch(`[x]!href{java\script:alert(1)}`)
+
+	require.Equal(t, `<a>x</a>`, poop.HTML())
+	requireIssueDescription(t, Issues{List: issues}, `attribute href scheme "javascript" is not allowed`)
+}
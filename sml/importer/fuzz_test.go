@@ -0,0 +1,62 @@
+package importer
+
+import (
+	"testing"
+
+	"github.com/Drolfothesgnir/shitposter/scum"
+	"github.com/Drolfothesgnir/shitposter/sml"
+)
+
+// requireValidSML fails if munching the imported source adds any issue.
+func requireValidSML(t *testing.T, eater sml.Eater, input, src string) {
+	t.Helper()
+
+	if _, issues := eater.Munch(src); len(issues) > 0 {
+		t.Fatalf("imported SML has issues:\ninput: %q\nsml:   %q\nissue: %v", input, src, issues[0])
+	}
+}
+
+func FuzzMarkdown_ResultIsValidSML(f *testing.F) {
+	seeds := []string{
+		"**bold** *it* _it_ ~~s~~ `c` [l](/x \"t\") <https://a.b>",
+		"***a** b* _a __b__ c_ a*b*c snake_case",
+		"[a [b](/b)](/c) ![i](/i) [r][] \n\n[r]: /r",
+		"# h\n- a\n  1. b\n> q\n```\ncode `x`\n```",
+		"\\$ $ \\\\ ~ | ^ ! [ ] ` `` ``` &amp;",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	eater, err := sml.NewEater(scum.WarnOverflowNoCap, 0)
+	if err != nil {
+		f.Fatalf("NewEater: %v", err)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		src, _ := Markdown(input)
+		requireValidSML(t, eater, input, src)
+	})
+}
+
+func FuzzHTML_ResultIsValidSML(f *testing.F) {
+	seeds := []string{
+		"<b>bold</b> <i>it</i> <u>u</u> <s>s</s> <code>c</code> <a href=/x title=t>l</a>",
+		"<b>a<i>b</b>c</i> snake<u>case</u> <u>x</u>y",
+		"<pre>a\n `b`</pre><ul><li>x<ol><li>y</ol></ul><table><tr><td>1<td>2</table>",
+		"<p>$ \\ ~ | ^ ! [ ] `</p><script>x</script><img alt=a>",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	eater, err := sml.NewEater(scum.WarnOverflowNoCap, 0)
+	if err != nil {
+		f.Fatalf("NewEater: %v", err)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		src, _ := HTML(input)
+		requireValidSML(t, eater, input, src)
+	})
+}
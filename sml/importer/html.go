@@ -0,0 +1,457 @@
+package importer
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/Drolfothesgnir/shitposter/scum"
+	"github.com/Drolfothesgnir/shitposter/sml"
+	"golang.org/x/net/html"
+)
+
+// htmlInline maps the inline elements to the SML tags.
+var htmlInline = map[string]string{
+	"b":      sml.Bold,
+	"strong": sml.Bold,
+	"i":      sml.Italic,
+	"em":     sml.Italic,
+	"u":      sml.Underline,
+	"ins":    sml.Underline,
+	"s":      sml.Strike,
+	"strike": sml.Strike,
+	"del":    sml.Strike,
+	"code":   sml.Code,
+	"kbd":    sml.Code,
+	"samp":   sml.Code,
+	"tt":     sml.Code,
+	"sup":    sml.Superscript,
+	"a":      sml.Link,
+}
+
+// htmlTransparent are the elements written as their content without an issue, since they don't change
+// the text much, like span or the containers of the pasted fragments.
+var htmlTransparent = map[string]bool{
+	"span": true, "font": true, "small": true, "big": true, "mark": true, "abbr": true, "cite": true,
+	"dfn": true, "var": true, "q": true, "time": true, "label": true, "bdi": true, "bdo": true, "data": true,
+	"html": true, "body": true, "tbody": true, "thead": true, "tfoot": true, "colgroup": true, "col": true,
+	"wbr": true, "source": true, "track": true, "area": true, "map": true, "picture": true,
+}
+
+// htmlBlocks are the elements which start and end a paragraph without an issue.
+var htmlBlocks = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "header": true, "footer": true, "main": true,
+	"nav": true, "aside": true, "address": true, "figure": true, "figcaption": true, "details": true,
+	"summary": true, "center": true, "dl": true, "dt": true, "dd": true, "fieldset": true, "legend": true,
+	"form": true, "caption": true,
+}
+
+// htmlHidden are the elements dropped with their content without an issue, they are not a part of the text.
+var htmlHidden = map[string]bool{
+	"head": true, "title": true, "style": true, "meta": true, "link": true, "base": true,
+}
+
+// htmlDropped are the elements dropped with their content and an issue.
+var htmlDropped = map[string]bool{
+	"script": true, "noscript": true, "template": true, "iframe": true, "frame": true, "frameset": true,
+	"object": true, "embed": true, "applet": true, "svg": true, "math": true, "canvas": true, "video": true,
+	"audio": true, "input": true, "select": true, "textarea": true, "button": true, "dialog": true,
+}
+
+// htmlVoid are the elements without the end tag.
+var htmlVoid = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true, "input": true,
+	"link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// HTML converts the HTML fragment, like the one copied from a web page, to SML, see the package doc for
+// the converted subset. The headings are converted to bold text, the preformatted text to inline code,
+// and the lists, the tables and the block quotes to plain lines. The images are dropped, keeping their
+// alt text, and so are the scripts, the embedded content and the forms, with their content.
+// Each of them adds an issue. The whitespace is collapsed the way the browsers do it.
+func HTML(src string) (string, []sml.SyntaxIssue) {
+	im := importer{src: src}
+	c := htmlConverter{im: &im, open: make(map[string]int), space: true}
+
+	z := html.NewTokenizer(strings.NewReader(src))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+
+		start := c.pos
+		c.pos += len(z.Raw())
+		span := scum.Span{Start: start, End: c.pos}
+
+		switch tt {
+		case html.TextToken:
+			c.text(string(z.Text()))
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			attrs := make(map[string]string)
+			for hasAttr {
+				var k, v []byte
+				k, v, hasAttr = z.TagAttr()
+				if _, ok := attrs[string(k)]; !ok {
+					attrs[string(k)] = string(v)
+				}
+			}
+			c.start(string(name), attrs, span, tt == html.SelfClosingTagToken)
+
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			c.end(string(name), span)
+		}
+	}
+
+	c.block()
+	return im.result()
+}
+
+// htmlFrame is an open inline element.
+type htmlFrame struct {
+	name string
+	// tag is the SML tag of the element, empty for the element written as its content.
+	tag         string
+	href, title string
+	children    []node
+	// pre is true for the preformatted text, which keeps its whitespace.
+	pre  bool
+	span scum.Span
+}
+
+// htmlList is an open list.
+type htmlList struct {
+	ordered bool
+	next    int
+}
+
+// htmlConverter converts the tokens of the HTML to the paragraphs of the importer.
+type htmlConverter struct {
+	im *importer
+	// frames are the open inline elements, open counts them by the tag, and pre counts the preformatted ones.
+	frames []htmlFrame
+	open   map[string]int
+	pre    int
+	// para is the content of the paragraph outside of the frames.
+	para []node
+	// space is true if the written text ends with a whitespace, or nothing is written in the paragraph.
+	space bool
+	lists []htmlList
+	// rowCells counts the cells of the current table row.
+	rowCells int
+	// skip is the name of the element which content is dropped, and depth is the count of its open elements.
+	skip  string
+	depth int
+	// pos is the offset of the current token.
+	pos int
+}
+
+// add adds the node to the innermost open element.
+func (c *htmlConverter) add(n node) {
+	if len(c.frames) > 0 {
+		top := &c.frames[len(c.frames)-1]
+		top.children = append(top.children, n)
+		return
+	}
+	c.para = append(c.para, n)
+}
+
+// inCode reports whether the text is written to the code, where the elements are ignored.
+func (c *htmlConverter) inCode() bool {
+	return c.open[sml.Code] > 0
+}
+
+// push opens the element. The element with the tag which is already open is written as its content.
+func (c *htmlConverter) push(f htmlFrame) {
+	if c.open[f.tag] > 0 {
+		f.tag = ""
+	}
+	c.open[f.tag]++
+	if f.pre {
+		c.pre++
+	}
+	c.frames = append(c.frames, f)
+}
+
+// pop closes the innermost element, returning it.
+func (c *htmlConverter) pop() htmlFrame {
+	f := c.frames[len(c.frames)-1]
+	c.frames = c.frames[:len(c.frames)-1]
+	c.open[f.tag]--
+	if f.pre {
+		c.pre--
+	}
+	return f
+}
+
+func (c *htmlConverter) text(s string) {
+	if c.skip != "" {
+		return
+	}
+
+	space := strings.HasSuffix(s, " ") || strings.HasSuffix(s, "\n")
+	if c.pre == 0 {
+		// the whitespace runs are collapsed to a space, dropped after a whitespace
+		var b strings.Builder
+		space = c.space
+		for _, r := range s {
+			if unicode.IsSpace(r) {
+				if !space {
+					b.WriteByte(' ')
+					space = true
+				}
+				continue
+			}
+			b.WriteRune(r)
+			space = false
+		}
+		s = b.String()
+	}
+
+	if s == "" {
+		return
+	}
+
+	c.add(textNode(s, scum.Span{Start: c.pos, End: c.pos}))
+	c.space = space
+}
+
+func (c *htmlConverter) start(name string, attrs map[string]string, span scum.Span, selfClosing bool) {
+	if c.skip != "" {
+		if name == c.skip && !selfClosing {
+			c.depth++
+		}
+		return
+	}
+
+	if c.inCode() {
+		if name == "br" {
+			c.add(textNode("\n", span))
+		}
+		return
+	}
+
+	void := htmlVoid[name] || selfClosing
+
+	switch {
+	case htmlHidden[name] || htmlDropped[name]:
+		if htmlDropped[name] {
+			c.im.drop(span, "<%s> element is dropped with its content", name)
+		}
+		if !void {
+			c.skip, c.depth = name, 1
+		}
+
+	case name == "br":
+		c.add(textNode("\n", span))
+		c.space = true
+
+	case name == "img":
+		c.im.drop(span, "image is dropped, its alt text is kept")
+		if alt := strings.TrimSpace(attrs["alt"]); alt != "" {
+			c.text(alt)
+		}
+
+	case name == "hr":
+		c.block()
+		c.im.drop(span, "thematic break is dropped")
+
+	case htmlInline[name] != "":
+		if void {
+			return
+		}
+
+		f := htmlFrame{name: name, tag: htmlInline[name], span: span}
+		if f.tag == sml.Link {
+			f.href, f.title = attrs["href"], attrs["title"]
+		}
+		c.push(f)
+
+	case name == "pre":
+		c.block()
+		c.im.drop(span, "preformatted text is imported as inline code")
+		c.push(htmlFrame{name: name, tag: sml.Code, pre: true, span: span})
+
+	case len(name) == 2 && name[0] == 'h' && '1' <= name[1] && name[1] <= '6':
+		c.block()
+		c.im.drop(span, "heading is imported as bold text")
+		c.push(htmlFrame{name: name, tag: sml.Bold, span: span})
+
+	case name == "ul" || name == "ol":
+		if len(c.lists) == 0 {
+			c.block()
+			c.im.drop(span, "list is imported as plain text lines")
+		}
+
+		l := htmlList{ordered: name == "ol", next: 1}
+		if n, err := strconv.Atoi(attrs["start"]); err == nil {
+			l.next = n
+		}
+		c.lists = append(c.lists, l)
+
+	case name == "li":
+		c.line()
+		marker := "- "
+		if len(c.lists) > 0 {
+			l := &c.lists[len(c.lists)-1]
+			if l.ordered {
+				marker = strconv.Itoa(l.next) + ". "
+				l.next++
+			}
+			marker = strings.Repeat("  ", len(c.lists)-1) + marker
+		}
+		c.add(textNode(marker, span))
+		c.space = true
+
+	case name == "table":
+		c.block()
+		c.im.drop(span, "table is imported as plain text lines")
+
+	case name == "tr":
+		c.line()
+		c.rowCells = 0
+
+	case name == "td" || name == "th":
+		if c.rowCells > 0 {
+			c.add(textNode(" | ", span))
+			c.space = true
+		}
+		c.rowCells++
+
+	case name == "blockquote":
+		c.block()
+		c.im.drop(span, "block quote is imported as plain paragraphs")
+
+	case htmlBlocks[name]:
+		if len(c.lists) == 0 {
+			c.block()
+		}
+
+	case htmlTransparent[name]:
+
+	default:
+		c.im.drop(span, "<%s> element is dropped, its text is kept", name)
+	}
+}
+
+func (c *htmlConverter) end(name string, span scum.Span) {
+	if c.skip != "" {
+		if name == c.skip {
+			c.depth--
+			if c.depth == 0 {
+				c.skip = ""
+			}
+		}
+		return
+	}
+
+	// the end tag closes the matching element and the elements left open inside it
+	for k := len(c.frames) - 1; k >= 0; k-- {
+		if c.frames[k].name != name {
+			continue
+		}
+
+		for len(c.frames) > k {
+			c.close(span)
+		}
+
+		if name == "pre" || name[0] == 'h' {
+			c.block()
+		}
+		return
+	}
+
+	if c.inCode() {
+		return
+	}
+
+	switch {
+	case name == "ul" || name == "ol":
+		if len(c.lists) > 0 {
+			c.lists = c.lists[:len(c.lists)-1]
+		}
+		if len(c.lists) == 0 {
+			c.block()
+		}
+
+	case name == "table" || name == "blockquote":
+		c.block()
+
+	case htmlBlocks[name]:
+		if len(c.lists) == 0 {
+			c.block()
+		}
+	}
+}
+
+// close closes the innermost open element, adding its node to the enclosing one.
+func (c *htmlConverter) close(end scum.Span) {
+	f := c.pop()
+	span := scum.Span{Start: f.span.Start, End: end.End}
+
+	switch f.tag {
+	case "":
+		for _, n := range f.children {
+			c.add(n)
+		}
+
+	case sml.Code:
+		var b strings.Builder
+		for _, n := range f.children {
+			b.WriteString(n.text)
+		}
+		code := b.String()
+		if f.pre {
+			code = strings.TrimPrefix(code, "\n")
+			code = strings.TrimRight(code, "\n")
+		}
+		if code != "" {
+			c.add(node{tag: sml.Code, text: code, span: span})
+		}
+
+	case sml.Link:
+		// the anchors without href are not links
+		if f.href == "" {
+			for _, n := range f.children {
+				c.add(n)
+			}
+			return
+		}
+		for _, n := range c.im.link(f.href, f.title, f.children, span) {
+			c.add(n)
+		}
+
+	default:
+		c.add(node{tag: f.tag, children: f.children, span: span})
+	}
+}
+
+// line starts a new line in the paragraph.
+func (c *htmlConverter) line() {
+	if len(c.para) > 0 || len(c.frames) > 0 {
+		c.add(textNode("\n", scum.Span{Start: c.pos, End: c.pos}))
+	}
+	c.space = true
+}
+
+// block ends the paragraph. The elements open in it are closed, and opened again in the next paragraph.
+func (c *htmlConverter) block() {
+	open := make([]htmlFrame, len(c.frames))
+	copy(open, c.frames)
+
+	for len(c.frames) > 0 {
+		c.close(scum.Span{Start: c.pos, End: c.pos})
+	}
+
+	c.im.para(c.para)
+	c.para = nil
+	c.space = true
+
+	for _, f := range open {
+		f.children = nil
+		c.push(f)
+	}
+}
@@ -0,0 +1,139 @@
+package importer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTML_RoundTrip(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		wantSML  string
+		wantHTML string
+	}{
+		{
+			name: "ClipboardFragment",
+			input: `<html><head><title>x</title><style>p{}</style></head><body><!--StartFragment-->` +
+				"<p>Hello <b>bold</b>,\n   <i>italic <strong>both</strong></i> &amp; <span style=\"x\">span</span></p>" +
+				`<p>costs $5 *not*</p><!--EndFragment--></body></html>`,
+			wantSML:  "Hello $bold$, *italic $both$* & span\n\ncosts \\$5 \\*not\\*",
+			wantHTML: "Hello <strong>bold</strong>, <em>italic <strong>both</strong></em> &amp; span\n\ncosts $5 *not*",
+		},
+		{
+			name:     "InlineTags",
+			input:    `<s>a</s><del>b</del> <u>c</u> <ins>d</ins> x<sup>2</sup> <code>$y</code> <kbd>Ctrl</kbd><br>next`,
+			wantSML:  "~~ab~~ _c_ _d_ x^2^ `$y` `Ctrl`\nnext",
+			wantHTML: `<s>ab</s> <span class="sml-internal-underline">c</span> <span class="sml-internal-underline">d</span> x<sup>2</sup> <code>$y</code> <code>Ctrl</code>` + "\nnext",
+		},
+		{
+			name:     "Links",
+			input:    `<a href="https://x.com/a" title=" The   title ">link <em>it</em></a> <a name="top">anchor</a> <a href="/e"></a>`,
+			wantSML:  "[link *it*]!href{https://x.com/a}!title{The title} anchor [/e]!href{/e}",
+			wantHTML: `<a href="https://x.com/a" title="The title">link <em>it</em></a> anchor <a href="/e">/e</a>`,
+		},
+		{
+			name:     "NestingIsFlattened",
+			input:    `<b>a <strong>b</strong></b><b>c</b> <a href="/a">x <a href="/b">y</a></a> <a href="/l"><span>s</span></a>`,
+			wantSML:  "$a bc$ [x y]!href{/a} [s]!href{/l}",
+			wantHTML: `<strong>a bc</strong> <a href="/a">x y</a> <a href="/l">s</a>`,
+		},
+		{
+			name:     "BlockInsideInline",
+			input:    "<b>bold <p>split</p>more</b>",
+			wantSML:  "$bold$\n\n$split$\n\n$more$",
+			wantHTML: "<strong>bold</strong>\n\n<strong>split</strong>\n\n<strong>more</strong>",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			src, issues := HTML(tc.input)
+
+			require.Empty(t, issues)
+			require.Equal(t, tc.wantSML, src)
+			require.Equal(t, tc.wantHTML, munch(t, src))
+		})
+	}
+}
+
+func TestHTML_UnsupportedConstructs(t *testing.T) {
+	cases := []struct {
+		name       string
+		input      string
+		wantSML    string
+		wantIssues []string
+	}{
+		{
+			name:       "HeadingAndLists",
+			input:      `<h2>Title</h2><ul><li>one</li><li>two<ol start="3"><li>a</li><li>b</li></ol></li></ul><p>after</p>`,
+			wantSML:    "$Title$\n\n- one\n- two\n  3. a\n  4. b\n\nafter",
+			wantIssues: []string{"heading is imported as bold text", "list is imported as plain text lines"},
+		},
+		{
+			name:    "PreQuoteAndBreak",
+			input:   "<pre><code>line 1\n  line $2</code></pre><blockquote><p>quoted</p></blockquote><hr>text",
+			wantSML: "`line 1\n  line $2`\n\nquoted\n\ntext",
+			wantIssues: []string{
+				"preformatted text is imported as inline code",
+				"block quote is imported as plain paragraphs",
+				"thematic break is dropped",
+			},
+		},
+		{
+			name:       "Table",
+			input:      "<table><tr><th>a</th><th>b</th></tr><tr><td>1</td><td><b>2</b></td></tr></table>",
+			wantSML:    "a \\| b\n1 \\| $2$",
+			wantIssues: []string{"table is imported as plain text lines"},
+		},
+		{
+			name:    "DroppedElements",
+			input:   `<p>x<script>alert("<b>")</script> <img src="a.png" alt="the cat"> H<sub>2</sub>O <svg><g><svg></svg><text>s</text></g></svg>y</p>`,
+			wantSML: "x the cat H2O y",
+			wantIssues: []string{
+				"<script> element is dropped with its content",
+				"image is dropped, its alt text is kept",
+				"<sub> element is dropped, its text is kept",
+				"<svg> element is dropped with its content",
+			},
+		},
+		{
+			name:       "UnsafeLink",
+			input:      `<a href="javascript:alert(1)">click</a> <a href="//evil.example">me</a>`,
+			wantSML:    "click me",
+			wantIssues: []string{`link to "javascript:alert(1)" is dropped, its text is kept`, `link to "//evil.example" is dropped, its text is kept`},
+		},
+		{
+			name:       "UnderlineInsideWord",
+			input:      `snake<u>case</u>`,
+			wantSML:    "snakecase",
+			wantIssues: []string{"underline inside a word is dropped, its text is kept"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			src, issues := HTML(tc.input)
+
+			require.Equal(t, tc.wantSML, src)
+			require.Len(t, issues, len(tc.wantIssues))
+			for i, issue := range issues {
+				require.Equal(t, "UNSUPPORTED_MARKUP", issue.Codename())
+				require.Equal(t, tc.wantIssues[i], issue.Description())
+			}
+
+			// the result is valid SML
+			munch(t, src)
+		})
+	}
+}
+
+func TestHTML_IssueSpans(t *testing.T) {
+	input := "<p>a</p>\n<h1 class=\"x\">b</h1><img src=c>"
+	_, issues := HTML(input)
+
+	require.Len(t, issues, 2)
+	require.Equal(t, `<h1 class="x">`, input[issues[0].Span().Start:issues[0].Span().End])
+	require.Equal(t, "<img src=c>", input[issues[1].Span().Start:issues[1].Span().End])
+}
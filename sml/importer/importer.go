@@ -0,0 +1,440 @@
+// Package importer converts the Markdown and the HTML pasted into the editor to SML source, so the formatting
+// copied from the other sites is kept instead of arriving as literal asterisks and angle brackets.
+//
+// Only a safe subset is converted: bold, italic, underline, strike, inline code, superscript and links with
+// the http, https and mailto schemes or relative ones. The other constructs are dropped with an
+// [sml.IssueUnsupportedMarkup] issue, keeping their text where it's readable, like the items of a list
+// or the alt text of an image. The SML-special characters of the text are escaped, so munching the result
+// with [sml.Eater.Munch] renders the text the way it was written.
+package importer
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Drolfothesgnir/shitposter/scum"
+	"github.com/Drolfothesgnir/shitposter/sml"
+)
+
+// special are the symbols of the SML dialect escaped in the text, see [sml.Dialect].
+const special = "\\$*_~|`^[]!"
+
+// delimiters are the open and close sequences of the tags written around their content.
+var delimiters = map[string][2]string{
+	sml.Bold:        {"$", "$"},
+	sml.Italic:      {"*", "*"},
+	sml.Underline:   {"_", "_"},
+	sml.Strike:      {"~~", "~~"},
+	sml.Spoiler:     {"||", "||"},
+	sml.Superscript: {"^", "^"},
+}
+
+// limits are the payload limits of the dialect, the code and the links longer than them are cut or dropped.
+var limits = dialectLimits()
+
+func dialectLimits() scum.Limits {
+	d, err := scum.LoadDictionary(sml.Dialect())
+	if err != nil {
+		panic(fmt.Sprintf("importer: load the SML dialect: %v", err))
+	}
+	return d.Limits
+}
+
+// linkChildren are the tags allowed inside a link, the others are written as their content.
+var linkChildren = []string{sml.Bold, sml.Italic, sml.Underline, sml.Strike, sml.Code, sml.Superscript}
+
+// node is a piece of the imported document: the text, or an SML tag with its children.
+type node struct {
+	// tag is the SML tag name, empty for the text.
+	tag string
+	// text is the unescaped text, or the content of the code.
+	text string
+	// href and title are the attributes of the link.
+	href, title string
+	children    []node
+	// span is the source of the node, for the issues.
+	span scum.Span
+}
+
+func textNode(s string, span scum.Span) node {
+	return node{text: s, span: span}
+}
+
+// importer collects the paragraphs and the issues of the imported document.
+type importer struct {
+	src    string
+	paras  [][]node
+	issues []sml.SyntaxIssue
+}
+
+// drop adds the issue of the unsupported construct.
+func (im *importer) drop(span scum.Span, format string, args ...any) {
+	im.issues = append(im.issues, sml.NewSyntaxIssueDescriptor(sml.IssueUnsupportedMarkup, span, fmt.Sprintf(format, args...)))
+}
+
+// para adds the paragraph, skipping the blank ones.
+func (im *importer) para(nodes []node) {
+	nodes = trimSpace(nodes)
+	if len(nodes) > 0 {
+		im.paras = append(im.paras, nodes)
+	}
+}
+
+// result writes the paragraphs as SML separated by the blank lines, and returns them with the located issues.
+func (im *importer) result() (string, []sml.SyntaxIssue) {
+	w := writer{im: im}
+	for i, p := range im.paras {
+		if i > 0 {
+			w.WriteString("\n\n")
+		}
+		w.nodes(merge(p), nil)
+	}
+
+	r := scum.NewPositionResolver(im.src)
+	for i, issue := range im.issues {
+		if d, ok := issue.(sml.SyntaxIssueDescriptor); ok {
+			d.Start, d.End = r.ResolveSpan(d.Sp)
+			im.issues[i] = d
+		}
+	}
+
+	return w.String(), im.issues
+}
+
+// link returns the link node, or the children alone with an issue if the href is not allowed.
+// The link without text shows its href.
+func (im *importer) link(href, title string, children []node, span scum.Span) []node {
+	href = strings.TrimSpace(href)
+	if !allowedHref(href) {
+		im.drop(span, "link to %q is dropped, its text is kept", href)
+		return children
+	}
+
+	if attributeLen(href) > limits.MaxAttrPayloadLen {
+		im.drop(span, "link to an href longer than %d bytes is dropped, its text is kept", limits.MaxAttrPayloadLen)
+		return children
+	}
+
+	// the escaped title of MaxTitleLength runes is shorter than the attribute payload limit,
+	// and the NULs forbidden in the titles are read as the whitespace
+	title = strings.Join(strings.FieldsFunc(title, func(r rune) bool { return r == 0 || unicode.IsSpace(r) }), " ")
+	if utf8.RuneCountInString(title) > sml.MaxTitleLength {
+		im.drop(span, "link title longer than %d characters is dropped", sml.MaxTitleLength)
+		title = ""
+	}
+
+	if len(trimSpace(children)) == 0 {
+		children = []node{textNode(href, span)}
+	}
+
+	return []node{{tag: sml.Link, href: href, title: title, children: children, span: span}}
+}
+
+// allowedHref reports whether the href is accepted by the SML links.
+func allowedHref(href string) bool {
+	if href == "" || strings.ContainsAny(href, "\x00\r\n\t") || strings.HasPrefix(href, "//") {
+		return false
+	}
+
+	u, err := url.Parse(href)
+	if err != nil {
+		return false
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "", "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+// merge joins the adjacent texts and the adjacent tags with the same name, which would read as a single
+// tag closed and opened again, like $a$$b$.
+func merge(nodes []node) []node {
+	out := nodes[:0]
+
+	// the texts of the run at the end of out, joined once the run ends
+	var run []string
+	flush := func() {
+		if len(run) > 1 {
+			out[len(out)-1].text = strings.Join(run, "")
+		}
+		run = run[:0]
+	}
+
+	for _, n := range nodes {
+		if len(out) > 0 {
+			last := &out[len(out)-1]
+			switch {
+			case n.tag == "" && last.tag == "":
+				run = append(run, n.text)
+				last.span.End = n.span.End
+				continue
+			case n.tag != "" && n.tag != sml.Link && n.tag != sml.Code && n.tag == last.tag:
+				last.children = append(last.children, n.children...)
+				last.span.End = n.span.End
+				continue
+			}
+		}
+
+		flush()
+		out = append(out, n)
+		if n.tag == "" {
+			run = append(run, n.text)
+		}
+	}
+	flush()
+
+	for i := range out {
+		out[i].children = merge(out[i].children)
+	}
+	return out
+}
+
+// trimSpace drops the leading and the trailing whitespace of the nodes, including the whitespace
+// at the edges of the tags.
+func trimSpace(nodes []node) []node {
+	for len(nodes) > 0 {
+		first := &nodes[0]
+		switch first.tag {
+		case "":
+			first.text = strings.TrimLeftFunc(first.text, unicode.IsSpace)
+			if first.text != "" {
+				break
+			}
+			nodes = nodes[1:]
+			continue
+		case sml.Code:
+		default:
+			first.children = trimSpace(first.children)
+			if len(first.children) == 0 {
+				nodes = nodes[1:]
+				continue
+			}
+		}
+		break
+	}
+
+	for len(nodes) > 0 {
+		last := &nodes[len(nodes)-1]
+		switch last.tag {
+		case "":
+			last.text = strings.TrimRightFunc(last.text, unicode.IsSpace)
+			if last.text != "" {
+				break
+			}
+			nodes = nodes[:len(nodes)-1]
+			continue
+		case sml.Code:
+		default:
+			last.children = trimSpace(last.children)
+			if len(last.children) == 0 {
+				nodes = nodes[:len(nodes)-1]
+				continue
+			}
+		}
+		break
+	}
+
+	return nodes
+}
+
+// writer writes the nodes as SML source.
+type writer struct {
+	strings.Builder
+	im *importer
+}
+
+// nodes writes the nodes inside the open tags. The tags which can't be nested there are written as their content.
+func (w *writer) nodes(nodes []node, open []string) {
+	for i := range nodes {
+		n := &nodes[i]
+
+		switch {
+		case n.tag == "":
+			w.text(n.text)
+
+		case slices.Contains(open, n.tag),
+			slices.Contains(open, sml.Link) && !slices.Contains(linkChildren, n.tag):
+			w.nodes(n.children, open)
+
+		case n.tag == sml.Code:
+			w.code(n.text, n.span)
+
+		default:
+			w.tag(n, nodes[i+1:], open)
+		}
+	}
+}
+
+// tag writes the tag around its content. The blank tags are written as their content.
+func (w *writer) tag(n *node, next []node, open []string) {
+	inner := writer{im: w.im}
+	inner.nodes(n.children, append(open[:len(open):len(open)], n.tag))
+	content := inner.String()
+
+	if strings.TrimSpace(content) == "" {
+		w.WriteString(content)
+		return
+	}
+
+	// the underline between the letters is read as text, like in snake_case
+	if n.tag == sml.Underline {
+		prev, _ := utf8.DecodeLastRuneInString(w.String())
+		first, _ := utf8.DecodeRuneInString(content)
+		last, _ := utf8.DecodeLastRuneInString(content)
+		if isWordPart(prev) && isWordPart(first) || isWordPart(last) && isWordPart(firstRune(next, open)) {
+			w.im.drop(n.span, "underline inside a word is dropped, its text is kept")
+			w.WriteString(content)
+			return
+		}
+	}
+
+	if n.tag == sml.Link {
+		w.WriteByte('[')
+		w.WriteString(content)
+		w.WriteByte(']')
+		w.attribute("href", n.href)
+		if n.title != "" {
+			w.attribute("title", n.title)
+		}
+		return
+	}
+
+	d := delimiters[n.tag]
+	w.WriteString(d[0])
+	w.WriteString(content)
+	w.WriteString(d[1])
+}
+
+// text writes the text, escaping the special symbols.
+func (w *writer) text(s string) {
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(special, s[i]) >= 0 {
+			w.WriteByte('\\')
+		}
+		w.WriteByte(s[i])
+	}
+}
+
+// code writes the inline code, delimited by a backtick run longer than any run inside it.
+// The code starting or ending with a backtick is padded with a space, so the backtick is not read as a delimiter.
+// The code longer than the payload limit of the dialect is truncated, and the code whose fence would be longer
+// than the key limit is written as text, both with an issue.
+func (w *writer) code(s string, span scum.Span) {
+	if s == "" {
+		return
+	}
+
+	// the room for the padding is kept
+	if maxLen := limits.MaxPayloadLen - 2; len(s) > maxLen {
+		w.im.drop(span, "inline code longer than %d bytes is truncated", maxLen)
+		s = truncate(s, maxLen)
+	}
+
+	longest, run := 0, 0
+	for i := 0; i < len(s); i++ {
+		if s[i] != '`' {
+			run = 0
+			continue
+		}
+		run++
+		longest = max(longest, run)
+	}
+
+	if longest >= limits.MaxKeyLen {
+		w.im.drop(span, "inline code with a run of %d or more backticks is imported as text", limits.MaxKeyLen)
+		w.text(s)
+		return
+	}
+	fence := strings.Repeat("`", longest+1)
+
+	if strings.HasPrefix(s, "`") {
+		s = " " + s
+	}
+	if strings.HasSuffix(s, "`") {
+		s += " "
+	}
+
+	w.WriteString(fence)
+	w.WriteString(s)
+	w.WriteString(fence)
+}
+
+// attribute writes the attribute, escaping the symbols which would end its payload.
+func (w *writer) attribute(name, payload string) {
+	w.WriteByte('!')
+	w.WriteString(name)
+	w.WriteByte('{')
+	for i := 0; i < len(payload); i++ {
+		if payload[i] == '\\' || payload[i] == '}' {
+			w.WriteByte('\\')
+		}
+		w.WriteByte(payload[i])
+	}
+	w.WriteByte('}')
+}
+
+// attributeLen returns the length of the attribute payload written by [writer.attribute] in bytes.
+func attributeLen(payload string) int {
+	return len(payload) + strings.Count(payload, "\\") + strings.Count(payload, "}")
+}
+
+// truncate cuts s to at most n bytes, not splitting a rune.
+func truncate(s string, n int) string {
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// firstRune returns the first rune the nodes are written with inside the open tags, or utf8.RuneError if none.
+func firstRune(nodes []node, open []string) rune {
+	for _, n := range nodes {
+		switch {
+		case n.tag == "":
+			if n.text == "" {
+				continue
+			}
+			if strings.IndexByte(special, n.text[0]) >= 0 {
+				return '\\'
+			}
+			r, _ := utf8.DecodeRuneInString(n.text)
+			return r
+
+		case n.tag == sml.Code:
+			return '`'
+
+		case n.tag == sml.Link && !slices.Contains(open, sml.Link):
+			return '['
+
+		case slices.Contains(open, n.tag) || slices.Contains(open, sml.Link) && !slices.Contains(linkChildren, n.tag):
+			if r := firstRune(n.children, open); r != utf8.RuneError {
+				return r
+			}
+
+		default:
+			r, _ := utf8.DecodeRuneInString(delimiters[n.tag][0])
+			return r
+		}
+	}
+	return utf8.RuneError
+}
+
+// isWordPart reports whether the rune next to an underline makes it a part of the word, the way
+// the intra-word rule of the dialect reads it.
+func isWordPart(r rune) bool {
+	switch {
+	case r == '_':
+		return true
+	case r == utf8.RuneError, strings.ContainsRune(special+"{}", r):
+		return false
+	case r < utf8.RuneSelf:
+		return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsPunct(r) || unicode.IsSymbol(r)
+	}
+	return unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsPunct(r)
+}
@@ -0,0 +1,109 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/Drolfothesgnir/shitposter/scum"
+	"github.com/Drolfothesgnir/shitposter/sml"
+	"github.com/stretchr/testify/require"
+)
+
+// munch returns the HTML of the SML source, requiring it to be munched without issues.
+func munch(t *testing.T, src string) string {
+	t.Helper()
+
+	eater, err := sml.NewEater(scum.WarnOverflowNoCap, 0)
+	require.NoError(t, err)
+
+	poop, issues := eater.Munch(src)
+	require.Empty(t, issues, src)
+	return poop.HTML()
+}
+
+func codenames(issues []sml.SyntaxIssue) []string {
+	names := make([]string, len(issues))
+	for i, issue := range issues {
+		names[i] = issue.Codename() + ": " + issue.Description()
+	}
+	return names
+}
+
+func TestWriter_EscapesSpecialSymbols(t *testing.T) {
+	text := `costs $5 * 2_000 ~ [x](y)! ||a|| ^b^ ` + "`c`" + ` \o/ {z}`
+	src, issues := Markdown(`costs $5 \* 2\_000 \~ \[x\](y)! \|\|a\|\| ^b^ \` + "`c\\`" + ` \\o/ {z}`)
+
+	require.Empty(t, issues)
+	require.Equal(t, `costs \$5 \* 2\_000 \~ \[x\](y)\! \|\|a\|\| \^b\^ \`+"`c\\`"+` \\o/ {z}`, src)
+	require.Equal(t, text, munch(t, src))
+}
+
+func TestWriter_UnderlineInsideWordIsDropped(t *testing.T) {
+	src, issues := HTML(`<u>whole</u> snake<u>case</u> <u>end</u>. <b><u>in</u></b>bold`)
+
+	require.Equal(t, `_whole_ snakecase end. $_in_$bold`, src)
+	require.Equal(t, []string{
+		"UNSUPPORTED_MARKUP: underline inside a word is dropped, its text is kept",
+		"UNSUPPORTED_MARKUP: underline inside a word is dropped, its text is kept",
+	}, codenames(issues))
+	require.Equal(t, `<span class="sml-internal-underline">whole</span> snakecase end. `+
+		`<strong><span class="sml-internal-underline">in</span></strong>bold`, munch(t, src))
+}
+
+func TestWriter_CodeFence(t *testing.T) {
+	src, _ := HTML("<code>a `b` c</code> <code>`tick</code> <code></code>")
+
+	require.Equal(t, "``a `b` c`` `` `tick``", src)
+	require.Equal(t, "<code>a `b` c</code> <code> `tick</code>", munch(t, src))
+}
+
+func TestWriter_LinkPayloadIsEscaped(t *testing.T) {
+	src, issues := HTML(`<a href="/a}b\c" title="{x}">link</a>`)
+
+	require.Empty(t, issues)
+	require.Equal(t, `[link]!href{/a\}b\\c}!title{{x\}}`, src)
+	require.Equal(t, `<a href="/a}b\c" title="{x}">link</a>`, munch(t, src))
+}
+
+func TestWriter_LongCodeIsTruncated(t *testing.T) {
+	maxLen := limits.MaxPayloadLen - 2
+
+	src, issues := Markdown("`" + strings.Repeat("x", 2000) + "`")
+	require.Equal(t, "`"+strings.Repeat("x", maxLen)+"`", src)
+	require.Equal(t, []string{"UNSUPPORTED_MARKUP: inline code longer than 1022 bytes is truncated"}, codenames(issues))
+	munch(t, src)
+
+	src, issues = HTML("<code>" + strings.Repeat("y", 2000) + "</code>")
+	require.Equal(t, "`"+strings.Repeat("y", maxLen)+"`", src)
+	require.Equal(t, []string{"UNSUPPORTED_MARKUP: inline code longer than 1022 bytes is truncated"}, codenames(issues))
+	munch(t, src)
+
+	// the padding of the code ending with a backtick still fits, and the rune is not split
+	src, issues = HTML("<code>`" + strings.Repeat("é", 600) + "</code>")
+	require.Len(t, issues, 1)
+	require.True(t, utf8.ValidString(src))
+	munch(t, src)
+}
+
+func TestWriter_LongBacktickRunIsText(t *testing.T) {
+	run := strings.Repeat("`", limits.MaxKeyLen)
+	src, issues := HTML("<code>a" + run + "b</code>")
+
+	require.Equal(t, `a`+strings.Repeat("\\`", limits.MaxKeyLen)+`b`, src)
+	require.Equal(t, []string{"UNSUPPORTED_MARKUP: inline code with a run of 128 or more backticks is imported as text"}, codenames(issues))
+	require.Equal(t, "a"+run+"b", munch(t, src))
+}
+
+func TestImporter_LongHrefIsDropped(t *testing.T) {
+	href := "/" + strings.Repeat("}", limits.MaxAttrPayloadLen/2)
+	src, issues := HTML(`<a href="` + href + `">link</a>`)
+
+	require.Equal(t, "link", src)
+	require.Equal(t, []string{"UNSUPPORTED_MARKUP: link to an href longer than 512 bytes is dropped, its text is kept"}, codenames(issues))
+	munch(t, src)
+
+	src, issues = Markdown("[link](/" + strings.Repeat("x", limits.MaxAttrPayloadLen-1) + ")")
+	require.Empty(t, issues)
+	munch(t, src)
+}
@@ -0,0 +1,920 @@
+package importer
+
+import (
+	"html"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Drolfothesgnir/shitposter/scum"
+	"github.com/Drolfothesgnir/shitposter/sml"
+)
+
+// mdMaxLabel is the maximum length of a link label.
+const mdMaxLabel = 999
+
+var (
+	mdFence      = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+	mdHeading    = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+|$)`)
+	mdSetext     = regexp.MustCompile(`^ {0,3}(?:=+|-+)[ \t]*$`)
+	mdBreak      = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	mdQuote      = regexp.MustCompile(`^ {0,3}> ?`)
+	mdItem       = regexp.MustCompile(`^( *)([-+*]|\d{1,9}[.)])(?:[ \t]+|$)`)
+	mdTableDelim = regexp.MustCompile(`^ {0,3}\|?[ \t]*:?-+:?[ \t]*(?:\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*$`)
+	mdRefDef     = regexp.MustCompile(`^ {0,3}\[((?:[^\]\\]|\\.)+)\]:[ \t]*(<[^<>\n]*>|\S+)(?:[ \t]+("(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'|\((?:[^()\\]|\\.)*\)))?[ \t]*$`)
+	mdEntity     = regexp.MustCompile(`^&(?:#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6}|[A-Za-z][A-Za-z0-9]{1,31});`)
+	mdAutolink   = regexp.MustCompile(`^<([A-Za-z][A-Za-z0-9+.-]{1,31}:[^\s<>]*)>`)
+	mdEmail      = regexp.MustCompile(`^<([A-Za-z0-9.!#$%&'*+/=?^_{|}~-]+@[A-Za-z0-9](?:[A-Za-z0-9-]{0,61}[A-Za-z0-9])?(?:\.[A-Za-z0-9](?:[A-Za-z0-9-]{0,61}[A-Za-z0-9])?)*)>`)
+)
+
+// Markdown converts the CommonMark source to SML, see the package doc for the converted subset.
+// The headings are converted to bold text, the code blocks to inline code, and the lists, the tables and the
+// block quotes to plain lines. The images are dropped, keeping their alt text. Each of them adds an issue.
+// The strikethrough of GitHub Flavored Markdown is converted too.
+func Markdown(src string) (string, []sml.SyntaxIssue) {
+	im := importer{src: src}
+	p := mdParser{im: &im, refs: make(map[string]mdRef)}
+
+	lines := mdLines(src)
+	p.collectRefs(lines)
+	p.blocks(lines)
+
+	return im.result()
+}
+
+// mdLine is a line of the Markdown source without its line break.
+type mdLine struct {
+	text string
+	// start is the offset of the text in the source.
+	start int
+	// isRef is true for the link reference definitions.
+	isRef bool
+}
+
+func (l mdLine) span() scum.Span {
+	return scum.Span{Start: l.start, End: l.start + len(l.text)}
+}
+
+// strip returns the line without its first n bytes.
+func (l mdLine) strip(n int) mdLine {
+	return mdLine{text: l.text[n:], start: l.start + n, isRef: l.isRef}
+}
+
+func (l mdLine) isBlank() bool {
+	return strings.TrimSpace(l.text) == ""
+}
+
+func mdLines(src string) []mdLine {
+	var lines []mdLine
+	for start := 0; start <= len(src); {
+		end := strings.IndexByte(src[start:], '\n')
+		if end < 0 {
+			end = len(src)
+		} else {
+			end += start
+		}
+
+		text := strings.TrimSuffix(src[start:end], "\r")
+		lines = append(lines, mdLine{text: text, start: start})
+		start = end + 1
+	}
+	return lines
+}
+
+// mdRef is a link reference definition.
+type mdRef struct {
+	href, title string
+}
+
+type mdParser struct {
+	im   *importer
+	refs map[string]mdRef
+}
+
+// collectRefs records the link reference definitions, which can be used before they are defined.
+// The definitions interrupt no paragraph, and there are none in the code blocks.
+func (p *mdParser) collectRefs(lines []mdLine) {
+	var fence string
+	afterText := false
+
+	for i := range lines {
+		l := &lines[i]
+
+		if m := mdFence.FindStringSubmatch(l.text); m != nil {
+			switch {
+			case fence == "":
+				fence = m[1]
+			case m[1][0] == fence[0] && len(m[1]) >= len(fence):
+				fence = ""
+			}
+			continue
+		}
+		if fence != "" {
+			continue
+		}
+
+		if m := mdRefDef.FindStringSubmatch(l.text); m != nil && !afterText {
+			label := mdLabel(m[1])
+			if _, ok := p.refs[label]; !ok {
+				p.refs[label] = mdRef{href: mdDestination(m[2]), title: mdTitle(m[3])}
+			}
+			l.isRef = true
+			continue
+		}
+
+		afterText = !l.isBlank()
+	}
+}
+
+// blocks converts the lines to paragraphs.
+func (p *mdParser) blocks(lines []mdLine) {
+	var para []mdLine
+
+	flush := func() {
+		if len(para) > 0 {
+			p.im.para(p.inline(para))
+			para = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		l := lines[i]
+
+		switch {
+		case l.isRef, l.isBlank():
+			flush()
+
+		case mdFence.MatchString(l.text):
+			flush()
+			i = p.fencedCode(lines, i)
+
+		case len(para) == 0 && mdIndent(l.text) > 0:
+			i = p.indentedCode(lines, i)
+
+		case mdHeading.MatchString(l.text):
+			flush()
+			p.heading(l)
+
+		case len(para) > 0 && mdSetext.MatchString(l.text):
+			p.im.drop(scum.Span{Start: para[0].start, End: l.start + len(l.text)}, "heading is imported as bold text")
+			p.im.para([]node{{tag: sml.Bold, children: p.inline(para)}})
+			para = nil
+
+		case mdBreak.MatchString(l.text):
+			flush()
+			p.im.drop(l.span(), "thematic break is dropped")
+
+		case mdQuote.MatchString(l.text):
+			flush()
+			i = p.quote(lines, i)
+
+		case mdItem.MatchString(l.text) && (len(para) == 0 || mdInterrupts(l.text)):
+			flush()
+			i = p.list(lines, i)
+
+		case len(para) == 0 && i+1 < len(lines) && strings.Contains(l.text, "|") && mdTableDelim.MatchString(lines[i+1].text):
+			i = p.table(lines, i)
+
+		default:
+			para = append(para, l)
+		}
+	}
+
+	flush()
+}
+
+// mdInterrupts reports whether the list item can interrupt a paragraph: a bullet or the ordered list starting with 1.
+func mdInterrupts(text string) bool {
+	m := mdItem.FindStringSubmatch(text)
+	marker := m[2]
+	return strings.TrimSpace(text) != marker && (len(marker) == 1 || marker[:len(marker)-1] == "1")
+}
+
+// fencedCode imports the fenced code block starting at the line i, and returns the index of its last line.
+func (p *mdParser) fencedCode(lines []mdLine, i int) int {
+	fence := mdFence.FindStringSubmatch(lines[i].text)[1]
+	indent := len(lines[i].text) - len(strings.TrimLeft(lines[i].text, " "))
+
+	var code []string
+	end := i + 1
+	for ; end < len(lines); end++ {
+		text := lines[end].text
+		if m := mdFence.FindStringSubmatch(text); m != nil && m[1][0] == fence[0] && len(m[1]) >= len(fence) &&
+			strings.TrimSpace(text[len(m[0]):]) == "" {
+			break
+		}
+
+		// the indentation of the fence is removed from the content
+		trim := min(indent, len(text)-len(strings.TrimLeft(text, " ")))
+		code = append(code, text[trim:])
+	}
+
+	last := min(end, len(lines)-1)
+	p.code(scum.Span{Start: lines[i].start, End: lines[last].start + len(lines[last].text)}, code)
+	return last
+}
+
+// indentedCode imports the indented code block starting at the line i, and returns the index of its last line.
+func (p *mdParser) indentedCode(lines []mdLine, i int) int {
+	var code []string
+	end := i
+	for j := i; j < len(lines); j++ {
+		l := lines[j]
+		n := mdIndent(l.text)
+		if !l.isBlank() && n == 0 {
+			break
+		}
+		if !l.isBlank() {
+			end = j
+		}
+		code = append(code, l.text[n:])
+	}
+
+	code = code[:end-i+1]
+	p.code(scum.Span{Start: lines[i].start, End: lines[end].start + len(lines[end].text)}, code)
+	return end
+}
+
+// mdIndent returns the length of the indentation of the code block line, a tab or 4 spaces, or 0.
+func mdIndent(text string) int {
+	switch {
+	case strings.HasPrefix(text, "\t"):
+		return 1
+	case strings.HasPrefix(text, "    "):
+		return 4
+	}
+	return 0
+}
+
+func (p *mdParser) code(span scum.Span, lines []string) {
+	p.im.drop(span, "code block is imported as inline code")
+	if code := strings.Join(lines, "\n"); strings.TrimSpace(code) != "" {
+		p.im.para([]node{{tag: sml.Code, text: code, span: span}})
+	}
+}
+
+func (p *mdParser) heading(l mdLine) {
+	m := mdHeading.FindStringSubmatchIndex(l.text)
+	content := l.strip(m[1])
+
+	// the closing sequence of # is dropped
+	text := strings.TrimRight(content.text, " ")
+	if trimmed := strings.TrimRight(text, "#"); trimmed == "" || strings.HasSuffix(trimmed, " ") {
+		text = trimmed
+	}
+	content.text = text
+
+	p.im.drop(l.span(), "heading is imported as bold text")
+	p.im.para([]node{{tag: sml.Bold, children: p.inline([]mdLine{content})}})
+}
+
+// quote imports the block quote starting at the line i as paragraphs, and returns the index of its last line.
+func (p *mdParser) quote(lines []mdLine, i int) int {
+	var content []mdLine
+	end := i
+	for ; end < len(lines); end++ {
+		m := mdQuote.FindStringIndex(lines[end].text)
+		if m == nil {
+			break
+		}
+		content = append(content, lines[end].strip(m[1]))
+	}
+	end--
+
+	p.im.drop(scum.Span{Start: lines[i].start, End: lines[end].start + len(lines[end].text)}, "block quote is imported as plain paragraphs")
+	p.blocks(content)
+	return end
+}
+
+// list imports the list starting at the line i as a paragraph with a line per item, and returns the index of its last line.
+// The nested items are indented by 2 spaces per level.
+func (p *mdParser) list(lines []mdLine, i int) int {
+	type item struct {
+		marker string
+		level  int
+		lines  []mdLine
+	}
+
+	var (
+		items   []item
+		indents []int
+	)
+
+	end := i
+	for ; end < len(lines); end++ {
+		l := lines[end]
+		if l.isBlank() || mdFence.MatchString(l.text) || mdHeading.MatchString(l.text) ||
+			mdBreak.MatchString(l.text) || mdQuote.MatchString(l.text) {
+			break
+		}
+
+		m := mdItem.FindStringSubmatchIndex(l.text)
+		if m == nil {
+			// the continuation of the item
+			last := &items[len(items)-1]
+			last.lines = append(last.lines, l.strip(len(l.text)-len(strings.TrimLeft(l.text, " "))))
+			continue
+		}
+
+		indent := m[3] - m[2]
+		for len(indents) > 0 && indents[len(indents)-1] > indent {
+			indents = indents[:len(indents)-1]
+		}
+		if len(indents) == 0 || indents[len(indents)-1] < indent {
+			indents = append(indents, indent)
+		}
+
+		marker := l.text[m[4]:m[5]]
+		if len(marker) == 1 {
+			marker = "-"
+		} else {
+			marker = marker[:len(marker)-1] + "."
+		}
+
+		items = append(items, item{marker: marker, level: len(indents) - 1, lines: []mdLine{l.strip(m[1])}})
+	}
+	end--
+
+	p.im.drop(scum.Span{Start: lines[i].start, End: lines[end].start + len(lines[end].text)}, "list is imported as plain text lines")
+
+	var para []node
+	for k, it := range items {
+		if k > 0 {
+			para = append(para, textNode("\n", scum.Span{}))
+		}
+		para = append(para, textNode(strings.Repeat("  ", it.level)+it.marker+" ", it.lines[0].span()))
+		para = append(para, p.inline(it.lines)...)
+	}
+	p.im.para(para)
+
+	return end
+}
+
+// table imports the table starting at the line i as a paragraph with a line per row, and returns the index of its last line.
+func (p *mdParser) table(lines []mdLine, i int) int {
+	var para []node
+
+	end := i
+	for ; end < len(lines) && !lines[end].isBlank(); end++ {
+		if end == i+1 {
+			// the delimiter row
+			continue
+		}
+
+		l := lines[end]
+		text := strings.TrimSpace(l.text)
+		text = strings.TrimPrefix(text, "|")
+		if !strings.HasSuffix(text, `\|`) {
+			text = strings.TrimSuffix(text, "|")
+		}
+
+		if len(para) > 0 {
+			para = append(para, textNode("\n", scum.Span{}))
+		}
+		for k, cell := range mdCells(text) {
+			if k > 0 {
+				para = append(para, textNode(" | ", scum.Span{}))
+			}
+			para = append(para, p.inline([]mdLine{{text: strings.TrimSpace(cell), start: l.start}})...)
+		}
+	}
+	end--
+
+	p.im.drop(scum.Span{Start: lines[i].start, End: lines[end].start + len(lines[end].text)}, "table is imported as plain text lines")
+	p.im.para(para)
+	return end
+}
+
+// mdCells splits the table row at the pipes which are not escaped.
+func mdCells(row string) []string {
+	var cells []string
+	last := 0
+	for i := 0; i < len(row); i++ {
+		switch row[i] {
+		case '\\':
+			i++
+		case '|':
+			cells = append(cells, row[last:i])
+			last = i + 1
+		}
+	}
+	return append(cells, row[last:])
+}
+
+// mdLabel normalizes the link label for the matching.
+func mdLabel(label string) string {
+	return strings.ToLower(strings.Join(strings.Fields(label), " "))
+}
+
+// mdDestination returns the link destination without the angle brackets, the escapes and the entities.
+func mdDestination(dest string) string {
+	if strings.HasPrefix(dest, "<") && strings.HasSuffix(dest, ">") {
+		dest = dest[1 : len(dest)-1]
+	}
+	return mdUnescape(dest)
+}
+
+// mdTitle returns the link title without the quotes, the escapes and the entities.
+func mdTitle(title string) string {
+	if len(title) < 2 {
+		return ""
+	}
+	return mdUnescape(title[1 : len(title)-1])
+}
+
+// mdUnescape removes the backslash escapes and decodes the entities.
+func mdUnescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return html.UnescapeString(b.String())
+}
+
+func isASCIIPunct(c byte) bool {
+	return c < utf8.RuneSelf && (unicode.IsPunct(rune(c)) || unicode.IsSymbol(rune(c)))
+}
+
+// mdInline is a piece of the inline content: a node, a run of the emphasis delimiters, or an opening bracket of a link.
+type mdInline struct {
+	node node
+	// delim is '*', '_' or '~' for the delimiter runs, '[' or '!' for the openers of the links and the images.
+	delim byte
+	// n is the count of the unused delimiters of the run, orig is the count in the source.
+	n, orig int
+	// canOpen and canClose are set by the flanking of the run.
+	canOpen, canClose bool
+	// pos is the offset of the item in the text.
+	pos int
+}
+
+// mdText is the inline content of a block: its lines joined with the line breaks.
+type mdText struct {
+	s     string
+	lines []mdLine
+	// offsets are the offsets of the lines in s.
+	offsets []int
+}
+
+// span returns the span of the source of s[start:end].
+func (t *mdText) span(start, end int) scum.Span {
+	return scum.Span{Start: t.src(start), End: t.src(end)}
+}
+
+func (t *mdText) src(i int) int {
+	k, found := slices.BinarySearch(t.offsets, i)
+	if !found {
+		k--
+	}
+	l := t.lines[k]
+	return l.start + min(i-t.offsets[k], len(l.text))
+}
+
+// inline converts the inline content of the lines.
+func (p *mdParser) inline(lines []mdLine) []node {
+	t := mdText{lines: make([]mdLine, 0, len(lines)), offsets: make([]int, 0, len(lines))}
+
+	var b strings.Builder
+	for k, l := range lines {
+		if k > 0 {
+			b.WriteByte('\n')
+		}
+
+		// the leading and the trailing spaces of the lines are dropped, and so is the backslash of the hard break
+		l = l.strip(len(l.text) - len(strings.TrimLeft(l.text, " ")))
+		l.text = strings.TrimRight(l.text, " ")
+		if k < len(lines)-1 && strings.HasSuffix(l.text, `\`) && !strings.HasSuffix(l.text, `\\`) {
+			l.text = l.text[:len(l.text)-1]
+		}
+
+		t.lines = append(t.lines, l)
+		t.offsets = append(t.offsets, b.Len())
+		b.WriteString(l.text)
+	}
+	t.s = b.String()
+
+	return p.parseInline(&t)
+}
+
+func (p *mdParser) parseInline(t *mdText) []node {
+	var items []*mdInline
+	s := t.s
+
+	// the indexes of the link openers in items, the openers before linkFloor are inactive
+	var brackets []int
+	linkFloor := 0
+
+	text := func(str string, start, end int) {
+		items = append(items, &mdInline{node: textNode(str, t.span(start, end)), pos: start})
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]):
+			text(s[i+1:i+2], i, i+2)
+			i += 2
+
+		case c == '`':
+			run := mdRun(s, i)
+			end := mdCodeEnd(s, i+run, run)
+			if end < 0 {
+				text(s[i:i+run], i, i+run)
+				i += run
+				continue
+			}
+
+			code := strings.ReplaceAll(s[i+run:end], "\n", " ")
+			if len(code) > 1 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "" {
+				code = code[1 : len(code)-1]
+			}
+			items = append(items, &mdInline{node: node{tag: sml.Code, text: code, span: t.span(i, end+run)}, pos: i})
+			i = end + run
+
+		case c == '*' || c == '_' || c == '~':
+			run := mdRun(s, i)
+			if c == '~' && run != 2 {
+				text(s[i:i+run], i, i+run)
+				i += run
+				continue
+			}
+
+			items = append(items, mdDelimiter(s, i, run))
+			i += run
+
+		case c == '[' || (c == '!' && i+1 < len(s) && s[i+1] == '['):
+			w := 1
+			if c == '!' {
+				w = 2
+			}
+			brackets = append(brackets, len(items))
+			items = append(items, &mdInline{node: textNode(s[i:i+w], t.span(i, i+w)), delim: c, pos: i})
+			i += w
+
+		case c == ']':
+			o := -1
+			if len(brackets) > 0 {
+				o = brackets[len(brackets)-1]
+				brackets = brackets[:len(brackets)-1]
+			}
+
+			var link bool
+			items, i, link = p.closeBracket(t, items, o, o >= 0 && o < linkFloor && items[o].delim == '[', i)
+			if link {
+				linkFloor = len(items)
+			}
+
+		case c == '<':
+			if m := mdAutolink.FindStringSubmatch(s[i:]); m != nil {
+				items = p.appendNodes(items, i, p.im.link(m[1], "", []node{textNode(m[1], t.span(i+1, i+len(m[0])-1))}, t.span(i, i+len(m[0]))))
+				i += len(m[0])
+				continue
+			}
+			if m := mdEmail.FindStringSubmatch(s[i:]); m != nil {
+				items = p.appendNodes(items, i, p.im.link("mailto:"+m[1], "", []node{textNode(m[1], t.span(i+1, i+len(m[0])-1))}, t.span(i, i+len(m[0]))))
+				i += len(m[0])
+				continue
+			}
+			text("<", i, i+1)
+			i++
+
+		case c == '&':
+			if m := mdEntity.FindString(s[i:]); m != "" {
+				text(html.UnescapeString(m), i, i+len(m))
+				i += len(m)
+				continue
+			}
+			text("&", i, i+1)
+			i++
+
+		default:
+			end := i + 1
+			for end < len(s) && strings.IndexByte("\\`*_~![]<&", s[end]) < 0 {
+				end++
+			}
+			text(s[i:end], i, end)
+			i = end
+		}
+	}
+
+	return p.emphasis(items)
+}
+
+func (p *mdParser) appendNodes(items []*mdInline, pos int, nodes []node) []*mdInline {
+	for _, n := range nodes {
+		items = append(items, &mdInline{node: n, pos: pos})
+	}
+	return items
+}
+
+// mdRun returns the length of the run of the byte at i.
+func mdRun(s string, i int) int {
+	n := 1
+	for i+n < len(s) && s[i+n] == s[i] {
+		n++
+	}
+	return n
+}
+
+// mdCodeEnd returns the start of the backtick run of the length closing the code span, or -1.
+func mdCodeEnd(s string, from, run int) int {
+	for i := from; i < len(s); {
+		if s[i] != '`' {
+			i++
+			continue
+		}
+		n := mdRun(s, i)
+		if n == run {
+			return i
+		}
+		i += n
+	}
+	return -1
+}
+
+// mdDelimiter returns the delimiter run with its flanking, as defined by CommonMark.
+func mdDelimiter(s string, i, run int) *mdInline {
+	before, after := ' ', ' '
+	if i > 0 {
+		before, _ = utf8.DecodeLastRuneInString(s[:i])
+	}
+	if i+run < len(s) {
+		after, _ = utf8.DecodeRuneInString(s[i+run:])
+	}
+
+	isPunct := func(r rune) bool { return unicode.IsPunct(r) || unicode.IsSymbol(r) }
+	left := !unicode.IsSpace(after) && (!isPunct(after) || unicode.IsSpace(before) || isPunct(before))
+	right := !unicode.IsSpace(before) && (!isPunct(before) || unicode.IsSpace(after) || isPunct(after))
+
+	d := &mdInline{
+		node:     node{text: s[i : i+run]},
+		delim:    s[i],
+		n:        run,
+		orig:     run,
+		canOpen:  left,
+		canClose: right,
+		pos:      i,
+	}
+	if s[i] == '_' {
+		d.canOpen = left && (!right || isPunct(before))
+		d.canClose = right && (!left || isPunct(after))
+	}
+	return d
+}
+
+// closeBracket handles the ']' at i, converting the items after the opener at o to a link or an image.
+// It returns the items, the index after the link, and whether a link is created.
+func (p *mdParser) closeBracket(t *mdText, items []*mdInline, o int, inactive bool, i int) ([]*mdInline, int, bool) {
+	s := t.s
+
+	literal := func() ([]*mdInline, int, bool) {
+		if o >= 0 {
+			items[o].delim = 0
+		}
+		return append(items, &mdInline{node: textNode("]", t.span(i, i+1)), pos: i}), i + 1, false
+	}
+
+	// the links are not nested
+	if o < 0 || inactive {
+		return literal()
+	}
+
+	opener := items[o]
+	href, title, end, ok := p.linkTail(s, i+1, s[opener.pos+len(opener.node.text):i])
+	if !ok {
+		return literal()
+	}
+
+	span := t.span(opener.pos, end)
+	children := p.emphasis(items[o+1:])
+	items = items[:o]
+
+	if opener.delim == '!' {
+		p.im.drop(span, "image is dropped, its alt text is kept")
+		return p.appendNodes(items, opener.pos, children), end, false
+	}
+
+	return p.appendNodes(items, opener.pos, p.im.link(href, title, children, span)), end, true
+}
+
+// linkTail parses the destination and the title of the link after its text, in parentheses or as a reference.
+// It returns the index after the link.
+func (p *mdParser) linkTail(s string, i int, label string) (href, title string, end int, ok bool) {
+	if i < len(s) && s[i] == '(' {
+		if href, title, end, ok = mdInlineLink(s, i+1); ok {
+			return href, title, end, true
+		}
+	}
+
+	// the full reference [text][label], the collapsed one [text][] and the shortcut one [text]
+	end = i
+	if i < len(s) && s[i] == '[' {
+		if close := strings.IndexByte(s[i:min(i+mdMaxLabel+2, len(s))], ']'); close > 0 {
+			if ref := s[i+1 : i+close]; ref != "" {
+				label = ref
+			}
+			end = i + close + 1
+		}
+	}
+
+	if len(label) > mdMaxLabel {
+		return "", "", 0, false
+	}
+
+	ref, ok := p.refs[mdLabel(label)]
+	return ref.href, ref.title, end, ok
+}
+
+// mdInlineLink parses the (destination "title") of the inline link starting after the '(' at i.
+func mdInlineLink(s string, i int) (href, title string, end int, ok bool) {
+	skip := func() {
+		for i < len(s) && (s[i] == ' ' || s[i] == '\n') {
+			i++
+		}
+	}
+
+	skip()
+	start := i
+	if i < len(s) && s[i] == '<' {
+		close := strings.IndexAny(s[i+1:], "<>\n")
+		if close < 0 || s[i+1+close] != '>' {
+			return "", "", 0, false
+		}
+		i += close + 2
+	} else {
+		depth := 0
+		for ; i < len(s) && s[i] > ' '; i++ {
+			if s[i] == '\\' && i+1 < len(s) {
+				i++
+				continue
+			}
+			if s[i] == '(' {
+				depth++
+			}
+			if s[i] == ')' {
+				if depth == 0 {
+					break
+				}
+				depth--
+			}
+		}
+	}
+	href = mdDestination(s[start:i])
+
+	skip()
+	if i < len(s) && strings.IndexByte(`"'(`, s[i]) >= 0 && i > start {
+		closing := s[i]
+		if closing == '(' {
+			closing = ')'
+		}
+		k := i + 1
+		for ; k < len(s) && s[k] != closing; k++ {
+			if s[k] == '\\' {
+				k++
+			}
+		}
+		if k >= len(s) {
+			return "", "", 0, false
+		}
+		title = mdTitle(s[i : k+1])
+		i = k + 1
+	}
+
+	skip()
+	if i >= len(s) || s[i] != ')' {
+		return "", "", 0, false
+	}
+	return href, title, i + 1, true
+}
+
+// emphasis matches the delimiter runs of the items, following the CommonMark algorithm, and returns the nodes.
+// The unmatched delimiters and openers are text.
+func (p *mdParser) emphasis(items []*mdInline) []node {
+	// the items are linked in a list, so the matched ones are wrapped without moving the others,
+	// len(items) is the end of the list
+	head := 0
+	prev := make([]int, len(items))
+	next := make([]int, len(items))
+	for i := range items {
+		prev[i], next[i] = i-1, i+1
+	}
+	unlink := func(i int) {
+		if prev[i] >= 0 {
+			next[prev[i]] = next[i]
+		} else {
+			head = next[i]
+		}
+		if next[i] < len(items) {
+			prev[next[i]] = prev[i]
+		}
+	}
+
+	// bottom is the index of the item below which there is no opener for the closers of the kind,
+	// so the unmatched closers don't scan the items again
+	type kind struct {
+		delim   byte
+		mod3    int
+		canOpen bool
+	}
+	bottom := make(map[kind]int)
+
+	for c := head; c < len(items); c = next[c] {
+		closer := items[c]
+		if !isEmphasis(closer) || !closer.canClose {
+			continue
+		}
+
+		for closer.n > 0 {
+			key := kind{closer.delim, closer.orig % 3, closer.canOpen}
+			floor, ok := bottom[key]
+			if !ok {
+				floor = -1
+			}
+
+			o := -1
+			for k := prev[c]; k > floor; k = prev[k] {
+				if opener := items[k]; isEmphasis(opener) && opener.delim == closer.delim && opener.canOpen && opener.n > 0 &&
+					!mdRuleOf3(opener, closer) && (closer.delim != '~' || opener.n == closer.n) {
+					o = k
+					break
+				}
+			}
+			if o < 0 {
+				bottom[key] = prev[c]
+				break
+			}
+
+			opener := items[o]
+			use := 1
+			if opener.n >= 2 && closer.n >= 2 {
+				use = 2
+			}
+
+			tag := sml.Italic
+			switch {
+			case closer.delim == '~':
+				tag = sml.Strike
+			case use == 2:
+				tag = sml.Bold
+			}
+
+			opener.n -= use
+			closer.n -= use
+
+			// the items between the runs are wrapped in the place of the first of them,
+			// a run is never next to the run it's matched with
+			var children []*mdInline
+			for k := next[o]; k != c; k = next[k] {
+				children = append(children, items[k])
+			}
+			slot := next[o]
+			items[slot] = &mdInline{node: node{tag: tag, children: mdNodes(children)}, pos: opener.pos}
+			next[slot], prev[c] = c, slot
+
+			if opener.n == 0 {
+				unlink(o)
+			}
+		}
+
+		if closer.n == 0 {
+			unlink(c)
+		}
+	}
+
+	var live []*mdInline
+	for i := head; i < len(items); i = next[i] {
+		live = append(live, items[i])
+	}
+	return mdNodes(live)
+}
+
+func isEmphasis(it *mdInline) bool {
+	return it.delim == '*' || it.delim == '_' || it.delim == '~'
+}
+
+// mdRuleOf3 reports whether the runs can't match by the "rule of 3" of CommonMark, so a**b** is not read as a* *b**.
+func mdRuleOf3(opener, closer *mdInline) bool {
+	return (opener.canClose || closer.canOpen) && (opener.orig+closer.orig)%3 == 0 &&
+		(opener.orig%3 != 0 || closer.orig%3 != 0)
+}
+
+// mdNodes returns the nodes of the items, with the unused delimiters as text.
+func mdNodes(items []*mdInline) []node {
+	nodes := make([]node, 0, len(items))
+	for _, it := range items {
+		switch {
+		case isEmphasis(it):
+			if it.n > 0 {
+				nodes = append(nodes, textNode(strings.Repeat(string(it.delim), it.n), scum.Span{}))
+			}
+		default:
+			nodes = append(nodes, it.node)
+		}
+	}
+	return nodes
+}
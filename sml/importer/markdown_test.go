@@ -0,0 +1,152 @@
+package importer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarkdown_RoundTrip(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		wantSML  string
+		wantHTML string
+	}{
+		{
+			name:     "Emphasis",
+			input:    "**bold**, *italic*, _it_, __b__ and ***both***",
+			wantSML:  "$bold$, *italic*, *it*, $b$ and *$both$*",
+			wantHTML: "<strong>bold</strong>, <em>italic</em>, <em>it</em>, <strong>b</strong> and <em><strong>both</strong></em>",
+		},
+		{
+			name:     "IntrawordDelimiters",
+			input:    "a**b**c, snake_case_name and 2*3*4",
+			wantSML:  `a$b$c, snake\_case\_name and 2*3*4`,
+			wantHTML: "a<strong>b</strong>c, snake_case_name and 2<em>3</em>4",
+		},
+		{
+			name:     "NestedEmphasis",
+			input:    "*a **b** c* **a *b* c**",
+			wantSML:  "*a $b$ c* $a *b* c$",
+			wantHTML: "<em>a <strong>b</strong> c</em> <strong>a <em>b</em> c</strong>",
+		},
+		{
+			name:     "StrikeAndCode",
+			input:    "~~gone~~ ~not~ `co*de* $x$` ``a`b``",
+			wantSML:  "~~gone~~ \\~not\\~ `co*de* $x$` ``a`b``",
+			wantHTML: "<s>gone</s> ~not~ <code>co*de* $x$</code> <code>a`b</code>",
+		},
+		{
+			name:     "Links",
+			input:    `[docs](https://example.com/a "The docs") and [*rel*](</x y>) <https://auto.link> <me@mail.org>`,
+			wantSML:  "[docs]!href{https://example.com/a}!title{The docs} and [*rel*]!href{/x y} [https://auto.link]!href{https://auto.link} [me@mail.org]!href{mailto:me@mail.org}",
+			wantHTML: `<a href="https://example.com/a" title="The docs">docs</a> and <a href="/x y"><em>rel</em></a> <a href="https://auto.link">https://auto.link</a> <a href="mailto:me@mail.org">me@mail.org</a>`,
+		},
+		{
+			name:     "ReferenceLinks",
+			input:    "[full][r], [Collapsed][] and [shortcut]\n\n[r]: /r\n[collapsed]: /c 'C'\n[shortcut]: <https://s.example>",
+			wantSML:  "[full]!href{/r}, [Collapsed]!href{/c}!title{C} and [shortcut]!href{https://s.example}",
+			wantHTML: `<a href="/r">full</a>, <a href="/c" title="C">Collapsed</a> and <a href="https://s.example">shortcut</a>`,
+		},
+		{
+			name:     "LinksAreNotNested",
+			input:    "[**a [b](/b)**](/a) [not a link] [x](",
+			wantSML:  `\[$a [b]!href{/b}$\](/a) \[not a link\] \[x\](`,
+			wantHTML: `[<strong>a <a href="/b">b</a></strong>](/a) [not a link] [x](`,
+		},
+		{
+			name:     "EscapesAndEntities",
+			input:    `\*not\* $5 \[x\] &amp; &copy; &#65; &nope; <b>raw</b> \\o/`,
+			wantSML:  `\*not\* \$5 \[x\] & © A &nope; <b>raw</b> \\o/`,
+			wantHTML: `*not* $5 [x] &amp; © A &amp;nope; &lt;b&gt;raw&lt;/b&gt; \o/`,
+		},
+		{
+			name:     "Paragraphs",
+			input:    "  one\nline  \nhard\\\nbreak\n\n\n\ntwo",
+			wantSML:  "one\nline\nhard\nbreak\n\ntwo",
+			wantHTML: "one\nline\nhard\nbreak\n\ntwo",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			src, issues := Markdown(tc.input)
+
+			require.Empty(t, issues)
+			require.Equal(t, tc.wantSML, src)
+			require.Equal(t, tc.wantHTML, munch(t, src))
+		})
+	}
+}
+
+func TestMarkdown_UnsupportedConstructs(t *testing.T) {
+	cases := []struct {
+		name       string
+		input      string
+		wantSML    string
+		wantIssues []string
+	}{
+		{
+			name:       "Headings",
+			input:      "# Title *one* ##\n\nSetext\n===",
+			wantSML:    "$Title *one*$\n\n$Setext$",
+			wantIssues: []string{"heading is imported as bold text", "heading is imported as bold text"},
+		},
+		{
+			name:       "Lists",
+			input:      "- one\n* two\n  continued\n  1. nested\n  2) second\n\ntext",
+			wantSML:    "- one\n- two\ncontinued\n  1. nested\n  2. second\n\ntext",
+			wantIssues: []string{"list is imported as plain text lines"},
+		},
+		{
+			name:       "QuoteAndBreak",
+			input:      "> quote *x*\n> > nested\n\n---",
+			wantSML:    "quote *x*\n\nnested",
+			wantIssues: []string{"block quote is imported as plain paragraphs", "block quote is imported as plain paragraphs", "thematic break is dropped"},
+		},
+		{
+			name:       "CodeBlocks",
+			input:      "```go\nfmt.Println(\"$x\")\n\tx := `y`\n```\n\n    indented\n\n    more",
+			wantSML:    "``fmt.Println(\"$x\")\n\tx := `y` ``\n\n`indented\n\nmore`",
+			wantIssues: []string{"code block is imported as inline code", "code block is imported as inline code"},
+		},
+		{
+			name:       "Table",
+			input:      "| a | b \\| c |\n|---|:-:|\n| 1 | *2* |",
+			wantSML:    "a \\| b \\| c\n1 \\| *2*",
+			wantIssues: []string{"table is imported as plain text lines"},
+		},
+		{
+			name:       "ImageAndUnsafeLink",
+			input:      "![the *cat*](/cat.png) [click](javascript:alert(1))",
+			wantSML:    "the *cat* click",
+			wantIssues: []string{"image is dropped, its alt text is kept", `link to "javascript:alert(1)" is dropped, its text is kept`},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			src, issues := Markdown(tc.input)
+
+			require.Equal(t, tc.wantSML, src)
+			require.Len(t, issues, len(tc.wantIssues))
+			for i, issue := range issues {
+				require.Equal(t, "UNSUPPORTED_MARKUP", issue.Codename())
+				require.Equal(t, tc.wantIssues[i], issue.Description())
+			}
+
+			// the result is valid SML
+			munch(t, src)
+		})
+	}
+}
+
+func TestMarkdown_IssueSpans(t *testing.T) {
+	input := "text\n\n## Title\n\nsee ![img](/a.png) here"
+	_, issues := Markdown(input)
+
+	require.Len(t, issues, 2)
+	require.Equal(t, "## Title", input[issues[0].Span().Start:issues[0].Span().End])
+	require.Equal(t, "![img](/a.png)", input[issues[1].Span().Start:issues[1].Span().End])
+}
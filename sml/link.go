@@ -3,35 +3,37 @@ package sml
 import (
 	"fmt"
 	"net/url"
+	"slices"
 	"strings"
 	"unicode/utf8"
 
 	"github.com/Drolfothesgnir/shitposter/scum"
 )
 
+// MaxTitleLength is the default maximum length of the link titles in runes, see [LinkPolicy].
 const MaxTitleLength int = 65
 
 func basicLinkCheck(i *Issues, a scum.SerializableAttribute, attrName string) (string, bool) {
 	if a.IsFlag {
-		i.Add(NewSyntaxIssueDescriptor(IssueAttributeInvalidPayload, fmt.Sprintf("attribute %s must have a value", attrName)))
+		i.Add(NewSyntaxIssueDescriptor(IssueAttributeInvalidPayload, a.Span(), fmt.Sprintf("attribute %s must have a value", attrName)))
 		return "", false
 	}
 
 	payload := strings.TrimSpace(a.Payload)
 	if payload == "" {
-		i.Add(NewSyntaxIssueDescriptor(IssueAttributeInvalidPayload, fmt.Sprintf("attribute %s must not be empty", attrName)))
+		i.Add(NewSyntaxIssueDescriptor(IssueAttributeInvalidPayload, a.PayloadSpan, fmt.Sprintf("attribute %s must not be empty", attrName)))
 		return "", false
 	}
 
 	if strings.ContainsAny(payload, "\x00\r\n\t") {
-		i.Add(NewSyntaxIssueDescriptor(IssueAttributeInvalidPayload, fmt.Sprintf("attribute %s contains forbidden control characters", attrName)))
+		i.Add(NewSyntaxIssueDescriptor(IssueAttributeInvalidPayload, a.PayloadSpan, fmt.Sprintf("attribute %s contains forbidden control characters", attrName)))
 		return "", false
 	}
 
 	return payload, true
 }
 
-func validateHref(i *Issues, a scum.SerializableAttribute) bool {
+func (p LinkPolicy) validateHref(i *Issues, a scum.SerializableAttribute) bool {
 	payload, ok := basicLinkCheck(i, a, "href")
 	if !ok {
 		return false
@@ -39,39 +41,55 @@ func validateHref(i *Issues, a scum.SerializableAttribute) bool {
 
 	u, err := url.Parse(payload)
 	if err != nil {
-		i.Add(NewSyntaxIssueDescriptor(IssueAttributeInvalidPayload, fmt.Sprintf("attribute href is invalid: %v", err)))
+		i.Add(NewSyntaxIssueDescriptor(IssueAttributeInvalidPayload, a.PayloadSpan, fmt.Sprintf("attribute href is invalid: %v", err)))
 		return false
 	}
 
-	switch strings.ToLower(u.Scheme) {
-	case "":
+	scheme := strings.ToLower(u.Scheme)
+	switch {
+	case scheme == "":
 		// Allow relative references, but reject protocol-relative URLs such as //evil.com.
 		if strings.HasPrefix(payload, "//") {
-			i.Add(NewSyntaxIssueDescriptor(IssueAttributeInvalidPayload, "attribute href must not be protocol-relative"))
+			i.Add(NewSyntaxIssueDescriptor(IssueAttributeInvalidPayload, a.PayloadSpan, "attribute href must not be protocol-relative"))
 			return false
 		}
 
-	case "http", "https", "mailto":
-		// Allowed schemes.
+	case !slices.Contains(p.Schemes, scheme):
+		i.Add(NewSyntaxIssueDescriptor(IssueAttributeInvalidPayload, a.PayloadSpan, fmt.Sprintf("attribute href scheme %q is not allowed", u.Scheme)))
+		return false
+	}
+
+	if u.Host == "" {
+		return true
+	}
+
+	host, err := asciiHost(u.Hostname())
+	if err != nil {
+		i.Add(NewSyntaxIssueDescriptor(IssueAttributeInvalidPayload, a.PayloadSpan, fmt.Sprintf("attribute href host %q is invalid", u.Hostname())))
+		return false
+	}
+
+	if isHomograph(host) {
+		i.Add(NewSyntaxIssueDescriptor(IssueAttributeInvalidPayload, a.PayloadSpan, fmt.Sprintf("attribute href host %q can be mistaken for another one", u.Hostname())))
+		return false
+	}
 
-	default:
-		i.Add(NewSyntaxIssueDescriptor(IssueAttributeInvalidPayload, fmt.Sprintf("attribute href scheme %q is not allowed", u.Scheme)))
+	if !p.allowsDomain(host) {
+		i.Add(NewSyntaxIssueDescriptor(IssueAttributeInvalidPayload, a.PayloadSpan, fmt.Sprintf("attribute href domain %q is not allowed", u.Hostname())))
 		return false
 	}
 
 	return true
 }
 
-func validateTarget(i *Issues, a scum.SerializableAttribute) (scum.SerializableAttribute, bool) {
+func (p LinkPolicy) validateTarget(i *Issues, a scum.SerializableAttribute) (scum.SerializableAttribute, bool) {
 	payload, ok := basicLinkCheck(i, a, "target")
 	if !ok {
 		return scum.SerializableAttribute{}, false
 	}
 
-	switch payload {
-	case "_blank", "_self": // some others?
-	default:
-		i.Add(NewSyntaxIssueDescriptor(IssueAttributeInvalidPayload, `attribute target must be one of "_blank" or "_self"`))
+	if !slices.Contains(p.Targets, payload) {
+		i.Add(NewSyntaxIssueDescriptor(IssueAttributeInvalidPayload, a.PayloadSpan, "attribute target must be one of "+quotedList(p.Targets)))
 		return scum.SerializableAttribute{}, false
 	}
 
@@ -84,25 +102,36 @@ func validateTarget(i *Issues, a scum.SerializableAttribute) (scum.SerializableA
 	return rel, true
 }
 
-func validateTitle(i *Issues, a scum.SerializableAttribute) bool {
+func (p LinkPolicy) validateTitle(i *Issues, a scum.SerializableAttribute) bool {
 	payload, ok := basicLinkCheck(i, a, "title")
 	if !ok {
 		return false
 	}
 
-	if utf8.RuneCountInString(payload) > MaxTitleLength {
-		// TODO: find the idiomatic way to make max title length configurable
-		i.Add(NewSyntaxIssueDescriptor(IssueAttributeInvalidPayload, fmt.Sprintf("attribute title must be at most %d characters long", MaxTitleLength)))
+	if utf8.RuneCountInString(payload) > p.MaxTitleLength {
+		i.Add(NewSyntaxIssueDescriptor(IssueAttributeInvalidPayload, a.PayloadSpan, fmt.Sprintf("attribute title must be at most %d characters long", p.MaxTitleLength)))
 		return false
 	}
 
 	return true
 }
 
+// normalizeLink normalizes the link with the [DefaultLinkPolicy].
 func normalizeLink(n *scum.SerializableNode, issues *Issues) {
-	var hasHref, hasTarget, hasTitle bool
+	defaultLinkPolicy.normalizeLink(n, issues)
+}
+
+func (p LinkPolicy) normalizeLink(n *scum.SerializableNode, issues *Issues) {
+	// the payloads are validated the way they are rendered
+	for i := range n.Attributes {
+		n.Attributes[i].Payload = scum.Unescape(n.Attributes[i].Payload, escapeSymbol)
+	}
+
+	var hasHref, hasTarget, hasTitle, outbound bool
 	allowed := n.Attributes[:0]
 	count := 3
+	// rel is the index of the rel attribute added for the target
+	rel := -1
 
 	for i := 0; i < len(n.Attributes) && count > 0; i++ {
 		a := n.Attributes[i]
@@ -113,8 +142,9 @@ func normalizeLink(n *scum.SerializableNode, issues *Issues) {
 				continue
 			}
 
-			if validateHref(issues, a) {
+			if p.validateHref(issues, a) {
 				allowed = append(allowed, a)
+				outbound = isOutbound(a.Payload)
 				hasHref = true
 				count--
 			}
@@ -124,10 +154,11 @@ func normalizeLink(n *scum.SerializableNode, issues *Issues) {
 				continue
 			}
 
-			if rel, ok := validateTarget(issues, a); ok {
+			if r, ok := p.validateTarget(issues, a); ok {
 				allowed = append(allowed, a)
-				if rel.Name != "" {
-					allowed = append(allowed, rel)
+				if r.Name != "" {
+					rel = len(allowed)
+					allowed = append(allowed, r)
 				}
 				hasTarget = true
 				count--
@@ -138,7 +169,7 @@ func normalizeLink(n *scum.SerializableNode, issues *Issues) {
 				continue
 			}
 
-			if validateTitle(issues, a) {
+			if p.validateTitle(issues, a) {
 				allowed = append(allowed, a)
 				hasTitle = true
 				count--
@@ -146,12 +177,32 @@ func normalizeLink(n *scum.SerializableNode, issues *Issues) {
 		}
 	}
 
+	if p.UGC && outbound {
+		if rel >= 0 {
+			allowed[rel].Payload += " " + ugcRel
+		} else {
+			allowed = append(allowed, scum.SerializableAttribute{Name: "rel", Payload: ugcRel})
+		}
+	}
+
 	n.Attributes = allowed
+}
 
-	for i := range n.Children {
-		c := &n.Children[i]
-		normalizeNode(c, issues)
+// renderLink writes the link as HTML, with the outbound href rewritten by the [LinkRedirect] if there is one.
+func (p LinkPolicy) renderLink(r *Renderer, n *scum.SerializableNode) {
+	i := slices.IndexFunc(n.Attributes, func(a scum.SerializableAttribute) bool {
+		return attrName(a) == "href"
+	})
+	if p.Redirect == nil || i < 0 || !isOutbound(n.Attributes[i].Payload) {
+		renderLink(r, n)
+		return
 	}
+
+	// the tree is shared by the renditions, so the href is rewritten in a copy
+	c := *n
+	c.Attributes = slices.Clone(n.Attributes)
+	c.Attributes[i].Payload = p.Redirect.Rewrite(strings.TrimSpace(c.Attributes[i].Payload))
+	renderLink(r, &c)
 }
 
 func attrName(a scum.SerializableAttribute) string {
@@ -0,0 +1,354 @@
+package sml
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// ugcRel marks the links as the user generated content for the search engines, see [LinkPolicy.UGC].
+const ugcRel = "ugc nofollow"
+
+// LinkPolicy defines which links are allowed and how they are rendered, see [WithLinkPolicy].
+// The zero fields fall back to the ones of the [DefaultLinkPolicy].
+//
+// The hosts of the hrefs are always checked for the IDN homographs: the internationalized domains mixing
+// the scripts, like Latin and Cyrillic in "pаypal.com", or written in the Cyrillic or Greek letters which
+// all look like the Latin ones, are rejected, whether they are written in Unicode or in punycode.
+type LinkPolicy struct {
+	// Schemes are the allowed schemes of the absolute hrefs. The relative hrefs are always allowed,
+	// except the protocol-relative ones.
+	Schemes []string
+
+	// AllowedDomains are the only domains the hrefs with a host can point to, all of them if empty.
+	// The domain matches itself, and "*.example.com" matches the subdomains of example.com, but not example.com.
+	AllowedDomains []string
+
+	// DeniedDomains are the domains the hrefs with a host can't point to, matched like the AllowedDomains.
+	// They take precedence over the AllowedDomains.
+	DeniedDomains []string
+
+	// MaxTitleLength is the maximum length of the titles in runes.
+	MaxTitleLength int
+
+	// Targets are the allowed values of the target attribute.
+	Targets []string
+
+	// UGC adds rel="ugc nofollow" to the links with a host, so the search engines don't credit
+	// the linked sites for the links posted by the users.
+	UGC bool
+
+	// Redirect rewrites the hrefs with a host in the HTML, if set. The tree and the other renditions
+	// keep the original hrefs.
+	Redirect *LinkRedirect
+}
+
+// defaultLinkPolicy is the policy of the links of the [Eater] created without [WithLinkPolicy].
+var defaultLinkPolicy = LinkPolicy{
+	Schemes:        []string{"http", "https", "mailto"},
+	MaxTitleLength: MaxTitleLength,
+	Targets:        []string{"_blank", "_self"},
+}
+
+// DefaultLinkPolicy returns the policy of the [Eater] created without [WithLinkPolicy]: the http, https and
+// mailto schemes, any domain, the titles of at most [MaxTitleLength] runes and the "_blank" and "_self" targets.
+func DefaultLinkPolicy() LinkPolicy {
+	p := defaultLinkPolicy
+	p.Schemes = slices.Clone(p.Schemes)
+	p.Targets = slices.Clone(p.Targets)
+	return p
+}
+
+// WithLinkPolicy makes the [Eater] validate and render the links with the policy instead of the [DefaultLinkPolicy].
+// The mentions are not affected. If the policy is invalid, [NewEater] returns a *[ConfigError].
+func WithLinkPolicy(p LinkPolicy) Option {
+	return func(e *Eater) {
+		p, err := p.compile()
+		if err != nil {
+			if e.err == nil {
+				e.err = err
+			}
+			return
+		}
+
+		e.tags[Link] = TagHandler{
+			Normalize: p.normalizeLink,
+			HTML:      p.renderLink,
+			Text:      textLink,
+			Markdown:  markdownLink,
+			ANSI:      ansiLink,
+		}
+	}
+}
+
+// compile returns the policy with the defaults of the zero fields, the schemes in lower case
+// and the domains in punycode.
+func (p LinkPolicy) compile() (LinkPolicy, error) {
+	if p.MaxTitleLength < 0 {
+		return LinkPolicy{}, fmt.Errorf("link policy max title length must not be negative, got %d", p.MaxTitleLength)
+	}
+	if p.MaxTitleLength == 0 {
+		p.MaxTitleLength = defaultLinkPolicy.MaxTitleLength
+	}
+
+	if len(p.Schemes) == 0 {
+		p.Schemes = defaultLinkPolicy.Schemes
+	}
+	schemes := make([]string, len(p.Schemes))
+	for i, s := range p.Schemes {
+		schemes[i] = strings.ToLower(strings.TrimSuffix(s, ":"))
+	}
+	p.Schemes = schemes
+
+	if len(p.Targets) == 0 {
+		p.Targets = defaultLinkPolicy.Targets
+	}
+	p.Targets = slices.Clone(p.Targets)
+
+	var err error
+	if p.AllowedDomains, err = compileDomains(p.AllowedDomains); err != nil {
+		return LinkPolicy{}, err
+	}
+	if p.DeniedDomains, err = compileDomains(p.DeniedDomains); err != nil {
+		return LinkPolicy{}, err
+	}
+
+	if p.Redirect != nil {
+		r := *p.Redirect
+		if !strings.HasPrefix(r.Path, "/") || strings.HasPrefix(r.Path, "//") || strings.ContainsAny(r.Path, "?#") {
+			return LinkPolicy{}, fmt.Errorf("link redirect path must be an absolute path without query, got %q", r.Path)
+		}
+		if len(r.Key) == 0 {
+			return LinkPolicy{}, errors.New("link redirect key must not be empty")
+		}
+		r.Key = slices.Clone(r.Key)
+		p.Redirect = &r
+	}
+
+	return p, nil
+}
+
+// compileDomains returns the domain patterns in lower case punycode.
+func compileDomains(patterns []string) ([]string, error) {
+	out := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		domain, wildcard := strings.CutPrefix(strings.TrimSpace(pattern), "*.")
+		host, err := asciiHost(domain)
+		if err != nil || host == "" || strings.Contains(host, "*") {
+			return nil, fmt.Errorf("link policy domain %q is invalid", pattern)
+		}
+
+		if wildcard {
+			host = "*." + host
+		}
+		out = append(out, host)
+	}
+	return out, nil
+}
+
+// allowsDomain reports whether the host in punycode can be linked to.
+func (p LinkPolicy) allowsDomain(host string) bool {
+	match := func(pattern string) bool {
+		if domain, ok := strings.CutPrefix(pattern, "*."); ok {
+			return strings.HasSuffix(host, "."+domain)
+		}
+		return host == pattern
+	}
+
+	if slices.ContainsFunc(p.DeniedDomains, match) {
+		return false
+	}
+	return len(p.AllowedDomains) == 0 || slices.ContainsFunc(p.AllowedDomains, match)
+}
+
+// asciiHost returns the host in lower case punycode, without the trailing dot.
+func asciiHost(host string) (string, error) {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if net.ParseIP(host) != nil {
+		return host, nil
+	}
+
+	if isASCII(host) && !strings.Contains(host, "xn--") {
+		return host, nil
+	}
+	return idna.Lookup.ToASCII(host)
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// isOutbound reports whether the href has a host, so it leads out of the site.
+func isOutbound(href string) bool {
+	u, err := url.Parse(strings.TrimSpace(href))
+	return err == nil && u.Host != ""
+}
+
+// latinLookalikes are the Cyrillic and Greek letters looking like the Latin ones.
+const latinLookalikes = "аеорсухіјѕһԁԛԝӏүԍьαικνορτυχ"
+
+// scriptMixes are the sets of the scripts which can be mixed in a label, the ones of the Chinese,
+// Japanese and Korean writing together with Latin.
+var scriptMixes = [][]string{
+	{"Latin", "Han", "Hiragana", "Katakana"},
+	{"Latin", "Han", "Bopomofo"},
+	{"Latin", "Han", "Hangul"},
+}
+
+// isHomograph reports whether the host in punycode can be mistaken for another one.
+func isHomograph(host string) bool {
+	if !strings.Contains(host, "xn--") {
+		return false
+	}
+
+	uni, err := idna.Lookup.ToUnicode(host)
+	if err != nil {
+		return true
+	}
+
+	for label := range strings.SplitSeq(uni, ".") {
+		if isConfusable(label) {
+			return true
+		}
+	}
+	return false
+}
+
+// isConfusable reports whether the label mixes the scripts in a way no language does, or is written
+// in the Cyrillic or Greek letters which all look like the Latin ones.
+func isConfusable(label string) bool {
+	var scripts []string
+	lookalikes := true
+	for _, r := range label {
+		s := script(r)
+		if s == "" {
+			continue
+		}
+
+		if !slices.Contains(scripts, s) {
+			scripts = append(scripts, s)
+		}
+		lookalikes = lookalikes && strings.ContainsRune(latinLookalikes, r)
+	}
+
+	switch len(scripts) {
+	case 0:
+		return false
+	case 1:
+		return (scripts[0] == "Cyrillic" || scripts[0] == "Greek") && lookalikes
+	}
+
+	for _, mix := range scriptMixes {
+		if !slices.ContainsFunc(scripts, func(s string) bool { return !slices.Contains(mix, s) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// confusableScripts are the scripts told apart by isConfusable, in the order they are checked.
+var confusableScripts = []struct {
+	name  string
+	table *unicode.RangeTable
+}{
+	{"Latin", unicode.Latin},
+	{"Cyrillic", unicode.Cyrillic},
+	{"Greek", unicode.Greek},
+	{"Han", unicode.Han},
+	{"Hiragana", unicode.Hiragana},
+	{"Katakana", unicode.Katakana},
+	{"Hangul", unicode.Hangul},
+	{"Bopomofo", unicode.Bopomofo},
+}
+
+// script returns the name of the script of the rune, "Other" for the letters of the scripts not in
+// confusableScripts, or "" for the runes shared by the scripts, like the digits and the "ー" of Japanese.
+func script(r rune) string {
+	if r < utf8.RuneSelf {
+		if unicode.IsLetter(r) {
+			return "Latin"
+		}
+		return ""
+	}
+
+	for _, s := range confusableScripts {
+		if unicode.Is(s.table, r) {
+			return s.name
+		}
+	}
+	if unicode.IsLetter(r) && !unicode.Is(unicode.Common, r) && !unicode.Is(unicode.Inherited, r) {
+		return "Other"
+	}
+	return ""
+}
+
+// quotedList returns the values quoted and joined, like `"a", "b" or "c"`.
+func quotedList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = strconv.Quote(v)
+	}
+
+	if len(quoted) < 2 {
+		return strings.Join(quoted, "")
+	}
+	return strings.Join(quoted[:len(quoted)-1], ", ") + " or " + quoted[len(quoted)-1]
+}
+
+// LinkRedirect rewrites the outbound links to go through the redirect endpoint of the site, like
+// /out?u=https%3A%2F%2Fexample.com&s=..., so the site can warn the readers before they leave it.
+//
+// The URL is signed with HMAC-SHA256, so the endpoint can't be used as an open redirect:
+// it must check the signature with [LinkRedirect.Verify] before redirecting.
+type LinkRedirect struct {
+	// Path is the absolute path of the endpoint, like "/out".
+	Path string
+	// Key is the secret key of the signatures.
+	Key []byte
+}
+
+// Rewrite returns the href of the endpoint redirecting to the URL.
+func (r LinkRedirect) Rewrite(u string) string {
+	q := url.Values{"u": {u}, "s": {r.sign(u)}}
+	return r.Path + "?" + q.Encode()
+}
+
+// Verify returns the URL of the query made by [LinkRedirect.Rewrite], and reports whether its signature is valid.
+func (r LinkRedirect) Verify(query url.Values) (string, bool) {
+	u := query.Get("u")
+	sig, err := base64.RawURLEncoding.DecodeString(query.Get("s"))
+	if err != nil || u == "" || len(r.Key) == 0 {
+		return "", false
+	}
+
+	if !hmac.Equal(sig, r.mac(u)) {
+		return "", false
+	}
+	return u, true
+}
+
+func (r LinkRedirect) sign(u string) string {
+	return base64.RawURLEncoding.EncodeToString(r.mac(u))
+}
+
+func (r LinkRedirect) mac(u string) []byte {
+	m := hmac.New(sha256.New, r.Key)
+	m.Write([]byte(u))
+	return m.Sum(nil)
+}
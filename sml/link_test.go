@@ -1,6 +1,7 @@
 package sml
 
 import (
+	"net/url"
 	"strings"
 	"testing"
 
@@ -27,9 +28,9 @@ func renderNormalizedLink(attrs []scum.SerializableAttribute, children ...scum.S
 
 	normalizeLink(&node, &issues)
 
-	var b strings.Builder
-	renderLink(&b, node)
-	return b.String(), issues
+	r := Renderer{tags: builtinTags}
+	renderLink(&r, &node)
+	return r.String(), issues
 }
 
 func TestAttrHref_AllowsHTTPS(t *testing.T) {
@@ -246,3 +247,160 @@ func requireIssueDescription(t *testing.T, issues Issues, desc string) {
 
 	require.Failf(t, "missing issue description", "expected issue description %q in %#v", desc, issues.List)
 }
+
+func policyEater(t *testing.T, p LinkPolicy) Eater {
+	t.Helper()
+
+	eater, err := NewEater(scum.WarnOverflowNoCap, 0, WithLinkPolicy(p))
+	require.NoError(t, err)
+	return eater
+}
+
+func TestLinkPolicy_DefaultRejectsHomographs(t *testing.T) {
+	eater, err := NewEater(scum.WarnOverflowNoCap, 0)
+	require.NoError(t, err)
+
+	tests := []struct {
+		href     string
+		wantDesc string
+	}{
+		{href: "https://münchen.de"},
+		{href: "https://пример.рф"},
+		{href: "https://例え.テスト.jp"},
+		{href: "https://コーヒーshop.jp"},
+		{href: "https://مثال.com"},
+		{href: "https://xn--mnchen-3ya.de/path"},
+		{href: "https://pаypal.com", wantDesc: `attribute href host "pаypal.com" can be mistaken for another one`},
+		{href: "https://xn--pypal-4ve.com", wantDesc: `attribute href host "xn--pypal-4ve.com" can be mistaken for another one`},
+		{href: "https://аррӏе.com", wantDesc: `attribute href host "аррӏе.com" can be mistaken for another one`},
+		{href: "https://xn--80ak6aa92e.com", wantDesc: `attribute href host "xn--80ak6aa92e.com" can be mistaken for another one`},
+		{href: "https://xn--zz.com", wantDesc: `attribute href host "xn--zz.com" is invalid`},
+		{href: "https://gօօgle.com", wantDesc: `attribute href host "gօօgle.com" can be mistaken for another one`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.href, func(t *testing.T) {
+			poop, issues := eater.Munch("[x]!href{" + tt.href + "}")
+
+			if tt.wantDesc == "" {
+				require.Empty(t, issues)
+				require.Contains(t, poop.HTML(), "href=")
+				return
+			}
+
+			require.Equal(t, `<a>x</a>`, poop.HTML())
+			requireIssueDescription(t, Issues{List: issues}, tt.wantDesc)
+		})
+	}
+}
+
+func TestLinkPolicy_Domains(t *testing.T) {
+	eater := policyEater(t, LinkPolicy{
+		AllowedDomains: []string{"Example.com", "*.example.org", "*.пример.рф"},
+		DeniedDomains:  []string{"bad.example.org"},
+	})
+
+	tests := []struct {
+		href    string
+		allowed bool
+	}{
+		{"https://example.com/a", true},
+		{"https://EXAMPLE.com./a", true},
+		{"https://www.example.com", false},
+		{"https://www.example.org", true},
+		{"https://a.b.example.org", true},
+		{"https://example.org", false},
+		{"https://bad.example.org", false},
+		{"https://evilexample.org", false},
+		{"https://сайт.пример.рф", true},
+		{"https://сайт.xn--e1afmkfd.xn--p1ai", true},
+		{"/relative", true},
+		{"mailto:a@elsewhere.com", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.href, func(t *testing.T) {
+			_, issues := eater.Munch("[x]!href{" + tt.href + "}")
+
+			if tt.allowed {
+				require.Empty(t, issues)
+				return
+			}
+			require.Len(t, issues, 1)
+			require.Contains(t, issues[0].Description(), "is not allowed")
+		})
+	}
+}
+
+func TestLinkPolicy_SchemesTargetsAndTitle(t *testing.T) {
+	eater := policyEater(t, LinkPolicy{
+		Schemes:        []string{"HTTPS:"},
+		Targets:        []string{"_self", "_parent", "_top"},
+		MaxTitleLength: 3,
+	})
+
+	poop, issues := eater.Munch("[a]!href{https://example.com}!target{_top}!title{abc} [b]!href{http://example.com}!target{_blank}!title{abcd}")
+
+	require.Equal(t, `<a href="https://example.com" target="_top" title="abc">a</a> <a>b</a>`, poop.HTML())
+	requireIssueDescription(t, Issues{List: issues}, `attribute href scheme "http" is not allowed`)
+	requireIssueDescription(t, Issues{List: issues}, `attribute target must be one of "_self", "_parent" or "_top"`)
+	requireIssueDescription(t, Issues{List: issues}, "attribute title must be at most 3 characters long")
+}
+
+func TestLinkPolicy_UGC(t *testing.T) {
+	eater := policyEater(t, LinkPolicy{UGC: true})
+
+	poop, issues := eater.Munch("[a]!href{https://example.com} [b]!href{https://example.com}!target{_blank} [c]!href{/posts/1}")
+
+	require.Empty(t, issues)
+	require.Equal(t, `<a href="https://example.com" rel="ugc nofollow">a</a> `+
+		`<a href="https://example.com" target="_blank" rel="noopener noreferrer ugc nofollow">b</a> `+
+		`<a href="/posts/1">c</a>`, poop.HTML())
+}
+
+func TestLinkPolicy_Redirect(t *testing.T) {
+	redirect := LinkRedirect{Path: "/out", Key: []byte("secret")}
+	eater := policyEater(t, LinkPolicy{Redirect: &redirect})
+
+	poop, issues := eater.Munch("[a]!href{https://example.com/?q=1} [b]!href{/posts/1}")
+	require.Empty(t, issues)
+
+	html := poop.HTML()
+	require.Contains(t, html, `<a href="/posts/1">b</a>`)
+	require.Equal(t, "[a](https://example.com/?q=1) [b](/posts/1)", poop.Markdown())
+
+	href := html[strings.Index(html, `"`)+1 : strings.Index(html, `">a`)]
+	u, err := url.Parse(strings.ReplaceAll(href, "&amp;", "&"))
+	require.NoError(t, err)
+	require.Equal(t, "/out", u.Path)
+
+	target, ok := redirect.Verify(u.Query())
+	require.True(t, ok)
+	require.Equal(t, "https://example.com/?q=1", target)
+
+	tampered := u.Query()
+	tampered.Set("u", "https://evil.example")
+	_, ok = redirect.Verify(tampered)
+	require.False(t, ok)
+
+	_, ok = LinkRedirect{Path: "/out", Key: []byte("other")}.Verify(u.Query())
+	require.False(t, ok)
+}
+
+func TestLinkPolicy_InvalidConfig(t *testing.T) {
+	policies := []LinkPolicy{
+		{AllowedDomains: []string{"*.*.example.com"}},
+		{DeniedDomains: []string{""}},
+		{MaxTitleLength: -1},
+		{Redirect: &LinkRedirect{Path: "https://evil.example/out", Key: []byte("k")}},
+		{Redirect: &LinkRedirect{Path: "/out"}},
+	}
+
+	for _, p := range policies {
+		_, err := NewEater(scum.WarnOverflowNoCap, 0, WithLinkPolicy(p))
+
+		var cfgErr *ConfigError
+		require.ErrorAs(t, err, &cfgErr)
+		require.Equal(t, ReasonInvalidParams, cfgErr.Reason)
+	}
+}
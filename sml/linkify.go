@@ -0,0 +1,291 @@
+package sml
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Drolfothesgnir/shitposter/scum"
+)
+
+// autolinkPrefixes are the starts of the bare URLs, matched case-insensitively.
+var autolinkPrefixes = []string{"https://", "http://", "www."}
+
+// autolinkTrailing are the characters which end a sentence rather than a URL, so they are left out
+// of the bare URLs ending with them.
+const autolinkTrailing = `?!.,:;*_~'"`
+
+// WithLinkify makes the [Eater] turn the bare URLs and emails in the text into links, like
+// https://example.com, www.example.com or bob@example.com. The links are validated by the [Link] handler,
+// so they follow the [LinkPolicy], and the ones it rejects stay plain text.
+//
+// The text inside the links and the verbatim tags, like [Code], is not linkified.
+func WithLinkify() Option {
+	return func(e *Eater) {
+		e.linkify = true
+	}
+}
+
+// linkify replaces the bare URLs and emails in the text nodes of the tree with the [Link] tags
+// accepted by the handler of the links.
+func linkify(n *scum.SerializableNode, tags tagHandlers) {
+	// out is allocated only when the first text node is split
+	var out []scum.SerializableNode
+
+	for i := range n.Children {
+		c := &n.Children[i]
+
+		if c.Type != "Text" {
+			if c.Type == "Tag" && c.Name != Link && !tags[c.Name].Verbatim {
+				linkify(c, tags)
+			}
+			if out != nil {
+				out = append(out, *c)
+			}
+			continue
+		}
+
+		split := false
+		last := 0
+
+		for start, end, href := nextAutolink(c.Content, 0); start >= 0; start, end, href = nextAutolink(c.Content, end) {
+			span := scum.Span{Start: c.Span.Start + start, End: c.Span.Start + end}
+			link, ok := autolinkNode(c.Content[start:end], href, span, tags)
+			if !ok {
+				continue
+			}
+
+			if out == nil {
+				out = make([]scum.SerializableNode, 0, len(n.Children)+2)
+				out = append(out, n.Children[:i]...)
+			}
+
+			if start > last {
+				out = append(out, textNode(c.Content[last:start], scum.Span{Start: c.Span.Start + last, End: span.Start}))
+			}
+			out = append(out, link)
+
+			last = end
+			split = true
+		}
+
+		switch {
+		case !split:
+			if out != nil {
+				out = append(out, *c)
+			}
+		case last < len(c.Content):
+			out = append(out, textNode(c.Content[last:], scum.Span{Start: c.Span.Start + last, End: c.Span.End}))
+		}
+	}
+
+	if out != nil {
+		n.Children = out
+	}
+}
+
+// autolinkNode creates the [Link] tag of the bare URL, normalized by the handler of the links.
+// It reports false if the handler drops the href.
+func autolinkNode(content, href string, span scum.Span, tags tagHandlers) (scum.SerializableNode, bool) {
+	link := scum.SerializableNode{
+		Name:       Link,
+		Type:       "Tag",
+		Attributes: []scum.SerializableAttribute{{Name: "href", Payload: href}},
+		Content:    content,
+		Span:       span,
+		Children:   []scum.SerializableNode{textNode(content, span)},
+	}
+
+	h := tags[Link]
+	if h.Normalize == nil {
+		return link, true
+	}
+
+	// the bare URLs are not markup, so the rejected ones are left as text without issues
+	var discard Issues
+	h.Normalize(&link, &discard)
+
+	for _, a := range link.Attributes {
+		if attrName(a) == "href" {
+			return link, true
+		}
+	}
+	return scum.SerializableNode{}, false
+}
+
+// nextAutolink returns the offsets of the first bare URL or email in s at or after i and its href,
+// or -1 if there is none. The escapes are kept in the href, the way they are in the attribute payloads.
+func nextAutolink(s string, i int) (start, end int, href string) {
+	for j := i; j < len(s); j++ {
+		switch s[j] {
+		case 'h', 'H', 'w', 'W':
+			if !autolinkBoundary(s, j) {
+				continue
+			}
+
+			for _, prefix := range autolinkPrefixes {
+				if len(s)-j < len(prefix) || !strings.EqualFold(s[j:j+len(prefix)], prefix) {
+					continue
+				}
+
+				end := trimAutolink(s, j, scanAutolink(s, j+len(prefix)))
+				hostStart := j + len(prefix)
+				if prefix == "www." {
+					hostStart = j
+				}
+				if !isAutolinkHost(s[hostStart:end]) {
+					break
+				}
+
+				href := s[j:end]
+				if prefix == "www." {
+					href = "https://" + href
+				}
+				return j, end, href
+			}
+
+		case '@':
+			if start, end, ok := scanEmail(s, i, j); ok {
+				return start, end, "mailto:" + s[start:end]
+			}
+		}
+	}
+
+	return -1, -1, ""
+}
+
+// autolinkBoundary reports whether a bare URL can start at the offset: it must not be a part of a word,
+// or an escaped character.
+func autolinkBoundary(s string, i int) bool {
+	if i == 0 {
+		return true
+	}
+
+	r, _ := utf8.DecodeLastRuneInString(s[:i])
+	return !isEscaped(s, i) && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// scanAutolink returns the end of the URL starting at the offset: the first whitespace, control
+// character, angle bracket or a backslash which doesn't escape a punctuation character.
+func scanAutolink(s string, i int) int {
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == '\\':
+			if i+1 == len(s) || !unicode.IsPunct(rune(s[i+1])) && !unicode.IsSymbol(rune(s[i+1])) {
+				return i
+			}
+			i += 2
+
+		case c < utf8.RuneSelf:
+			if c <= ' ' || c == 0x7f || c == '<' || c == '>' {
+				return i
+			}
+			i++
+
+		default:
+			r, w := utf8.DecodeRuneInString(s[i:])
+			if unicode.IsSpace(r) || unicode.IsControl(r) {
+				return i
+			}
+			i += w
+		}
+	}
+	return i
+}
+
+// trimAutolink returns the end of the URL without the trailing punctuation, and without the closing
+// parentheses which have no opening ones in the URL, like in "(see https://example.com)".
+// The escaped characters are written on purpose, so they are kept.
+func trimAutolink(s string, start, end int) int {
+	for end > start {
+		c := s[end-1]
+		switch {
+		case end-2 >= start && s[end-2] == '\\':
+			return end
+		case strings.IndexByte(autolinkTrailing, c) >= 0:
+			end--
+		case c == ')' && strings.Count(s[start:end], "(") < strings.Count(s[start:end], ")"):
+			end--
+		default:
+			return end
+		}
+	}
+	return end
+}
+
+// isAutolinkHost reports whether the URL after the scheme starts with a domain of at least two labels
+// of the letters, digits and hyphens. The user info is not allowed, so "https://bank.com@evil.com" is not a link.
+func isAutolinkHost(rest string) bool {
+	host := rest
+	if i := strings.IndexAny(rest, "/?#"); i >= 0 {
+		host = rest[:i]
+	}
+	if i := strings.LastIndexByte(host, ':'); i >= 0 {
+		host = host[:i]
+	}
+
+	if !strings.Contains(host, ".") {
+		return false
+	}
+
+	for label := range strings.SplitSeq(host, ".") {
+		if label == "" || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// scanEmail returns the offsets of the email with the '@' at the offset at, starting at or after i.
+func scanEmail(s string, i, at int) (start, end int, ok bool) {
+	start = at
+	for start > i && isEmailLocal(s[start-1]) {
+		start--
+	}
+	// the user info of a URL, like in https://bank.com@evil.com, is not an email
+	if start == at || !autolinkBoundary(s, start) || start > 0 && strings.IndexByte("/:", s[start-1]) >= 0 {
+		return 0, 0, false
+	}
+
+	end = at + 1
+	for end < len(s) && (isASCIIAlnum(s[end]) || s[end] == '-' || s[end] == '.') {
+		end++
+	}
+	for end > at+1 && (s[end-1] == '.' || s[end-1] == '-') {
+		end--
+	}
+
+	domain := s[at+1 : end]
+	dot := strings.LastIndexByte(domain, '.')
+	if dot < 0 || !isAutolinkHost(domain) || !isEmailTLD(domain[dot+1:]) {
+		return 0, 0, false
+	}
+	return start, end, true
+}
+
+func isEmailLocal(c byte) bool {
+	return isASCIIAlnum(c) || strings.IndexByte(".+-_%", c) >= 0
+}
+
+func isASCIIAlnum(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// isEmailTLD reports whether the last label of the email domain is at least two letters.
+func isEmailTLD(label string) bool {
+	if len(label) < 2 {
+		return false
+	}
+	for i := 0; i < len(label); i++ {
+		if !('a' <= label[i] && label[i] <= 'z' || 'A' <= label[i] && label[i] <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,123 @@
+package sml
+
+import (
+	"testing"
+
+	"github.com/Drolfothesgnir/shitposter/scum"
+	"github.com/stretchr/testify/require"
+)
+
+func linkifyEater(t *testing.T, opts ...Option) Eater {
+	t.Helper()
+
+	eater, err := NewEater(scum.WarnOverflowNoCap, 0, append([]Option{WithLinkify()}, opts...)...)
+	require.NoError(t, err)
+	return eater
+}
+
+func TestLinkify(t *testing.T) {
+	eater := linkifyEater(t)
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "trailing punctuation",
+			input: `see https://example.com/a?b=1&c=2. or "http://example.com/x"?`,
+			want: `see <a href="https://example.com/a?b=1&amp;c=2">https://example.com/a?b=1&amp;c=2</a>. ` +
+				`or &#34;<a href="http://example.com/x">http://example.com/x</a>&#34;?`,
+		},
+		{
+			name:  "balanced parentheses",
+			input: `(see https://en.wikipedia.org/wiki/Go_(language))`,
+			want:  `(see <a href="https://en.wikipedia.org/wiki/Go_(language)">https://en.wikipedia.org/wiki/Go_(language)</a>)`,
+		},
+		{
+			name:  "www",
+			input: `WWW.Example.com/path, then`,
+			want:  `<a href="https://WWW.Example.com/path">WWW.Example.com/path</a>, then`,
+		},
+		{
+			name:  "email",
+			input: `mail bob.smith+tag@example.co.uk;`,
+			want:  `mail <a href="mailto:bob.smith+tag@example.co.uk">bob.smith+tag@example.co.uk</a>;`,
+		},
+		{
+			name:  "escapes",
+			input: `https://example.com/a\*b\*.`,
+			want:  `<a href="https://example.com/a*b*">https://example.com/a*b*</a>.`,
+		},
+		{
+			name:  "inside tags",
+			input: `$bold https://example.com$`,
+			want:  `<strong>bold <a href="https://example.com">https://example.com</a></strong>`,
+		},
+		{
+			name:  "not inside links and code",
+			input: "[https://a.example]!href{https://b.example} `https://c.example`",
+			want:  `<a href="https://b.example">https://a.example</a> <code>https://c.example</code>`,
+		},
+		{
+			name:  "not a url",
+			input: `xhttps://example.com http://localhost https://bank.com@evil.com @alice a@b.c`,
+			want:  `xhttps://example.com http://localhost https://bank.com@evil.com @alice a@b.c`,
+		},
+		{
+			name:  "rejected by the policy",
+			input: `https://pаypal.com`,
+			want:  `https://pаypal.com`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			poop, issues := eater.Munch(tt.input)
+
+			require.Empty(t, issues)
+			require.Equal(t, tt.want, poop.HTML())
+		})
+	}
+}
+
+func TestLinkify_Disabled(t *testing.T) {
+	eater, err := NewEater(scum.WarnOverflowNoCap, 0)
+	require.NoError(t, err)
+
+	poop, _ := eater.Munch("https://example.com")
+	require.Equal(t, "https://example.com", poop.HTML())
+}
+
+func TestLinkify_FollowsLinkPolicy(t *testing.T) {
+	eater := linkifyEater(t, WithLinkPolicy(LinkPolicy{UGC: true, DeniedDomains: []string{"*.evil.example"}}))
+
+	poop, issues := eater.Munch("https://example.com https://www.evil.example")
+
+	require.Empty(t, issues)
+	require.Equal(t, `<a href="https://example.com" rel="ugc nofollow">https://example.com</a> https://www.evil.example`, poop.HTML())
+}
+
+func TestLinkify_KeepsMentions(t *testing.T) {
+	r := &fakeResolver{users: map[string]int64{"alice": 1}}
+	eater := linkifyEater(t, WithMentionResolver(r))
+
+	poop, issues := eater.Munch("https://example.com/@alice hi @alice")
+
+	require.Empty(t, issues)
+	require.Equal(t, `<a href="https://example.com/@alice">https://example.com/@alice</a> `+
+		`hi <a href="/users/1" class="sml-internal-mention">@alice</a>`, poop.HTML())
+	require.Equal(t, [][]string{{"alice"}}, r.calls)
+}
+
+func TestLinkify_Spans(t *testing.T) {
+	eater := linkifyEater(t)
+
+	poop, _ := eater.Munch("$a$ go to www.example.com now")
+
+	link := poop.Tree.Children[2]
+	require.Equal(t, Link, link.Name)
+	require.Equal(t, scum.Span{Start: 10, End: 25}, link.Span)
+	require.Equal(t, "www.example.com", poop.Input[link.Span.Start:link.Span.End])
+	require.Equal(t, scum.Span{Start: 25, End: 29}, poop.Tree.Children[3].Span)
+}
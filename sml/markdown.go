@@ -0,0 +1,187 @@
+package sml
+
+import (
+	"strings"
+
+	"github.com/Drolfothesgnir/shitposter/scum"
+)
+
+const (
+	// markdownSpecial are escaped in the text anywhere, they could start an inline construct.
+	markdownSpecial = "\\`*_[]<>&~|"
+	// markdownLineStart are escaped at the start of a line, they could start a block.
+	markdownLineStart = "#+-="
+	// markdownSpace are moved outside of the emphasis delimiters.
+	markdownSpace = " \t\r\n"
+)
+
+// Markdown returns the parsed input as CommonMark, for the places which don't speak SML, like the Markdown-based bots.
+// The Markdown-special characters of the text are escaped, so the text is not formatted by accident.
+//
+// The tags without the CommonMark equivalent are written as the widely supported extensions: [Underline] and [Superscript]
+// as inline HTML, [Strike] as ~~text~~ and [Spoiler] as ||text||.
+func (p Poop) Markdown() string {
+	r := Renderer{tags: p.handlers(), mode: renderMarkdown}
+	r.Children(&p.Tree)
+	return r.String()
+}
+
+// markdownTag returns the Markdown renderer wrapping the children in the open and close delimiters.
+// The spaces around the children are moved outside of the delimiters, otherwise CommonMark doesn't
+// treat them as emphasis.
+func markdownTag(open, close string) func(r *Renderer, n *scum.SerializableNode) {
+	return func(r *Renderer, n *scum.SerializableNode) {
+		inner := r.sub(renderMarkdown)
+		inner.Children(n)
+		s := inner.String()
+
+		content := strings.Trim(s, markdownSpace)
+		if content == "" {
+			r.WriteString(s)
+			return
+		}
+
+		lead := len(s) - len(strings.TrimLeft(s, markdownSpace))
+		trail := len(s) - len(strings.TrimRight(s, markdownSpace))
+
+		r.WriteString(s[:lead])
+		r.WriteString(open)
+		r.WriteString(content)
+		r.WriteString(close)
+		r.WriteString(s[len(s)-trail:])
+	}
+}
+
+// markdownCode writes the code span, delimited by a backtick run longer than any run inside the code.
+func markdownCode(r *Renderer, n *scum.SerializableNode) {
+	inner := r.sub(renderText)
+	inner.Children(n)
+	code := inner.String()
+
+	longest, run := 0, 0
+	for i := 0; i < len(code); i++ {
+		if code[i] != '`' {
+			run = 0
+			continue
+		}
+		run++
+		longest = max(longest, run)
+	}
+	fence := strings.Repeat("`", longest+1)
+
+	// CommonMark strips a single space on both sides, so the code starting or ending with a backtick
+	// or with a space is padded
+	pad := strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") ||
+		(strings.HasPrefix(code, " ") && strings.HasSuffix(code, " ") && strings.Trim(code, " ") != "")
+
+	r.WriteString(fence)
+	if pad {
+		r.WriteByte(' ')
+	}
+	r.WriteString(code)
+	if pad {
+		r.WriteByte(' ')
+	}
+	r.WriteString(fence)
+}
+
+// markdownLink writes the link with its title. The link without href is written as its text.
+func markdownLink(r *Renderer, n *scum.SerializableNode) {
+	href, title := linkAttributes(n)
+	if href == "" {
+		r.Children(n)
+		return
+	}
+
+	r.WriteByte('[')
+	r.Children(n)
+	r.WriteString("](")
+	writeMarkdownDestination(r, href)
+	if title != "" {
+		r.WriteString(` "`)
+		for i := 0; i < len(title); i++ {
+			if title[i] == '"' || title[i] == '\\' {
+				r.WriteByte('\\')
+			}
+			r.WriteByte(title[i])
+		}
+		r.WriteByte('"')
+	}
+	r.WriteByte(')')
+}
+
+// writeMarkdownDestination writes the URL percent-encoding the characters which would end the link destination.
+func writeMarkdownDestination(r *Renderer, url string) {
+	const hex = "0123456789ABCDEF"
+	for i := 0; i < len(url); i++ {
+		c := url[i]
+		switch c {
+		case ' ', '(', ')', '<', '>', '\\':
+			r.WriteByte('%')
+			r.WriteByte(hex[c>>4])
+			r.WriteByte(hex[c&0xF])
+		default:
+			r.WriteByte(c)
+		}
+	}
+}
+
+// linkAttributes returns the href and the title of the normalized link, or of the mention.
+func linkAttributes(n *scum.SerializableNode) (href, title string) {
+	for _, a := range n.Attributes {
+		switch attrName(a) {
+		case "href":
+			href = strings.TrimSpace(a.Payload)
+		case "title":
+			title = strings.TrimSpace(a.Payload)
+		}
+	}
+	return href, title
+}
+
+// markdownText writes the text escaping the characters which could start a Markdown construct.
+func (r *Renderer) markdownText(s string) {
+	lineStart := r.atLineStart()
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		switch {
+		case c == '\n':
+			lineStart = true
+			r.WriteByte(c)
+			continue
+
+		case lineStart && c == ' ':
+			// the indentation keeps the line start
+			r.WriteByte(c)
+			continue
+
+		case strings.IndexByte(markdownSpecial, c) >= 0:
+			r.WriteByte('\\')
+
+		case lineStart && strings.IndexByte(markdownLineStart, c) >= 0:
+			r.WriteByte('\\')
+
+		case lineStart && '0' <= c && c <= '9':
+			// the number followed by '.' or ')' starts an ordered list
+			j := i
+			for j < len(s) && '0' <= s[j] && s[j] <= '9' {
+				j++
+			}
+			r.WriteString(s[i:j])
+			if j < len(s) && (s[j] == '.' || s[j] == ')') {
+				r.WriteByte('\\')
+				r.WriteByte(s[j])
+				j++
+			}
+
+			i = j - 1
+			lineStart = false
+			continue
+		}
+
+		r.WriteByte(c)
+		lineStart = false
+	}
+}
@@ -0,0 +1,273 @@
+package sml
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Drolfothesgnir/shitposter/scum"
+)
+
+// MaxMentions is the maximum number of distinct users which can be mentioned in a single input.
+// The mentions of the other users are left as plain text.
+const MaxMentions = 20
+
+// The bounds of the username length in runes, the same as the API enforces on the signup.
+const (
+	minUsernameLen = 3
+	maxUsernameLen = 50
+)
+
+// mentionHref is the path of the user profile the mention links to, followed by the user ID.
+const mentionHref = "/users/"
+
+// MentionResolver verifies the mentioned usernames, see [WithMentionResolver].
+// It is satisfied by the db.Store.
+type MentionResolver interface {
+	// ResolveUsernames returns the IDs of the existing users among the usernames, keyed by the username.
+	// The usernames missing from the map are treated as unknown.
+	ResolveUsernames(ctx context.Context, usernames []string) (map[string]int64, error)
+}
+
+// MentionedUser is a user mentioned in the input and found by the [MentionResolver].
+type MentionedUser struct {
+	UserID   int64  `json:"user_id"`
+	Username string `json:"username"`
+}
+
+// WithMentionResolver makes the [Eater] recognize the @username mentions in the text outside of the links
+// and the inline code. The usernames are verified with r in a single call per input, and the mentions of
+// the existing users become [Mention] tags rendered as the links to their profiles, while the others are
+// left as plain text with an issue. The mentioned users are listed in [Poop.Mentions].
+//
+// Without the resolver the mentions are not recognized at all.
+func WithMentionResolver(r MentionResolver) Option {
+	return func(e *Eater) {
+		e.mentions = r
+	}
+}
+
+// resolveMentions verifies the usernames mentioned in the tree with r, replaces the mentions of the existing
+// users with the [Mention] tags and appends the users to mentions.
+func resolveMentions(
+	ctx context.Context,
+	r MentionResolver,
+	input string,
+	tree *scum.SerializableNode,
+	mentions *[]MentionedUser,
+	issues *Issues,
+) {
+	var names []string
+	var first scum.Span
+	looked := make(map[string]bool)
+	overflow := false
+
+	collectMentions(input, tree, func(name string, span scum.Span) {
+		if looked[name] {
+			return
+		}
+
+		if len(names) == MaxMentions {
+			if !overflow {
+				issues.Add(NewSyntaxIssueDescriptor(
+					IssueTooManyMentions,
+					span,
+					fmt.Sprintf("at most %d users can be mentioned", MaxMentions),
+				))
+				overflow = true
+			}
+			return
+		}
+
+		if len(names) == 0 {
+			first = span
+		}
+		looked[name] = true
+		names = append(names, name)
+	})
+
+	if len(names) == 0 {
+		return
+	}
+
+	ids, err := r.ResolveUsernames(ctx, names)
+	if err != nil {
+		// the mentions are left as plain text, the details of the failure are not for the user
+		issues.Add(NewSyntaxIssueDescriptor(IssueInternal, first, "the mentioned users could not be verified"))
+		return
+	}
+
+	for _, name := range names {
+		if id, ok := ids[name]; ok {
+			*mentions = append(*mentions, MentionedUser{UserID: id, Username: name})
+		}
+	}
+
+	splitMentions(input, tree, ids, looked, issues)
+}
+
+// collectMentions calls fn with the username and the span of each mention in the text nodes of the tree.
+// The links and the inline code are skipped, see [hasMentions].
+func collectMentions(input string, n *scum.SerializableNode, fn func(name string, span scum.Span)) {
+	for i := range n.Children {
+		c := &n.Children[i]
+
+		switch {
+		case c.Type == "Text":
+			before := runeBefore(input, c.Span.Start)
+			for start, end := nextMention(c.Content, 0, before); start >= 0; start, end = nextMention(c.Content, end, before) {
+				fn(c.Content[start+1:end], scum.Span{Start: c.Span.Start + start, End: c.Span.Start + end})
+			}
+
+		case hasMentions(c):
+			collectMentions(input, c, fn)
+		}
+	}
+}
+
+// splitMentions replaces the text nodes containing the mentions of the users in ids with the text
+// around them and the [Mention] tags. The mentions of the other looked up users get an issue.
+func splitMentions(input string, n *scum.SerializableNode, ids map[string]int64, looked map[string]bool, issues *Issues) {
+	// out is allocated only when the first text node is split
+	var out []scum.SerializableNode
+
+	for i := range n.Children {
+		c := &n.Children[i]
+
+		if c.Type != "Text" {
+			if hasMentions(c) {
+				splitMentions(input, c, ids, looked, issues)
+			}
+			if out != nil {
+				out = append(out, *c)
+			}
+			continue
+		}
+
+		before := runeBefore(input, c.Span.Start)
+		split := false
+		last := 0
+
+		for start, end := nextMention(c.Content, 0, before); start >= 0; start, end = nextMention(c.Content, end, before) {
+			name := c.Content[start+1 : end]
+			span := scum.Span{Start: c.Span.Start + start, End: c.Span.Start + end}
+
+			id, ok := ids[name]
+			if !ok {
+				if looked[name] {
+					issues.Add(NewSyntaxIssueDescriptor(
+						IssueUnknownMention,
+						span,
+						fmt.Sprintf("mentioned user %q does not exist", name),
+					))
+				}
+				continue
+			}
+
+			if out == nil {
+				out = make([]scum.SerializableNode, 0, len(n.Children)+2)
+				out = append(out, n.Children[:i]...)
+			}
+
+			if start > last {
+				out = append(out, textNode(c.Content[last:start], scum.Span{Start: c.Span.Start + last, End: span.Start}))
+			}
+			out = append(out, mentionNode(c.Content[start:end], span, id))
+
+			last = end
+			split = true
+		}
+
+		switch {
+		case !split:
+			if out != nil {
+				out = append(out, *c)
+			}
+		case last < len(c.Content):
+			out = append(out, textNode(c.Content[last:], scum.Span{Start: c.Span.Start + last, End: c.Span.End}))
+		}
+	}
+
+	if out != nil {
+		n.Children = out
+	}
+}
+
+// nextMention returns the offsets of the first mention in s at or after i, including the '@', or -1 if there is none.
+// before is the rune preceding s in the input. A mention must not follow a letter or a digit, so the emails are not mentions.
+func nextMention(s string, i int, before rune) (start, end int) {
+	for {
+		j := strings.IndexByte(s[i:], '@')
+		if j < 0 {
+			return -1, -1
+		}
+
+		start = i + j
+		prev := before
+		if start > 0 {
+			prev, _ = utf8.DecodeLastRuneInString(s[:start])
+		}
+
+		end = start + 1
+		n := 0
+		for end < len(s) {
+			r, w := utf8.DecodeRuneInString(s[end:])
+			if !isUsernameRune(r) {
+				break
+			}
+			end += w
+			n++
+		}
+
+		if !isUsernameRune(prev) && n >= minUsernameLen && n <= maxUsernameLen {
+			return start, end
+		}
+
+		i = end
+	}
+}
+
+// hasMentions reports whether the text inside the tag can contain the mentions. The links can't, so they are never
+// nested, and the inline code is kept as written.
+func hasMentions(n *scum.SerializableNode) bool {
+	return n.Name != Link && n.Name != Code
+}
+
+// runeBefore returns the rune preceding the position in the input, or [utf8.RuneError] at its start.
+func runeBefore(input string, pos int) rune {
+	r, _ := utf8.DecodeLastRuneInString(input[:pos])
+	return r
+}
+
+func isUsernameRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func textNode(content string, span scum.Span) scum.SerializableNode {
+	return scum.SerializableNode{
+		Name:       "TEXT",
+		Type:       "Text",
+		Attributes: []scum.SerializableAttribute{},
+		Content:    content,
+		Span:       span,
+		Children:   []scum.SerializableNode{},
+	}
+}
+
+// mentionNode creates the [Mention] tag linking to the profile of the user.
+func mentionNode(content string, span scum.Span, userID int64) scum.SerializableNode {
+	return scum.SerializableNode{
+		Name: Mention,
+		Type: "Tag",
+		Attributes: []scum.SerializableAttribute{
+			{Name: "href", Payload: mentionHref + strconv.FormatInt(userID, 10)},
+			{Name: "class", Payload: "sml-internal-mention"},
+		},
+		Content:  content,
+		Span:     span,
+		Children: []scum.SerializableNode{textNode(content, span)},
+	}
+}
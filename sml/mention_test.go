@@ -0,0 +1,184 @@
+package sml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Drolfothesgnir/shitposter/scum"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeResolver resolves the usernames from the map and records the calls.
+type fakeResolver struct {
+	users map[string]int64
+	err   error
+	calls [][]string
+}
+
+func (r *fakeResolver) ResolveUsernames(_ context.Context, usernames []string) (map[string]int64, error) {
+	r.calls = append(r.calls, usernames)
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	ids := make(map[string]int64)
+	for _, name := range usernames {
+		if id, ok := r.users[name]; ok {
+			ids[name] = id
+		}
+	}
+	return ids, nil
+}
+
+func mentionEater(t *testing.T, r MentionResolver) Eater {
+	t.Helper()
+
+	eater, err := NewEater(scum.WarnOverflowNoCap, 0, WithMentionResolver(r))
+	require.NoError(t, err)
+	return eater
+}
+
+func TestMentions_RenderAsProfileLinks(t *testing.T) {
+	r := &fakeResolver{users: map[string]int64{"alice": 1, "bob": 2}}
+	eater := mentionEater(t, r)
+
+	poop, issues := eater.Munch("hi @alice and $@bob$, @alice again")
+
+	require.Empty(t, issues)
+	require.Equal(t,
+		`hi <a href="/users/1" class="sml-internal-mention">@alice</a> and `+
+			`<strong><a href="/users/2" class="sml-internal-mention">@bob</a></strong>, `+
+			`<a href="/users/1" class="sml-internal-mention">@alice</a> again`,
+		poop.HTML(),
+	)
+	require.Equal(t, []MentionedUser{{UserID: 1, Username: "alice"}, {UserID: 2, Username: "bob"}}, poop.Mentions)
+	require.Equal(t, "hi @alice and @bob, @alice again", poop.Text())
+
+	// a single lookup per input, with every username once
+	require.Equal(t, [][]string{{"alice", "bob"}}, r.calls)
+}
+
+func TestMentions_UnknownUserStaysText(t *testing.T) {
+	eater := mentionEater(t, &fakeResolver{users: map[string]int64{"alice": 1}})
+
+	input := "@ghost meets @alice"
+	poop, issues := eater.Munch(input)
+
+	require.Equal(t, `@ghost meets <a href="/users/1" class="sml-internal-mention">@alice</a>`, poop.HTML())
+	require.Len(t, issues, 1)
+	require.Equal(t, "UNKNOWN_MENTION", issues[0].Codename())
+	require.Equal(t, "@ghost", input[issues[0].Span().Start:issues[0].Span().End])
+	require.Equal(t, []MentionedUser{{UserID: 1, Username: "alice"}}, poop.Mentions)
+}
+
+func TestMentions_NotRecognized(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+	}{
+		{"Email", "mail alice@example.com"},
+		{"TooShort", "@al"},
+		{"TooLong", "@" + strings.Repeat("a", maxUsernameLen+1)},
+		{"AfterLetter", "x@alice"},
+		{"InsideLink", "[see @alice]!href{https://example.com}"},
+		{"InsideCode", "`@alice`"},
+		{"LoneAt", "@ @ @"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &fakeResolver{users: map[string]int64{"alice": 1, "example": 2, strings.Repeat("a", maxUsernameLen+1): 3}}
+			eater := mentionEater(t, r)
+
+			poop, issues := eater.Munch(tc.input)
+
+			require.Empty(t, issues)
+			require.Empty(t, poop.Mentions)
+			require.Empty(t, r.calls)
+			require.NotContains(t, poop.HTML(), "sml-internal-mention")
+		})
+	}
+}
+
+func TestMentions_UnicodeUsername(t *testing.T) {
+	eater := mentionEater(t, &fakeResolver{users: map[string]int64{"józef": 7}})
+
+	poop, issues := eater.Munch("(@józef)")
+
+	require.Empty(t, issues)
+	require.Equal(t, `(<a href="/users/7" class="sml-internal-mention">@józef</a>)`, poop.HTML())
+}
+
+func TestMentions_MaxMentions(t *testing.T) {
+	users := make(map[string]int64)
+	var b strings.Builder
+	for i := range MaxMentions + 2 {
+		name := fmt.Sprintf("user%d", i)
+		users[name] = int64(i)
+		b.WriteString("@" + name + " ")
+	}
+
+	r := &fakeResolver{users: users}
+	eater := mentionEater(t, r)
+
+	poop, issues := eater.Munch(b.String())
+
+	require.Len(t, poop.Mentions, MaxMentions)
+	require.Len(t, r.calls[0], MaxMentions)
+	require.Len(t, issues, 1)
+	require.Equal(t, "TOO_MANY_MENTIONS", issues[0].Codename())
+	require.Contains(t, poop.HTML(), fmt.Sprintf(">@user%d</a>", MaxMentions-1))
+	require.Contains(t, poop.HTML(), fmt.Sprintf(" @user%d ", MaxMentions))
+}
+
+func TestMentions_ResolverError(t *testing.T) {
+	eater := mentionEater(t, &fakeResolver{err: errors.New("connection refused")})
+
+	input := "hi @alice"
+	poop, issues := eater.Munch(input)
+
+	require.Equal(t, "hi @alice", poop.HTML())
+	require.Empty(t, poop.Mentions)
+	require.Len(t, issues, 1)
+	require.Equal(t, "INTERNAL", issues[0].Codename())
+	require.NotContains(t, issues[0].Description(), "connection refused")
+	require.Equal(t, "@alice", input[issues[0].Span().Start:issues[0].Span().End])
+}
+
+func TestMentions_WithoutResolver(t *testing.T) {
+	eater, err := NewEater(scum.WarnOverflowNoCap, 0)
+	require.NoError(t, err)
+
+	poop, issues := eater.Munch("hi @alice")
+
+	require.Empty(t, issues)
+	require.Empty(t, poop.Mentions)
+	require.Equal(t, "hi @alice", poop.HTML())
+}
+
+func TestMentions_MunchIntoResetsMentions(t *testing.T) {
+	eater := mentionEater(t, &fakeResolver{users: map[string]int64{"alice": 1}})
+
+	var poop Poop
+	eater.MunchInto(&poop, "@alice")
+	require.Len(t, poop.Mentions, 1)
+
+	eater.MunchInto(&poop, "nobody")
+	require.Empty(t, poop.Mentions)
+}
+
+func TestMentions_SplitKeepsSpans(t *testing.T) {
+	eater := mentionEater(t, &fakeResolver{users: map[string]int64{"alice": 1}})
+
+	input := "a @alice b"
+	poop, _ := eater.Munch(input)
+
+	require.Len(t, poop.Tree.Children, 3)
+	for _, c := range poop.Tree.Children {
+		require.Equal(t, input[c.Span.Start:c.Span.End], c.Content)
+	}
+	require.Equal(t, Mention, poop.Tree.Children[1].Name)
+}
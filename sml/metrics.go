@@ -0,0 +1,29 @@
+package sml
+
+import (
+	"time"
+
+	"github.com/Drolfothesgnir/shitposter/metrics"
+)
+
+var (
+	munchDuration = metrics.Default.NewHistogram(
+		"sml_munch_duration_seconds",
+		"Time spent in Eater.Munch parsing, validating and normalizing the input.",
+		[]float64{.00001, .000025, .00005, .0001, .00025, .0005, .001, .0025, .005, .01, .025, .1},
+	)
+
+	munchIssues = metrics.Default.NewCounter(
+		"sml_munch_issues_total",
+		"Syntax issues reported by Eater.Munch, by issue codename.",
+		"codename",
+	)
+)
+
+// recordMunch records the duration and the issues of a single [Eater.Munch] call.
+func recordMunch(start time.Time, issues []SyntaxIssue) {
+	munchDuration.ObserveSince(start)
+	for _, iss := range issues {
+		munchIssues.Inc(iss.Codename())
+	}
+}
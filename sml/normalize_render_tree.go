@@ -7,53 +7,27 @@ import (
 )
 
 // normalizeRenderTree strips the tree from reduntant and incorrect attributes.
-func normalizeRenderTree(tree *scum.SerializableNode, issues *Issues) {
+func normalizeRenderTree(tree *scum.SerializableNode, tags tagHandlers, issues *Issues) {
 	for i := range tree.Children {
 		c := &tree.Children[i]
-		normalizeNode(c, issues)
-	}
-}
-
-func normalizeNode(n *scum.SerializableNode, issues *Issues) {
-	switch n.Type {
-	case "Text":
-		normalizeText(n, issues)
-	case "Tag":
-		normalizeTagNode(n, issues)
-	default:
-		panic(fmt.Sprintf("AAAAAAAAAAAAAA: data corrupted; unknown node type %q in the SML parsed tree.", n.Type))
-	}
-}
-
-func normalizeTagNode(n *scum.SerializableNode, issues *Issues) {
-	switch n.Name {
-	case Bold, Italic, Underline:
-		normalizeSimpleTag(n, issues)
-	case Link:
-		normalizeLink(n, issues)
-	default:
-		panic(fmt.Sprintf("AAAAAAAAAAAAAA: data corrupted; unknown tag %q in the SML parsed tree.", n.Name))
+		normalizeNode(c, tags, issues)
 	}
 }
 
 func normalizeSimpleTag(n *scum.SerializableNode, issues *Issues) {
-	// [Bold], [Italic] and [Underline] tags should have no attributes
+	// the formatting tags, like [Bold] or [Code], should have no attributes
 	if len(n.Attributes) > 0 {
 		// otherwise add an issue for each attribute
 		for _, a := range n.Attributes {
 			issues.Add(NewSyntaxIssueDescriptor(
 				IssueAttributeNotAllowed,
+				a.Span(),
 				fmt.Sprintf("unknown attribute %q for the tag %q", a.Name, n.Name),
 			))
 		}
 		// clear the attribute slice
 		n.Attributes = n.Attributes[:0]
 	}
-
-	for i := range n.Children {
-		c := &n.Children[i]
-		normalizeNode(c, issues)
-	}
 }
 
 func normalizeText(n *scum.SerializableNode, issues *Issues) {
@@ -63,6 +37,7 @@ func normalizeText(n *scum.SerializableNode, issues *Issues) {
 		for _, a := range n.Attributes {
 			issues.Add(NewSyntaxIssueDescriptor(
 				IssueAttributeNotAllowed,
+				a.Span(),
 				fmt.Sprintf("unknown attribute %q for the text node", a.Name),
 			))
 		}
@@ -71,3 +46,30 @@ func normalizeText(n *scum.SerializableNode, issues *Issues) {
 		n.Attributes = n.Attributes[:0]
 	}
 }
+
+// unescapeTree removes the escape symbols from the text of the tree, except for the children of the verbatim tags.
+// It returns the number of the removed bytes.
+func unescapeTree(n *scum.SerializableNode, tags tagHandlers) (removed int) {
+	for i := range n.Children {
+		c := &n.Children[i]
+		switch {
+		case c.Type == "Text":
+			text := scum.Unescape(c.Content, escapeSymbol)
+			removed += len(c.Content) - len(text)
+			c.Content = text
+		case c.Type == "Tag" && !tags[c.Name].Verbatim:
+			removed += unescapeTree(c, tags)
+		}
+	}
+	return removed
+}
+
+// isEscaped reports whether the byte at the offset of the raw text is escaped, that is preceded by an odd
+// number of the escape symbols.
+func isEscaped(s string, i int) bool {
+	escapes := 0
+	for k := i - 1; k >= 0 && s[k] == escapeSymbol; k-- {
+		escapes++
+	}
+	return escapes%2 == 1
+}
@@ -0,0 +1,111 @@
+package sml
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Drolfothesgnir/shitposter/scum"
+)
+
+// PlainTextOptions configures [Poop.PlainText].
+type PlainTextOptions struct {
+	// LinkFootnotes appends the URLs of the links after the text, as the numbered footnotes referenced
+	// after the link texts, like "the docs [1]". A URL linked several times gets a single footnote.
+	LinkFootnotes bool
+
+	// Width wraps the lines longer than Width runes at the spaces, for the places like the email digests.
+	// The words longer than Width are not broken. Zero doesn't wrap the lines.
+	Width int
+}
+
+// PlainText returns the parsed input as plain text, like [Poop.Text], formatted with the options.
+func (p Poop) PlainText(opts PlainTextOptions) string {
+	var footnotes []string
+
+	r := Renderer{tags: p.handlers(), mode: renderText}
+	if opts.LinkFootnotes {
+		r.footnotes = &footnotes
+	}
+	r.Grow(p.AST.TextByteLen)
+	r.Children(&p.Tree)
+
+	text := r.String()
+	if opts.Width > 0 {
+		text = wrapText(text, opts.Width)
+	}
+
+	if len(footnotes) == 0 {
+		return text
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.TrimRight(text, "\n"))
+	b.WriteByte('\n')
+	for i, url := range footnotes {
+		b.WriteString("\n[")
+		b.WriteString(strconv.Itoa(i + 1))
+		b.WriteString("] ")
+		b.WriteString(url)
+	}
+
+	return b.String()
+}
+
+// textLink writes the text of the link, followed by the reference to its footnote if the footnotes are enabled.
+func textLink(r *Renderer, n *scum.SerializableNode) {
+	r.Children(n)
+
+	href, _ := linkAttributes(n)
+	if r.footnotes == nil || href == "" {
+		return
+	}
+
+	idx := slices.Index(*r.footnotes, href)
+	if idx < 0 {
+		*r.footnotes = append(*r.footnotes, href)
+		idx = len(*r.footnotes) - 1
+	}
+
+	r.WriteString(" [")
+	r.WriteString(strconv.Itoa(idx + 1))
+	r.WriteByte(']')
+}
+
+// wrapText breaks the lines of the text longer than width runes at the spaces. The spaces of the broken lines
+// are collapsed.
+func wrapText(text string, width int) string {
+	var b strings.Builder
+	b.Grow(len(text))
+
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+
+		if utf8.RuneCountInString(line) <= width {
+			b.WriteString(line)
+			continue
+		}
+
+		col := 0
+		for _, word := range strings.Fields(line) {
+			n := utf8.RuneCountInString(word)
+
+			switch {
+			case col > 0 && col+1+n > width:
+				b.WriteByte('\n')
+				col = 0
+			case col > 0:
+				b.WriteByte(' ')
+				col++
+			}
+
+			b.WriteString(word)
+			col += n
+		}
+	}
+
+	return b.String()
+}
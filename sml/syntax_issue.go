@@ -11,6 +11,8 @@ type SyntaxIssue interface {
 	Code() int
 	Codename() string
 	Description() string
+	// Span returns the byte range of the input the issue points at.
+	Span() scum.Span
 }
 
 type Issue int
@@ -26,6 +28,14 @@ const (
 	IssueUnknownTag
 	IssueAttributeNotAllowed
 	IssueAttributeInvalidPayload
+	IssueUnknownMention
+	IssueTooManyMentions
+	IssueUnsupportedMarkup
+	IssueTextTooLong
+	IssueTooManyLinks
+	IssueTooManyNodes
+	IssueNestingTooDeep
+	IssueHTMLTooLarge
 
 	maxIssueCode
 )
@@ -45,6 +55,14 @@ func init() {
 	mapIssueToStr[issueIndex(IssueUnknownTag)] = "UNKNOWN_TAG"
 	mapIssueToStr[issueIndex(IssueAttributeNotAllowed)] = "ATTRIBUTE_NOT_ALLOWED"
 	mapIssueToStr[issueIndex(IssueAttributeInvalidPayload)] = "ATTRIBUTE_INVALID_PAYLOAD"
+	mapIssueToStr[issueIndex(IssueUnknownMention)] = "UNKNOWN_MENTION"
+	mapIssueToStr[issueIndex(IssueTooManyMentions)] = "TOO_MANY_MENTIONS"
+	mapIssueToStr[issueIndex(IssueUnsupportedMarkup)] = "UNSUPPORTED_MARKUP"
+	mapIssueToStr[issueIndex(IssueTextTooLong)] = "TEXT_TOO_LONG"
+	mapIssueToStr[issueIndex(IssueTooManyLinks)] = "TOO_MANY_LINKS"
+	mapIssueToStr[issueIndex(IssueTooManyNodes)] = "TOO_MANY_NODES"
+	mapIssueToStr[issueIndex(IssueNestingTooDeep)] = "NESTING_TOO_DEEP"
+	mapIssueToStr[issueIndex(IssueHTMLTooLarge)] = "HTML_TOO_LARGE"
 }
 
 type SyntaxIssueDescriptor struct {
@@ -52,6 +70,11 @@ type SyntaxIssueDescriptor struct {
 	CName string `json:"codename"`
 	// Description is a human-readable description of the issue.
 	Desc string `json:"description"`
+	// Sp is the byte range of the input the issue points at.
+	Sp scum.Span `json:"span"`
+	// Start and End are the positions of the span in the input, set by [Eater.Munch].
+	Start scum.Position `json:"start"`
+	End   scum.Position `json:"end"`
 }
 
 func (i SyntaxIssueDescriptor) String() string {
@@ -70,7 +93,11 @@ func (i SyntaxIssueDescriptor) Description() string {
 	return i.Desc
 }
 
-func NewSyntaxIssueDescriptor(code Issue, desc string) SyntaxIssueDescriptor {
+func (i SyntaxIssueDescriptor) Span() scum.Span {
+	return i.Sp
+}
+
+func NewSyntaxIssueDescriptor(code Issue, span scum.Span, desc string) SyntaxIssueDescriptor {
 	// i don't want to return any errors because i think returning errors from the error factory
 	// is stupid. What do you think?
 	if code < issueCodeBase || code >= maxIssueCode {
@@ -80,6 +107,7 @@ func NewSyntaxIssueDescriptor(code Issue, desc string) SyntaxIssueDescriptor {
 		C:     code,
 		CName: mapIssueToStr[issueIndex(code)],
 		Desc:  desc,
+		Sp:    span,
 	}
 }
 
@@ -103,6 +131,29 @@ func (w Warning) Description() string {
 	return w.SerializableWarning.Description
 }
 
+func (w Warning) Span() scum.Span {
+	return w.SerializableWarning.Span
+}
+
+// locateIssues resolves the spans of the issues into positions in the input.
+func locateIssues(issues []SyntaxIssue, input string) {
+	if len(issues) == 0 {
+		return
+	}
+
+	r := scum.NewPositionResolver(input)
+	for i, issue := range issues {
+		switch v := issue.(type) {
+		case Warning:
+			v.Locate(r)
+			issues[i] = v
+		case SyntaxIssueDescriptor:
+			v.Start, v.End = r.ResolveSpan(v.Sp)
+			issues[i] = v
+		}
+	}
+}
+
 type Issues struct {
 	List []SyntaxIssue `json:"issues"`
 }
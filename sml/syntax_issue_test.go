@@ -3,11 +3,13 @@ package sml
 import (
 	"testing"
 
+	"github.com/Drolfothesgnir/shitposter/scum"
+
 	"github.com/stretchr/testify/require"
 )
 
 func TestNewSyntaxIssueDescriptor_InvalidCodeFallsBackToInternal(t *testing.T) {
-	issue := NewSyntaxIssueDescriptor(Issue(1), "something exploded sideways")
+	issue := NewSyntaxIssueDescriptor(Issue(1), scum.Span{}, "something exploded sideways")
 
 	require.Equal(t, int(IssueInternal), issue.Code())
 	require.Equal(t, "INTERNAL", issue.Codename())
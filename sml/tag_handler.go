@@ -0,0 +1,280 @@
+package sml
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/Drolfothesgnir/shitposter/scum"
+)
+
+// TagHandler defines how the tags with a name are validated and rendered, see [WithTagHandler] and [WithTag].
+// The nil functions fall back to the defaults.
+type TagHandler struct {
+	// Normalize validates the attributes of the tag, drops the invalid ones and adds an issue for each of them.
+	// The children are normalized after it. The default keeps the attributes as they are.
+	Normalize func(n *scum.SerializableNode, issues *Issues)
+
+	// HTML writes the tag as HTML, the children are written with [Renderer.Children], so their text is escaped.
+	// The default writes the input of the tag as escaped text.
+	HTML func(r *Renderer, n *scum.SerializableNode)
+
+	// Text writes the tag as plain text. The default writes the text of the children.
+	Text func(r *Renderer, n *scum.SerializableNode)
+
+	// Markdown writes the tag as CommonMark, the children are written with [Renderer.Children], so their text is escaped.
+	// The default writes the text of the children.
+	Markdown func(r *Renderer, n *scum.SerializableNode)
+
+	// ANSI writes the tag as text styled with the ANSI escape sequences, for the terminals.
+	// The default writes the children.
+	ANSI func(r *Renderer, n *scum.SerializableNode)
+
+	// Verbatim keeps the escapes in the text of the children, for the tags taking their content as written, like [Code].
+	Verbatim bool
+}
+
+// tagHandlers maps the tag names to their handlers.
+type tagHandlers map[string]TagHandler
+
+// builtinTags are the handlers of the SML tags. Each [Eater] gets its own copy.
+var builtinTags = tagHandlers{
+	Bold: {
+		Normalize: normalizeSimpleTag,
+		HTML:      htmlTag("strong", "strong"),
+		Markdown:  markdownTag("**", "**"),
+		ANSI:      ansiTag("1", "22"),
+	},
+	Italic: {
+		Normalize: normalizeSimpleTag,
+		HTML:      htmlTag("em", "em"),
+		Markdown:  markdownTag("*", "*"),
+		ANSI:      ansiTag("3", "23"),
+	},
+	Underline: {
+		Normalize: normalizeSimpleTag,
+		HTML:      htmlTag(`span class="sml-internal-underline"`, "span"),
+		Markdown:  markdownTag("<u>", "</u>"),
+		ANSI:      ansiTag("4", "24"),
+	},
+	Strike: {
+		Normalize: normalizeSimpleTag,
+		HTML:      htmlTag("s", "s"),
+		Markdown:  markdownTag("~~", "~~"),
+		ANSI:      ansiTag("9", "29"),
+	},
+	Spoiler: {
+		Normalize: normalizeSimpleTag,
+		HTML:      htmlTag(spoilerStart, "span"),
+		Markdown:  markdownTag("||", "||"),
+		ANSI:      ansiSpoiler,
+	},
+	Code: {
+		Normalize: normalizeSimpleTag,
+		HTML:      htmlTag("code", "code"),
+		Markdown:  markdownCode,
+		Verbatim:  true,
+	},
+	Superscript: {
+		Normalize: normalizeSimpleTag,
+		HTML:      htmlTag("sup", "sup"),
+		Markdown:  markdownTag("<sup>", "</sup>"),
+		ANSI:      ansiSuperscript,
+	},
+	Link: {
+		Normalize: normalizeLink,
+		HTML:      renderLink,
+		Text:      textLink,
+		Markdown:  markdownLink,
+		ANSI:      ansiLink,
+	},
+	// the mentions are created after the normalization with the valid attributes only
+	Mention: {
+		HTML:     renderLink,
+		Markdown: markdownLink,
+		ANSI:     ansiLink,
+	},
+	Emoji: {
+		HTML:     renderEmoji,
+		Text:     textEmoji,
+		Markdown: markdownEmoji,
+		ANSI:     textEmoji,
+	},
+}
+
+// WithTagHandler registers the handler of the tags with the name, replacing the built-in one if there is any.
+func WithTagHandler(name string, h TagHandler) Option {
+	return func(e *Eater) {
+		e.tags[name] = h
+	}
+}
+
+// WithTag adds the tag to the SML dialect, with the handler of its name. The tags with the same name,
+// like the opening and the closing tags of a link, share the handler. If the tag can't be added to the
+// dialect, [NewEater] returns a *[ConfigError].
+func WithTag(spec scum.TagSpec, h TagHandler) Option {
+	return func(e *Eater) {
+		e.extraTags = append(e.extraTags, spec)
+		e.tags[spec.Name] = h
+	}
+}
+
+// renderMode is the rendition written by a [Renderer].
+type renderMode int
+
+const (
+	renderHTML renderMode = iota
+	renderText
+	renderMarkdown
+	renderANSI
+)
+
+// Renderer writes the tree as HTML, Markdown, plain text or ANSI-styled text, dispatching the tags to their [TagHandler].
+type Renderer struct {
+	strings.Builder
+	tags tagHandlers
+	mode renderMode
+
+	// footnotes collects the URLs of the links in the plain text, see [PlainTextOptions.LinkFootnotes].
+	footnotes *[]string
+
+	// midLine is true for the Renderer of the content which doesn't start a line, see [Renderer.sub].
+	midLine bool
+}
+
+// Node writes the node.
+func (r *Renderer) Node(n *scum.SerializableNode) {
+	if n.Type == "Tag" {
+		r.tag(n)
+		return
+	}
+
+	// the text and the nodes of the unknown types, which are left by the normalization as text
+	r.Text(n.Content)
+}
+
+// Children writes the children of the node.
+func (r *Renderer) Children(n *scum.SerializableNode) {
+	for i := range n.Children {
+		r.Node(&n.Children[i])
+	}
+}
+
+// Text writes the text, escaped when rendering HTML or Markdown. The control characters are replaced
+// when rendering ANSI, so the text can't inject escape sequences of its own.
+func (r *Renderer) Text(s string) {
+	switch r.mode {
+	case renderHTML:
+		r.WriteString(html.EscapeString(s))
+	case renderMarkdown:
+		r.markdownText(s)
+	case renderANSI:
+		r.ansiText(s)
+	default:
+		r.WriteString(s)
+	}
+}
+
+// sub returns the Renderer writing the content of a tag in the mode on its own, for the handlers which
+// need to transform it as a whole.
+func (r *Renderer) sub(mode renderMode) Renderer {
+	return Renderer{
+		tags:      r.tags,
+		mode:      mode,
+		footnotes: r.footnotes,
+		midLine:   !r.atLineStart(),
+	}
+}
+
+// atLineStart reports whether the output is at the start of a line.
+func (r *Renderer) atLineStart() bool {
+	if r.Len() == 0 {
+		return !r.midLine
+	}
+
+	s := r.String()
+	return s[len(s)-1] == '\n'
+}
+
+func (r *Renderer) tag(n *scum.SerializableNode) {
+	h, ok := r.tags[n.Name]
+	if !ok {
+		// the normalization turns the unknown tags into text, this is for the trees built by hand
+		r.Text(n.Content)
+		return
+	}
+
+	switch r.mode {
+	case renderText:
+		if h.Text != nil {
+			h.Text(r, n)
+			return
+		}
+		r.Children(n)
+
+	case renderMarkdown:
+		if h.Markdown != nil {
+			h.Markdown(r, n)
+			return
+		}
+		r.Children(n)
+
+	case renderANSI:
+		if h.ANSI != nil {
+			h.ANSI(r, n)
+			return
+		}
+		r.Children(n)
+
+	default:
+		if h.HTML != nil {
+			h.HTML(r, n)
+			return
+		}
+		r.Text(n.Content)
+	}
+}
+
+// htmlTag returns the HTML renderer wrapping the children in the element with the start and end tags.
+func htmlTag(start, end string) func(r *Renderer, n *scum.SerializableNode) {
+	return func(r *Renderer, n *scum.SerializableNode) {
+		r.WriteByte('<')
+		r.WriteString(start)
+		r.WriteByte('>')
+		r.Children(n)
+		r.WriteString("</")
+		r.WriteString(end)
+		r.WriteByte('>')
+	}
+}
+
+// normalizeNode normalizes the node with the handler of its tag, and then its children.
+// The unknown tags and node types are turned into text with an issue.
+func normalizeNode(n *scum.SerializableNode, tags tagHandlers, issues *Issues) {
+	switch n.Type {
+	case "Text":
+		normalizeText(n, issues)
+		return
+
+	case "Tag":
+		h, ok := tags[n.Name]
+		if !ok {
+			issues.Add(NewSyntaxIssueDescriptor(IssueUnknownTag, n.Span, fmt.Sprintf("unknown tag %q is rendered as text", n.Name)))
+			*n = textNode(n.Content, n.Span)
+			return
+		}
+
+		if h.Normalize != nil {
+			h.Normalize(n, issues)
+		}
+
+	default:
+		issues.Add(NewSyntaxIssueDescriptor(IssueUnknownNodeType, n.Span, fmt.Sprintf("unknown node type %q is rendered as text", n.Type)))
+		*n = textNode(n.Content, n.Span)
+		return
+	}
+
+	for i := range n.Children {
+		normalizeNode(&n.Children[i], tags, issues)
+	}
+}
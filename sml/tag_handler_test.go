@@ -0,0 +1,92 @@
+package sml
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Drolfothesgnir/shitposter/scum"
+	"github.com/stretchr/testify/require"
+)
+
+var highlightSpec = scum.TagSpec{Name: "HIGHLIGHT", Seq: "==", OpenID: "=", CloseID: "="}
+
+func TestWithTag_AddsTagToDialect(t *testing.T) {
+	eater, err := NewEater(scum.WarnOverflowNoCap, 0, WithTag(highlightSpec, TagHandler{
+		Normalize: normalizeSimpleTag,
+		HTML:      htmlTag("mark", "mark"),
+	}))
+	require.NoError(t, err)
+
+	poop, issues := eater.Munch("a ==b $c$== <d>")
+
+	require.Empty(t, issues)
+	require.Equal(t, "a <mark>b <strong>c</strong></mark> &lt;d&gt;", poop.HTML())
+	require.Equal(t, "a b c <d>", poop.Text())
+
+	// the handlers are used by the Poop after it's copied
+	cp := poop
+	require.Equal(t, poop.HTML(), cp.HTML())
+
+	_, issues = eater.Munch("==x==!class{y}")
+	require.Len(t, issues, 1)
+	require.Equal(t, "ATTRIBUTE_NOT_ALLOWED", issues[0].Codename())
+}
+
+func TestWithTag_InvalidSpec(t *testing.T) {
+	_, err := NewEater(scum.WarnOverflowNoCap, 0, WithTag(scum.TagSpec{Name: "DOLLAR", Seq: "$$"}, TagHandler{}))
+	require.Error(t, err)
+
+	var configErr *ConfigError
+	require.True(t, errors.As(err, &configErr))
+	require.Equal(t, ReasonInvalidParams, configErr.Reason)
+}
+
+func TestWithTag_DefaultHandlerRendersSource(t *testing.T) {
+	eater, err := NewEater(scum.WarnOverflowNoCap, 0, WithTag(highlightSpec, TagHandler{}))
+	require.NoError(t, err)
+
+	poop, issues := eater.Munch("==<b>==!x{1}")
+
+	require.Empty(t, issues)
+	require.Equal(t, "==&lt;b&gt;==", poop.HTML())
+	require.Equal(t, "<b>", poop.Text())
+}
+
+func TestWithTagHandler_ReplacesBuiltinHandler(t *testing.T) {
+	hidden := TagHandler{
+		Normalize: normalizeSimpleTag,
+		HTML:      htmlTag(spoilerStart, "span"),
+		Text: func(r *Renderer, _ *scum.SerializableNode) {
+			r.WriteString("[spoiler]")
+		},
+	}
+	eater, err := NewEater(scum.WarnOverflowNoCap, 0, WithTagHandler(Spoiler, hidden))
+	require.NoError(t, err)
+
+	poop, _ := eater.Munch("the butler ||did it||")
+	require.Equal(t, "the butler [spoiler]", poop.Text())
+	require.Contains(t, poop.HTML(), ">did it</span>")
+
+	// the other Eaters keep the built-in handlers
+	plain, err := NewEater(scum.WarnOverflowNoCap, 0)
+	require.NoError(t, err)
+
+	poop, _ = plain.Munch("the butler ||did it||")
+	require.Equal(t, "the butler did it", poop.Text())
+}
+
+func TestEaterMunch_TagWithoutHandlerDegradesToText(t *testing.T) {
+	dropStrike := func(e *Eater) {
+		delete(e.tags, Strike)
+	}
+	eater, err := NewEater(scum.WarnOverflowNoCap, 0, dropStrike)
+	require.NoError(t, err)
+
+	input := "~~<a>~~ $b$"
+	poop, issues := eater.Munch(input)
+
+	require.Equal(t, "~~&lt;a&gt;~~ <strong>b</strong>", poop.HTML())
+	require.Len(t, issues, 1)
+	require.Equal(t, "UNKNOWN_TAG", issues[0].Codename())
+	require.Equal(t, "~~<a>~~", input[issues[0].Span().Start:issues[0].Span().End])
+}
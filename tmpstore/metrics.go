@@ -0,0 +1,50 @@
+package tmpstore
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Drolfothesgnir/shitposter/metrics"
+	"github.com/redis/go-redis/v9"
+)
+
+var redisCommandDuration = metrics.Default.NewHistogram(
+	"redis_command_duration_seconds",
+	"Latency of Redis commands by command name and outcome.",
+	[]float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	"command", "outcome",
+)
+
+// metricsHook is a go-redis hook which records the latency of every command.
+type metricsHook struct{}
+
+func (metricsHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (metricsHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		redisCommandDuration.ObserveSince(start, cmd.Name(), commandOutcome(err))
+		return err
+	}
+}
+
+func (metricsHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		redisCommandDuration.ObserveSince(start, "pipeline", commandOutcome(err))
+		return err
+	}
+}
+
+// commandOutcome classifies the command result. A missing key is not a failure.
+func commandOutcome(err error) string {
+	if err == nil || errors.Is(err, redis.Nil) {
+		return "ok"
+	}
+	return "error"
+}
@@ -42,6 +42,20 @@ func (m *MockStore) EXPECT() *MockStoreMockRecorder {
 	return m.recorder
 }
 
+// Close mocks base method.
+func (m *MockStore) Close() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Close")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockStoreMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockStore)(nil).Close))
+}
+
 // DeleteUserAuthSession mocks base method.
 func (m *MockStore) DeleteUserAuthSession(ctx context.Context, sessionID string) error {
 	m.ctrl.T.Helper()
@@ -100,6 +114,20 @@ func (mr *MockStoreMockRecorder) GetUserRegSession(ctx, sessionID any) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserRegSession", reflect.TypeOf((*MockStore)(nil).GetUserRegSession), ctx, sessionID)
 }
 
+// Ping mocks base method.
+func (m *MockStore) Ping(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockStoreMockRecorder) Ping(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockStore)(nil).Ping), ctx)
+}
+
 // SaveUserAuthSession mocks base method.
 func (m *MockStore) SaveUserAuthSession(ctx context.Context, sessionID string, data tmpstore.PendingAuthentication, ttl time.Duration) error {
 	m.ctrl.T.Helper()
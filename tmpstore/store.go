@@ -42,6 +42,11 @@ type Store interface {
 	SaveUserAuthSession(ctx context.Context, sessionID string, data PendingAuthentication, ttl time.Duration) error
 	GetUserAuthSession(ctx context.Context, sessionID string) (*PendingAuthentication, error)
 	DeleteUserAuthSession(ctx context.Context, sessionID string) error
+	// Ping checks that Redis is reachable.
+	Ping(ctx context.Context) error
+	// Close releases the underlying client connections.
+	// The store must not be used after it's closed.
+	Close() error
 }
 
 type RedisStore struct {
@@ -56,6 +61,8 @@ func NewStore(config *util.Config) Store {
 		DB:       0,                   // 0 for default database
 	})
 
+	rdb.AddHook(metricsHook{})
+
 	return &RedisStore{client: rdb}
 }
 
@@ -147,3 +154,13 @@ func (store *RedisStore) DeleteUserAuthSession(ctx context.Context, sessionID st
 	key := PendingAuthenticationPrefix + sessionID
 	return store.client.Del(ctx, key).Err()
 }
+
+// Ping checks that Redis is reachable.
+func (store *RedisStore) Ping(ctx context.Context) error {
+	return store.client.Ping(ctx).Err()
+}
+
+// Close closes the Redis client and its connection pool.
+func (store *RedisStore) Close() error {
+	return store.client.Close()
+}
@@ -14,6 +14,7 @@ type Config struct {
 	DBSource                   string        `mapstructure:"DB_SOURCE"`
 	MigrationURL               string        `mapstructure:"MIGRATION_URL"`
 	HTTPServerAddress          URLString     `mapstructure:"HTTP_SERVER_ADDRESS"`
+	AdminServerAddress         URLString     `mapstructure:"ADMIN_SERVER_ADDRESS"`
 	PublicOrigin               URLString     `mapstructure:"PUBLIC_ORIGIN"`
 	RPDisplayName              string        `mapstructure:"RP_DISPLAY_NAME"`
 	AllowedOrigins             []string      `mapstructure:"ALLOWED_ORIGINS"`
@@ -28,6 +29,7 @@ type Config struct {
 	RefreshTokenDuration       time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
 	CommentMaxNestingDepth     int32         `mapstructure:"COMMENT_MAX_NESTING_DEPTH"`
 	CommentMaxRootCountPerUser int64         `mapstructure:"COMMENT_MAX_ROOT_COUNT_PER_USER"`
+	ShutdownDrainDelay         time.Duration `mapstructure:"SHUTDOWN_DRAIN_DELAY"`
 }
 
 func LoadConfig(path string) (config Config, err error) {